@@ -8,7 +8,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/permafrost-dev/zeget/lib/assets"
+	"github.com/permafrost-dev/zeget/lib/credentials"
 	"github.com/permafrost-dev/zeget/lib/home"
+	"github.com/permafrost-dev/zeget/lib/utilities"
 	pb "github.com/schollz/progressbar/v3"
 )
 
@@ -26,19 +29,81 @@ func tokenFrom(s string) (string, error) {
 	return s, nil
 }
 
+// secretFrom expands environment variables in s and then reads it using tokenFrom,
+// so configuration values may be given as "$VAR", "@/path/to/file" or literally.
+func secretFrom(s string) (string, error) {
+	return tokenFrom(os.ExpandEnv(s))
+}
+
 var ErrNoToken = errors.New("no github token")
 
-func getGithubToken() (string, error) {
-	if os.Getenv("ZEGET_GITHUB_TOKEN") != "" {
-		return tokenFrom(os.Getenv("EGET_GITHUB_TOKEN"))
+// githubTokenSources returns the locations a GitHub token is read from, in
+// order of precedence.
+func (app *Application) githubTokenSources() []credentials.TokenSource {
+	env := func(name string) credentials.TokenSource {
+		return credentials.TokenSource{
+			Name:    name,
+			Resolve: func() (string, error) { return tokenFrom(os.Getenv(name)) },
+		}
 	}
-	if os.Getenv("EGET_GITHUB_TOKEN") != "" {
-		return tokenFrom(os.Getenv("EGET_GITHUB_TOKEN"))
+
+	global := ConfigGlobal{}
+	if app.Config != nil {
+		global = app.Config.Global
 	}
-	if os.Getenv("GITHUB_TOKEN") != "" {
-		return tokenFrom(os.Getenv("GITHUB_TOKEN"))
+
+	return []credentials.TokenSource{
+		env("ZEGET_GITHUB_TOKEN"),
+		env("EGET_GITHUB_TOKEN"),
+		{
+			Name:    "config github_token",
+			Resolve: func() (string, error) { return secretFrom(global.GithubToken) },
+		},
+		env("GITHUB_TOKEN"),
+		env("GH_TOKEN"),
+		{
+			Name:    "config token_command",
+			Resolve: func() (string, error) { return credentials.RunTokenCommand(global.TokenCommand) },
+		},
+		{
+			Name:    "gh hosts.yml",
+			Resolve: func() (string, error) { return credentials.GhHostsToken("github.com") },
+		},
+		{
+			Name: "git credential helper",
+			Resolve: func() (string, error) {
+				return credentials.GitCredentialToken(credentials.RunGitCredentialFill, "github.com")
+			},
+		},
 	}
-	return "", ErrNoToken
+}
+
+// GithubToken resolves the GitHub token once per run, reporting each source
+// that was consulted when --verbose is used.
+func (app *Application) GithubToken() (string, error) {
+	if app.githubTokenResolved {
+		return app.githubToken, utilities.SetIf(app.githubToken == "", nil, ErrNoToken)
+	}
+
+	token, _, err := credentials.ResolveToken(app.githubTokenSources(), func(source string, found bool, err error) {
+		switch {
+		case err != nil:
+			app.WriteVerboseLine("› github token: %s failed: %v", source, err)
+		case found:
+			app.WriteVerboseLine("› github token: using %s", source)
+		default:
+			app.WriteVerboseLine("› github token: %s not set", source)
+		}
+	})
+
+	app.githubToken = token
+	app.githubTokenResolved = true
+
+	if err != nil {
+		return "", ErrNoToken
+	}
+
+	return token, nil
 }
 
 func (app *Application) getDownloadProgressBar(size int64) *pb.ProgressBar {
@@ -71,10 +136,20 @@ func (app *Application) getDownloadProgressBar(size int64) *pb.ProgressBar {
 		}))
 }
 
-// Download the file at 'url' and write the http response body to 'out'. The
-// 'getbar' function allows the caller to construct a progress bar given the
-// size of the file being downloaded, and the download will write to the
-// returned progress bar.
-func (app *Application) Download(url string, out io.Writer) error {
-	return app.DownloadClient().Download(url, out, app.getDownloadProgressBar)
+// getAssetProgressBar returns a progress bar constructor that falls back to the
+// size reported by the release when the server does not send a Content-Length.
+func (app *Application) getAssetProgressBar(asset *assets.Asset) func(int64) *pb.ProgressBar {
+	return func(size int64) *pb.ProgressBar {
+		if size <= 0 && asset.Size > 0 {
+			size = asset.Size
+		}
+
+		return app.getDownloadProgressBar(size)
+	}
+}
+
+// Download the asset and write the http response body to 'out', showing a
+// progress bar sized from the response or the asset metadata.
+func (app *Application) Download(asset *assets.Asset, out io.Writer) error {
+	return app.DownloadClient().Download(app.assetURL(*asset), out, app.getAssetProgressBar(asset))
 }
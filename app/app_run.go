@@ -2,6 +2,7 @@ package app
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	. "github.com/permafrost-dev/zeget/lib/assets"
@@ -19,6 +20,13 @@ func (app *Application) Run() *ReturnStatus {
 		return returnStatus
 	}
 
+	if target == "search" {
+		var err error
+		if target, err = app.searchTarget(strings.Join(app.Args[1:], " ")); err != nil {
+			return NewReturnStatus(FatalError, err, fmt.Sprintf("error: %v", err))
+		}
+	}
+
 	if err := app.targetToProject(target); err != nil {
 		return NewReturnStatus(FatalError, err, fmt.Sprintf("error: %v", err))
 	}
@@ -56,23 +64,31 @@ func (app *Application) Run() *ReturnStatus {
 		return result
 	}
 
-	assetWrapper.Asset = &detected.Asset
+	selected := []*Asset{&detected.Asset}
 
 	if len(detected.Candidates) != 0 {
-		assetWrapper.Asset, err = app.selectFromMultipleAssets(detected.Candidates, err) // manually select which asset to download
+		selected, err = app.selectFromMultipleAssets(detected.Candidates, err) // manually select which assets to download
 		if err != nil {
 			return NewReturnStatus(FatalError, err, fmt.Sprintf("error: %v", err))
 		}
 	}
 
-	body, result := app.DownloadAndVerify(assetWrapper, findResult)
-	if result != nil {
-		return result
-	}
+	var body []byte
+	extractedCount := 0
 
-	extractedCount, result := app.ExtractDownloadedAsset(assetWrapper, body, finder)
-	if result != nil {
-		return result
+	for _, asset := range selected {
+		assetWrapper.Asset = asset
+
+		var result *ReturnStatus
+		if body, result = app.DownloadAndVerify(assetWrapper, findResult); result != nil {
+			return result
+		}
+
+		count, result := app.ExtractDownloadedAsset(assetWrapper, body, finder)
+		if result != nil {
+			return result
+		}
+		extractedCount += count
 	}
 
 	cacheItem.Filters = assetWrapper.Asset.Filters
@@ -10,6 +10,9 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,17 +20,23 @@ import (
 	. "github.com/permafrost-dev/zeget/lib/appflags"
 	"github.com/permafrost-dev/zeget/lib/assets"
 	. "github.com/permafrost-dev/zeget/lib/assets"
+	"github.com/permafrost-dev/zeget/lib/credentials"
 	"github.com/permafrost-dev/zeget/lib/data"
 	"github.com/permafrost-dev/zeget/lib/detectors"
 	"github.com/permafrost-dev/zeget/lib/download"
+	zerrors "github.com/permafrost-dev/zeget/lib/errors"
 	. "github.com/permafrost-dev/zeget/lib/extraction"
 	"github.com/permafrost-dev/zeget/lib/filters"
 	"github.com/permafrost-dev/zeget/lib/finders"
 	"github.com/permafrost-dev/zeget/lib/github"
 	. "github.com/permafrost-dev/zeget/lib/globals"
+	"github.com/permafrost-dev/zeget/lib/goproxy"
 	"github.com/permafrost-dev/zeget/lib/home"
+	"github.com/permafrost-dev/zeget/lib/oci"
+	"github.com/permafrost-dev/zeget/lib/picker"
 	"github.com/permafrost-dev/zeget/lib/registry"
 	"github.com/permafrost-dev/zeget/lib/reporters"
+	"github.com/permafrost-dev/zeget/lib/sources"
 	"github.com/permafrost-dev/zeget/lib/utilities"
 	. "github.com/permafrost-dev/zeget/lib/utilities"
 	"github.com/permafrost-dev/zeget/lib/verifiers"
@@ -51,12 +60,27 @@ type Application struct {
 	Cache       data.Cache
 	Filesystem  vfs.FS
 	Reference   *RepositoryReference
+	Actions     *ActionsReference
+	OCI         *oci.Reference
+	GoModule    *goproxy.Reference
+	Index       *finders.IndexFinder
 	Registry    *registry.LockFile
 	Target      string
 	TargetFound bool
+	mirrors     []*download.Mirror
+	credentials *credentials.Store
+	limits      Limits
+	assetExpr   *filters.CELFilter
+	// outputDir is set once several files are selected, so that --to names
+	// the directory they are written to, as with --all
+	outputDir bool
+
+	githubToken         string
+	githubTokenResolved bool
 }
 
 var ErrNoTargetGiven = errors.New("no target given")
+var ErrActionsNeedToken = errors.New("downloading workflow artifacts requires a GitHub token")
 var ErrSuccess = errors.New("success")
 
 func NewApplicationOutputs(stdout io.Writer, stderr io.Writer) *ApplicationOutputs {
@@ -92,6 +116,7 @@ func NewApplication(outputs *ApplicationOutputs) *Application {
 		Outputs:    outputs,
 		Filesystem: vf,
 		Registry:   &registryLockFile,
+		limits:     BuiltinLimits,
 	}
 
 	result.initOutputs()
@@ -104,8 +129,8 @@ func (app *Application) ToolName() string {
 }
 
 func (app *Application) DownloadClient() *download.Client {
-	token, _ := getGithubToken()
-	return download.NewClient(token)
+	token, _ := app.GithubToken()
+	return download.NewClient(token).SetMirrors(app.mirrors).SetCredentials(app.credentials)
 }
 
 func (app *Application) RunSetup(_ ProcessFlagsErrorHandlerFunc) (string, *ReturnStatus) {
@@ -172,11 +197,82 @@ func (app *Application) cacheTarget(finding *finders.ValidFinder, findResult *fi
 	return item
 }
 
+// configRepository returns the configuration section for the target, if any.
+func (app *Application) configRepository(target string) (ConfigRepository, bool) {
+	if app.Config == nil {
+		return ConfigRepository{}, false
+	}
+
+	repo, found := app.Config.Repositories[target]
+	return repo, found
+}
+
 func (app *Application) targetToProject(target string) error {
 	var err error
 
 	app.Target = target
 	app.TargetFound = false
+	app.Actions = nil
+	app.OCI = nil
+	app.GoModule = nil
+	app.Index = nil
+
+	repo, _ := app.configRepository(target)
+	def := repo.Definition()
+	if builtin, found := sources.Lookup(target); found {
+		def = builtin.Merge(def)
+	}
+
+	if !def.IsEmpty() {
+		if app.Index, err = def.Finder(); err != nil {
+			return err
+		}
+
+		app.Reference = &RepositoryReference{Owner: path.Dir(target), Name: SetIf(repo.Name == "", repo.Name, path.Base(target))}
+
+		return nil
+	}
+
+	if goproxy.IsReference(target) {
+		if app.GoModule, err = goproxy.ParseReference(target); err != nil {
+			return err
+		}
+
+		app.Reference = &RepositoryReference{Owner: path.Dir(app.GoModule.Module), Name: app.GoModule.ToolName()}
+
+		return nil
+	}
+
+	if oci.IsReference(target) {
+		if app.OCI, err = oci.ParseReference(target); err != nil {
+			return err
+		}
+
+		app.Reference = &RepositoryReference{Owner: path.Dir(app.OCI.Repository), Name: app.OCI.Name()}
+
+		return nil
+	}
+
+	if IsActionsReference(target) {
+		if app.Actions, err = ParseActionsReference(target); err != nil {
+			return err
+		}
+
+		if token, _ := app.GithubToken(); token == "" {
+			return ErrActionsNeedToken
+		}
+
+		app.Reference = &app.Actions.Repository
+
+		return nil
+	}
+
+	// bare names such as "ripgrep" are looked up with the GitHub search API
+	if isBareName(target) {
+		if app.Target, err = app.searchTarget(target); err != nil {
+			return err
+		}
+	}
 
 	if app.Reference, err = ParseRepositoryReference(app.Target); err != nil {
 		return err
@@ -185,37 +281,122 @@ func (app *Application) targetToProject(target string) error {
 	return nil
 }
 
-// if multiple candidates are returned, the user must select manually which one to download
-func (app *Application) selectFromMultipleAssets(candidates []Asset, err error) (*Asset, error) {
+func isBareName(target string) bool {
+	return !strings.Contains(target, "/") && !IsURL(target) && !IsLocalFile(target) && github.IsValidSearchQuery(target)
+}
+
+// searchTarget searches GitHub for the query and has the user pick one of the
+// repositories found, returning it as "owner/repo".
+func (app *Application) searchTarget(query string) (string, error) {
+	if strings.TrimSpace(query) == "" {
+		return "", zerrors.EmptyCLIInputError{}
+	}
+
+	detector, err := detectors.DetermineCorrectDetector(&app.Opts, app.Config.Global.IgnorePatterns, nil)
+	if err != nil {
+		return "", err
+	}
+
+	search := finders.GithubSearch{
+		Query: query,
+		Match: func(assets []Asset) bool {
+			_, err := detector.Detect(assets)
+			return err == nil
+		},
+	}
+
+	results, err := search.Search(app.DownloadClient())
+	if err != nil {
+		return "", err
+	}
+
+	if len(results) == 1 {
+		app.WriteVerboseLine("› found %s", results[0].Repository.FullName)
+		return results[0].Repository.FullName, nil
+	}
+
 	if app.cli.NoInteraction || app.Opts.NoInteraction {
-		return &Asset{}, fmt.Errorf("error: multiple candidates found, cannot select automatically (user interaction disabled)")
+		return "", fmt.Errorf("error: %d repositories found for '%s', cannot select automatically (user interaction disabled)", len(results), query)
+	}
+
+	app.WriteErrorLine("repositories matching '%s':", query)
+
+	choices := make([]interface{}, len(results))
+	for i := range results {
+		choices[i] = results[i]
+	}
+
+	choice, err := app.userSelect(choices)
+	if err != nil {
+		return "", err
+	}
+
+	return results[choice-1].Repository.FullName, nil
+}
+
+// assetLabel returns the name of the asset followed by its known metadata.
+func assetLabel(asset Asset) string {
+	if summary := asset.Summary(); summary != "" {
+		return fmt.Sprintf("%s (%s)", path.Base(asset.Name), summary)
+	}
+
+	return path.Base(asset.Name)
+}
+
+// if multiple candidates are returned, the user must select manually which ones to download
+func (app *Application) selectFromMultipleAssets(candidates []Asset, err error) ([]*Asset, error) {
+	if app.cli.NoInteraction || app.Opts.NoInteraction {
+		return nil, fmt.Errorf("error: multiple candidates found, cannot select automatically (user interaction disabled)")
 	}
 
 	app.WriteErrorLine("%v: please select manually", err)
 	choices := make([]interface{}, len(candidates))
 
 	for i := range candidates {
-		choices[i] = path.Base(candidates[i].Name)
+		choices[i] = assetLabel(candidates[i])
 	}
 
-	choice, err := app.userSelect(choices)
+	selection, err := app.userSelectMany(choices)
 	if err != nil {
-		return &Asset{}, fmt.Errorf("error: %v", err)
+		return nil, fmt.Errorf("error: %v", err)
+	}
+
+	result := make([]*Asset, 0, len(selection))
+	names := make([]string, 0, len(selection))
+
+	for _, choice := range selection {
+		asset := candidates[choice-1]
+		result = append(result, &asset)
+		names = append(names, path.Base(asset.Name))
+	}
+
+	// remember the filters shared by all selected assets
+	filters := commonAssetFilters(names)
+	for _, asset := range result {
+		asset.Filters = filters
 	}
+	app.outputDir = app.outputDir || len(result) > 1
 
-	choiceStr := fmt.Sprintf("%s", choices[choice-1])
+	return result, nil
+}
 
-	result := candidates[choice-1]
-	result.Filters = utilities.FilenameToAssetFilters(choiceStr)
+func commonAssetFilters(names []string) []string {
+	result := utilities.FilenameToAssetFilters(names[0])
 
-	return &result, nil
+	for _, name := range names[1:] {
+		filters := utilities.FilenameToAssetFilters(name)
+		result = FilterArr(result, func(f string) bool {
+			return IsInArr(filters, f, func(a string, b string) bool { return a == b })
+		})
+	}
 
+	return result
 }
 
 // if there are multiple candidates, have the user select manually
-func (app *Application) selectFromMultipleCandidates(bin ExtractedFile, bins []ExtractedFile, err error) (ExtractedFile, error) {
+func (app *Application) selectFromMultipleCandidates(bin ExtractedFile, bins []ExtractedFile, err error) ([]ExtractedFile, error) {
 	if app.cli.NoInteraction || app.Opts.NoInteraction {
-		return ExtractedFile{}, fmt.Errorf("error: multiple assets found, cannot prompt user for selection (user interaction disabled)")
+		return nil, fmt.Errorf("error: multiple assets found, cannot prompt user for selection (user interaction disabled)")
 	}
 
 	app.WriteErrorLine("%v: please select manually", err)
@@ -226,19 +407,23 @@ func (app *Application) selectFromMultipleCandidates(bin ExtractedFile, bins []E
 	}
 
 	choices[len(bins)] = "all"
-	choice, err := app.userSelect(choices)
+	selection, err := app.userSelectMany(choices)
 
 	if err != nil {
-		return ExtractedFile{}, err
+		return nil, err
 	}
 
-	if choice == len(bins)+1 {
-		app.Opts.All = true
-	} else {
-		return bins[choice-1], nil
+	result := make([]ExtractedFile, 0, len(selection))
+	for _, choice := range selection {
+		if choice == len(bins)+1 {
+			app.Opts.All = true
+			return []ExtractedFile{bin}, nil
+		}
+		result = append(result, bins[choice-1])
 	}
+	app.outputDir = app.outputDir || len(result) > 1
 
-	return bin, nil
+	return result, nil
 }
 
 func (app *Application) RateLimitExceeded() error {
@@ -376,19 +561,48 @@ func (app *Application) ProcessFlags(errorHandler ProcessFlagsErrorHandlerFunc)
 
 	app.Opts.Filters = []*filters.Filter{}
 	if app.cli.Filters != nil {
-		app.Opts.Filters = filters.NewParser().ParseDefinitions(*app.cli.Filters)
+		parser := filters.NewParser().WithSystemMatcher(detectors.SystemMatcher{})
+		if app.Opts.Filters, err = parser.ParseDefinitions(*app.cli.Filters); err != nil {
+			errorHandler(err)
+			return "", err
+		}
+	}
+
+	app.assetExpr = nil
+	if app.Opts.AssetExpr != "" {
+		if app.assetExpr, err = filters.NewCELFilter(app.Opts.AssetExpr); err != nil {
+			errorHandler(err)
+			return "", err
+		}
+		app.Opts.Filters = append(app.Opts.Filters, app.assetExpr.Filter(app.targetSystem()))
 	}
 
 	return target, nil
 }
 
+// assetURL returns the URL to download an asset from. When a GitHub token is
+// available the release asset API URL is preferred, as it also works for
+// private repositories where the browser download URL returns a 404, unless a
+// mirror serves the browser download URL.
+func (app *Application) assetURL(asset Asset) string {
+	if asset.APIURL == "" || download.FindMirror(app.mirrors, asset.DownloadURL) != nil {
+		return asset.DownloadURL
+	}
+
+	if token, _ := app.GithubToken(); token == "" {
+		return asset.DownloadURL
+	}
+
+	return asset.APIURL
+}
+
 func (app *Application) downloadAsset(asset *Asset, findResult *finders.FindResult) ([]byte, error) {
 	buf := &bytes.Buffer{}
 
 	repo, _ := app.Cache.AddRepository(asset.Name, "", []string{}, findResult, time.Now().Add(time.Hour*1))
 	repo.UpdateCheckedAt()
 
-	if err := app.Download(asset.DownloadURL, buf); err != nil {
+	if err := app.Download(asset, buf); err != nil {
 		return []byte{}, fmt.Errorf("%s (URL: %s)", err, asset.DownloadURL)
 	}
 
@@ -466,7 +680,7 @@ func (app *Application) extract(bin ExtractedFile) error {
 		out = filepath.Join(app.Opts.Output, out)
 	}
 
-	if app.Opts.Output != "" && !IsDirectory(app.Opts.Output) && app.Opts.All {
+	if app.Opts.Output != "" && app.Opts.Output != "-" && !IsDirectory(app.Opts.Output) && (app.Opts.All || app.outputDir) {
 		os.MkdirAll(app.Opts.Output, 0755)
 		out = filepath.Join(app.Opts.Output, out)
 	}
@@ -508,6 +722,37 @@ func (app *Application) getFinder() finders.ValidFinder {
 		return finders.NewValidFinder(found, toolName)
 	}
 
+	if app.OCI != nil {
+		if app.credentials == nil {
+			app.credentials = credentials.NewStore()
+		}
+
+		goos, goarch := app.targetSystem()
+		return finders.NewValidFinder(finders.NewOCIFinder(app.OCI, goos, goarch, app.credentials), app.ToolName())
+	}
+
+	if app.Index != nil {
+		finder := *app.Index
+		finder.Version = SetIf(app.Opts.Tag != "", "latest", app.Opts.Tag)
+		finder.Prerelease = app.Opts.Prerelease
+		finder.OS, finder.Arch = app.targetSystem()
+
+		return finders.NewValidFinder(finder, app.ToolName())
+	}
+
+	if app.GoModule != nil {
+		var mint time.Time
+		if app.Opts.UpgradeOnly {
+			mint = Bintime(app.ToolName(), app.Opts.Output)
+		}
+
+		return finders.NewValidFinder(finders.NewGoModuleFinder(app.GoModule, app.Opts.Prerelease, mint), app.ToolName())
+	}
+
+	if app.Actions != nil {
+		return finders.NewValidFinder(finders.NewGithubActionsFinder(app.Actions), app.ToolName())
+	}
+
 	if app.Opts.Source {
 		tag := SetIf(app.Opts.Tag != "", "main", app.Opts.Tag)
 		result := finders.GithubSourceFinder{Repo: app.Reference.String(), Tag: tag, Tool: app.ToolName()}
@@ -527,6 +772,21 @@ func (app *Application) getFinder() finders.ValidFinder {
 	return finders.NewValidFinder(result, app.ToolName())
 }
 
+// targetSystem returns the OS/Arch pair assets are selected for, or empty
+// strings when --system is "all".
+func (app *Application) targetSystem() (string, string) {
+	if app.Opts.System == "all" {
+		return "", ""
+	}
+
+	// the system may also name a libc or an arch level, as in "linux/armv7/musl"
+	if parts := strings.Split(app.Opts.System, "/"); len(parts) > 1 {
+		return parts[0], detectors.BaseArch(parts[1])
+	}
+
+	return runtime.GOOS, runtime.GOARCH
+}
+
 func (app *Application) getVerifier(asset Asset, assets []Asset) (verifier verifiers.Verifier, _ Asset, err error) {
 	// sumAsset = Asset{
 	// 	Filters: []string{},
@@ -540,11 +800,38 @@ func (app *Application) getVerifier(asset Asset, assets []Asset) (verifier verif
 		return verifier, Asset{}, nil
 	}
 
+	// checksums published in an index
+	if asset.Checksum != "" {
+		app.WriteVerboseLine("› verifying against the checksum published in the index")
+		verifier, err = verifiers.NewSha256Verifier(app.DownloadClient(), asset.Checksum)
+		if err != nil {
+			return nil, Asset{}, fmt.Errorf("create Sha256Verifier: %w", err)
+		}
+		return verifier, Asset{}, nil
+	}
+
+	// checksum files given by the finder, as for assets found in an index
+	if asset.ChecksumURL != "" {
+		app.WriteVerboseLine("› performing checksum verifications against %s", asset.ChecksumURL)
+		sumAsset := Asset{Name: path.Base(asset.ChecksumURL), DownloadURL: asset.ChecksumURL}
+		return &verifiers.Sha256SumFileAssetVerifier{Sha256SumAssetURL: asset.ChecksumURL, BinaryName: asset.Name, Client: app.DownloadClient()}, sumAsset, nil
+	}
+
+	// registry blobs are content addressed, so their digest is the checksum
+	if digest, ok := oci.BlobDigest(asset.DownloadURL); ok {
+		app.WriteVerboseLine("› verifying against the OCI layer digest")
+		verifier, err = verifiers.NewSha256Verifier(app.DownloadClient(), digest)
+		if err != nil {
+			return nil, Asset{}, fmt.Errorf("create Sha256Verifier: %w", err)
+		}
+		return verifier, Asset{}, nil
+	}
+
 	for _, item := range assets {
 		if item.Name == asset.Name+".sha256sum" || item.Name == asset.Name+".sha256" {
 			app.WriteVerboseLine("verification against %s (%s)", item.Name, item.DownloadURL)
 
-			verifier := verifiers.Sha256AssetVerifier{AssetURL: item.DownloadURL}
+			verifier := verifiers.Sha256AssetVerifier{AssetURL: app.assetURL(item)}
 			verifier.WithClient(app.DownloadClient())
 
 			return &verifier, item, nil
@@ -552,11 +839,11 @@ func (app *Application) getVerifier(asset Asset, assets []Asset) (verifier verif
 		if strings.Contains(item.Name, "checksum") {
 			binaryURL, err := url.Parse(asset.DownloadURL)
 			if err != nil {
-				return nil, item, fmt.Errorf("extract binary name from asset url: %s: %w", asset, err)
+				return nil, item, fmt.Errorf("extract binary name from asset url: %s: %w", asset.DownloadURL, err)
 			}
 			binaryName := path.Base(binaryURL.Path)
 			app.WriteVerboseLine("› performing checksum verifications against %s (%s)", item.Name, item.DownloadURL)
-			return &verifiers.Sha256SumFileAssetVerifier{Sha256SumAssetURL: item.DownloadURL, BinaryName: binaryName, Client: download.NewClient("")}, item, nil
+			return &verifiers.Sha256SumFileAssetVerifier{Sha256SumAssetURL: app.assetURL(item), BinaryName: binaryName, Client: app.DownloadClient()}, item, nil
 		}
 	}
 
@@ -564,36 +851,66 @@ func (app *Application) getVerifier(asset Asset, assets []Asset) (verifier verif
 }
 
 // Determine which extractor to use. If --download-only is provided, we
-// just "extract" the downloaded archive to itself. Otherwise we try to
-// extract the literal file provided by --file, or by default we just
-// extract a binary with the tool name that was possibly auto-detected
-// above.
+// just "extract" the downloaded archive to itself. AppImages are unpacked
+// with --unpack-appimage. Otherwise we try to extract the literal file
+// provided by --file, or by default we just extract a binary with the tool
+// name that was possibly auto-detected above.
 func (app *Application) getExtractor(asset *Asset, tool string) (extractor Extractor, err error) {
 	if app.Opts.DLOnly {
 		return &SingleFileExtractor{
-			Name:   path.Base(asset.DownloadURL),
-			Rename: path.Base(asset.DownloadURL),
+			Name:   assetFileName(asset),
+			Rename: assetFileName(asset),
 			Decompress: func(r io.Reader) (io.Reader, error) {
 				return r, nil
 			},
 		}, nil
 	}
 
+	var chooser Chooser
 	if app.Opts.ExtractFile != "" {
-		gc, err := NewGlobChooser(app.Opts.ExtractFile)
-		if err != nil {
+		if chooser, err = NewGlobChooser(app.Opts.ExtractFile); err != nil {
 			return nil, err
 		}
-		return NewExtractor(app.Filesystem, path.Base(asset.DownloadURL), tool, gc), nil
 	}
 
-	return NewExtractor(app.Filesystem, path.Base(asset.DownloadURL), tool, &BinaryChooser{Tool: tool}), nil
+	if app.Opts.UnpackAppImage && strings.HasSuffix(strings.ToLower(assetFileName(asset)), ".appimage") {
+		extractor := NewAppImageExtractor(tool, chooser, app.Filesystem)
+		extractor.Limits = app.limits
+		return extractor, nil
+	}
+
+	if chooser != nil {
+		return NewExtractor(app.Filesystem, assetFileName(asset), tool, chooser, app.limits), nil
+	}
+
+	return NewExtractor(app.Filesystem, assetFileName(asset), tool, &BinaryChooser{Tool: tool}, app.limits), nil
+}
+
+// assetFileName returns the file name used to pick an extractor. Registry blobs
+// and workflow artifacts have URLs without a file name, so the asset name is
+// used for those instead.
+func assetFileName(asset *Asset) string {
+	name := path.Base(asset.DownloadURL)
+	if !strings.Contains(name, ".") && asset.Name != "" {
+		return path.Base(asset.Name)
+	}
+
+	return name
 }
 
-// Would really like generics to implement this...
 // Make the user select one of the choices and return the index of the
-// selection.
+// selection, starting at 1. An interactive picker is used if stdin is a
+// terminal, and a numbered prompt otherwise.
 func (app *Application) userSelect(choices []interface{}) (int, error) {
+	if app.canUsePicker() {
+		selection, err := picker.Select("", choiceLabels(choices), false)
+		if err != nil {
+			return -1, zerrors.ExitUserSelectionError{Err: err}
+		}
+
+		return selection[0] + 1, nil
+	}
+
 	for i, c := range choices {
 		app.WriteErrorLine("(%d) %v", i+1, c)
 	}
@@ -619,6 +936,100 @@ func (app *Application) userSelect(choices []interface{}) (int, error) {
 	return choice, nil
 }
 
+// Make the user select one or more of the choices and return the sorted
+// indexes of the selection, starting at 1.
+func (app *Application) userSelectMany(choices []interface{}) ([]int, error) {
+	if app.canUsePicker() {
+		selection, err := picker.Select("", choiceLabels(choices), true)
+		if err != nil {
+			return nil, zerrors.ExitUserSelectionError{Err: err}
+		}
+
+		for i := range selection {
+			selection[i]++
+		}
+
+		return selection, nil
+	}
+
+	for i, c := range choices {
+		app.WriteErrorLine("(%d) %v", i+1, c)
+	}
+
+	for {
+		app.WriteError("Enter selection numbers, separated by spaces: ")
+		line, err := readLine(os.Stdin)
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			return nil, fmt.Errorf("Error reading selection")
+		}
+
+		selection, err := parseSelection(line, len(choices))
+		if err == nil {
+			return selection, nil
+		}
+
+		app.WriteErrorLine("Invalid selection: %v", err)
+	}
+}
+
+func (app *Application) canUsePicker() bool {
+	return picker.IsTerminal(os.Stdin) && picker.IsTerminal(os.Stderr)
+}
+
+func choiceLabels(choices []interface{}) []string {
+	result := make([]string, len(choices))
+	for i, c := range choices {
+		result[i] = fmt.Sprintf("%v", c)
+	}
+
+	return result
+}
+
+// readLine reads a single line one byte at a time, so that no input meant for
+// later prompts is buffered.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+
+	for {
+		n, err := r.Read(b)
+		if n > 0 && b[0] != '\n' {
+			line = append(line, b[0])
+		}
+		if n > 0 && b[0] == '\n' {
+			return strings.TrimSpace(string(line)), nil
+		}
+		if err != nil {
+			return strings.TrimSpace(string(line)), err
+		}
+	}
+}
+
+// parseSelection parses a list of selection numbers separated by spaces or commas.
+func parseSelection(line string, count int) ([]int, error) {
+	fields := strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("nothing selected")
+	}
+
+	result := []int{}
+	for _, field := range fields {
+		choice, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a number", field)
+		}
+		if choice <= 0 || choice > count {
+			return nil, fmt.Errorf("%d is out of bounds", choice)
+		}
+		if !IsInArr(result, choice, func(a int, b int) bool { return a == b }) {
+			result = append(result, choice)
+		}
+	}
+	sort.Ints(result)
+
+	return result, nil
+}
+
 func (app *Application) downloadConfigRepositories() error {
 	hasError := false
 	errorList := []error{}
@@ -648,16 +1059,16 @@ func (app *Application) downloadConfigRepositories() error {
 
 func (app *Application) ProcessFilters(finder *finders.ValidFinder, findResult *finders.FindResult) *ReturnStatus {
 	if len(app.Opts.Filters) > 0 {
-		var temp []assets.Asset = []assets.Asset{}
-
-		for _, filter := range app.Opts.Filters {
-			for _, a := range findResult.Assets {
-				if filter.Apply(a) {
-					temp = append(temp, a)
-				}
-			}
+		// assets must pass every filter
+		filter := filters.AllOf(app.Opts.Filters...)
+		findResult.Assets = FilterArr(findResult.Assets, filter.Apply)
+
+		// an expression which fails to evaluate is reported rather than
+		// leaving no assets to choose from
+		if app.assetExpr != nil && app.assetExpr.Err() != nil {
+			findResult.Error = app.assetExpr.Err()
+			return NewReturnStatus(FatalError, findResult.Error, fmt.Sprintf("error: %v", findResult.Error))
 		}
-		findResult.Assets = temp
 
 		if len(findResult.Assets) == 0 {
 			findResult.Error = fmt.Errorf("no assets found matching filters")
@@ -671,6 +1082,10 @@ func (app *Application) ProcessFilters(finder *finders.ValidFinder, findResult *
 func (app *Application) DownloadAndVerify(assetWrapper *AssetWrapper, findResult *finders.FindResult) ([]byte, *ReturnStatus) {
 	app.WriteLine("› " + "downloading " + assetWrapper.Asset.DownloadURL + "...") // print the URL
 
+	if mirrored := app.DownloadClient().RewriteURL(assetWrapper.Asset.DownloadURL); mirrored != assetWrapper.Asset.DownloadURL {
+		app.WriteVerboseLine("› using mirror %s", mirrored)
+	}
+
 	body, err := app.downloadAsset(assetWrapper.Asset, findResult) // download with progress bar and get the response body
 	if err != nil {
 		return nil, NewReturnStatus(FatalError, err, fmt.Sprintf("error: %v", err))
@@ -678,6 +1093,10 @@ func (app *Application) DownloadAndVerify(assetWrapper *AssetWrapper, findResult
 
 	app.VerifyChecksums(assetWrapper, body)
 
+	if app.Opts.Verbose {
+		reporters.NewAssetInfoReporter(assetWrapper.Asset, app.Output).Report()
+	}
+
 	if app.Opts.Sha256 || app.Opts.Hash {
 		reporters.NewAssetSha256HashReporter(assetWrapper.Asset, app.Output).Report(string(body))
 	}
@@ -693,11 +1112,15 @@ func (app *Application) ExtractDownloadedAsset(assetWrapper *AssetWrapper, body
 
 	bin, bins, err := extractor.Extract(body, app.Opts.All) // get extraction candidates
 	if err != nil && len(bins) != 0 && !app.Opts.All {
-		var e error
-		bin, e = app.selectFromMultipleCandidates(bin, bins, err)
+		chosen, e := app.selectFromMultipleCandidates(bin, bins, err)
 		if e != nil {
 			return -1, NewReturnStatus(FatalError, e, fmt.Sprintf("error: %v", e))
 		}
+
+		if len(chosen) > 1 {
+			return app.ExtractBins(bin, chosen, true), nil
+		}
+		bin = chosen[0]
 	}
 
 	extractedCount := app.ExtractBins(bin, app.wrapBins(bins, bin), app.Opts.All)
@@ -733,6 +1156,16 @@ func (app *Application) DetectAssets(assetWrapper *AssetWrapper) (*detectors.Det
 		return nil, err
 	}
 
+	if system, ok := detector.(*detectors.SystemDetector); ok && system.Libc != detectors.LibcUnknown {
+		app.WriteVerboseLine("› target libc: %s", system.Libc)
+	}
+
+	if e, ok := detector.(detectors.Explainer); ok && app.Opts.Verbose {
+		for _, score := range e.Explain(assetWrapper.Assets) {
+			app.WriteVerboseLine("› score %4d %s (%s)", score.Total, score.Asset.Name, score)
+		}
+	}
+
 	// get the url and candidates from the detector
 	detected, err := detector.Detect(assetWrapper.Assets)
 	if err != nil {
@@ -5,27 +5,44 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/BurntSushi/toml"
+	"github.com/permafrost-dev/zeget/lib/credentials"
+	"github.com/permafrost-dev/zeget/lib/detectors"
+	"github.com/permafrost-dev/zeget/lib/download"
+	"github.com/permafrost-dev/zeget/lib/extraction"
+	"github.com/permafrost-dev/zeget/lib/filters"
+	"github.com/permafrost-dev/zeget/lib/finders"
 	"github.com/permafrost-dev/zeget/lib/globals"
 	"github.com/permafrost-dev/zeget/lib/home"
+	"github.com/permafrost-dev/zeget/lib/sources"
 	"github.com/permafrost-dev/zeget/lib/utilities"
 	"github.com/twpayne/go-vfs/v5"
 )
 
 type ConfigGlobal struct {
-	All            bool     `toml:"all"`
-	DownloadOnly   bool     `toml:"download_only"`
-	File           string   `toml:"file"`
-	GithubToken    string   `toml:"github_token"`
-	Quiet          bool     `toml:"quiet"`
-	ShowHash       bool     `toml:"show_hash"`
-	Source         bool     `toml:"download_source"`
-	System         string   `toml:"system"`
-	Target         string   `toml:"target"`
-	UpgradeOnly    bool     `toml:"upgrade_only"`
-	RemoveExisting bool     `toml:"remove_existing"`
-	IgnorePatterns []string `toml:"ignore_patterns"`
+	All              bool     `toml:"all"`
+	DownloadOnly     bool     `toml:"download_only"`
+	File             string   `toml:"file"`
+	GithubToken      string   `toml:"github_token"`
+	TokenCommand     string   `toml:"token_command"`
+	Quiet            bool     `toml:"quiet"`
+	ShowHash         bool     `toml:"show_hash"`
+	Source           bool     `toml:"download_source"`
+	System           string   `toml:"system"`
+	Target           string   `toml:"target"`
+	UpgradeOnly      bool     `toml:"upgrade_only"`
+	RemoveExisting   bool     `toml:"remove_existing"`
+	IgnorePatterns   []string `toml:"ignore_patterns"`
+	CredentialHelper string   `toml:"credential_helper"`
+	Netrc            bool     `toml:"netrc"`
+	AssetExpr        string   `toml:"asset_expr"`
+	Libc             string   `toml:"libc"`
+	UnpackAppImage   bool     `toml:"unpack_appimage"`
+	MaxFileSize      string   `toml:"max_file_size"`
+	MaxTotalSize     string   `toml:"max_total_size"`
+	MaxRatio         *float64 `toml:"max_ratio"`
 }
 
 type ConfigRepository struct {
@@ -44,6 +61,41 @@ type ConfigRepository struct {
 	Verify         string   `toml:"verify_sha256"`
 	DisableSSL     bool     `toml:"disable_ssl"`
 	RemoveExisting bool     `toml:"remove_existing"`
+	AssetExpr      string   `toml:"asset_expr"`
+	Libc           string   `toml:"libc"`
+	UnpackAppImage bool     `toml:"unpack_appimage"`
+
+	Detectors detectors.Rules `toml:"detectors"`
+
+	IndexURL           string `toml:"index_url"`
+	IndexRegex         string `toml:"index_regex"`
+	IndexJSONPath      string `toml:"index_json_path"`
+	IndexNameField     string `toml:"index_name_field"`
+	IndexURLField      string `toml:"index_url_field"`
+	IndexVersionField  string `toml:"index_version_field"`
+	IndexChecksumField string `toml:"index_checksum_field"`
+	DownloadURL        string `toml:"download_url"`
+	VersionURL         string `toml:"version_url"`
+	ChecksumURL        string `toml:"checksum_url"`
+}
+
+type ConfigMirror struct {
+	Prefix       string   `toml:"prefix"`
+	Regex        string   `toml:"regex"`
+	Replace      string   `toml:"replace"`
+	Headers      []string `toml:"headers"`
+	Username     string   `toml:"username"`
+	Password     string   `toml:"password"`
+	Token        string   `toml:"token"`
+	ForwardToken bool     `toml:"forward_token"`
+}
+
+type ConfigCredential struct {
+	Type     string `toml:"type"`
+	Token    string `toml:"token"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	Header   string `toml:"header"`
 }
 
 type Config struct {
@@ -51,7 +103,10 @@ type Config struct {
 		Keys     []string
 		MetaData *toml.MetaData
 	}
-	Global       ConfigGlobal `toml:"global"`
+	Global       ConfigGlobal                `toml:"global"`
+	Mirrors      map[string]ConfigMirror     `toml:"mirrors"`
+	Credentials  map[string]ConfigCredential `toml:"credentials"`
+	Detectors    detectors.Rules             `toml:"detectors"`
 	Repositories map[string]ConfigRepository
 }
 
@@ -179,7 +234,10 @@ func (app *Application) initializeConfig() {
 				Source:         false,
 				UpgradeOnly:    false,
 				IgnorePatterns: []string{},
+				Netrc:          true,
 			},
+			Mirrors:      make(map[string]ConfigMirror, 0),
+			Credentials:  make(map[string]ConfigCredential, 0),
 			Repositories: make(map[string]ConfigRepository, 0),
 		}
 		app.WriteErrorLine("error loading configuration file: %s", err.Error())
@@ -187,6 +245,9 @@ func (app *Application) initializeConfig() {
 	}
 
 	delete(config.Repositories, "global")
+	delete(config.Repositories, "mirrors")
+	delete(config.Repositories, "credentials")
+	delete(config.Repositories, "detectors")
 
 	// set default global values
 	config.Global.All = utilities.SetIf(!config.Meta.MetaData.IsDefined("global", "system"), config.Global.All, false)
@@ -198,6 +259,7 @@ func (app *Application) initializeConfig() {
 	config.Global.Target = utilities.SetIf(!config.Meta.MetaData.IsDefined("global", "target"), config.Global.Target, utilities.GetCurrentDirectory())
 	config.Global.RemoveExisting = utilities.SetIf(!config.Meta.MetaData.IsDefined("global", "remove_existing"), config.Global.RemoveExisting, false)
 	config.Global.IgnorePatterns = utilities.SetIf(!config.Meta.MetaData.IsDefined("global", "ignore_patterns"), config.Global.IgnorePatterns, []string{})
+	config.Global.Netrc = utilities.SetIf(!config.Meta.MetaData.IsDefined("global", "netrc"), config.Global.Netrc, true)
 
 	// ensure "~" in the target directory is expanded
 	config.Global.Target, _ = home.Expand(config.Global.Target)
@@ -239,11 +301,187 @@ func update[T any](config T, cli *T) T {
 	return *cli
 }
 
+// MirrorNames returns the names of the configured mirrors in the order they
+// were defined in the configuration file.
+func (c *Config) MirrorNames() []string {
+	result := []string{}
+
+	for _, key := range c.Meta.Keys {
+		name, found := strings.CutPrefix(key, "mirrors.")
+		if !found || strings.Contains(name, ".") {
+			continue
+		}
+		if _, ok := c.Mirrors[name]; ok {
+			result = append(result, name)
+		}
+	}
+
+	return result
+}
+
+// GetLimits returns the limits of extracted files: the built-in limits,
+// overridden by the global settings. A limit of zero is not enforced.
+func (c *Config) GetLimits() (extraction.Limits, error) {
+	limits := extraction.BuiltinLimits
+
+	for _, setting := range []struct {
+		name  string
+		value string
+		limit *int64
+	}{
+		{"max_file_size", c.Global.MaxFileSize, &limits.MaxFileSize},
+		{"max_total_size", c.Global.MaxTotalSize, &limits.MaxTotalSize},
+	} {
+		if setting.value == "" {
+			continue
+		}
+
+		size, err := filters.ParseSize(setting.value)
+		if err != nil {
+			return limits, fmt.Errorf("%s: %w", setting.name, err)
+		}
+		*setting.limit = size
+	}
+
+	if c.Global.MaxRatio != nil {
+		limits.MaxRatio = *c.Global.MaxRatio
+	}
+
+	return limits, nil
+}
+
+// GetMirrors builds the download mirrors defined in the `[mirrors]` section.
+func (c *Config) GetMirrors() ([]*download.Mirror, error) {
+	result := []*download.Mirror{}
+
+	for _, name := range c.MirrorNames() {
+		cfg := c.Mirrors[name]
+
+		var mirror *download.Mirror
+		var err error
+
+		switch {
+		case cfg.Regex != "":
+			mirror, err = download.NewRegexMirror(name, cfg.Regex, cfg.Replace)
+		case cfg.Prefix != "":
+			mirror = download.NewPrefixMirror(name, cfg.Prefix, cfg.Replace)
+		default:
+			err = fmt.Errorf("mirror %s: either prefix or regex must be set", name)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		mirror.Headers = cfg.Headers
+		mirror.Username = os.ExpandEnv(cfg.Username)
+		mirror.ForwardToken = cfg.ForwardToken
+
+		if mirror.Password, err = secretFrom(cfg.Password); err != nil {
+			return nil, fmt.Errorf("mirror %s: %w", name, err)
+		}
+		if mirror.Token, err = secretFrom(cfg.Token); err != nil {
+			return nil, fmt.Errorf("mirror %s: %w", name, err)
+		}
+
+		result = append(result, mirror)
+	}
+
+	return result, nil
+}
+
+// GetCredentialStore builds the per-host credential store from the
+// `[credentials]` section, the netrc file and the configured credential helper.
+func (c *Config) GetCredentialStore() (*credentials.Store, error) {
+	store := credentials.NewStore().SetHelper(c.Global.CredentialHelper)
+
+	if c.Global.Netrc {
+		if err := store.LoadNetrc(credentials.NetrcFilename()); err != nil {
+			return nil, fmt.Errorf("netrc: %w", err)
+		}
+	}
+
+	for host, cfg := range c.Credentials {
+		token, err := secretFrom(cfg.Token)
+		if err != nil {
+			return nil, fmt.Errorf("credentials %s: %w", host, err)
+		}
+
+		password, err := secretFrom(cfg.Password)
+		if err != nil {
+			return nil, fmt.Errorf("credentials %s: %w", host, err)
+		}
+
+		var cred *credentials.Credential
+
+		switch credentials.Kind(strings.ToLower(cfg.Type)) {
+		case credentials.KindBasic:
+			cred = credentials.NewBasicCredential(host, os.ExpandEnv(cfg.Username), password)
+		case credentials.KindHeader:
+			cred = credentials.NewHeaderCredential(host, cfg.Header, token)
+		case credentials.KindBearer, "":
+			cred = credentials.NewBearerCredential(host, token)
+		default:
+			return nil, fmt.Errorf("credentials %s: unknown type %q", host, cfg.Type)
+		}
+
+		if !cred.IsValid() {
+			return nil, fmt.Errorf("credentials %s: missing values for type %s", host, cred.Kind)
+		}
+
+		store.Add(cred)
+	}
+
+	return store, nil
+}
+
+// Definition returns the source settings of a repository section.
+func (r ConfigRepository) Definition() sources.Definition {
+	return sources.Definition{
+		Name:               r.Name,
+		IndexURL:           r.IndexURL,
+		DownloadURL:        r.DownloadURL,
+		VersionURL:         r.VersionURL,
+		ChecksumURL:        r.ChecksumURL,
+		IndexRegex:         r.IndexRegex,
+		IndexJSONPath:      r.IndexJSONPath,
+		IndexNameField:     r.IndexNameField,
+		IndexURLField:      r.IndexURLField,
+		IndexVersionField:  r.IndexVersionField,
+		IndexChecksumField: r.IndexChecksumField,
+	}
+}
+
+// IndexFinder builds the finder for a repository section that scrapes its
+// assets from an index page or JSON manifest, or returns nil if the section
+// has neither an `index_url` nor a `download_url`.
+func (r ConfigRepository) IndexFinder() (*finders.IndexFinder, error) {
+	def := r.Definition()
+	if def.IsEmpty() {
+		return nil, nil
+	}
+
+	return def.Finder()
+}
+
 // Move the loaded configuration file global options into the opts variable
 func (app *Application) SetGlobalOptionsFromConfig() error {
+	var err error
+
+	if app.mirrors, err = app.Config.GetMirrors(); err != nil {
+		return err
+	}
+
+	if app.credentials, err = app.Config.GetCredentialStore(); err != nil {
+		return err
+	}
 
-	if app.Config.Global.GithubToken != "" && os.Getenv("EGET_GITHUB_TOKEN") == "" {
-		os.Setenv("EGET_GITHUB_TOKEN", app.Config.Global.GithubToken)
+	if err = detectors.ApplyRules(app.Config.Detectors); err != nil {
+		return err
+	}
+
+	if app.limits, err = app.Config.GetLimits(); err != nil {
+		return err
 	}
 
 	app.Opts.Tag = update("", app.cli.Tag)
@@ -263,6 +501,9 @@ func (app *Application) SetGlobalOptionsFromConfig() error {
 	app.Opts.Verify = update("", app.cli.Verify)
 	app.Opts.Remove = update(app.Config.Global.RemoveExisting, app.cli.Remove)
 	app.Opts.DisableSSL = update(false, app.cli.DisableSSL)
+	app.Opts.AssetExpr = update(app.Config.Global.AssetExpr, app.cli.AssetExpr)
+	app.Opts.Libc = app.Config.Global.Libc
+	app.Opts.UnpackAppImage = update(app.Config.Global.UnpackAppImage, app.cli.UnpackAppImage)
 
 	return nil
 }
@@ -294,6 +535,13 @@ func (app *Application) SetProjectOptionsFromConfig(projectName string) error {
 		app.Opts.UpgradeOnly = update(repo.UpgradeOnly, app.cli.UpgradeOnly)
 		app.Opts.Verify = update(repo.Verify, app.cli.Verify)
 		app.Opts.DisableSSL = update(repo.DisableSSL, app.cli.DisableSSL)
+		app.Opts.AssetExpr = update(utilities.SetIf(repo.AssetExpr == "", repo.AssetExpr, app.Opts.AssetExpr), app.cli.AssetExpr)
+		app.Opts.Libc = utilities.SetIf(repo.Libc == "", repo.Libc, app.Opts.Libc)
+		app.Opts.UnpackAppImage = update(repo.UnpackAppImage || app.Opts.UnpackAppImage, app.cli.UnpackAppImage)
+
+		if err := detectors.ApplyRules(app.Config.Detectors, repo.Detectors); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
 
 		break
 	}
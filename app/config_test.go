@@ -8,6 +8,8 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/permafrost-dev/zeget/app"
+	"github.com/permafrost-dev/zeget/lib/detectors"
+	"github.com/permafrost-dev/zeget/lib/extraction"
 	. "github.com/permafrost-dev/zeget/lib/globals"
 )
 
@@ -28,6 +30,14 @@ download_source = true
 system = "linux"
 target = "/tmp"
 upgrade_only = true
+asset_expr = "!release.prerelease"
+libc = "musl"
+
+[detectors.os.darwin]
+match = ["apple"]
+
+[detectors.arch.arm64]
+match = ["m1"]
 
 [repositories]
   [repositories.repo1]
@@ -45,6 +55,12 @@ upgrade_only = true
   upgrade_only = false
   verify_sha256 = "abc123"
   disable_ssl = true
+  asset_expr = 'name.endsWith(".zip")'
+  libc = "gnu"
+
+  [repositories.repo1.detectors.arch.amd64]
+  match = ["lin64"]
+  anti = ["-v4"]
 `
 	)
 
@@ -57,7 +73,7 @@ upgrade_only = true
 	})
 
 	AfterEach(func() {
-		os.RemoveAll(tempDir)
+		os.Remove(configPath)
 	})
 
 	Describe("Loading configuration file", func() {
@@ -73,6 +89,13 @@ upgrade_only = true
 				Expect(repo1.All).To(Equal(false))
 				Expect(repo1.AssetFilters).To(ConsistOf([]string{"*.zip", "*.tar.gz"}))
 				Expect(repo1.DisableSSL).To(Equal(true))
+				Expect(config.Global.AssetExpr).To(Equal("!release.prerelease"))
+				Expect(repo1.AssetExpr).To(Equal(`name.endsWith(".zip")`))
+				Expect(config.Global.Libc).To(Equal("musl"))
+				Expect(repo1.Libc).To(Equal("gnu"))
+				Expect(config.Detectors.OS).To(HaveKeyWithValue("darwin", detectors.Rule{Match: []string{"apple"}}))
+				Expect(config.Detectors.Arch).To(HaveKeyWithValue("arm64", detectors.Rule{Match: []string{"m1"}}))
+				Expect(repo1.Detectors.Arch).To(HaveKeyWithValue("amd64", detectors.Rule{Match: []string{"lin64"}, Anti: []string{"-v4"}}))
 			})
 		})
 
@@ -110,3 +133,178 @@ upgrade_only = true
 		})
 	})
 })
+
+var _ = Describe("Mirrors", func() {
+	var configPath string
+
+	BeforeEach(func() {
+		configPath = filepath.Join(GinkgoT().TempDir(), "."+ApplicationName+".toml")
+
+		err := os.WriteFile(configPath, []byte(`
+[global]
+target = "/tmp"
+
+[mirrors.github-api]
+regex = '^https://api\.github\.com/(.*)$'
+replace = "https://artifactory.corp/api/github/$1"
+forward_token = true
+
+[mirrors.github]
+prefix = "https://github.com/"
+replace = "https://artifactory.corp/github/"
+headers = ["X-JFrog-Art-Api: abc"]
+username = "builder"
+password = "secret"
+`), 0644)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("Should build mirrors in the order they are defined", func() {
+		config, err := LoadConfigurationFile(configPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		mirrors, err := config.GetMirrors()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mirrors).To(HaveLen(2))
+		Expect(mirrors[0].Name).To(Equal("github-api"))
+		Expect(mirrors[0].ForwardToken).To(BeTrue())
+		Expect(mirrors[1].Name).To(Equal("github"))
+		Expect(mirrors[1].Rewrite("https://github.com/a/b")).To(Equal("https://artifactory.corp/github/a/b"))
+		Expect(mirrors[1].Headers).To(ConsistOf("X-JFrog-Art-Api: abc"))
+		Expect(mirrors[1].Password).To(Equal("secret"))
+	})
+
+	It("Should not treat the mirrors section as a repository", func() {
+		config, err := LoadConfigurationFile(configPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Mirrors).To(HaveKey("github"))
+	})
+
+	It("Should reject a mirror without a prefix or regex", func() {
+		config := &Config{Mirrors: map[string]ConfigMirror{"empty": {Replace: "https://x/"}}}
+		config.Meta.Keys = []string{"mirrors", "mirrors.empty"}
+
+		_, err := config.GetMirrors()
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Extraction limits", func() {
+	It("Should use the built-in limits by default", func() {
+		limits, err := (&Config{}).GetLimits()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limits).To(Equal(extraction.BuiltinLimits))
+	})
+
+	It("Should override the limits with the global settings", func() {
+		ratio := 0.0
+		config := &Config{Global: ConfigGlobal{MaxFileSize: "512MB", MaxTotalSize: "0", MaxRatio: &ratio}}
+
+		limits, err := config.GetLimits()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limits.MaxFileSize).To(Equal(int64(512 << 20)))
+		Expect(limits.MaxTotalSize).To(BeZero())
+		Expect(limits.MaxRatio).To(BeZero())
+	})
+
+	It("Should reject invalid sizes", func() {
+		_, err := (&Config{Global: ConfigGlobal{MaxTotalSize: ">1GB"}}).GetLimits()
+		Expect(err).To(MatchError(ContainSubstring("max_total_size")))
+	})
+})
+
+var _ = Describe("Credentials", func() {
+	It("Should build a credential store from the credentials section", func() {
+		configPath := filepath.Join(GinkgoT().TempDir(), "."+ApplicationName+".toml")
+		err := os.WriteFile(configPath, []byte(`
+[credentials."artifactory.corp"]
+type = "basic"
+username = "builder"
+password = "secret"
+
+[credentials."*.example.com"]
+type = "header"
+header = "X-Api-Key"
+token = "abc"
+`), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		config, err := LoadConfigurationFile(configPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Credentials).To(HaveLen(2))
+
+		store, err := config.GetCredentialStore()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Lookup("artifactory.corp").Username).To(Equal("builder"))
+		Expect(store.Lookup("dl.example.com").Header).To(Equal("X-Api-Key"))
+	})
+
+	It("Should reject unknown credential types", func() {
+		config := &Config{Credentials: map[string]ConfigCredential{"example.com": {Type: "magic", Token: "x"}}}
+
+		_, err := config.GetCredentialStore()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should reject incomplete credentials", func() {
+		config := &Config{Credentials: map[string]ConfigCredential{"example.com": {Type: "basic"}}}
+
+		_, err := config.GetCredentialStore()
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Index sources", func() {
+	It("Should build an index finder from a repository section", func() {
+		configPath := filepath.Join(GinkgoT().TempDir(), "."+ApplicationName+".toml")
+		err := os.WriteFile(configPath, []byte(`
+["terraform"]
+index_url = "https://releases.hashicorp.com/terraform/index.json"
+index_json_path = "$.versions.*.builds[*]"
+index_name_field = "filename"
+
+["kubectl"]
+index_url = "https://dl.k8s.io/release/v{{version}}/bin/{{os}}/{{arch}}/"
+index_regex = 'href="(?P<url>[^"]+)"'
+`), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		config, err := LoadConfigurationFile(configPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		finder, err := config.Repositories["terraform"].IndexFinder()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(finder.JSONPath).To(Equal("$.versions.*.builds[*]"))
+		Expect(finder.NameField).To(Equal("filename"))
+
+		finder, err = config.Repositories["kubectl"].IndexFinder()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(finder.Regex.SubexpNames()).To(ContainElement("url"))
+	})
+
+	It("Should return nil for repositories without an index", func() {
+		finder, err := ConfigRepository{}.IndexFinder()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(finder).To(BeNil())
+	})
+
+	It("Should reject an index without a regex or JSONPath", func() {
+		_, err := ConfigRepository{IndexURL: "https://example.com/"}.IndexFinder()
+		Expect(err).To(HaveOccurred())
+
+		_, err = ConfigRepository{IndexURL: "https://example.com/", IndexRegex: "("}.IndexFinder()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should build a finder from a download URL template", func() {
+		finder, err := ConfigRepository{
+			DownloadURL: "https://example.com/tool/{{version}}/tool_{{os}}_{{arch}}",
+			VersionURL:  "https://example.com/tool/latest.txt",
+			ChecksumURL: "{{url}}.sha256",
+		}.IndexFinder()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(finder.DownloadURL).To(Equal("https://example.com/tool/{{version}}/tool_{{os}}_{{arch}}"))
+		Expect(finder.VersionURL).To(Equal("https://example.com/tool/latest.txt"))
+		Expect(finder.ChecksumURL).To(Equal("{{url}}.sha256"))
+	})
+})
@@ -0,0 +1,48 @@
+package archives
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/permafrost-dev/zeget/lib/files"
+)
+
+// apkArchive skips the signature and package metadata of an Alpine package,
+// which are stored as dotfiles at the top level of the archive.
+type apkArchive struct {
+	packageArchive
+}
+
+func (a *apkArchive) Next() (files.File, error) {
+	for {
+		f, err := a.packageArchive.Next()
+		if err != nil {
+			return f, err
+		}
+
+		if !strings.HasPrefix(f.Name, ".") || strings.Contains(f.Name, "/") {
+			return f, nil
+		}
+	}
+}
+
+// NewApkArchive reads the files installed by an Alpine package. Alpine
+// packages are gzip streams of tar segments that together form a single tar
+// archive. Android packages, which share the extension, are zip archives.
+func NewApkArchive(data []byte, decompress DecompressFunc) (Archive, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return NewZipArchive(data, decompress)
+	}
+
+	if !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		return nil, fmt.Errorf("apk: unsupported package format")
+	}
+
+	ar, err := NewTarArchive(data, Gunzip)
+	if err != nil {
+		return nil, fmt.Errorf("apk: %w", err)
+	}
+
+	return &apkArchive{packageArchive{ar}}, nil
+}
@@ -0,0 +1,74 @@
+package archives
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+var elfMagic = []byte("\x7fELF")
+
+// NewSquashfsArchive reads the files of a SquashFS file system. Files
+// compressed with LZO are not supported.
+func NewSquashfsArchive(data []byte, _ DecompressFunc) (Archive, error) {
+	entries, err := readSquashfs(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return newEntryArchive(entries), nil
+}
+
+// NewAppImageArchive reads the files of the SquashFS file system embedded in
+// an AppImage, which follows the runtime executable. Only type 2 AppImages are
+// supported; type 1 AppImages embed an ISO 9660 image instead.
+func NewAppImageArchive(data []byte, _ DecompressFunc) (Archive, error) {
+	offset, err := appImageOffset(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSquashfsArchive(data[offset:], nil)
+}
+
+// IsAppImage reports whether data is a type 2 AppImage.
+func IsAppImage(data []byte) bool {
+	_, err := appImageOffset(data)
+
+	return err == nil
+}
+
+// appImageOffset returns the offset of the file system of an AppImage, at the
+// end of the runtime executable, after its section header table.
+func appImageOffset(data []byte) (int, error) {
+	if !bytes.HasPrefix(data, elfMagic) || len(data) < 64 {
+		return 0, fmt.Errorf("appimage: not an AppImage")
+	}
+
+	// AppImages are marked in the padding of the ELF identification
+	switch string(data[8:11]) {
+	case "AI\x02":
+	case "AI\x01":
+		return 0, fmt.Errorf("appimage: type 1 AppImages are not supported")
+	default:
+		return 0, fmt.Errorf("appimage: not an AppImage")
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if data[5] == 2 {
+		order = binary.BigEndian
+	}
+
+	var offset uint64
+	if data[4] == 2 {
+		offset = order.Uint64(data[0x28:]) + uint64(order.Uint16(data[0x3a:]))*uint64(order.Uint16(data[0x3c:]))
+	} else {
+		offset = uint64(order.Uint32(data[0x20:])) + uint64(order.Uint16(data[0x2e:]))*uint64(order.Uint16(data[0x30:]))
+	}
+
+	if offset > uint64(len(data)) || !bytes.HasPrefix(data[offset:], squashfsMagic) {
+		return 0, fmt.Errorf("appimage: file system not found")
+	}
+
+	return int(offset), nil
+}
@@ -0,0 +1,280 @@
+package archives
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/permafrost-dev/zeget/lib/files"
+)
+
+var cabMagic = []byte("MSCF")
+
+const (
+	cabHeaderSize = 36
+	cabFolderSize = 8
+	cabFileSize   = 16
+	cabDataSize   = 8
+
+	cabPrevCabinet = 0x0001
+	cabNextCabinet = 0x0002
+	cabReserve     = 0x0004
+
+	cabCompressNone    = 0
+	cabCompressMSZIP   = 1
+	cabCompressQuantum = 2
+	cabCompressLZX     = 3
+
+	cabAttrExec = 0x40
+	cabAttrUTF8 = 0x80
+)
+
+type cabFolder struct {
+	offset uint32
+	blocks int
+	kind   uint16
+}
+
+// A cabinet is a Microsoft cabinet file, which stores files in folders
+// compressed as a whole.
+type cabinet struct {
+	data      []byte
+	folders   []cabFolder
+	blockResv int
+	decoded   map[int][]byte
+}
+
+// NewCabArchive reads the files of a Microsoft cabinet. Stored, MSZIP and
+// LZX folders are supported; files split across several cabinets are not.
+func NewCabArchive(data []byte, _ DecompressFunc) (Archive, error) {
+	entries, err := readCab(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return newEntryArchive(entries), nil
+}
+
+// readCab returns the files of a cabinet.
+func readCab(data []byte) ([]entry, error) {
+	if !bytes.HasPrefix(data, cabMagic) || len(data) < cabHeaderSize {
+		return nil, fmt.Errorf("cab: not a cabinet")
+	}
+
+	c := &cabinet{data: data, decoded: map[int][]byte{}}
+	numFolders := int(binary.LittleEndian.Uint16(data[26:]))
+	numFiles := int(binary.LittleEndian.Uint16(data[28:]))
+	flags := binary.LittleEndian.Uint16(data[30:])
+	filesOffset := int(binary.LittleEndian.Uint32(data[16:]))
+
+	offset := cabHeaderSize
+	folderResv := 0
+	if flags&cabReserve != 0 {
+		if len(data) < offset+4 {
+			return nil, fmt.Errorf("cab: truncated header")
+		}
+		headerResv := int(binary.LittleEndian.Uint16(data[36:]))
+		folderResv = int(data[38])
+		c.blockResv = int(data[39])
+		offset += 4 + headerResv
+	}
+
+	// the names of the previous and next cabinets and disks
+	for _, flag := range []uint16{cabPrevCabinet, cabNextCabinet} {
+		if flags&flag == 0 {
+			continue
+		}
+		for i := 0; i < 2; i++ {
+			end := bytes.IndexByte(data[min(offset, len(data)):], 0)
+			if end < 0 {
+				return nil, fmt.Errorf("cab: truncated header")
+			}
+			offset += end + 1
+		}
+	}
+
+	for i := 0; i < numFolders; i++ {
+		if offset+cabFolderSize > len(data) {
+			return nil, fmt.Errorf("cab: truncated folder list")
+		}
+		c.folders = append(c.folders, cabFolder{
+			offset: binary.LittleEndian.Uint32(data[offset:]),
+			blocks: int(binary.LittleEndian.Uint16(data[offset+4:])),
+			kind:   binary.LittleEndian.Uint16(data[offset+6:]),
+		})
+		offset += cabFolderSize + folderResv
+	}
+
+	var entries []entry
+	offset = filesOffset
+	for i := 0; i < numFiles; i++ {
+		if offset < 0 || offset+cabFileSize > len(data) {
+			return nil, fmt.Errorf("cab: truncated file list")
+		}
+
+		size := int(binary.LittleEndian.Uint32(data[offset:]))
+		start := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		folder := int(binary.LittleEndian.Uint16(data[offset+8:]))
+		attrs := binary.LittleEndian.Uint16(data[offset+14:])
+
+		end := bytes.IndexByte(data[offset+cabFileSize:], 0)
+		if end < 0 {
+			return nil, fmt.Errorf("cab: truncated file list")
+		}
+		name := string(data[offset+cabFileSize : offset+cabFileSize+end])
+		offset += cabFileSize + end + 1
+
+		if attrs&cabAttrUTF8 == 0 {
+			name = latin1(name)
+		}
+
+		mode := fs.FileMode(0o644)
+		if attrs&cabAttrExec != 0 {
+			mode = 0o755
+		}
+
+		entries = append(entries, entry{
+			File: files.File{
+				Name: path.Clean(strings.ReplaceAll(name, `\`, "/")),
+				Mode: mode,
+				Type: files.TypeNormal,
+			},
+			read: func(limit int64) ([]byte, error) {
+				return c.file(folder, start, size, limit)
+			},
+		})
+	}
+
+	return entries, nil
+}
+
+// file returns the data of a file stored in a folder. Given a limit of zero
+// or more, the folder is only decompressed as far as the first limit+1 bytes
+// of a larger file.
+func (c *cabinet) file(folder, start, size int, limit int64) ([]byte, error) {
+	if folder >= len(c.folders) {
+		// files continued from or in another cabinet use special indexes
+		return nil, fmt.Errorf("cab: files split across cabinets are not supported")
+	}
+
+	want := size
+	if limit >= 0 && limit < int64(size) {
+		want = int(limit) + 1
+	}
+
+	data, ok := c.decoded[folder]
+	if !ok || start > len(data) || want > len(data)-start {
+		end := -1
+		if want < size {
+			end = start + want
+		}
+
+		var err error
+		if data, err = c.decode(c.folders[folder], end); err != nil {
+			return nil, fmt.Errorf("cab: %w", err)
+		}
+		c.decoded = map[int][]byte{folder: data}
+	}
+
+	if start > len(data) || want > len(data)-start {
+		return nil, fmt.Errorf("cab: file beyond the end of its folder")
+	}
+
+	return data[start : start+want], nil
+}
+
+// decode decompresses the data blocks of a folder, or at least its first end
+// bytes unless end is negative.
+func (c *cabinet) decode(f cabFolder, end int) ([]byte, error) {
+	var blocks [][]byte
+	var sizes []int
+	total := 0
+
+	offset := int(f.offset)
+	for i := 0; i < f.blocks; i++ {
+		if offset < 0 || offset+cabDataSize+c.blockResv > len(c.data) {
+			return nil, fmt.Errorf("truncated data block")
+		}
+
+		compressed := int(binary.LittleEndian.Uint16(c.data[offset+4:]))
+		size := int(binary.LittleEndian.Uint16(c.data[offset+6:]))
+		offset += cabDataSize + c.blockResv
+
+		if offset+compressed > len(c.data) {
+			return nil, fmt.Errorf("truncated data block")
+		}
+		blocks = append(blocks, c.data[offset:offset+compressed])
+		sizes = append(sizes, size)
+		total += size
+		offset += compressed
+	}
+
+	want := total
+	if end >= 0 && end < total {
+		want = end
+	}
+	out := make([]byte, 0, want)
+
+	switch f.kind & 0x0f {
+	case cabCompressNone:
+		for _, b := range blocks {
+			if len(out) >= want {
+				break
+			}
+			out = append(out, b...)
+		}
+
+	case cabCompressMSZIP:
+		// blocks are deflate streams which may refer to the previous block
+		for i, b := range blocks {
+			if len(out) >= want {
+				break
+			}
+			if !bytes.HasPrefix(b, []byte("CK")) {
+				return nil, fmt.Errorf("invalid MSZIP block")
+			}
+
+			dict := out[max(0, len(out)-32768):]
+			r := flate.NewReaderDict(bytes.NewReader(b[2:]), dict)
+			block := make([]byte, sizes[i])
+			if _, err := io.ReadFull(r, block); err != nil {
+				return nil, fmt.Errorf("invalid MSZIP block: %w", err)
+			}
+			out = append(out, block...)
+		}
+
+	case cabCompressLZX:
+		// matches do not cross frames, so the output can end with one
+		want = min(total, (want+lzxFrameSize-1)/lzxFrameSize*lzxFrameSize)
+		return lzxDecompress(bytes.Join(blocks, nil), uint(f.kind>>8&0x1f), want)
+
+	case cabCompressQuantum:
+		return nil, fmt.Errorf("Quantum compression is not supported")
+
+	default:
+		return nil, fmt.Errorf("unsupported compression type %d", f.kind&0x0f)
+	}
+
+	return out, nil
+}
+
+// latin1 converts a string of an unspecified 8-bit encoding to UTF-8, as
+// Latin-1 when it is not valid UTF-8 already.
+func latin1(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+
+	return string(runes)
+}
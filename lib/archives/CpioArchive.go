@@ -0,0 +1,212 @@
+package archives
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/permafrost-dev/zeget/lib/files"
+)
+
+const cpioTrailer = "TRAILER!!!"
+
+// CpioArchive reads archives in the portable "newc" cpio format, as used by
+// RPM payloads and initramfs images, and in the "odc" format, as used by macOS
+// installer packages.
+type CpioArchive struct {
+	r       *bufio.Reader
+	offset  int64
+	current io.Reader
+
+	// the alignment of the current entry, which depends on the format
+	align int64
+}
+
+// NewCpioArchive reads a cpio archive, decompressed with the given function.
+func NewCpioArchive(data []byte, decompress DecompressFunc) (Archive, error) {
+	dr, err := decompress(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return newCpioReader(dr), nil
+}
+
+func newCpioReader(r io.Reader) *CpioArchive {
+	return &CpioArchive{r: bufio.NewReader(r), align: 1}
+}
+
+func (c *CpioArchive) Next() (files.File, error) {
+	for {
+		f, err := c.next()
+		if err != nil || f.Type != files.TypeOther {
+			return f, err
+		}
+	}
+}
+
+// readHeader reads the header of the next entry and returns its mode, data
+// size and name size.
+func (c *CpioArchive) readHeader() (mode, size, namesize int64, err error) {
+	magic := make([]byte, 6)
+	if _, err = io.ReadFull(c.r, magic); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("cpio: truncated header")
+		}
+		return
+	}
+
+	var widths []int
+	base := 16
+
+	switch string(magic) {
+	case "070701", "070702":
+		// ino, mode, uid, gid, nlink, mtime, filesize, devmajor, devminor,
+		// rdevmajor, rdevminor, namesize, check
+		widths = []int{8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8}
+		c.align = 4
+	case "070707":
+		// dev, ino, mode, uid, gid, nlink, rdev, mtime, namesize, filesize
+		widths = []int{6, 6, 6, 6, 6, 6, 6, 11, 6, 11}
+		base = 8
+		c.align = 1
+	default:
+		return 0, 0, 0, fmt.Errorf("cpio: unsupported format %q", magic)
+	}
+
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+
+	header := make([]byte, total)
+	if _, err = io.ReadFull(c.r, header); err != nil {
+		return 0, 0, 0, fmt.Errorf("cpio: truncated header")
+	}
+	c.offset += int64(len(magic) + total)
+
+	fields := make([]int64, len(widths))
+	for i, w := range widths {
+		if fields[i], err = strconv.ParseInt(string(header[:w]), base, 64); err != nil {
+			return 0, 0, 0, fmt.Errorf("cpio: invalid header: %w", err)
+		}
+		header = header[w:]
+	}
+
+	if base == 8 {
+		mode, size, namesize = fields[2], fields[9], fields[8]
+	} else {
+		mode, size, namesize = fields[1], fields[6], fields[11]
+	}
+	if namesize < 1 || size < 0 {
+		return 0, 0, 0, fmt.Errorf("cpio: invalid header")
+	}
+
+	return mode, size, namesize, nil
+}
+
+func (c *CpioArchive) next() (files.File, error) {
+	// skip the unread data of the previous entry
+	if c.current != nil {
+		if _, err := io.Copy(io.Discard, c.current); err != nil {
+			return files.File{}, err
+		}
+		c.current = nil
+	}
+	if err := c.skip(c.padding(c.align)); err != nil {
+		return files.File{}, err
+	}
+
+	mode, size, namesize, err := c.readHeader()
+	if err != nil {
+		return files.File{}, err
+	}
+
+	name := make([]byte, namesize)
+	if _, err := io.ReadFull(c.r, name); err != nil {
+		return files.File{}, fmt.Errorf("cpio: truncated name")
+	}
+	c.offset += namesize
+
+	if err := c.skip(c.padding(c.align)); err != nil {
+		return files.File{}, err
+	}
+
+	f := files.File{
+		Name: strings.TrimPrefix(strings.TrimRight(string(name), "\x00"), "./"),
+		Mode: fs.FileMode(mode & 0o7777),
+	}
+
+	if f.Name == cpioTrailer {
+		return files.File{}, io.EOF
+	}
+
+	c.current = io.LimitReader(c.r, size)
+	c.offset += size
+
+	// skip the root directory
+	if f.Name == "." || f.Name == "" {
+		return files.File{Type: files.TypeOther}, nil
+	}
+
+	switch mode & 0o170000 {
+	case 0o100000:
+		f.Type = files.TypeNormal
+	case 0o040000:
+		f.Type = files.TypeDir
+		f.Mode |= fs.ModeDir
+		// directories end with a slash, as they do in tar and zip archives
+		f.Name += "/"
+	case 0o120000:
+		f.Type = files.TypeSymlink
+		target, err := io.ReadAll(c.current)
+		if err != nil {
+			return files.File{}, err
+		}
+		f.LinkName = string(target)
+	default:
+		f.Type = files.TypeOther
+	}
+
+	return f, nil
+}
+
+// padding returns the number of bytes needed to align the offset.
+func (c *CpioArchive) padding(align int64) int64 {
+	return (align - c.offset%align) % align
+}
+
+func (c *CpioArchive) skip(n int64) error {
+	skipped, err := c.r.Discard(int(n))
+	c.offset += int64(skipped)
+
+	if err == io.EOF && skipped == 0 {
+		return io.EOF
+	}
+
+	return err
+}
+
+func (c *CpioArchive) ReadAll() ([]byte, error) {
+	if c.current == nil {
+		return nil, io.EOF
+	}
+
+	data, err := io.ReadAll(c.current)
+	c.current = nil
+
+	return data, err
+}
+
+// stream leaves the rest of the entry to be skipped by the next call to Next.
+func (c *CpioArchive) stream() (io.ReadCloser, error) {
+	if c.current == nil {
+		return nil, io.EOF
+	}
+
+	return io.NopCloser(c.current), nil
+}
@@ -0,0 +1,115 @@
+package archives
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/permafrost-dev/zeget/lib/files"
+)
+
+const (
+	arMagic      = "!<arch>\n"
+	arHeaderSize = 60
+)
+
+// An arMember is a file stored in an ar archive.
+type arMember struct {
+	Name string
+	Data []byte
+}
+
+// readAr returns the members of an ar archive, such as a Debian package.
+func readAr(data []byte) ([]arMember, error) {
+	if !bytes.HasPrefix(data, []byte(arMagic)) {
+		return nil, fmt.Errorf("ar: not an ar archive")
+	}
+
+	var members []arMember
+	offset := len(arMagic)
+
+	for offset+arHeaderSize <= len(data) {
+		header := data[offset : offset+arHeaderSize]
+		if string(header[58:60]) != "`\n" {
+			return nil, fmt.Errorf("ar: invalid header at offset %d", offset)
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(string(header[48:58])))
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("ar: invalid size at offset %d", offset)
+		}
+
+		offset += arHeaderSize
+		if offset+size > len(data) {
+			return nil, fmt.Errorf("ar: truncated member at offset %d", offset)
+		}
+
+		// GNU ar terminates names with a slash
+		name := strings.TrimSuffix(strings.TrimSpace(string(header[:16])), "/")
+		members = append(members, arMember{Name: name, Data: data[offset : offset+size]})
+
+		// members are aligned to two bytes
+		offset += size + size%2
+	}
+
+	return members, nil
+}
+
+// packageArchive lists the files of a package relative to the root of the
+// file system it installs to, without the root directory itself.
+// Names may be relative to the root or absolute.
+type packageArchive struct {
+	Archive
+}
+
+func (p *packageArchive) stream() (io.ReadCloser, error) {
+	if s, ok := p.Archive.(streamer); ok {
+		return s.stream()
+	}
+
+	data, err := p.Archive.ReadAll()
+
+	return io.NopCloser(bytes.NewReader(data)), err
+}
+
+func (p *packageArchive) Next() (files.File, error) {
+	for {
+		f, err := p.Archive.Next()
+		if err != nil {
+			return f, err
+		}
+
+		f.Name = strings.TrimLeft(strings.TrimPrefix(f.Name, "./"), "/")
+		if f.Name != "" && f.Name != "." {
+			return f, nil
+		}
+	}
+}
+
+// NewDebArchive reads the files installed by a Debian package, which are
+// stored in the data.tar member of the package. The member may be compressed
+// with gzip, bzip2, xz or zstd, or not at all.
+func NewDebArchive(data []byte, _ DecompressFunc) (Archive, error) {
+	members, err := readAr(data)
+	if err != nil {
+		return nil, fmt.Errorf("deb: %w", err)
+	}
+
+	for _, m := range members {
+		if !strings.HasPrefix(path.Base(m.Name), "data.tar") {
+			continue
+		}
+
+		ar, err := NewTarArchive(m.Data, DetectDecompressor(m.Data))
+		if err != nil {
+			return nil, fmt.Errorf("deb: %s: %w", m.Name, err)
+		}
+
+		return &packageArchive{ar}, nil
+	}
+
+	return nil, fmt.Errorf("deb: no data.tar member found")
+}
@@ -0,0 +1,408 @@
+package archives
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	udifTrailerSize = 512
+	udifSectorSize  = 512
+	mishHeaderSize  = 204
+	mishChunkSize   = 40
+
+	// the largest decoded chunk, well above the 1 MiB chunks of hdiutil
+	maxUdifChunkSize = 64 << 20
+
+	chunkZero    = 0x00000000
+	chunkRaw     = 0x00000001
+	chunkFree    = 0x00000002
+	chunkADC     = 0x80000004
+	chunkZlib    = 0x80000005
+	chunkBzip2   = 0x80000006
+	chunkLZFSE   = 0x80000007
+	chunkLZMA    = 0x80000008
+	chunkComment = 0x7ffffffe
+	chunkEnd     = 0xffffffff
+)
+
+// NewDmgArchive reads the files of a macOS disk image. Images in the UDIF
+// format, read-only or compressed with zlib, bzip2, lzma or ADC, and raw
+// images are supported, as long as they contain an HFS+ volume. Compressed
+// partitions are decoded a chunk at a time as their files are read.
+func NewDmgArchive(data []byte, _ DecompressFunc) (Archive, error) {
+	partitions, err := udifPartitions(data)
+	if err != nil {
+		return nil, fmt.Errorf("dmg: %w", err)
+	}
+
+	apfs := false
+	for _, decode := range partitions {
+		partition, err := decode()
+		if err != nil {
+			return nil, fmt.Errorf("dmg: %w", err)
+		}
+		header, err := partition.readAt(0, min(partition.size(), hfsHeaderOffset+512))
+		if err != nil {
+			return nil, fmt.Errorf("dmg: %w", err)
+		}
+
+		if isHFS(header) {
+			entries, err := readHFS(partition)
+			if err != nil {
+				return nil, fmt.Errorf("dmg: %w", err)
+			}
+			return newEntryArchive(entries), nil
+		}
+
+		apfs = apfs || len(header) >= 36 && string(header[32:36]) == "NXSB"
+	}
+
+	if apfs {
+		return nil, fmt.Errorf("dmg: APFS volumes are not supported")
+	}
+
+	return nil, fmt.Errorf("dmg: no HFS+ volume found")
+}
+
+// A partition is the data of a volume in a disk image.
+type partition interface {
+	size() uint64
+	// readAt returns the n bytes of the partition at an offset.
+	readAt(offset, n uint64) ([]byte, error)
+}
+
+// rawPartition is a partition stored as is.
+type rawPartition []byte
+
+func (p rawPartition) size() uint64 {
+	return uint64(len(p))
+}
+
+func (p rawPartition) readAt(offset, n uint64) ([]byte, error) {
+	if offset > p.size() || n > p.size()-offset {
+		return nil, fmt.Errorf("read beyond the end of the partition")
+	}
+
+	return p[offset : offset+n], nil
+}
+
+// udifPartitions returns functions decoding the partitions of a UDIF image,
+// or the image itself if it has no UDIF trailer.
+func udifPartitions(data []byte) ([]func() (partition, error), error) {
+	raw := func() (partition, error) { return rawPartition(data), nil }
+
+	if len(data) < udifTrailerSize || string(data[len(data)-udifTrailerSize:][:4]) != "koly" {
+		return []func() (partition, error){raw}, nil
+	}
+
+	trailer := data[len(data)-udifTrailerSize:]
+	dataFork := binary.BigEndian.Uint64(trailer[24:])
+	xmlOffset := binary.BigEndian.Uint64(trailer[216:])
+	xmlLength := binary.BigEndian.Uint64(trailer[224:])
+
+	if xmlOffset > uint64(len(data)) || xmlLength > uint64(len(data))-xmlOffset {
+		return nil, fmt.Errorf("truncated image")
+	}
+
+	plist, err := parsePlist(data[xmlOffset : xmlOffset+xmlLength])
+	if err != nil {
+		return nil, err
+	}
+
+	root, _ := plist.(map[string]any)
+	resources, _ := root["resource-fork"].(map[string]any)
+	blkx, _ := resources["blkx"].([]any)
+	if len(blkx) == 0 {
+		return nil, fmt.Errorf("no partitions found")
+	}
+
+	var partitions []func() (partition, error)
+	for _, b := range blkx {
+		p, _ := b.(map[string]any)
+		table, _ := p["Data"].([]byte)
+		partitions = append(partitions, func() (partition, error) {
+			return parseMish(data, dataFork, table)
+		})
+	}
+
+	return partitions, nil
+}
+
+// A udifChunk is a run of sectors of a partition stored in the image.
+type udifChunk struct {
+	kind          uint32
+	start, length uint64
+	offset, size  uint64
+}
+
+// udifPartition is a partition of a UDIF image, whose chunks are decoded
+// when read.
+type udifPartition struct {
+	data    []byte
+	sectors uint64
+	chunks  []udifChunk
+	decoded map[int][]byte
+}
+
+// parseMish reads the block table of a partition. Partitions may not be
+// larger than their chunks, and chunks holding data may not be larger than
+// maxUdifChunkSize once decoded.
+func parseMish(data []byte, dataFork uint64, table []byte) (*udifPartition, error) {
+	if len(table) < mishHeaderSize || string(table[:4]) != "mish" {
+		return nil, fmt.Errorf("invalid block table")
+	}
+
+	sectors := binary.BigEndian.Uint64(table[16:])
+	base := dataFork + binary.BigEndian.Uint64(table[24:])
+	count := uint64(binary.BigEndian.Uint32(table[200:]))
+
+	if mishHeaderSize+count*mishChunkSize > uint64(len(table)) || sectors > 1<<32 {
+		return nil, fmt.Errorf("invalid block table")
+	}
+
+	p := &udifPartition{data: data, sectors: sectors}
+	var end uint64
+
+	for i := uint64(0); i < count; i++ {
+		chunk := table[mishHeaderSize+i*mishChunkSize:]
+		c := udifChunk{
+			kind:   binary.BigEndian.Uint32(chunk),
+			start:  binary.BigEndian.Uint64(chunk[8:]),
+			length: binary.BigEndian.Uint64(chunk[16:]),
+			offset: base + binary.BigEndian.Uint64(chunk[24:]),
+			size:   binary.BigEndian.Uint64(chunk[32:]),
+		}
+
+		if c.kind == chunkComment {
+			continue
+		}
+		if c.kind == chunkEnd {
+			break
+		}
+		if c.start > sectors || c.length > sectors-c.start {
+			return nil, fmt.Errorf("chunk beyond the end of the partition")
+		}
+		end = max(end, c.start+c.length)
+
+		if c.kind == chunkZero || c.kind == chunkFree {
+			continue
+		}
+		if c.offset > uint64(len(data)) || c.size > uint64(len(data))-c.offset {
+			return nil, fmt.Errorf("truncated image")
+		}
+		if c.length > maxUdifChunkSize/udifSectorSize {
+			return nil, fmt.Errorf("chunk of %d sectors larger than %d bytes", c.length, maxUdifChunkSize)
+		}
+		p.chunks = append(p.chunks, c)
+	}
+
+	if sectors > end {
+		return nil, fmt.Errorf("partition of %d sectors larger than its chunks", sectors)
+	}
+
+	return p, nil
+}
+
+func (p *udifPartition) size() uint64 {
+	return p.sectors * udifSectorSize
+}
+
+// readAt decodes the chunks holding n bytes of the partition at an offset.
+// Sectors outside of them are zero.
+func (p *udifPartition) readAt(offset, n uint64) ([]byte, error) {
+	if offset > p.size() || n > p.size()-offset {
+		return nil, fmt.Errorf("read beyond the end of the partition")
+	}
+	out := make([]byte, n)
+
+	for i, c := range p.chunks {
+		start, end := c.start*udifSectorSize, (c.start+c.length)*udifSectorSize
+		if end <= offset || start >= offset+n {
+			continue
+		}
+
+		data, err := p.chunk(i)
+		if err != nil {
+			return nil, err
+		}
+
+		from, to := max(start, offset), min(end, offset+n)
+		copy(out[from-offset:to-offset], data[from-start:to-start])
+	}
+
+	return out, nil
+}
+
+// chunk returns the decoded data of a chunk, caching the last one decoded.
+func (p *udifPartition) chunk(i int) ([]byte, error) {
+	if data, ok := p.decoded[i]; ok {
+		return data, nil
+	}
+
+	c := p.chunks[i]
+	dst := make([]byte, c.length*udifSectorSize)
+	src := p.data[c.offset : c.offset+c.size]
+
+	var r io.Reader
+	var err error
+
+	switch c.kind {
+	case chunkRaw:
+		copy(dst, src)
+	case chunkADC:
+		err = adcDecompress(dst, src)
+	case chunkZlib:
+		r, err = zlib.NewReader(bytes.NewReader(src))
+	case chunkBzip2:
+		r, err = Bunzip2(bytes.NewReader(src))
+	case chunkLZMA:
+		r, err = DetectDecompressor(src)(bytes.NewReader(src))
+	case chunkLZFSE:
+		return nil, fmt.Errorf("LZFSE compression is not supported")
+	default:
+		return nil, fmt.Errorf("unsupported chunk type %#x", c.kind)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	if r != nil {
+		if _, err := io.ReadFull(r, dst); err != nil {
+			return nil, fmt.Errorf("truncated chunk: %w", err)
+		}
+	}
+	p.decoded = map[int][]byte{i: dst}
+
+	return dst, nil
+}
+
+// adcDecompress decompresses Apple Data Compression, an LZ77 variant used
+// by old disk images.
+func adcDecompress(dst, src []byte) error {
+	out := 0
+
+	for i := 0; i < len(src) && out < len(dst); {
+		b := src[i]
+
+		var length, distance int
+		switch {
+		case b&0x80 != 0:
+			length = int(b&0x7f) + 1
+			if i+1+length > len(src) || out+length > len(dst) {
+				return fmt.Errorf("adc: corrupt input")
+			}
+			copy(dst[out:], src[i+1:i+1+length])
+			out += length
+			i += 1 + length
+			continue
+		case b&0x40 != 0:
+			if i+2 >= len(src) {
+				return fmt.Errorf("adc: corrupt input")
+			}
+			length = int(b&0x3f) + 4
+			distance = int(src[i+1])<<8 | int(src[i+2])
+			i += 3
+		default:
+			if i+1 >= len(src) {
+				return fmt.Errorf("adc: corrupt input")
+			}
+			length = int(b&0x3c)>>2 + 3
+			distance = int(b&0x03)<<8 | int(src[i+1])
+			i += 2
+		}
+
+		from := out - distance - 1
+		if from < 0 || out+length > len(dst) {
+			return fmt.Errorf("adc: corrupt input")
+		}
+		// the copy may overlap the output
+		for j := 0; j < length; j++ {
+			dst[out+j] = dst[from+j]
+		}
+		out += length
+	}
+
+	return nil
+}
+
+// parsePlist parses an XML property list into maps, slices, strings and, for
+// data elements, byte slices.
+func parsePlist(data []byte) (any, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid property list: %w", err)
+		}
+
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local != "plist" {
+			return parsePlistValue(d, start)
+		}
+	}
+}
+
+func parsePlistValue(d *xml.Decoder, start xml.StartElement) (any, error) {
+	switch start.Name.Local {
+	case "dict", "array":
+		dict := map[string]any{}
+		var array []any
+		var key string
+
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return nil, fmt.Errorf("invalid property list: %w", err)
+			}
+
+			switch t := tok.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if err := d.DecodeElement(&key, &t); err != nil {
+						return nil, err
+					}
+					continue
+				}
+
+				value, err := parsePlistValue(d, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = value
+				array = append(array, value)
+			case xml.EndElement:
+				if start.Name.Local == "dict" {
+					return dict, nil
+				}
+				return array, nil
+			}
+		}
+
+	case "data":
+		var s string
+		if err := d.DecodeElement(&s, &start); err != nil {
+			return nil, err
+		}
+
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+
+	default:
+		var s string
+		if err := d.DecodeElement(&s, &start); err != nil {
+			return nil, err
+		}
+		if s == "" {
+			// true and false are empty elements
+			s = start.Name.Local
+		}
+
+		return s, nil
+	}
+}
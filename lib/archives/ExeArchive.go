@@ -0,0 +1,121 @@
+package archives
+
+import (
+	"archive/zip"
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+)
+
+var (
+	sevenZipMagic = []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}
+	zipEndMagic   = []byte("PK\x05\x06")
+
+	// the identifier of the setup data of Inno Setup installers
+	innoMagic = []byte("Inno Setup Setup Data (")
+)
+
+// NewExeArchive reads the files of a self-extracting Windows executable:
+// NSIS installers, and executables carrying a zip, 7z or cabinet archive,
+// such as 7-Zip and IExpress self-extracting archives. Inno Setup
+// installers are not supported.
+func NewExeArchive(data []byte, _ DecompressFunc) (Archive, error) {
+	overlay := peOverlay(data)
+	if overlay < 0 {
+		return nil, fmt.Errorf("exe: not a Windows executable")
+	}
+
+	switch {
+	case findNSIS(data, overlay) >= 0:
+		entries, err := readNSIS(data, overlay)
+		if err != nil {
+			return nil, fmt.Errorf("exe: %w", err)
+		}
+		return newEntryArchive(entries), nil
+
+	// executables may contain the magic bytes of installer data and archives
+	// anyway, so only the data appended to them is searched
+	case bytes.Contains(data[overlay:], innoMagic):
+		return nil, fmt.Errorf("exe: Inno Setup installers are not supported")
+
+	case bytes.Contains(data[overlay:], sevenZipMagic):
+		return NewSevenZipArchive(data[overlay+bytes.Index(data[overlay:], sevenZipMagic):], nil)
+
+	case hasZipDirectory(data):
+		return NewZipArchive(data, nil)
+
+	case findCab(data) >= 0:
+		return NewCabArchive(data[findCab(data):], nil)
+	}
+
+	return nil, fmt.Errorf("exe: no embedded archive found")
+}
+
+// isSelfExtracting reports whether data is a Windows executable carrying an
+// installer payload or an archive.
+func isSelfExtracting(data []byte) bool {
+	overlay := peOverlay(data)
+	if overlay < 0 {
+		return false
+	}
+
+	return findNSIS(data, overlay) >= 0 || bytes.Contains(data[overlay:], innoMagic) ||
+		bytes.Contains(data[overlay:], sevenZipMagic) || hasZipDirectory(data) || findCab(data) >= 0
+}
+
+// peOverlay returns the offset of the data appended to a Windows executable,
+// after its last section, or -1 if data is not an executable.
+func peOverlay(data []byte) int {
+	if !bytes.HasPrefix(data, []byte("MZ")) {
+		return -1
+	}
+
+	f, err := pe.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return -1
+	}
+	defer f.Close()
+
+	end := 0
+	for _, s := range f.Sections {
+		end = max(end, int(s.Offset)+int(s.Size))
+	}
+
+	return min(end, len(data))
+}
+
+// hasZipDirectory reports whether data ends with the directory of a zip
+// archive, as self-extracting zip archives do.
+func hasZipDirectory(data []byte) bool {
+	if !bytes.Contains(data[max(0, len(data)-(22+65535)):], zipEndMagic) {
+		return false
+	}
+	_, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+
+	return err == nil
+}
+
+// findCab returns the offset of a cabinet embedded in data, or -1. The
+// header is validated, as executables may contain the magic bytes anyway.
+func findCab(data []byte) int {
+	for offset := 0; ; {
+		i := bytes.Index(data[offset:], cabMagic)
+		if i < 0 {
+			return -1
+		}
+		offset += i
+
+		header := data[offset:]
+		if len(header) >= cabHeaderSize &&
+			binary.LittleEndian.Uint32(header[4:]) == 0 &&
+			uint64(binary.LittleEndian.Uint32(header[8:])) <= uint64(len(header)) &&
+			binary.LittleEndian.Uint32(header[16:]) < binary.LittleEndian.Uint32(header[8:]) &&
+			header[24] == 3 && header[25] == 1 &&
+			binary.LittleEndian.Uint16(header[26:]) > 0 && binary.LittleEndian.Uint16(header[28:]) > 0 {
+			return offset
+		}
+
+		offset++
+	}
+}
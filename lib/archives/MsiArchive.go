@@ -0,0 +1,344 @@
+package archives
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	msiTypeString    = 0x0800
+	msiTypeTemporary = 0x4000
+	msiTypeBinary    = 0x0900
+	msiLongRefs      = 0x80000000
+)
+
+// the characters packed into the names of the streams of MSI databases
+const msiNameChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz._"
+
+// msiDecodeName decodes the name of a stream of an MSI database. Names are
+// packed two characters per UTF-16 unit, and the names of tables start with
+// an exclamation mark once decoded.
+func msiDecodeName(name string) string {
+	var b strings.Builder
+
+	for _, r := range name {
+		switch {
+		case r >= 0x3800 && r < 0x4800:
+			r -= 0x3800
+			b.WriteByte(msiNameChars[r&0x3f])
+			b.WriteByte(msiNameChars[r>>6&0x3f])
+		case r >= 0x4800 && r < 0x4840:
+			b.WriteByte(msiNameChars[r-0x4800])
+		case r == 0x4840:
+			b.WriteByte('!')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+type msiColumn struct {
+	name   string
+	number int
+	kind   int
+}
+
+// An msiDatabase is the relational database of a Windows Installer package.
+type msiDatabase struct {
+	cfb     *cfbFile
+	streams map[string]*cfbEntry
+	strings []string
+	refSize int
+	columns map[string][]msiColumn
+}
+
+// openMsi reads the string pool and the table schemas of an MSI database.
+func openMsi(data []byte) (*msiDatabase, error) {
+	c, err := openCFB(data)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &msiDatabase{cfb: c, streams: map[string]*cfbEntry{}, refSize: 2}
+	for name, e := range c.streams() {
+		db.streams[msiDecodeName(name)] = e
+	}
+
+	pool, err := db.stream("!_StringPool")
+	if err != nil {
+		return nil, err
+	}
+	stringData, err := db.stream("!_StringData")
+	if err != nil {
+		return nil, err
+	}
+	if db.strings, err = db.parseStrings(pool, stringData); err != nil {
+		return nil, err
+	}
+
+	// the schema of the table of columns is fixed
+	db.columns = map[string][]msiColumn{"_Columns": {
+		{name: "Table", number: 1, kind: msiTypeString},
+		{name: "Number", number: 2, kind: 2},
+		{name: "Name", number: 3, kind: msiTypeString},
+		{name: "Type", number: 4, kind: 2},
+	}}
+	rows, err := db.table("_Columns")
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		number, _ := strconv.Atoi(row["Number"])
+		kind, _ := strconv.Atoi(row["Type"])
+		db.columns[row["Table"]] = append(db.columns[row["Table"]], msiColumn{
+			name:   row["Name"],
+			number: number,
+			kind:   kind,
+		})
+	}
+	for _, columns := range db.columns {
+		sort.Slice(columns, func(i, j int) bool { return columns[i].number < columns[j].number })
+	}
+
+	return db, nil
+}
+
+func (db *msiDatabase) stream(name string) ([]byte, error) {
+	e, ok := db.streams[name]
+	if !ok {
+		return nil, fmt.Errorf("msi: missing stream %s", strings.TrimPrefix(name, "!"))
+	}
+
+	return db.cfb.read(e)
+}
+
+// parseStrings returns the strings of the string pool, indexed from one. The
+// pool lists the length and reference count of each string, whose data is
+// concatenated in a second stream.
+func (db *msiDatabase) parseStrings(pool, data []byte) ([]string, error) {
+	if len(pool) < 4 {
+		return nil, fmt.Errorf("msi: invalid string pool")
+	}
+	if binary.LittleEndian.Uint32(pool)&msiLongRefs != 0 {
+		db.refSize = 3
+	}
+
+	strs := []string{""}
+	offset := 0
+	for i := 4; i+4 <= len(pool); i += 4 {
+		length := int(binary.LittleEndian.Uint16(pool[i:]))
+		refs := binary.LittleEndian.Uint16(pool[i+2:])
+
+		// strings of 64 KiB or more store their length in the next entry
+		if length == 0 && refs != 0 && i+8 <= len(pool) {
+			i += 4
+			length = int(binary.LittleEndian.Uint16(pool[i+2:]))<<16 | int(binary.LittleEndian.Uint16(pool[i:]))
+		}
+
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("msi: invalid string pool")
+		}
+		strs = append(strs, latin1(string(data[offset:offset+length])))
+		offset += length
+	}
+
+	return strs, nil
+}
+
+// table returns the rows of a table, with integers formatted in decimal and
+// empty strings for null values. Tables are stored column by column.
+func (db *msiDatabase) table(name string) ([]map[string]string, error) {
+	columns, ok := db.columns[name]
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := db.stream("!" + name)
+	if err != nil {
+		// empty tables have no stream
+		return nil, nil
+	}
+
+	sizes := make([]int, len(columns))
+	rowSize := 0
+	for i, c := range columns {
+		switch {
+		case c.kind&msiTypeTemporary != 0:
+			sizes[i] = 0
+		case c.kind&^0x1000 == msiTypeBinary, c.kind&0xff <= 2 && c.kind&msiTypeString == 0:
+			sizes[i] = 2
+		case c.kind&msiTypeString != 0:
+			sizes[i] = db.refSize
+		default:
+			sizes[i] = 4
+		}
+		rowSize += sizes[i]
+	}
+	if rowSize == 0 {
+		return nil, nil
+	}
+
+	count := len(data) / rowSize
+	rows := make([]map[string]string, count)
+	for i := range rows {
+		rows[i] = map[string]string{}
+	}
+
+	offset := 0
+	for i, c := range columns {
+		for r := 0; r < count && sizes[i] > 0; r++ {
+			v := data[offset+r*sizes[i]:]
+			switch {
+			case c.kind&^0x1000 == msiTypeBinary:
+			case c.kind&msiTypeString != 0:
+				ref := int(binary.LittleEndian.Uint16(v))
+				if db.refSize == 3 {
+					ref |= int(v[2]) << 16
+				}
+				if ref < len(db.strings) {
+					rows[r][c.name] = db.strings[ref]
+				}
+			case sizes[i] == 2:
+				if n := binary.LittleEndian.Uint16(v); n != 0 {
+					rows[r][c.name] = strconv.Itoa(int(n) - 0x8000)
+				}
+			default:
+				if n := binary.LittleEndian.Uint32(v); n != 0 {
+					rows[r][c.name] = strconv.Itoa(int(int32(n ^ msiLongRefs)))
+				}
+			}
+		}
+		offset += count * sizes[i]
+	}
+
+	return rows, nil
+}
+
+// longName returns the long name of a "short|long" file name.
+func longName(name string) string {
+	if i := strings.IndexByte(name, '|'); i >= 0 {
+		return name[i+1:]
+	}
+
+	return name
+}
+
+// filePaths returns the paths of the files of the package by the keys of the
+// File table, which are the names of the files in the cabinets. Paths are
+// relative to the standard folder they are installed to, such as the
+// program files folder.
+func (db *msiDatabase) filePaths() (map[string]string, error) {
+	dirs, err := db.table("Directory")
+	if err != nil {
+		return nil, err
+	}
+	parents := map[string]string{}
+	names := map[string]string{}
+	for _, d := range dirs {
+		parents[d["Directory"]] = d["Directory_Parent"]
+		// the target directory comes before the source directory
+		target, _, _ := strings.Cut(d["DefaultDir"], ":")
+		names[d["Directory"]] = longName(target)
+	}
+
+	var dirPath func(id string, depth int) string
+	dirPath = func(id string, depth int) string {
+		parent := parents[id]
+		if parent == "" || parent == id || depth > 64 {
+			return ""
+		}
+		if parents[parent] == "" && strings.HasSuffix(id, "Folder") {
+			return ""
+		}
+
+		name := names[id]
+		if name == "." {
+			name = ""
+		}
+
+		return path.Join(dirPath(parent, depth+1), name)
+	}
+
+	components, err := db.table("Component")
+	if err != nil {
+		return nil, err
+	}
+	componentDirs := map[string]string{}
+	for _, c := range components {
+		componentDirs[c["Component"]] = c["Directory_"]
+	}
+
+	fileRows, err := db.table("File")
+	if err != nil {
+		return nil, err
+	}
+	paths := map[string]string{}
+	for _, f := range fileRows {
+		dir := dirPath(componentDirs[f["Component_"]], 0)
+		paths[f["File"]] = path.Join(dir, longName(f["FileName"]))
+	}
+
+	return paths, nil
+}
+
+// NewMsiArchive reads the files installed by a Windows Installer package
+// from the cabinets embedded in it. Files are named after the directories
+// they are installed to; packages whose files are stored outside of the
+// package are not supported.
+func NewMsiArchive(data []byte, _ DecompressFunc) (Archive, error) {
+	db, err := openMsi(data)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := db.filePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	media, err := db.table("Media")
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []entry
+	for _, m := range media {
+		// embedded cabinets are named after a number sign
+		name, ok := strings.CutPrefix(m["Cabinet"], "#")
+		if !ok {
+			continue
+		}
+
+		cab, err := db.stream(name)
+		if err != nil {
+			return nil, err
+		}
+		cabEntries, err := readCab(cab)
+		if err != nil {
+			return nil, fmt.Errorf("msi: %s: %w", name, err)
+		}
+
+		for _, e := range cabEntries {
+			if p, ok := paths[e.Name]; ok {
+				e.Name = p
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("msi: no embedded cabinet found")
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return newEntryArchive(entries), nil
+}
@@ -0,0 +1,233 @@
+package archives
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/permafrost-dev/zeget/lib/files"
+)
+
+var xarMagic = []byte("xar!")
+
+type xarFile struct {
+	Name  string    `xml:"name"`
+	Type  string    `xml:"type"`
+	Mode  string    `xml:"mode"`
+	Link  string    `xml:"link"`
+	Data  *xarData  `xml:"data"`
+	Files []xarFile `xml:"file"`
+}
+
+type xarData struct {
+	Offset   uint64 `xml:"offset"`
+	Length   uint64 `xml:"length"`
+	Encoding struct {
+		Style string `xml:"style,attr"`
+	} `xml:"encoding"`
+}
+
+// readXar returns the files of a xar archive, the format of macOS installer
+// packages.
+func readXar(data []byte) ([]entry, error) {
+	if !bytes.HasPrefix(data, xarMagic) || len(data) < 28 {
+		return nil, fmt.Errorf("xar: not a xar archive")
+	}
+
+	headerSize := uint64(binary.BigEndian.Uint16(data[4:]))
+	tocSize := binary.BigEndian.Uint64(data[8:])
+	if headerSize+tocSize > uint64(len(data)) {
+		return nil, fmt.Errorf("xar: truncated table of contents")
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(data[headerSize : headerSize+tocSize]))
+	if err != nil {
+		return nil, fmt.Errorf("xar: %w", err)
+	}
+
+	var toc struct {
+		Files []xarFile `xml:"toc>file"`
+	}
+	if err := xml.NewDecoder(zr).Decode(&toc); err != nil {
+		return nil, fmt.Errorf("xar: invalid table of contents: %w", err)
+	}
+
+	heap := data[headerSize+tocSize:]
+
+	var entries []entry
+	var walk func(dir string, list []xarFile)
+	walk = func(dir string, list []xarFile) {
+		for _, f := range list {
+			mode, _ := strconv.ParseUint(f.Mode, 8, 32)
+			e := entry{File: files.File{
+				Name: path.Join(dir, f.Name),
+				Mode: fs.FileMode(mode),
+			}}
+
+			switch f.Type {
+			case "directory":
+				e.Type = files.TypeDir
+				e.Mode |= fs.ModeDir
+				e.Name += "/"
+			case "symlink":
+				e.Type = files.TypeSymlink
+				e.LinkName = f.Link
+			case "file":
+				e.Type = files.TypeNormal
+				e.read = xarReader(heap, f.Data)
+			default:
+				e.Type = files.TypeOther
+			}
+
+			if e.Type != files.TypeOther {
+				entries = append(entries, e)
+			}
+			walk(path.Join(dir, f.Name), f.Files)
+		}
+	}
+	walk("", toc.Files)
+
+	return entries, nil
+}
+
+// xarReader returns a function reading the data of a file in the heap.
+func xarReader(heap []byte, d *xarData) func(limit int64) ([]byte, error) {
+	return func(limit int64) ([]byte, error) {
+		if d == nil {
+			return []byte{}, nil
+		}
+		if d.Offset > uint64(len(heap)) || d.Length > uint64(len(heap))-d.Offset {
+			return nil, fmt.Errorf("xar: truncated heap")
+		}
+		data := heap[d.Offset : d.Offset+d.Length]
+
+		var r io.Reader
+		var err error
+		switch d.Encoding.Style {
+		case "", "application/octet-stream":
+			return data, nil
+		case "application/x-gzip":
+			// despite the name, xar compresses data with zlib
+			if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+				r, err = Gunzip(bytes.NewReader(data))
+			} else {
+				r, err = zlib.NewReader(bytes.NewReader(data))
+			}
+		case "application/x-bzip2":
+			r, err = Bunzip2(bytes.NewReader(data))
+		default:
+			r, err = DetectDecompressor(data)(bytes.NewReader(data))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xar: %w", err)
+		}
+		if limit >= 0 {
+			r = io.LimitReader(r, limit+1)
+		}
+
+		return io.ReadAll(r)
+	}
+}
+
+// pkgArchive lists the files of the payloads of an installer package.
+type pkgArchive struct {
+	payloads [][]byte
+	current  Archive
+}
+
+// NewPkgArchive reads the files installed by a macOS installer package. Both
+// component packages and product archives, which contain several component
+// packages, are supported. Their payloads are cpio archives compressed with
+// gzip or pbzx. FreeBSD packages, which share the extension, are compressed
+// tar archives.
+func NewPkgArchive(data []byte, _ DecompressFunc) (Archive, error) {
+	if !bytes.HasPrefix(data, xarMagic) {
+		if strings.HasPrefix(DetectFormat(data), ".tar") {
+			ar, err := NewTarArchive(data, DetectDecompressor(data))
+			if err != nil {
+				return nil, fmt.Errorf("pkg: %w", err)
+			}
+			return &packageArchive{ar}, nil
+		}
+		return nil, fmt.Errorf("pkg: unsupported package format")
+	}
+
+	entries, err := readXar(data)
+	if err != nil {
+		return nil, fmt.Errorf("pkg: %w", err)
+	}
+
+	p := &pkgArchive{}
+	for _, e := range entries {
+		if e.Type != files.TypeNormal || path.Base(e.Name) != "Payload" {
+			continue
+		}
+
+		payload, err := e.read(-1)
+		if err != nil {
+			return nil, fmt.Errorf("pkg: %s: %w", e.Name, err)
+		}
+		p.payloads = append(p.payloads, payload)
+	}
+
+	if len(p.payloads) == 0 {
+		return nil, fmt.Errorf("pkg: no payload found")
+	}
+
+	return p, nil
+}
+
+func (p *pkgArchive) Next() (files.File, error) {
+	for {
+		if p.current == nil {
+			if len(p.payloads) == 0 {
+				return files.File{}, io.EOF
+			}
+
+			payload := p.payloads[0]
+			p.payloads = p.payloads[1:]
+
+			decompress := DetectDecompressor(payload)
+			if bytes.HasPrefix(payload, []byte("pbzx")) {
+				decompress = Unpbzx
+			}
+
+			dr, err := decompress(bytes.NewReader(payload))
+			if err != nil {
+				return files.File{}, fmt.Errorf("pkg: payload: %w", err)
+			}
+			p.current = newCpioReader(dr)
+		}
+
+		f, err := p.current.Next()
+		if err == io.EOF {
+			p.current = nil
+			continue
+		}
+
+		return f, err
+	}
+}
+
+func (p *pkgArchive) ReadAll() ([]byte, error) {
+	if p.current == nil {
+		return nil, io.EOF
+	}
+
+	return p.current.ReadAll()
+}
+
+func (p *pkgArchive) stream() (io.ReadCloser, error) {
+	if p.current == nil {
+		return nil, io.EOF
+	}
+
+	return p.current.(streamer).stream()
+}
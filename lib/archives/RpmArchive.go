@@ -0,0 +1,69 @@
+package archives
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+const rpmLeadSize = 96
+
+var (
+	rpmMagic       = []byte{0xed, 0xab, 0xee, 0xdb}
+	rpmHeaderMagic = []byte{0x8e, 0xad, 0xe8, 0x01}
+)
+
+// NewRpmArchive reads the files installed by an RPM package, which are stored
+// as a cpio archive after the package headers. The payload may be compressed
+// with gzip, bzip2, xz or zstd, or not at all.
+func NewRpmArchive(data []byte, _ DecompressFunc) (Archive, error) {
+	if !bytes.HasPrefix(data, rpmMagic) || len(data) < rpmLeadSize {
+		return nil, fmt.Errorf("rpm: not an rpm package")
+	}
+
+	offset := rpmLeadSize
+
+	// the signature header is padded to a multiple of eight bytes
+	size, err := rpmHeaderSize(data, offset)
+	if err != nil {
+		return nil, fmt.Errorf("rpm: signature: %w", err)
+	}
+	offset += size + (8-size%8)%8
+
+	size, err = rpmHeaderSize(data, offset)
+	if err != nil {
+		return nil, fmt.Errorf("rpm: header: %w", err)
+	}
+	offset += size
+
+	if offset > len(data) {
+		return nil, fmt.Errorf("rpm: truncated package")
+	}
+
+	payload := data[offset:]
+	dr, err := DetectDecompressor(payload)(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("rpm: payload: %w", err)
+	}
+
+	return newCpioReader(dr), nil
+}
+
+// rpmHeaderSize returns the size of the RPM header structure at the given
+// offset, which consists of a 16-byte preamble, 16 bytes per index entry and
+// the data store.
+func rpmHeaderSize(data []byte, offset int) (int, error) {
+	if offset+16 > len(data) || !bytes.Equal(data[offset:offset+4], rpmHeaderMagic) {
+		return 0, fmt.Errorf("invalid header at offset %d", offset)
+	}
+
+	entries := binary.BigEndian.Uint32(data[offset+8:])
+	store := binary.BigEndian.Uint32(data[offset+12:])
+
+	size := 16 + uint64(entries)*16 + uint64(store)
+	if uint64(offset)+size > uint64(len(data)) {
+		return 0, fmt.Errorf("truncated header at offset %d", offset)
+	}
+
+	return int(size), nil
+}
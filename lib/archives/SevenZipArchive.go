@@ -0,0 +1,126 @@
+package archives
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/permafrost-dev/zeget/lib/files"
+)
+
+type SevenZipArchive struct {
+	r   *sevenzip.Reader
+	idx int
+}
+
+// sevenZipSignatureSize is the size of the signature header, which is followed
+// by the packed streams and then the header at the offset and of the size it gives.
+const sevenZipSignatureSize = 32
+
+// sevenZipMaxLinkSize is the longest symbolic link target read.
+const sevenZipMaxLinkSize = 4096
+
+// decompressor does nothing for a 7z archive because it already has built-in
+// compression.
+func NewSevenZipArchive(data []byte, _ DecompressFunc) (ar Archive, err error) {
+	if len(data) >= sevenZipSignatureSize {
+		offset := binary.LittleEndian.Uint64(data[12:])
+		size := binary.LittleEndian.Uint64(data[20:])
+		available := uint64(len(data) - sevenZipSignatureSize)
+
+		if offset > available || size > available-offset {
+			return nil, fmt.Errorf("7z: header of %d bytes at offset %d exceeds the archive size", size, offset)
+		}
+	}
+
+	// the reader panics on some malformed headers, as it sizes slices by them
+	defer func() {
+		if r := recover(); r != nil {
+			ar, err = nil, fmt.Errorf("7z: malformed header: %v", r)
+		}
+	}()
+
+	zr, err := sevenzip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("7z: %w", err)
+	}
+
+	return &SevenZipArchive{
+		r:   zr,
+		idx: -1,
+	}, nil
+}
+
+func (z *SevenZipArchive) Next() (files.File, error) {
+	for {
+		f, err := z.next()
+		if err != nil || f.Type != files.TypeOther {
+			return f, err
+		}
+	}
+}
+
+func (z *SevenZipArchive) next() (files.File, error) {
+	z.idx++
+
+	if z.idx < 0 || z.idx >= len(z.r.File) {
+		return files.File{}, io.EOF
+	}
+
+	f := z.r.File[z.idx]
+	mode := f.Mode()
+
+	file := files.File{
+		Name: f.Name,
+		Mode: mode,
+		Type: files.TypeNormal,
+	}
+
+	switch {
+	case mode.IsDir():
+		file.Type = files.TypeDir
+		file.Name = strings.TrimSuffix(file.Name, "/") + "/"
+	case mode&fs.ModeSymlink != 0:
+		// the target of a symlink is stored as its content
+		target, err := ReadAtMost(z, sevenZipMaxLinkSize)
+		if err != nil {
+			return files.File{}, err
+		}
+		if len(target) > sevenZipMaxLinkSize {
+			return files.File{}, fmt.Errorf("7z: %s: link target longer than %d bytes", f.Name, sevenZipMaxLinkSize)
+		}
+		file.Type = files.TypeSymlink
+		file.LinkName = string(target)
+	case !mode.IsRegular():
+		file.Type = files.TypeOther
+	}
+
+	return file, nil
+}
+
+func (z *SevenZipArchive) ReadAll() ([]byte, error) {
+	rc, err := z.stream()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
+func (z *SevenZipArchive) stream() (io.ReadCloser, error) {
+	if z.idx < 0 || z.idx >= len(z.r.File) {
+		return nil, io.EOF
+	}
+
+	rc, err := z.r.File[z.idx].Open()
+	if err != nil {
+		return nil, fmt.Errorf("7z extract: %w", err)
+	}
+
+	return rc, nil
+}
@@ -45,3 +45,7 @@ func (t *TarArchive) Next() (files.File, error) {
 func (t *TarArchive) ReadAll() ([]byte, error) {
 	return io.ReadAll(t.r)
 }
+
+func (t *TarArchive) stream() (io.ReadCloser, error) {
+	return io.NopCloser(t.r), nil
+}
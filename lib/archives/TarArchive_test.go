@@ -118,4 +118,80 @@ var _ = Describe("TarArchive", func() {
 			// Expect(string(readData)).To(Equal(data))
 		})
 	})
+
+	Describe("ReadAtMost", func() {
+		It("stops reading after the limit", func() {
+			for _, name := range []string{"a.txt", "b.txt"} {
+				writer.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: 11})
+				writer.Write([]byte("hello world"))
+			}
+			writer.Close()
+
+			archive, err := NewTarArchive(tarData.Bytes(), NoDecompress)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = archive.Next()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ReadAtMost(archive, 4)).To(Equal([]byte("hello")))
+
+			// the rest of the file is skipped
+			file, err := archive.Next()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(file.Name).To(Equal("b.txt"))
+			Expect(ReadAtMost(archive, 100)).To(Equal([]byte("hello world")))
+		})
+
+		It("does not decompress the rest of a cabinet folder", func() {
+			cab := makeCab(cabFiles, cabMSZIP)
+			copy(cab[len(cab)-16:], bytes.Repeat([]byte{0xff}, 16))
+
+			archive, err := NewCabArchive(cab, nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = archive.Next()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ReadAtMost(archive, 2)).To(Equal(cabFiles[0].data[:3]))
+			_, err = archive.ReadAll()
+			Expect(err).To(MatchError(ContainSubstring("invalid MSZIP block")))
+		})
+
+		DescribeTable("stops reading files listed up front after the limit",
+			func(open func() (Archive, error), name, data string) {
+				archive, err := open()
+				Expect(err).NotTo(HaveOccurred())
+
+				for {
+					f, err := archive.Next()
+					Expect(err).NotTo(HaveOccurred())
+					if f.Name == name {
+						break
+					}
+				}
+				Expect(ReadAtMost(archive, 2)).To(Equal([]byte(data[:3])))
+				Expect(ReadAtMost(archive, int64(len(data)))).To(Equal([]byte(data)))
+				Expect(archive.ReadAll()).To(Equal([]byte(data)))
+			},
+			Entry("HFS+ images", func() (Archive, error) {
+				return NewDmgArchive(makeUDIF(makeHFS(hfsItems), chunkZlib), nil)
+			}, "Tool.app/Contents/MacOS/tool", "#!/bin/sh\necho tool\n"),
+			Entry("SquashFS file systems", func() (Archive, error) {
+				return NewSquashfsArchive(makeSquashfs(sqfsTree, true), nil)
+			}, "tool-1.0/bin/large", large),
+			Entry("stored cabinets", func() (Archive, error) {
+				return NewCabArchive(makeCab(cabFiles, cabStored), nil)
+			}, "bin/tool.exe", string(cabFiles[0].data)),
+			Entry("MSZIP cabinets", func() (Archive, error) {
+				return NewCabArchive(makeCab(cabFiles, cabMSZIP), nil)
+			}, "bin/tool.exe", string(cabFiles[0].data)),
+			Entry("LZX cabinets", func() (Archive, error) {
+				return NewCabArchive(makeCab(cabFiles, cabLZX), nil)
+			}, "bin/tool.exe", string(cabFiles[0].data)),
+			Entry("NSIS installers", func() (Archive, error) {
+				return NewExeArchive(makePE(makeNSIS(false)), nil)
+			}, "bin/tool.exe", "MZ tool"),
+			Entry("solid NSIS installers", func() (Archive, error) {
+				return NewExeArchive(makePE(makeNSIS(true)), nil)
+			}, "bin/tool.exe", "MZ tool"),
+		)
+	})
 })
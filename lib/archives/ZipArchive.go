@@ -48,15 +48,23 @@ func (z *ZipArchive) Next() (files.File, error) {
 }
 
 func (z *ZipArchive) ReadAll() ([]byte, error) {
+	rc, err := z.stream()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	return data, err
+}
+
+func (z *ZipArchive) stream() (io.ReadCloser, error) {
 	if z.idx < 0 || z.idx >= len(z.r.File) {
 		return nil, io.EOF
 	}
-	f := z.r.File[z.idx]
-	rc, err := f.Open()
+	rc, err := z.r.File[z.idx].Open()
 	if err != nil {
 		return nil, fmt.Errorf("zip extract: %w", err)
 	}
-	defer rc.Close()
-	data, err := io.ReadAll(rc)
-	return data, err
+
+	return rc, nil
 }
@@ -0,0 +1,269 @@
+package archives_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/archives"
+	"github.com/permafrost-dev/zeget/lib/files"
+)
+
+const sqfsBlock = 4096
+
+type sqfsItem struct {
+	name     string
+	mode     uint16
+	data     string
+	children []sqfsItem
+}
+
+// sqfsBuilder builds a SquashFS file system with the inode and directory
+// tables in a metadata block each, and the tails of all files in a single
+// fragment.
+type sqfsBuilder struct {
+	compress bool
+	data     *bytes.Buffer
+	fragment []byte
+	inodes   []byte
+	dirs     []byte
+	count    uint32
+}
+
+// block returns a data or metadata block, compressed when that makes it
+// smaller, and whether it is stored uncompressed.
+func (b *sqfsBuilder) block(data []byte) ([]byte, bool) {
+	if b.compress {
+		if z := zlibbed(data); len(z) < len(data) {
+			return z, false
+		}
+	}
+
+	return data, true
+}
+
+func (b *sqfsBuilder) metadata(data []byte) []byte {
+	block, raw := b.block(data)
+	header := uint16(len(block))
+	if raw {
+		header |= 0x8000
+	}
+
+	return append(binary.LittleEndian.AppendUint16(nil, header), block...)
+}
+
+// inode adds an inode with its header and returns its reference.
+func (b *sqfsBuilder) inode(kind, mode uint16, body []byte) uint32 {
+	ref := uint32(len(b.inodes))
+	b.count++
+
+	header := make([]byte, 16)
+	binary.LittleEndian.PutUint16(header, kind)
+	binary.LittleEndian.PutUint16(header[2:], mode&0o7777)
+	binary.LittleEndian.PutUint32(header[12:], b.count)
+	b.inodes = append(append(b.inodes, header...), body...)
+
+	return ref
+}
+
+func (b *sqfsBuilder) add(item sqfsItem) (uint32, uint16) {
+	switch item.mode & 0o170000 {
+	case 0o040000:
+		// each entry in a run of its own, as all inodes are in the first
+		// metadata block
+		listing := []byte{}
+		for _, child := range item.children {
+			ref, kind := b.add(child)
+			entry := make([]byte, 20)
+			binary.LittleEndian.PutUint32(entry[8:], b.count)
+			binary.LittleEndian.PutUint16(entry[12:], uint16(ref))
+			binary.LittleEndian.PutUint16(entry[16:], kind)
+			binary.LittleEndian.PutUint16(entry[18:], uint16(len(child.name)-1))
+			listing = append(append(listing, entry...), child.name...)
+		}
+
+		body := make([]byte, 16)
+		binary.LittleEndian.PutUint32(body[4:], 2)
+		binary.LittleEndian.PutUint16(body[8:], uint16(len(listing)+3))
+		binary.LittleEndian.PutUint16(body[10:], uint16(len(b.dirs)))
+		b.dirs = append(b.dirs, listing...)
+
+		return b.inode(1, item.mode, body), 1
+
+	case 0o120000:
+		body := make([]byte, 8)
+		binary.LittleEndian.PutUint32(body, 1)
+		binary.LittleEndian.PutUint32(body[4:], uint32(len(item.data)))
+
+		return b.inode(3, item.mode, append(body, item.data...)), 3
+
+	default:
+		data := []byte(item.data)
+		body := make([]byte, 16)
+		binary.LittleEndian.PutUint32(body, uint32(sqfsBlock+b.data.Len()))
+		binary.LittleEndian.PutUint32(body[12:], uint32(len(data)))
+
+		for len(data) >= sqfsBlock {
+			block, raw := b.block(data[:sqfsBlock])
+			size := uint32(len(block))
+			if raw {
+				size |= 1 << 24
+			}
+			body = binary.LittleEndian.AppendUint32(body, size)
+			b.data.Write(block)
+			data = data[sqfsBlock:]
+		}
+
+		binary.LittleEndian.PutUint32(body[4:], 0xffffffff)
+		if len(data) > 0 {
+			binary.LittleEndian.PutUint32(body[4:], 0)
+			binary.LittleEndian.PutUint32(body[8:], uint32(len(b.fragment)))
+			b.fragment = append(b.fragment, data...)
+		}
+
+		return b.inode(2, item.mode, body), 2
+	}
+}
+
+// makeSquashfs builds a SquashFS file system of a root directory, with its
+// data blocks after a block for the superblock.
+func makeSquashfs(root sqfsItem, compress bool) []byte {
+	b := &sqfsBuilder{compress: compress, data: new(bytes.Buffer)}
+	rootRef, _ := b.add(root)
+
+	image := make([]byte, sqfsBlock)
+	image = append(image, b.data.Bytes()...)
+
+	fragments := 0
+	entry := make([]byte, 16)
+	if len(b.fragment) > 0 {
+		block, raw := b.block(b.fragment)
+		size := uint32(len(block))
+		if raw {
+			size |= 1 << 24
+		}
+		binary.LittleEndian.PutUint64(entry, uint64(len(image)))
+		binary.LittleEndian.PutUint32(entry[8:], size)
+		image = append(image, block...)
+		fragments = 1
+	}
+
+	inodeTable := len(image)
+	image = append(image, b.metadata(b.inodes)...)
+	dirTable := len(image)
+	image = append(image, b.metadata(b.dirs)...)
+	fragmentBlock := len(image)
+	image = append(image, b.metadata(entry)...)
+	fragmentTable := len(image)
+	image = binary.LittleEndian.AppendUint64(image, uint64(fragmentBlock))
+
+	copy(image, "hsqs")
+	binary.LittleEndian.PutUint32(image[4:], b.count)
+	binary.LittleEndian.PutUint32(image[12:], sqfsBlock)
+	binary.LittleEndian.PutUint32(image[16:], uint32(fragments))
+	binary.LittleEndian.PutUint16(image[20:], 1)
+	binary.LittleEndian.PutUint16(image[22:], 12)
+	binary.LittleEndian.PutUint16(image[28:], 4)
+	binary.LittleEndian.PutUint64(image[32:], uint64(rootRef))
+	binary.LittleEndian.PutUint64(image[40:], uint64(len(image)))
+	binary.LittleEndian.PutUint64(image[64:], uint64(inodeTable))
+	binary.LittleEndian.PutUint64(image[72:], uint64(dirTable))
+	binary.LittleEndian.PutUint64(image[80:], uint64(fragmentTable))
+
+	return image
+}
+
+// makeAppImage builds an AppImage of a 64-bit ELF runtime stub with no
+// sections followed by a file system.
+func makeAppImage(kind byte, fs []byte) []byte {
+	runtime := make([]byte, 256)
+	copy(runtime, "\x7fELF\x02\x01\x01\x00AI")
+	runtime[10] = kind
+	binary.LittleEndian.PutUint64(runtime[0x28:], 192)
+	binary.LittleEndian.PutUint16(runtime[0x3a:], 64)
+	binary.LittleEndian.PutUint16(runtime[0x3c:], 1)
+
+	return append(runtime, fs...)
+}
+
+var large = strings.Repeat("0123456789abcdef", 600)
+
+var sqfsTree = sqfsItem{mode: 0o040755, children: []sqfsItem{
+	{name: "tool-1.0", mode: 0o040755, children: []sqfsItem{
+		{name: "README", mode: 0o100644, data: "readme"},
+		{name: "bin", mode: 0o040755, children: []sqfsItem{
+			{name: "tool", mode: 0o100755, data: "#!/bin/sh\necho tool\n"},
+			{name: "tl", mode: 0o120777, data: "tool"},
+			{name: "large", mode: 0o100644, data: large},
+		}},
+		{name: "empty", mode: 0o040755},
+	}},
+}}
+
+var _ = Describe("SquashFS archives", func() {
+	DescribeTable("NewSquashfsArchive",
+		func(compress bool) {
+			ar, err := NewSquashfsArchive(makeSquashfs(sqfsTree, compress), nil)
+			Expect(err).NotTo(HaveOccurred())
+			expectFixtureFiles(ar)
+		},
+		Entry("uncompressed", false),
+		Entry("zlib", true),
+	)
+
+	It("reads files spanning several blocks", func() {
+		ar, err := NewSquashfsArchive(makeSquashfs(sqfsTree, true), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		found := map[string]string{}
+		for f, err := ar.Next(); err == nil; f, err = ar.Next() {
+			if f.Type == files.TypeNormal {
+				data, err := ar.ReadAll()
+				Expect(err).NotTo(HaveOccurred())
+				found[f.Name] = string(data)
+			} else {
+				found[f.Name] = ""
+			}
+		}
+		Expect(found).To(HaveKeyWithValue("tool-1.0/bin/large", large))
+		Expect(found).To(HaveKeyWithValue("tool-1.0/README", "readme"))
+		Expect(found).To(HaveKey("tool-1.0/empty/"))
+	})
+
+	It("fails for other files", func() {
+		_, err := NewSquashfsArchive([]byte("not a file system"), nil)
+		Expect(err).To(MatchError("squashfs: not a SquashFS file system"))
+	})
+
+	Describe("NewAppImageArchive", func() {
+		It("reads the file system after the runtime", func() {
+			ar, err := NewAppImageArchive(makeAppImage(2, makeSquashfs(sqfsTree, true)), nil)
+			Expect(err).NotTo(HaveOccurred())
+			expectFixtureFiles(ar)
+		})
+
+		It("fails for type 1 AppImages", func() {
+			_, err := NewAppImageArchive(makeAppImage(1, makeSquashfs(sqfsTree, true)), nil)
+			Expect(err).To(MatchError("appimage: type 1 AppImages are not supported"))
+		})
+
+		It("fails for other executables", func() {
+			_, err := NewAppImageArchive(makeAppImage(0, makeSquashfs(sqfsTree, true)), nil)
+			Expect(err).To(MatchError("appimage: not an AppImage"))
+
+			_, err = NewAppImageArchive(makeAppImage(2, nil), nil)
+			Expect(err).To(MatchError("appimage: file system not found"))
+		})
+
+		It("detects AppImages", func() {
+			Expect(IsAppImage(makeAppImage(2, makeSquashfs(sqfsTree, false)))).To(BeTrue())
+			Expect(IsAppImage(makeAppImage(0, makeSquashfs(sqfsTree, false)))).To(BeFalse())
+		})
+	})
+
+	It("is detected by its magic", func() {
+		Expect(DetectFormat(makeSquashfs(sqfsTree, false))).To(Equal(".squashfs"))
+	})
+})
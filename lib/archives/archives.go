@@ -13,3 +13,41 @@ type Archive interface {
 	Next() (files.File, error)
 	ReadAll() ([]byte, error)
 }
+
+// A streamer is an archive which can read the current file as a stream.
+type streamer interface {
+	stream() (io.ReadCloser, error)
+}
+
+// A limitedReader is an archive which can stop reading the current file
+// after n+1 bytes without streaming it.
+type limitedReader interface {
+	readAtMost(n int64) ([]byte, error)
+}
+
+// ReadAtMost reads the current file of an archive like ReadAll, but stops
+// after n+1 bytes for archives which stream their files or read them on
+// demand, so that larger files can be told apart without reading them into
+// memory.
+func ReadAtMost(ar Archive, n int64) ([]byte, error) {
+	if l, ok := ar.(limitedReader); ok {
+		data, err := l.readAtMost(n)
+		if err != nil {
+			return nil, err
+		}
+		return data[:min(int64(len(data)), n+1)], nil
+	}
+
+	s, ok := ar.(streamer)
+	if !ok {
+		return ar.ReadAll()
+	}
+
+	r, err := s.stream()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(io.LimitReader(r, n+1))
+}
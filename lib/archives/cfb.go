@@ -0,0 +1,249 @@
+package archives
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+var cfbMagic = []byte{0xd0, 0xcf, 0x11, 0xe0, 0xa1, 0xb1, 0x1a, 0xe1}
+
+const (
+	cfbHeaderSize = 512
+	cfbEntrySize  = 128
+
+	cfbMaxSector  = 0xfffffffa
+	cfbEndOfChain = 0xfffffffe
+	cfbNoStream   = 0xffffffff
+
+	cfbStorage = 1
+	cfbStream  = 2
+	cfbRoot    = 5
+)
+
+type cfbEntry struct {
+	name   string
+	kind   byte
+	left   uint32
+	right  uint32
+	child  uint32
+	start  uint32
+	size   uint64
+	parent uint32
+}
+
+// A cfbFile is a compound file, the container of MSI databases: a small FAT
+// file system whose files are called streams.
+type cfbFile struct {
+	data       []byte
+	sectorSize uint64
+	fat        []uint32
+	miniFAT    []uint32
+	miniStream []byte
+	cutoff     uint64
+	entries    []cfbEntry
+}
+
+// openCFB parses the allocation tables and the directory of a compound file.
+func openCFB(data []byte) (*cfbFile, error) {
+	if !bytes.HasPrefix(data, cfbMagic) || len(data) < cfbHeaderSize {
+		return nil, fmt.Errorf("cfb: not a compound file")
+	}
+
+	shift := binary.LittleEndian.Uint16(data[30:])
+	if shift != 9 && shift != 12 {
+		return nil, fmt.Errorf("cfb: invalid sector size")
+	}
+
+	c := &cfbFile{
+		data:       data,
+		sectorSize: 1 << shift,
+		cutoff:     uint64(binary.LittleEndian.Uint32(data[56:])),
+	}
+
+	// the header lists the first 109 FAT sectors, further ones are listed in
+	// a chain of DIFAT sectors
+	var fatSectors []uint32
+	for i := 0; i < 109; i++ {
+		fatSectors = append(fatSectors, binary.LittleEndian.Uint32(data[76+4*i:]))
+	}
+	next := binary.LittleEndian.Uint32(data[68:])
+	for n := 0; next <= cfbMaxSector; n++ {
+		sector, err := c.sector(next)
+		if err != nil || n > len(data)/int(c.sectorSize) {
+			return nil, fmt.Errorf("cfb: invalid DIFAT")
+		}
+		for i := uint64(0); i < c.sectorSize/4-1; i++ {
+			fatSectors = append(fatSectors, binary.LittleEndian.Uint32(sector[4*i:]))
+		}
+		next = binary.LittleEndian.Uint32(sector[c.sectorSize-4:])
+	}
+
+	count := int(binary.LittleEndian.Uint32(data[44:]))
+	if count > len(fatSectors) {
+		return nil, fmt.Errorf("cfb: invalid FAT")
+	}
+	for _, s := range fatSectors[:count] {
+		sector, err := c.sector(s)
+		if err != nil {
+			return nil, fmt.Errorf("cfb: invalid FAT")
+		}
+		for i := uint64(0); i < c.sectorSize; i += 4 {
+			c.fat = append(c.fat, binary.LittleEndian.Uint32(sector[i:]))
+		}
+	}
+
+	dir, err := c.chain(binary.LittleEndian.Uint32(data[48:]), c.fat, c.sectorSize, c.sector)
+	if err != nil {
+		return nil, fmt.Errorf("cfb: directory: %w", err)
+	}
+	for i := 0; i+cfbEntrySize <= len(dir); i += cfbEntrySize {
+		e := parseCFBEntry(dir[i : i+cfbEntrySize])
+		if c.sectorSize == 512 {
+			// version 3 files only use the low half of the size
+			e.size &= 0xffffffff
+		}
+		c.entries = append(c.entries, e)
+	}
+	if len(c.entries) == 0 || c.entries[0].kind != cfbRoot {
+		return nil, fmt.Errorf("cfb: missing root entry")
+	}
+
+	miniFAT, err := c.chain(binary.LittleEndian.Uint32(data[60:]), c.fat, c.sectorSize, c.sector)
+	if err != nil {
+		return nil, fmt.Errorf("cfb: mini FAT: %w", err)
+	}
+	for i := 0; i+4 <= len(miniFAT); i += 4 {
+		c.miniFAT = append(c.miniFAT, binary.LittleEndian.Uint32(miniFAT[i:]))
+	}
+
+	root := c.entries[0]
+	if c.miniStream, err = c.chain(root.start, c.fat, c.sectorSize, c.sector); err != nil {
+		return nil, fmt.Errorf("cfb: mini stream: %w", err)
+	}
+	if root.size < uint64(len(c.miniStream)) {
+		c.miniStream = c.miniStream[:root.size]
+	}
+
+	c.link(0, 0)
+
+	return c, nil
+}
+
+func parseCFBEntry(b []byte) cfbEntry {
+	nameLength := int(binary.LittleEndian.Uint16(b[64:]))
+	if nameLength > 64 {
+		nameLength = 64
+	}
+
+	units := make([]uint16, 0, 32)
+	for i := 0; i+1 < nameLength; i += 2 {
+		if u := binary.LittleEndian.Uint16(b[i:]); u != 0 {
+			units = append(units, u)
+		}
+	}
+
+	return cfbEntry{
+		name:   string(utf16.Decode(units)),
+		kind:   b[66],
+		left:   binary.LittleEndian.Uint32(b[68:]),
+		right:  binary.LittleEndian.Uint32(b[72:]),
+		child:  binary.LittleEndian.Uint32(b[76:]),
+		start:  binary.LittleEndian.Uint32(b[116:]),
+		size:   binary.LittleEndian.Uint64(b[120:]),
+		parent: cfbNoStream,
+	}
+}
+
+// link records the parent storage of the entries of the tree rooted at the
+// child of the storage.
+func (c *cfbFile) link(storage uint32, depth int) {
+	if depth > 64 {
+		return
+	}
+
+	var walk func(id uint32, n int)
+	walk = func(id uint32, n int) {
+		if id >= uint32(len(c.entries)) || n > len(c.entries) || c.entries[id].parent != cfbNoStream || id == 0 {
+			return
+		}
+		c.entries[id].parent = storage
+		walk(c.entries[id].left, n+1)
+		walk(c.entries[id].right, n+1)
+		if c.entries[id].kind == cfbStorage {
+			c.link(id, depth+1)
+		}
+	}
+	walk(c.entries[storage].child, 0)
+}
+
+func (c *cfbFile) sector(id uint32) ([]byte, error) {
+	offset := (uint64(id) + 1) * c.sectorSize
+	if offset+c.sectorSize > uint64(len(c.data)) {
+		return nil, fmt.Errorf("sector %d beyond the end of the file", id)
+	}
+
+	return c.data[offset : offset+c.sectorSize], nil
+}
+
+func (c *cfbFile) miniSector(id uint32) ([]byte, error) {
+	offset := uint64(id) * 64
+	if offset+64 > uint64(len(c.miniStream)) {
+		return nil, fmt.Errorf("mini sector %d beyond the end of the mini stream", id)
+	}
+
+	return c.miniStream[offset : offset+64], nil
+}
+
+// chain concatenates the sectors of a chain of an allocation table.
+func (c *cfbFile) chain(start uint32, table []uint32, size uint64, sector func(uint32) ([]byte, error)) ([]byte, error) {
+	var data []byte
+
+	for id := start; id != cfbEndOfChain && id != cfbNoStream; id = table[id] {
+		if id >= uint32(len(table)) || uint64(len(data)) > uint64(len(table))*size {
+			return nil, fmt.Errorf("invalid sector chain")
+		}
+
+		s, err := sector(id)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, s...)
+	}
+
+	return data, nil
+}
+
+// streams returns the streams stored in the root storage by name.
+func (c *cfbFile) streams() map[string]*cfbEntry {
+	streams := map[string]*cfbEntry{}
+	for i := range c.entries {
+		if e := &c.entries[i]; e.kind == cfbStream && e.parent == 0 {
+			streams[e.name] = e
+		}
+	}
+
+	return streams
+}
+
+// read returns the content of a stream. Streams smaller than the cutoff are
+// stored in the mini stream, in 64-byte sectors.
+func (c *cfbFile) read(e *cfbEntry) ([]byte, error) {
+	var data []byte
+	var err error
+
+	if e.size < c.cutoff {
+		data, err = c.chain(e.start, c.miniFAT, 64, c.miniSector)
+	} else {
+		data, err = c.chain(e.start, c.fat, c.sectorSize, c.sector)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cfb: %s: %w", e.name, err)
+	}
+	if uint64(len(data)) < e.size {
+		return nil, fmt.Errorf("cfb: %s: truncated stream", e.name)
+	}
+
+	return data[:e.size], nil
+}
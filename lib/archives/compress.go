@@ -0,0 +1,129 @@
+package archives
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+const (
+	lzwClear     = 256
+	lzwBlockMode = 0x80
+	lzwMaxBits   = 16
+)
+
+// Uncompress decompresses data in the LZW format of the Unix compress tool
+// (.Z files), which differs from the formats supported by compress/lzw.
+func Uncompress(r io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 3 || data[0] != 0x1f || data[1] != 0x9d {
+		return nil, fmt.Errorf("compress: invalid header")
+	}
+
+	maxbits := int(data[2] & 0x1f)
+	block := data[2]&lzwBlockMode != 0
+	if maxbits < 9 || maxbits > lzwMaxBits {
+		return nil, fmt.Errorf("compress: unsupported code size %d", maxbits)
+	}
+
+	// codes are read three bytes at a time, so pad the input
+	total := (len(data) - 3) * 8
+	data = append(data[3:], 0, 0, 0)
+
+	maxmax := 1 << maxbits
+	prefix := make([]uint16, maxmax)
+	suffix := make([]byte, maxmax)
+	for i := 0; i < 256; i++ {
+		suffix[i] = byte(i)
+	}
+
+	nbits, maxcode, free := 9, 1<<9-1, 256
+	if block {
+		free = 257
+	}
+
+	// Codes are written in groups of eight. When the code size changes or
+	// the table is cleared, the rest of the current group is skipped.
+	pos, group := 0, 0
+	align := func() {
+		size := nbits * 8
+		pos = group + (pos-group+size-1)/size*size
+		group = pos
+	}
+
+	out := new(bytes.Buffer)
+	stack := make([]byte, 0, maxmax)
+	oldcode := -1
+	var finchar byte
+
+	for pos+nbits <= total {
+		if free > maxcode {
+			align()
+			nbits++
+			if nbits == maxbits {
+				maxcode = maxmax
+			} else {
+				maxcode = 1<<nbits - 1
+			}
+			continue
+		}
+
+		i := pos >> 3
+		v := int(data[i]) | int(data[i+1])<<8 | int(data[i+2])<<16
+		code := (v >> (pos & 7)) & (1<<nbits - 1)
+		pos += nbits
+
+		if oldcode == -1 {
+			if code >= 256 {
+				return nil, fmt.Errorf("compress: corrupt input")
+			}
+			finchar = byte(code)
+			out.WriteByte(finchar)
+			oldcode = code
+			continue
+		}
+
+		if code == lzwClear && block {
+			// the next code replaces the clear code in the table
+			free = 256
+			align()
+			nbits, maxcode = 9, 1<<9-1
+			continue
+		}
+
+		incode := code
+		stack = stack[:0]
+
+		if code >= free {
+			if code > free {
+				return nil, fmt.Errorf("compress: corrupt input")
+			}
+			stack = append(stack, finchar)
+			code = oldcode
+		}
+
+		for code >= 256 {
+			stack = append(stack, suffix[code])
+			code = int(prefix[code])
+		}
+		finchar = byte(code)
+		stack = append(stack, finchar)
+
+		for j := len(stack) - 1; j >= 0; j-- {
+			out.WriteByte(stack[j])
+		}
+
+		if free < maxmax {
+			prefix[free] = uint16(oldcode)
+			suffix[free] = finchar
+			free++
+		}
+		oldcode = incode
+	}
+
+	return out, nil
+}
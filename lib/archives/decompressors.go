@@ -0,0 +1,210 @@
+package archives
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+	"github.com/ulikunitz/xz/lzma"
+)
+
+func Gunzip(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+func Bunzip2(r io.Reader) (io.Reader, error) {
+	return bzip2.NewReader(r), nil
+}
+
+func Unxz(r io.Reader) (io.Reader, error) {
+	return xz.NewReader(bufio.NewReader(r))
+}
+
+func Unzstd(r io.Reader) (io.Reader, error) {
+	return zstd.NewReader(r)
+}
+
+func Unlzma(r io.Reader) (io.Reader, error) {
+	return lzma.NewReader(bufio.NewReader(r))
+}
+
+// Unlzip decompresses the first member of an lzip file, which is an LZMA
+// stream with a different header.
+func Unlzip(r io.Reader) (io.Reader, error) {
+	header := make([]byte, 6)
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.HasPrefix(header, []byte("LZIP")) {
+		return nil, fmt.Errorf("lzip: invalid header")
+	}
+	if header[4] != 1 {
+		return nil, fmt.Errorf("lzip: unsupported version %d", header[4])
+	}
+
+	base := uint32(1) << (header[5] & 0x1f)
+	dictSize := base - (base/16)*uint32(header[5]>>5)
+
+	// lzip streams use the default LZMA properties, end with a marker, and
+	// are followed by a trailer that the LZMA reader ignores
+	lzmaHeader := make([]byte, 13)
+	lzmaHeader[0] = 0x5d
+	binary.LittleEndian.PutUint32(lzmaHeader[1:], dictSize)
+	binary.LittleEndian.PutUint64(lzmaHeader[5:], ^uint64(0))
+
+	return lzma.NewReader(io.MultiReader(bytes.NewReader(lzmaHeader), bufio.NewReader(r)))
+}
+
+func Unlz4(r io.Reader) (io.Reader, error) {
+	return lz4.NewReader(r), nil
+}
+
+func Unbrotli(r io.Reader) (io.Reader, error) {
+	return brotli.NewReader(r), nil
+}
+
+// Unpbzx decompresses the pbzx format of macOS installer payloads, a series of
+// chunks that are compressed with xz or stored. The chunks are decompressed
+// as they are read, and none may be larger than the chunk size in the header.
+func Unpbzx(r io.Reader) (io.Reader, error) {
+	p := &pbzxReader{r: bufio.NewReader(r), remaining: -1}
+	if lr, ok := r.(interface{ Len() int }); ok {
+		p.remaining = int64(lr.Len())
+	}
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(p.r, header); err != nil || !bytes.HasPrefix(header, []byte("pbzx")) {
+		return nil, fmt.Errorf("pbzx: invalid header")
+	}
+
+	p.chunkSize = binary.BigEndian.Uint64(header[4:])
+	if p.chunkSize == 0 || p.chunkSize > maxPbzxChunkSize {
+		return nil, fmt.Errorf("pbzx: invalid chunk size %d", p.chunkSize)
+	}
+	p.remaining = max(p.remaining-int64(len(header)), -1)
+
+	return p, nil
+}
+
+// maxPbzxChunkSize bounds the chunk size of pbzx streams, which is 16 MiB in
+// installer payloads.
+const maxPbzxChunkSize = 1 << 30
+
+type pbzxReader struct {
+	r         *bufio.Reader
+	chunkSize uint64
+	// remaining is the size of the input left, or -1 if it is unknown
+	remaining int64
+	// chunk is the input of the current chunk, and out its decompressed data
+	chunk *io.LimitedReader
+	out   *io.LimitedReader
+}
+
+func (p *pbzxReader) Read(b []byte) (int, error) {
+	for {
+		if p.out == nil {
+			if err := p.next(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := p.out.Read(b)
+		if err == io.EOF {
+			err = p.finish()
+		} else if err != nil {
+			err = fmt.Errorf("pbzx: %w", err)
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// next starts reading the next chunk.
+func (p *pbzxReader) next() error {
+	chunk := make([]byte, 16)
+	if _, err := io.ReadFull(p.r, chunk); err == io.EOF {
+		return io.EOF
+	} else if err != nil {
+		return fmt.Errorf("pbzx: truncated chunk")
+	}
+
+	length := binary.BigEndian.Uint64(chunk[8:])
+	if p.remaining >= 0 {
+		if p.remaining -= int64(len(chunk)); length > uint64(p.remaining) {
+			return fmt.Errorf("pbzx: chunk of %d bytes exceeds the input", length)
+		}
+		p.remaining -= int64(length)
+	}
+	p.chunk = &io.LimitedReader{R: p.r, N: int64(length)}
+
+	var cr io.Reader = p.chunk
+	if magic, _ := p.r.Peek(6); bytes.Equal(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}) {
+		xr, err := xz.NewReader(p.chunk)
+		if err != nil {
+			return fmt.Errorf("pbzx: %w", err)
+		}
+		cr = xr
+	} else if length > p.chunkSize {
+		return fmt.Errorf("pbzx: chunk of %d bytes exceeds the chunk size", length)
+	}
+
+	// one more byte than a chunk may hold tells an oversized chunk apart
+	p.out = &io.LimitedReader{R: cr, N: int64(p.chunkSize) + 1}
+
+	return nil
+}
+
+// finish checks the current chunk once its data is read.
+func (p *pbzxReader) finish() error {
+	if p.out.N == 0 {
+		return fmt.Errorf("pbzx: chunk exceeds the chunk size of %d bytes", p.chunkSize)
+	}
+	if _, err := io.Copy(io.Discard, p.chunk); err != nil || p.chunk.N > 0 {
+		return fmt.Errorf("pbzx: truncated chunk")
+	}
+
+	p.out = nil
+	return nil
+}
+
+// NoDecompress returns the data as is.
+func NoDecompress(r io.Reader) (io.Reader, error) {
+	return r, nil
+}
+
+// DetectDecompressor returns the decompressor for data compressed with gzip,
+// bzip2, xz, zstd, lzip, lzma, lz4 or compress, identified by its magic
+// bytes. Data in other formats, including brotli which has no magic bytes, is
+// assumed to be uncompressed.
+func DetectDecompressor(data []byte) DecompressFunc {
+	for _, c := range compressionFormats {
+		if bytes.HasPrefix(data, c.magic) {
+			return c.decompress
+		}
+	}
+
+	if isLzma(data) {
+		return Unlzma
+	}
+
+	return NoDecompress
+}
+
+// isLzma reports whether data starts with the header of an LZMA stream in the
+// legacy .lzma format, with the default properties and a dictionary size
+// that is a power of two.
+func isLzma(data []byte) bool {
+	if len(data) < 13 || data[0] != 0x5d {
+		return false
+	}
+
+	dictSize := binary.LittleEndian.Uint32(data[1:])
+
+	return dictSize >= 1<<12 && dictSize&(dictSize-1) == 0
+}
@@ -0,0 +1,47 @@
+package archives
+
+import (
+	"io"
+
+	"github.com/permafrost-dev/zeget/lib/files"
+)
+
+// An entry is a file listed up front, such as a file of a disk image, whose
+// content is read on demand. Given a limit of zero or more, read may stop
+// after limit+1 bytes of a larger file.
+type entry struct {
+	files.File
+	read func(limit int64) ([]byte, error)
+}
+
+// entryArchive lists a fixed set of entries.
+type entryArchive struct {
+	entries []entry
+	idx     int
+}
+
+func newEntryArchive(entries []entry) *entryArchive {
+	return &entryArchive{entries: entries, idx: -1}
+}
+
+func (e *entryArchive) Next() (files.File, error) {
+	e.idx++
+
+	if e.idx >= len(e.entries) {
+		return files.File{}, io.EOF
+	}
+
+	return e.entries[e.idx].File, nil
+}
+
+func (e *entryArchive) ReadAll() ([]byte, error) {
+	return e.readAtMost(-1)
+}
+
+func (e *entryArchive) readAtMost(n int64) ([]byte, error) {
+	if e.idx < 0 || e.idx >= len(e.entries) || e.entries[e.idx].read == nil {
+		return nil, io.EOF
+	}
+
+	return e.entries[e.idx].read(n)
+}
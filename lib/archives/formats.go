@@ -0,0 +1,89 @@
+package archives
+
+import (
+	"bytes"
+	"io"
+)
+
+// the magic bytes, file extensions and decompressors of compression formats
+var compressionFormats = []struct {
+	magic      []byte
+	ext        string
+	decompress DecompressFunc
+}{
+	{[]byte{0x1f, 0x8b}, ".gz", Gunzip},
+	{[]byte("BZh"), ".bz2", Bunzip2},
+	{[]byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, ".xz", Unxz},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, ".zst", Unzstd},
+	{[]byte("LZIP"), ".lz", Unlzip},
+	{[]byte{0x04, 0x22, 0x4d, 0x18}, ".lz4", Unlz4},
+	{[]byte{0x1f, 0x9d}, ".Z", Uncompress},
+}
+
+// DetectFormat returns the file extension for the format of data, identified
+// by its magic bytes, such as ".zip", ".tar.xz" or ".gz". Self-extracting
+// Windows executables and installers are identified as ".exe". It returns an
+// empty string for other data, such as plain executables.
+func DetectFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		return ".zip"
+	case bytes.HasPrefix(data, sevenZipMagic):
+		return ".7z"
+	case bytes.HasPrefix(data, []byte(arMagic+"debian-binary")):
+		return ".deb"
+	case bytes.HasPrefix(data, rpmMagic):
+		return ".rpm"
+	case bytes.HasPrefix(data, xarMagic):
+		return ".pkg"
+	case len(data) >= udifTrailerSize && bytes.HasPrefix(data[len(data)-udifTrailerSize:], []byte("koly")):
+		return ".dmg"
+	case bytes.HasPrefix(data, cfbMagic):
+		return ".msi"
+	case bytes.HasPrefix(data, cabMagic):
+		return ".cab"
+	case bytes.HasPrefix(data, squashfsMagic):
+		return ".squashfs"
+	case isSelfExtracting(data):
+		return ".exe"
+	case isCpio(data):
+		return ".cpio"
+	case isTar(data):
+		return ".tar"
+	}
+
+	ext := ""
+	for _, c := range compressionFormats {
+		if bytes.HasPrefix(data, c.magic) {
+			ext = c.ext
+			break
+		}
+	}
+	if ext == "" && isLzma(data) {
+		ext = ".lzma"
+	}
+	if ext == "" {
+		return ""
+	}
+
+	// look for a tar archive in the first block of the decompressed data
+	dr, err := DetectDecompressor(data)(bytes.NewReader(data))
+	if err != nil {
+		return ext
+	}
+	block := make([]byte, 512)
+	if n, _ := io.ReadFull(dr, block); isTar(block[:n]) {
+		return ".tar" + ext
+	}
+
+	return ext
+}
+
+func isTar(data []byte) bool {
+	return len(data) >= 263 && bytes.Equal(data[257:262], []byte("ustar"))
+}
+
+func isCpio(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("070701")) || bytes.HasPrefix(data, []byte("070702")) ||
+		bytes.HasPrefix(data, []byte("070707"))
+}
@@ -0,0 +1,159 @@
+package archives_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/andybalholm/brotli"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/archives"
+	"github.com/permafrost-dev/zeget/lib/files"
+	"github.com/pierrec/lz4/v4"
+)
+
+func readFixture(name string) []byte {
+	data, err := os.ReadFile(filepath.Join("..", "..", "test", "archives", name))
+	Expect(err).NotTo(HaveOccurred())
+
+	return data
+}
+
+// expectFixtureFiles checks the files of the test/archives fixtures, which
+// contain the tool-1.0 directory.
+func expectFixtureFiles(ar Archive) {
+	found := map[string]files.File{}
+	for {
+		f, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		Expect(err).NotTo(HaveOccurred())
+		found[f.Name] = f
+
+		if f.Name == "tool-1.0/bin/tool" {
+			data, err := ar.ReadAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("#!/bin/sh\necho tool\n"))
+		}
+	}
+
+	Expect(found).To(HaveKey("tool-1.0/"))
+	Expect(found["tool-1.0/"].Dir()).To(BeTrue())
+	Expect(found).To(HaveKey("tool-1.0/README"))
+	Expect(found).To(HaveKey("tool-1.0/bin/tool"))
+	Expect(found["tool-1.0/bin/tool"].Mode.Perm()).To(Equal(os.FileMode(0o755)))
+	Expect(found).To(HaveKey("tool-1.0/bin/tl"))
+	Expect(found["tool-1.0/bin/tl"].Type).To(Equal(files.TypeSymlink))
+	Expect(found["tool-1.0/bin/tl"].LinkName).To(Equal("tool"))
+}
+
+var _ = Describe("Archive formats", func() {
+	DescribeTable("reading fixtures",
+		func(name string, ar ArchiveFunc, decompress DecompressFunc) {
+			archive, err := ar(readFixture(name), decompress)
+			Expect(err).NotTo(HaveOccurred())
+			expectFixtureFiles(archive)
+		},
+		Entry("7z", "tool.7z", NewSevenZipArchive, NoDecompress),
+		Entry("cpio", "tool.cpio", NewCpioArchive, NoDecompress),
+		Entry("odc cpio", "tool.odc.cpio", NewCpioArchive, NoDecompress),
+		Entry("tar.Z", "tool.tar.Z", NewTarArchive, Uncompress),
+		Entry("tar.lz", "tool.tar.lz", NewTarArchive, Unlzip),
+		Entry("tar.lzma", "tool.tar.lzma", NewTarArchive, Unlzma),
+	)
+
+	Describe("NewSevenZipArchive", func() {
+		It("fails for a corrupted header", func() {
+			_, err := NewSevenZipArchive(readFixture("corrupt.7z"), NoDecompress)
+			Expect(err).To(MatchError(HavePrefix("7z: malformed header")))
+		})
+
+		It("fails for a header beyond the end of the archive", func() {
+			data := readFixture("tool.7z")
+			binary.LittleEndian.PutUint64(data[20:], 1<<40)
+
+			_, err := NewSevenZipArchive(data, NoDecompress)
+			Expect(err).To(MatchError(ContainSubstring("exceeds the archive size")))
+		})
+	})
+
+	Describe("decompressors", func() {
+		It("decompresses lz4 frames", func() {
+			buf := new(bytes.Buffer)
+			w := lz4.NewWriter(buf)
+			_, err := w.Write([]byte("lz4 data"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+
+			r, err := DetectDecompressor(buf.Bytes())(bytes.NewReader(buf.Bytes()))
+			Expect(err).NotTo(HaveOccurred())
+			data, err := io.ReadAll(r)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("lz4 data"))
+		})
+
+		It("decompresses brotli streams", func() {
+			buf := new(bytes.Buffer)
+			w := brotli.NewWriter(buf)
+			_, err := w.Write([]byte("brotli data"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+
+			r, err := Unbrotli(buf)
+			Expect(err).NotTo(HaveOccurred())
+			data, err := io.ReadAll(r)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("brotli data"))
+		})
+
+		It("rejects corrupt compress data", func() {
+			_, err := Uncompress(bytes.NewReader([]byte{0x1f, 0x9d, 0x90, 0xff, 0xff, 0xff}))
+			Expect(err).To(MatchError("compress: corrupt input"))
+		})
+
+		It("rejects invalid lzip headers", func() {
+			_, err := Unlzip(bytes.NewReader([]byte("LZIP\x02\x17")))
+			Expect(err).To(MatchError("lzip: unsupported version 2"))
+		})
+	})
+
+	Describe("DetectFormat", func() {
+		DescribeTable("detecting fixtures",
+			func(name string, expected string) {
+				Expect(DetectFormat(readFixture(name))).To(Equal(expected))
+			},
+			Entry("7z", "tool.7z", ".7z"),
+			Entry("cpio", "tool.cpio", ".cpio"),
+			Entry("odc cpio", "tool.odc.cpio", ".cpio"),
+			Entry("tar.Z", "tool.tar.Z", ".tar.Z"),
+			Entry("tar.lz", "tool.tar.lz", ".tar.lz"),
+			Entry("tar.lzma", "tool.tar.lzma", ".tar.lzma"),
+		)
+
+		It("detects compressed files that are not archives", func() {
+			Expect(DetectFormat(gzipped([]byte("binary")))).To(Equal(".gz"))
+		})
+
+		It("detects tar archives", func() {
+			data := makeTar(packageEntries)
+			Expect(DetectFormat(data)).To(Equal(".tar"))
+			Expect(DetectFormat(gzipped(data))).To(Equal(".tar.gz"))
+		})
+
+		It("detects packages", func() {
+			Expect(DetectFormat(makeRpm(nil))).To(Equal(".rpm"))
+			deb := makeAr(map[string][]byte{"debian-binary": []byte("2.0\n")}, "debian-binary")
+			Expect(DetectFormat(deb)).To(Equal(".deb"))
+		})
+
+		It("returns nothing for executables", func() {
+			Expect(DetectFormat([]byte("\x7fELF\x02\x01\x01"))).To(BeEmpty())
+			Expect(DetectFormat([]byte("MZ\x90\x00"))).To(BeEmpty())
+			Expect(DetectFormat([]byte("#!/bin/sh\n"))).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,315 @@
+package archives
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/permafrost-dev/zeget/lib/files"
+)
+
+const (
+	hfsHeaderOffset = 1024
+	hfsRootFolderID = 2
+
+	hfsFolderRecord = 1
+	hfsFileRecord   = 2
+
+	// the BSD flag of files whose content is compressed into an attribute
+	hfsCompressedFlag = 0x20
+
+	// the largest catalog read, far above that of an application image
+	hfsMaxCatalogSize = 256 << 20
+	// the longest symbolic link target read
+	hfsMaxLinkSize = 4096
+)
+
+type hfsExtent struct {
+	start uint32
+	count uint32
+}
+
+// an hfsFork locates the data of a file in the volume
+type hfsFork struct {
+	size    uint64
+	blocks  uint32
+	extents [8]hfsExtent
+}
+
+func parseHFSFork(b []byte) hfsFork {
+	f := hfsFork{
+		size:   binary.BigEndian.Uint64(b),
+		blocks: binary.BigEndian.Uint32(b[12:]),
+	}
+	for i := range f.extents {
+		f.extents[i] = hfsExtent{
+			start: binary.BigEndian.Uint32(b[16+i*8:]),
+			count: binary.BigEndian.Uint32(b[20+i*8:]),
+		}
+	}
+
+	return f
+}
+
+type hfsVolume struct {
+	data      partition
+	blockSize uint64
+}
+
+// isHFS reports whether data starts with an HFS+ or HFSX volume.
+func isHFS(data []byte) bool {
+	if len(data) < hfsHeaderOffset+512 {
+		return false
+	}
+	signature := string(data[hfsHeaderOffset : hfsHeaderOffset+2])
+
+	return signature == "H+" || signature == "HX"
+}
+
+// readFork returns the data of a fork. Forks with more than eight extents,
+// whose remaining extents are stored in the extents overflow file, are not
+// supported; files in disk images are rarely fragmented. Given a limit of
+// zero or more, only the first limit+1 bytes of a larger fork are read.
+func (v *hfsVolume) readFork(f hfsFork, limit int64) ([]byte, error) {
+	var extents []hfsExtent
+	var blocks uint32
+	var length uint64
+
+	for _, e := range f.extents {
+		if e.count == 0 {
+			break
+		}
+
+		start := uint64(e.start) * v.blockSize
+		end := start + uint64(e.count)*v.blockSize
+		if end > v.data.size() {
+			return nil, fmt.Errorf("hfs: extent beyond the end of the volume")
+		}
+
+		extents = append(extents, e)
+		blocks += e.count
+		length += end - start
+	}
+
+	if blocks < f.blocks {
+		return nil, fmt.Errorf("hfs: fragmented files are not supported")
+	}
+	if length < f.size {
+		return nil, fmt.Errorf("hfs: fork larger than its extents")
+	}
+
+	size := f.size
+	if limit >= 0 {
+		size = min(size, uint64(limit)+1)
+	}
+	data := make([]byte, 0, size)
+	for _, e := range extents {
+		if uint64(len(data)) >= size {
+			break
+		}
+
+		n := min(uint64(e.count)*v.blockSize, size-uint64(len(data)))
+		b, err := v.data.readAt(uint64(e.start)*v.blockSize, n)
+		if err != nil {
+			return nil, fmt.Errorf("hfs: %w", err)
+		}
+		data = append(data, b...)
+	}
+
+	return data, nil
+}
+
+type hfsRecord struct {
+	parent uint32
+	name   string
+	body   []byte
+}
+
+// readHFS returns the files of an HFS+ or HFSX volume, from the leaf records
+// of its catalog.
+func readHFS(data partition) ([]entry, error) {
+	header, err := data.readAt(0, min(data.size(), hfsHeaderOffset+512))
+	if err != nil {
+		return nil, fmt.Errorf("hfs: %w", err)
+	}
+	if !isHFS(header) {
+		return nil, fmt.Errorf("hfs: not an HFS+ volume")
+	}
+
+	header = header[hfsHeaderOffset:]
+	v := &hfsVolume{data: data, blockSize: uint64(binary.BigEndian.Uint32(header[40:]))}
+	if v.blockSize < 512 || v.blockSize&(v.blockSize-1) != 0 {
+		return nil, fmt.Errorf("hfs: invalid block size %d", v.blockSize)
+	}
+
+	fork := parseHFSFork(header[272:352])
+	if fork.size > hfsMaxCatalogSize {
+		return nil, fmt.Errorf("hfs: catalog larger than %d bytes", hfsMaxCatalogSize)
+	}
+	catalog, err := v.readFork(fork, -1)
+	if err != nil {
+		return nil, fmt.Errorf("hfs: catalog: %w", err)
+	}
+
+	records, err := hfsLeafRecords(catalog)
+	if err != nil {
+		return nil, err
+	}
+
+	folders := map[uint32]hfsRecord{}
+	for _, r := range records {
+		if len(r.body) >= 88 && int16(binary.BigEndian.Uint16(r.body)) == hfsFolderRecord {
+			folders[binary.BigEndian.Uint32(r.body[8:])] = r
+		}
+	}
+
+	var pathOf func(id uint32, depth int) (string, bool)
+	pathOf = func(id uint32, depth int) (string, bool) {
+		if id == hfsRootFolderID {
+			return "", true
+		}
+
+		folder, ok := folders[id]
+		if !ok || depth > 256 || strings.HasPrefix(folder.name, "\x00") {
+			return "", false
+		}
+
+		dir, ok := pathOf(folder.parent, depth+1)
+
+		return path.Join(dir, folder.name), ok
+	}
+
+	var entries []entry
+	for _, r := range records {
+		dir, ok := pathOf(r.parent, 0)
+		if !ok || strings.HasPrefix(r.name, "\x00") || strings.HasPrefix(r.name, ".HFS+ Private") {
+			continue
+		}
+
+		if e, ok := v.entry(path.Join(dir, r.name), r.body); ok {
+			entries = append(entries, e)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries, nil
+}
+
+// entry returns the archive entry for a folder or file record.
+func (v *hfsVolume) entry(name string, body []byte) (entry, bool) {
+	kind := int16(binary.BigEndian.Uint16(body))
+
+	switch {
+	case kind == hfsFolderRecord && len(body) >= 88:
+		mode := fs.FileMode(binary.BigEndian.Uint16(body[42:]) & 0o7777)
+		if mode == 0 {
+			mode = 0o755
+		}
+
+		return entry{File: files.File{Name: name + "/", Mode: mode | fs.ModeDir, Type: files.TypeDir}}, true
+
+	case kind == hfsFileRecord && len(body) >= 248:
+		// hard links are stored as references to hidden files
+		if string(body[48:56]) == "hlnkhfs+" {
+			return entry{}, false
+		}
+
+		bsdMode := binary.BigEndian.Uint16(body[42:])
+		mode := fs.FileMode(bsdMode & 0o7777)
+		if bsdMode == 0 {
+			mode = 0o644
+		}
+
+		fork := parseHFSFork(body[88:168])
+		read := func(limit int64) ([]byte, error) {
+			if body[41]&hfsCompressedFlag != 0 {
+				return nil, fmt.Errorf("hfs: %s: compressed files are not supported", name)
+			}
+			return v.readFork(fork, limit)
+		}
+
+		e := entry{File: files.File{Name: name, Mode: mode, Type: files.TypeNormal}, read: read}
+		if bsdMode&0o170000 == 0o120000 {
+			target, err := read(hfsMaxLinkSize)
+			if err != nil || len(target) > hfsMaxLinkSize {
+				return entry{}, false
+			}
+			e.Type = files.TypeSymlink
+			e.LinkName = string(target)
+			e.read = nil
+		}
+
+		return e, true
+	}
+
+	return entry{}, false
+}
+
+// hfsLeafRecords returns the records of the leaf nodes of a catalog B-tree.
+func hfsLeafRecords(catalog []byte) ([]hfsRecord, error) {
+	if len(catalog) < 14+106 {
+		return nil, fmt.Errorf("hfs: truncated catalog")
+	}
+
+	nodeSize := uint64(binary.BigEndian.Uint16(catalog[32:]))
+	if nodeSize < 512 {
+		return nil, fmt.Errorf("hfs: invalid catalog node size %d", nodeSize)
+	}
+
+	var records []hfsRecord
+	visited := map[uint32]bool{}
+
+	for node := binary.BigEndian.Uint32(catalog[24:]); node != 0; {
+		offset := uint64(node) * nodeSize
+		if visited[node] || offset+nodeSize > uint64(len(catalog)) {
+			return nil, fmt.Errorf("hfs: invalid catalog node %d", node)
+		}
+		visited[node] = true
+
+		n := catalog[offset : offset+nodeSize]
+		if int8(n[8]) != -1 {
+			return nil, fmt.Errorf("hfs: catalog node %d is not a leaf node", node)
+		}
+
+		count := int(binary.BigEndian.Uint16(n[10:]))
+		if uint64(2*count+14) > nodeSize {
+			return nil, fmt.Errorf("hfs: invalid catalog node %d", node)
+		}
+
+		for i := 0; i < count; i++ {
+			start := uint64(binary.BigEndian.Uint16(n[nodeSize-uint64(2*(i+1)):]))
+			if start+8 > nodeSize {
+				return nil, fmt.Errorf("hfs: invalid record in catalog node %d", node)
+			}
+
+			keyLength := uint64(binary.BigEndian.Uint16(n[start:]))
+			nameLength := uint64(binary.BigEndian.Uint16(n[start+6:]))
+			if 6+2*nameLength > keyLength || start+2+keyLength > nodeSize {
+				return nil, fmt.Errorf("hfs: invalid record in catalog node %d", node)
+			}
+
+			units := make([]uint16, nameLength)
+			for j := range units {
+				units[j] = binary.BigEndian.Uint16(n[start+8+2*uint64(j):])
+			}
+
+			records = append(records, hfsRecord{
+				parent: binary.BigEndian.Uint32(n[start+2:]),
+				// slashes are allowed in names and shown as colons by macOS
+				name: strings.ReplaceAll(string(utf16.Decode(units)), "/", ":"),
+				body: n[start+2+keyLength:],
+			})
+		}
+
+		node = binary.BigEndian.Uint32(n)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,553 @@
+package archives
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	lzxFrameSize     = 32768
+	lzxMinMatch      = 2
+	lzxNumChars      = 256
+	lzxPrimaryLength = 7
+	lzxLengthSymbols = 249
+	lzxMaxMainTree   = lzxNumChars + 50*8
+
+	lzxVerbatim     = 1
+	lzxAligned      = 2
+	lzxUncompressed = 3
+
+	// huffman codes up to this length are decoded with a lookup table
+	lzxFastBits = 10
+)
+
+// the number of position slots for window sizes of 2^15 to 2^21 bytes
+var lzxPositionSlots = [...]int{30, 32, 34, 36, 38, 42, 50}
+
+// lzxExtraBits and lzxPositionBase locate the match offsets of each
+// position slot.
+var lzxExtraBits, lzxPositionBase = lzxSlotTables()
+
+func lzxSlotTables() (extra, base [50]uint32) {
+	for i := range extra {
+		switch {
+		case i < 4:
+			extra[i] = 0
+		case i < 36:
+			extra[i] = uint32(i/2 - 1)
+		default:
+			extra[i] = 17
+		}
+		if i > 0 {
+			base[i] = base[i-1] + 1<<extra[i-1]
+		}
+	}
+
+	return extra, base
+}
+
+// lzxBits reads the bits of an LZX stream, which is a sequence of 16-bit
+// little-endian words whose bits are read from the most significant one.
+type lzxBits struct {
+	src     []byte
+	pos     int
+	buf     uint64
+	n       uint
+	padding int
+}
+
+func (b *lzxBits) ensure(n uint) error {
+	for b.n < n {
+		var w uint64
+		switch {
+		case b.pos+1 < len(b.src):
+			w = uint64(b.src[b.pos+1])<<8 | uint64(b.src[b.pos])
+			b.pos += 2
+		case b.pos < len(b.src):
+			w = uint64(b.src[b.pos])
+			b.pos++
+		default:
+			// the decoder may look ahead of the last symbol of the stream
+			if b.padding++; b.padding > 2 {
+				return fmt.Errorf("lzx: unexpected end of input")
+			}
+		}
+
+		b.buf |= w << (48 - b.n)
+		b.n += 16
+	}
+
+	return nil
+}
+
+func (b *lzxBits) peek(n uint) uint32 {
+	return uint32(b.buf >> (64 - n))
+}
+
+func (b *lzxBits) remove(n uint) {
+	b.buf <<= n
+	b.n -= n
+}
+
+func (b *lzxBits) read(n uint) (uint32, error) {
+	if n == 0 {
+		return 0, nil
+	}
+	if err := b.ensure(n); err != nil {
+		return 0, err
+	}
+	v := b.peek(n)
+	b.remove(n)
+
+	return v, nil
+}
+
+// lzxTree decodes the canonical huffman code given by the code lengths of
+// its symbols.
+type lzxTree struct {
+	count   [17]int
+	symbols []uint16
+	fast    []uint32
+	empty   bool
+}
+
+func newLZXTree(lengths []byte) (*lzxTree, error) {
+	t := &lzxTree{empty: true}
+
+	for _, l := range lengths {
+		t.count[l]++
+		t.empty = t.empty && l == 0
+	}
+	t.count[0] = 0
+
+	left := 1
+	for l := 1; l <= 16; l++ {
+		if left = left<<1 - t.count[l]; left < 0 {
+			return nil, fmt.Errorf("lzx: invalid huffman code")
+		}
+	}
+
+	var offsets [18]int
+	for l := 1; l <= 16; l++ {
+		offsets[l+1] = offsets[l] + t.count[l]
+	}
+	t.symbols = make([]uint16, offsets[17])
+	for s, l := range lengths {
+		if l != 0 {
+			t.symbols[offsets[l]] = uint16(s)
+			offsets[l]++
+		}
+	}
+
+	// entries of the lookup table hold the symbol and the length of a code
+	t.fast = make([]uint32, 1<<lzxFastBits)
+	code, index := 0, 0
+	for l := 1; l <= lzxFastBits; l++ {
+		for i := 0; i < t.count[l]; i++ {
+			fill := 1 << (lzxFastBits - l)
+			for j := 0; j < fill; j++ {
+				t.fast[code<<(lzxFastBits-l)+j] = uint32(t.symbols[index])<<8 | uint32(l)
+			}
+			code++
+			index++
+		}
+		code <<= 1
+	}
+
+	return t, nil
+}
+
+func (t *lzxTree) decode(b *lzxBits) (int, error) {
+	if t.empty {
+		return 0, fmt.Errorf("lzx: symbol of an empty huffman code")
+	}
+	if err := b.ensure(16); err != nil {
+		return 0, err
+	}
+
+	bits := b.peek(16)
+	if e := t.fast[bits>>(16-lzxFastBits)]; e&0xff != 0 {
+		b.remove(uint(e & 0xff))
+		return int(e >> 8), nil
+	}
+
+	code, first, index := 0, 0, 0
+	for l := 1; l <= 16; l++ {
+		code |= int(bits>>(16-l)) & 1
+		if code-first < t.count[l] {
+			b.remove(uint(l))
+			return int(t.symbols[index+code-first]), nil
+		}
+		index += t.count[l]
+		first = (first + t.count[l]) << 1
+		code <<= 1
+	}
+
+	return 0, fmt.Errorf("lzx: invalid huffman code")
+}
+
+type lzxDecoder struct {
+	bits       lzxBits
+	window     uint32
+	numOffsets int
+
+	blockType      int
+	blockLength    int
+	blockRemaining int
+	r              [3]uint32
+	intelStarted   bool
+
+	// code lengths are coded as differences to those of the previous block
+	mainLengths   [lzxMaxMainTree + 32]byte
+	lengthLengths [lzxLengthSymbols + 32]byte
+	main          *lzxTree
+	length        *lzxTree
+	aligned       *lzxTree
+}
+
+// lzxDecompress decodes an LZX stream, the compression method of most
+// cabinets, into size bytes. The window size is 2^windowBits bytes.
+func lzxDecompress(src []byte, windowBits uint, size int) ([]byte, error) {
+	if windowBits < 15 || windowBits > 21 {
+		return nil, fmt.Errorf("lzx: invalid window size")
+	}
+
+	d := &lzxDecoder{
+		bits:       lzxBits{src: src},
+		window:     1 << windowBits,
+		numOffsets: lzxPositionSlots[windowBits-15] << 3,
+		r:          [3]uint32{1, 1, 1},
+	}
+
+	// the stream starts with the file size used by the x86 call translation
+	var fileSize uint32
+	if translate, err := d.bits.read(1); err != nil {
+		return nil, err
+	} else if translate == 1 {
+		high, _ := d.bits.read(16)
+		low, err := d.bits.read(16)
+		if err != nil {
+			return nil, err
+		}
+		fileSize = high<<16 | low
+	}
+
+	history := make([]byte, 0, size)
+	out := make([]byte, size)
+
+	for frame := 0; len(history) < size; frame++ {
+		start := len(history)
+		frameSize := min(lzxFrameSize, size-start)
+
+		var err error
+		if history, err = d.decodeFrame(history, start+frameSize); err != nil {
+			return nil, err
+		}
+
+		// frames end on a 16-bit boundary
+		if d.bits.n > 0 {
+			if err := d.bits.ensure(16); err != nil {
+				return nil, err
+			}
+		}
+		d.bits.remove(d.bits.n & 15)
+
+		copy(out[start:], history[start:])
+		if d.intelStarted && fileSize != 0 && frame < 32768 && frameSize > 10 {
+			lzxTranslateE8(out[start:start+frameSize], int32(start), int32(fileSize))
+		}
+	}
+
+	return out, nil
+}
+
+// decodeFrame decodes blocks until the output reaches end bytes.
+func (d *lzxDecoder) decodeFrame(out []byte, end int) ([]byte, error) {
+	for todo := end - len(out); todo > 0; {
+		if d.blockRemaining == 0 {
+			if err := d.readBlockHeader(); err != nil {
+				return nil, err
+			}
+		}
+
+		run := min(d.blockRemaining, todo)
+		todo -= run
+		d.blockRemaining -= run
+
+		switch d.blockType {
+		case lzxVerbatim, lzxAligned:
+			for run > 0 {
+				symbol, err := d.main.decode(&d.bits)
+				if err != nil {
+					return nil, err
+				}
+
+				if symbol < lzxNumChars {
+					out = append(out, byte(symbol))
+					run--
+					continue
+				}
+
+				length, offset, err := d.readMatch(symbol - lzxNumChars)
+				if err != nil {
+					return nil, err
+				}
+
+				pos := len(out)
+				if uint32(pos)%d.window+uint32(length) > d.window || pos+length > end {
+					return nil, fmt.Errorf("lzx: match beyond the end of the frame")
+				}
+				if offset == 0 || int(offset) > pos {
+					return nil, fmt.Errorf("lzx: match offset beyond the start of the stream")
+				}
+
+				// the copy may overlap the output
+				for i := 0; i < length; i++ {
+					out = append(out, out[pos-int(offset)+i])
+				}
+				run -= length
+			}
+
+		case lzxUncompressed:
+			b := &d.bits
+			if b.pos+run > len(b.src) {
+				return nil, fmt.Errorf("lzx: unexpected end of input")
+			}
+			out = append(out, b.src[b.pos:b.pos+run]...)
+			b.pos += run
+			run = 0
+		}
+
+		// the last match may run past the length of the run
+		if run < 0 {
+			if -run > d.blockRemaining {
+				return nil, fmt.Errorf("lzx: match beyond the end of the block")
+			}
+			d.blockRemaining += run
+		}
+	}
+
+	if len(out) != end {
+		return nil, fmt.Errorf("lzx: match beyond the end of the frame")
+	}
+
+	return out, nil
+}
+
+func (d *lzxDecoder) readBlockHeader() error {
+	b := &d.bits
+
+	// uncompressed blocks are padded to an even length
+	if d.blockType == lzxUncompressed && d.blockLength&1 == 1 {
+		b.pos++
+	}
+
+	blockType, _ := b.read(3)
+	high, _ := b.read(16)
+	low, err := b.read(8)
+	if err != nil {
+		return err
+	}
+	d.blockType = int(blockType)
+	d.blockLength = int(high<<8 | low)
+	d.blockRemaining = d.blockLength
+
+	switch d.blockType {
+	case lzxAligned:
+		var lengths [8]byte
+		for i := range lengths {
+			l, err := b.read(3)
+			if err != nil {
+				return err
+			}
+			lengths[i] = byte(l)
+		}
+		if d.aligned, err = newLZXTree(lengths[:]); err != nil {
+			return err
+		}
+		fallthrough
+
+	case lzxVerbatim:
+		if err := d.readLengths(d.mainLengths[:], 0, lzxNumChars); err != nil {
+			return err
+		}
+		if err := d.readLengths(d.mainLengths[:], lzxNumChars, lzxNumChars+d.numOffsets); err != nil {
+			return err
+		}
+		if d.main, err = newLZXTree(d.mainLengths[:lzxNumChars+d.numOffsets]); err != nil {
+			return err
+		}
+		if d.mainLengths[0xe8] != 0 {
+			d.intelStarted = true
+		}
+
+		if err := d.readLengths(d.lengthLengths[:], 0, lzxLengthSymbols); err != nil {
+			return err
+		}
+		if d.length, err = newLZXTree(d.lengthLengths[:lzxLengthSymbols]); err != nil {
+			return err
+		}
+
+	case lzxUncompressed:
+		d.intelStarted = true
+
+		// skip to the next 16-bit boundary, or a whole word when aligned
+		if b.n == 0 {
+			if err := b.ensure(16); err != nil {
+				return err
+			}
+		}
+		b.buf, b.n = 0, 0
+
+		if b.pos+12 > len(b.src) {
+			return fmt.Errorf("lzx: unexpected end of input")
+		}
+		for i := range d.r {
+			d.r[i] = binary.LittleEndian.Uint32(b.src[b.pos+4*i:])
+		}
+		b.pos += 12
+
+	default:
+		return fmt.Errorf("lzx: invalid block type %d", d.blockType)
+	}
+
+	return nil
+}
+
+// readLengths reads the code lengths of the symbols first to last, coded
+// with a pretree.
+func (d *lzxDecoder) readLengths(lengths []byte, first, last int) error {
+	var pre [20]byte
+	for i := range pre {
+		l, err := d.bits.read(4)
+		if err != nil {
+			return err
+		}
+		pre[i] = byte(l)
+	}
+
+	tree, err := newLZXTree(pre[:])
+	if err != nil {
+		return err
+	}
+
+	delta := func(l byte, z int) byte {
+		return byte((int(l) - z + 17) % 17)
+	}
+
+	for x := first; x < last; {
+		z, err := tree.decode(&d.bits)
+		if err != nil {
+			return err
+		}
+
+		var run uint32
+		var value byte
+		switch z {
+		case 17:
+			run, err = d.bits.read(4)
+			run += 4
+		case 18:
+			run, err = d.bits.read(5)
+			run += 20
+		case 19:
+			run, err = d.bits.read(1)
+			run += 4
+			if err == nil {
+				z, err = tree.decode(&d.bits)
+				value = delta(lengths[x], z)
+			}
+		default:
+			run, value = 1, delta(lengths[x], z)
+		}
+		if err != nil {
+			return err
+		}
+
+		if x+int(run) > len(lengths) {
+			return fmt.Errorf("lzx: invalid code lengths")
+		}
+		for ; run > 0; run-- {
+			lengths[x] = value
+			x++
+		}
+	}
+
+	return nil
+}
+
+// readMatch returns the length and offset of the match of a main tree
+// symbol, updating the repeated offsets.
+func (d *lzxDecoder) readMatch(element int) (int, uint32, error) {
+	length := element & lzxPrimaryLength
+	if length == lzxPrimaryLength {
+		footer, err := d.length.decode(&d.bits)
+		if err != nil {
+			return 0, 0, err
+		}
+		length += footer
+	}
+	length += lzxMinMatch
+
+	slot := element >> 3
+	switch slot {
+	case 0:
+		return length, d.r[0], nil
+	case 1:
+		d.r[0], d.r[1] = d.r[1], d.r[0]
+		return length, d.r[0], nil
+	case 2:
+		d.r[0], d.r[2] = d.r[2], d.r[0]
+		return length, d.r[0], nil
+	}
+
+	extra := lzxExtraBits[slot]
+	offset := lzxPositionBase[slot] - 2
+
+	if extra >= 3 && d.blockType == lzxAligned {
+		verbatim, err := d.bits.read(uint(extra - 3))
+		if err != nil {
+			return 0, 0, err
+		}
+		aligned, err := d.aligned.decode(&d.bits)
+		if err != nil {
+			return 0, 0, err
+		}
+		offset += verbatim<<3 + uint32(aligned)
+	} else {
+		verbatim, err := d.bits.read(uint(extra))
+		if err != nil {
+			return 0, 0, err
+		}
+		offset += verbatim
+	}
+
+	d.r[2], d.r[1], d.r[0] = d.r[1], d.r[0], offset
+
+	return length, offset, nil
+}
+
+// lzxTranslateE8 undoes the translation of the targets of x86 call
+// instructions from relative to absolute addresses, which makes them
+// compress better.
+func lzxTranslateE8(frame []byte, pos, fileSize int32) {
+	for i := 0; i < len(frame)-10; {
+		if frame[i] != 0xe8 {
+			i++
+			pos++
+			continue
+		}
+
+		abs := int32(binary.LittleEndian.Uint32(frame[i+1:]))
+		if abs >= -pos && abs < fileSize {
+			rel := abs + fileSize
+			if abs >= 0 {
+				rel = abs - pos
+			}
+			binary.LittleEndian.PutUint32(frame[i+1:], uint32(rel))
+		}
+
+		i += 5
+		pos += 5
+	}
+}
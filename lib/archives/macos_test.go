@@ -0,0 +1,486 @@
+package archives_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"unicode/utf16"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/archives"
+	"github.com/ulikunitz/xz"
+)
+
+type xarEntry struct {
+	name     string
+	typ      string
+	data     []byte
+	children []xarEntry
+}
+
+func zlibbed(data []byte) []byte {
+	buf := new(bytes.Buffer)
+	w := zlib.NewWriter(buf)
+	_, err := w.Write(data)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(w.Close()).To(Succeed())
+
+	return buf.Bytes()
+}
+
+func makeXar(entries []xarEntry) []byte {
+	heap := new(bytes.Buffer)
+	toc := new(strings.Builder)
+	id := 0
+
+	var write func([]xarEntry)
+	write = func(list []xarEntry) {
+		for _, e := range list {
+			id++
+			fmt.Fprintf(toc, `<file id="%d"><name>%s</name><type>%s</type><mode>0644</mode>`, id, e.name, e.typ)
+			if e.data != nil {
+				stored := zlibbed(e.data)
+				fmt.Fprintf(toc, `<data><length>%d</length><offset>%d</offset><size>%d</size>`+
+					`<encoding style="application/x-gzip"/></data>`, len(stored), heap.Len(), len(e.data))
+				heap.Write(stored)
+			}
+			write(e.children)
+			toc.WriteString("</file>")
+		}
+	}
+	write(entries)
+
+	xml := `<?xml version="1.0" encoding="UTF-8"?><xar><toc>` + toc.String() + `</toc></xar>`
+	compressed := zlibbed([]byte(xml))
+
+	buf := bytes.NewBufferString("xar!")
+	Expect(binary.Write(buf, binary.BigEndian, uint16(28))).To(Succeed())
+	Expect(binary.Write(buf, binary.BigEndian, uint16(1))).To(Succeed())
+	Expect(binary.Write(buf, binary.BigEndian, uint64(len(compressed)))).To(Succeed())
+	Expect(binary.Write(buf, binary.BigEndian, uint64(len(xml)))).To(Succeed())
+	Expect(binary.Write(buf, binary.BigEndian, uint32(0))).To(Succeed())
+	buf.Write(compressed)
+	buf.Write(heap.Bytes())
+
+	return buf.Bytes()
+}
+
+func makeOdc(entries []packageEntry) []byte {
+	buf := new(bytes.Buffer)
+	write := func(name string, mode int64, data string) {
+		fmt.Fprintf(buf, "070707%06o%06o%06o%06o%06o%06o%06o%011o%06o%011o",
+			0, 0, mode, 0, 0, 1, 0, 0, len(name)+1, len(data))
+		buf.WriteString(name + "\x00" + data)
+	}
+	for _, e := range entries {
+		data := e.data
+		if e.target != "" {
+			data = e.target
+		}
+		write(strings.TrimSuffix(e.name, "/"), e.mode, data)
+	}
+	write("TRAILER!!!", 0, "")
+
+	return buf.Bytes()
+}
+
+func makePbzx(data []byte, chunkSize int) []byte {
+	buf := bytes.NewBufferString("pbzx")
+	Expect(binary.Write(buf, binary.BigEndian, uint64(chunkSize))).To(Succeed())
+
+	for i := 0; i < len(data); i += chunkSize {
+		chunk := data[i:min(i+chunkSize, len(data))]
+
+		compressed := new(bytes.Buffer)
+		w, err := xz.NewWriter(compressed)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = w.Write(chunk)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Close()).To(Succeed())
+
+		Expect(binary.Write(buf, binary.BigEndian, uint64(1<<24))).To(Succeed())
+		Expect(binary.Write(buf, binary.BigEndian, uint64(compressed.Len()))).To(Succeed())
+		buf.Write(compressed.Bytes())
+	}
+
+	return buf.Bytes()
+}
+
+const hfsBlock = 4096
+
+type hfsItem struct {
+	parent uint32
+	id     uint32
+	name   string
+	mode   uint16
+	data   string
+	// the Finder type and creator, as used for hard links
+	finder string
+}
+
+// makeHFS builds an HFS+ volume with the catalog in blocks 1 and 2, and the
+// data of each file in a block of its own.
+func makeHFS(items []hfsItem) []byte {
+	volume := make([]byte, (3+len(items))*hfsBlock)
+
+	header := volume[1024:]
+	copy(header, "H+")
+	binary.BigEndian.PutUint16(header[2:], 4)
+	binary.BigEndian.PutUint32(header[40:], hfsBlock)
+	binary.BigEndian.PutUint32(header[44:], uint32(len(volume)/hfsBlock))
+	putFork(header[272:], 2*hfsBlock, 1, 2)
+
+	catalog := volume[hfsBlock : 3*hfsBlock]
+
+	// the header node
+	catalog[8] = 1
+	binary.BigEndian.PutUint16(catalog[10:], 3)
+	binary.BigEndian.PutUint16(catalog[14:], 1)
+	binary.BigEndian.PutUint32(catalog[16:], 1)
+	binary.BigEndian.PutUint32(catalog[20:], uint32(len(items)))
+	binary.BigEndian.PutUint32(catalog[24:], 1)
+	binary.BigEndian.PutUint32(catalog[28:], 1)
+	binary.BigEndian.PutUint16(catalog[32:], hfsBlock)
+	binary.BigEndian.PutUint16(catalog[34:], 516)
+	binary.BigEndian.PutUint32(catalog[36:], 2)
+
+	// the leaf node
+	leaf := catalog[hfsBlock:]
+	leaf[8] = 0xff
+	leaf[9] = 1
+	binary.BigEndian.PutUint16(leaf[10:], uint16(len(items)))
+
+	offset := 14
+	for i, item := range items {
+		binary.BigEndian.PutUint16(leaf[hfsBlock-2*(i+1):], uint16(offset))
+
+		name := utf16.Encode([]rune(item.name))
+		binary.BigEndian.PutUint16(leaf[offset:], uint16(6+2*len(name)))
+		binary.BigEndian.PutUint32(leaf[offset+2:], item.parent)
+		binary.BigEndian.PutUint16(leaf[offset+6:], uint16(len(name)))
+		for j, u := range name {
+			binary.BigEndian.PutUint16(leaf[offset+8+2*j:], u)
+		}
+		offset += 8 + 2*len(name)
+
+		body := leaf[offset:]
+		binary.BigEndian.PutUint32(body[8:], item.id)
+		binary.BigEndian.PutUint16(body[42:], item.mode)
+
+		if item.mode&0o170000 == 0o040000 {
+			binary.BigEndian.PutUint16(body, 1)
+			offset += 88
+			continue
+		}
+
+		binary.BigEndian.PutUint16(body, 2)
+		copy(body[48:], item.finder)
+		block := uint32(3 + i)
+		copy(volume[block*hfsBlock:], item.data)
+		putFork(body[88:], uint64(len(item.data)), block, 1)
+		offset += 248
+	}
+
+	return volume
+}
+
+func putFork(b []byte, size uint64, start uint32, count uint32) {
+	binary.BigEndian.PutUint64(b, size)
+	binary.BigEndian.PutUint32(b[12:], count)
+	binary.BigEndian.PutUint32(b[16:], start)
+	binary.BigEndian.PutUint32(b[20:], count)
+}
+
+var hfsItems = []hfsItem{
+	{parent: 1, id: 2, name: "Tool", mode: 0o040755},
+	{parent: 2, id: 16, name: "Tool.app", mode: 0o040755},
+	{parent: 16, id: 17, name: "Contents", mode: 0o040755},
+	{parent: 17, id: 18, name: "MacOS", mode: 0o040755},
+	{parent: 18, id: 20, name: "tool", mode: 0o100755, data: "#!/bin/sh\necho tool\n"},
+	{parent: 18, id: 21, name: "tl", mode: 0o120755, data: "tool"},
+	{parent: 2, id: 22, name: "README", mode: 0o100644, data: "readme"},
+	{parent: 2, id: 23, name: "link", finder: "hlnkhfs+"},
+	{parent: 2, id: 19, name: "\x00\x00\x00\x00HFS+ Private Data", mode: 0o040755},
+	{parent: 19, id: 24, name: "iNode23", mode: 0o100755, data: "hidden"},
+}
+
+const (
+	chunkRaw  = 0x00000001
+	chunkADC  = 0x80000004
+	chunkZlib = 0x80000005
+)
+
+// adcEncode encodes data with Apple Data Compression, using back references
+// for runs of repeated bytes.
+func adcEncode(data []byte) []byte {
+	out := new(bytes.Buffer)
+	var literal []byte
+	flush := func() {
+		for len(literal) > 0 {
+			n := min(len(literal), 128)
+			out.WriteByte(0x80 | byte(n-1))
+			out.Write(literal[:n])
+			literal = literal[n:]
+		}
+	}
+
+	for i := 0; i < len(data); {
+		run := 0
+		for i > 0 && i+run < len(data) && data[i+run] == data[i-1] && run < 67 {
+			run++
+		}
+		if run >= 4 {
+			flush()
+			out.Write([]byte{0x40 | byte(run-4), 0, 0})
+			i += run
+			continue
+		}
+		literal = append(literal, data[i])
+		i++
+	}
+	flush()
+
+	return out.Bytes()
+}
+
+// makeUDIF wraps a partition in a UDIF image with a protective MBR partition
+// before it.
+func makeUDIF(partition []byte, kind uint32) []byte {
+	sectors := uint64(len(partition) / 512)
+
+	return makeUDIFSectors(partition, kind, sectors, sectors)
+}
+
+// makeUDIFSectors builds a UDIF image like makeUDIF, declaring a partition
+// of sectors sectors stored in a chunk of length sectors.
+func makeUDIFSectors(partition []byte, kind uint32, sectors, length uint64) []byte {
+	var stored []byte
+	switch kind {
+	case chunkRaw:
+		stored = partition
+	case chunkADC:
+		stored = adcEncode(partition)
+	case chunkZlib:
+		stored = zlibbed(partition)
+	}
+
+	mish := func(sectors uint64, chunks ...[5]uint64) string {
+		table := make([]byte, 204+40*len(chunks))
+		copy(table, "mish")
+		binary.BigEndian.PutUint32(table[4:], 1)
+		binary.BigEndian.PutUint64(table[16:], sectors)
+		binary.BigEndian.PutUint32(table[200:], uint32(len(chunks)))
+		for i, c := range chunks {
+			chunk := table[204+40*i:]
+			binary.BigEndian.PutUint32(chunk, uint32(c[0]))
+			binary.BigEndian.PutUint64(chunk[8:], c[1])
+			binary.BigEndian.PutUint64(chunk[16:], c[2])
+			binary.BigEndian.PutUint64(chunk[24:], c[3])
+			binary.BigEndian.PutUint64(chunk[32:], c[4])
+		}
+		return base64.StdEncoding.EncodeToString(table)
+	}
+
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>resource-fork</key>
+	<dict>
+		<key>blkx</key>
+		<array>
+			<dict>
+				<key>Data</key>
+				<data>%s</data>
+				<key>Name</key>
+				<string>Protective Master Boot Record (MBR : 0)</string>
+			</dict>
+			<dict>
+				<key>Attributes</key>
+				<string>0x0050</string>
+				<key>Data</key>
+				<data>
+				%s
+				</data>
+				<key>Name</key>
+				<string>disk image (Apple_HFS : 1)</string>
+			</dict>
+		</array>
+		<key>plst</key>
+		<array><dict><key>Hidden</key><true/></dict></array>
+	</dict>
+</dict>
+</plist>
+`, mish(1, [5]uint64{0, 0, 1, 0, 0}, [5]uint64{0xffffffff, 1, 0, 0, 0}),
+		mish(sectors, [5]uint64{uint64(kind), 0, length, 0, uint64(len(stored))}, [5]uint64{0xffffffff, sectors, 0, 0, 0}))
+
+	buf := bytes.NewBuffer(stored)
+	xmlOffset := buf.Len()
+	buf.WriteString(plist)
+
+	koly := make([]byte, 512)
+	copy(koly, "koly")
+	binary.BigEndian.PutUint32(koly[4:], 4)
+	binary.BigEndian.PutUint32(koly[8:], 512)
+	binary.BigEndian.PutUint64(koly[32:], uint64(len(stored)))
+	binary.BigEndian.PutUint64(koly[216:], uint64(xmlOffset))
+	binary.BigEndian.PutUint64(koly[224:], uint64(len(plist)))
+	buf.Write(koly)
+
+	return buf.Bytes()
+}
+
+func expectDmgFiles(ar Archive) {
+	found := listArchive(ar)
+
+	var names []string
+	for _, f := range found {
+		names = append(names, f.Name)
+	}
+	Expect(names).To(Equal([]string{
+		"README", "Tool.app/", "Tool.app/Contents/", "Tool.app/Contents/MacOS/",
+		"Tool.app/Contents/MacOS/tl", "Tool.app/Contents/MacOS/tool",
+	}))
+
+	Expect(found[4].LinkName).To(Equal("tool"))
+	Expect(found[5].Mode.Perm()).To(Equal(fs.FileMode(0o755)))
+}
+
+var _ = Describe("macOS archives", func() {
+	Describe("NewPkgArchive", func() {
+		It("reads the payload of component packages", func() {
+			pkg := makeXar([]xarEntry{
+				{name: "Bom", typ: "file", data: []byte("bom")},
+				{name: "PackageInfo", typ: "file", data: []byte("<pkg-info/>")},
+				{name: "Payload", typ: "file", data: gzipped(makeOdc(packageEntries))},
+			})
+
+			ar, err := NewPkgArchive(pkg, nil)
+			Expect(err).NotTo(HaveOccurred())
+			expectPackageFiles(ar)
+		})
+
+		It("reads pbzx payloads of product archives", func() {
+			pkg := makeXar([]xarEntry{
+				{name: "Distribution", typ: "file", data: []byte("<installer-gui-script/>")},
+				{name: "tool.pkg", typ: "directory", children: []xarEntry{
+					{name: "Payload", typ: "file", data: makePbzx(makeOdc(packageEntries), 100)},
+				}},
+			})
+
+			ar, err := NewPkgArchive(pkg, nil)
+			Expect(err).NotTo(HaveOccurred())
+			expectPackageFiles(ar)
+		})
+
+		It("fails without a payload", func() {
+			pkg := makeXar([]xarEntry{{name: "Distribution", typ: "file", data: []byte("<installer-gui-script/>")}})
+			_, err := NewPkgArchive(pkg, nil)
+			Expect(err).To(MatchError("pkg: no payload found"))
+		})
+
+		It("reads FreeBSD packages", func() {
+			ar, err := NewPkgArchive(gzipped(makeTar(packageEntries)), nil)
+			Expect(err).NotTo(HaveOccurred())
+			expectPackageFiles(ar)
+		})
+
+		It("fails for other files", func() {
+			_, err := NewPkgArchive([]byte("not a package"), nil)
+			Expect(err).To(MatchError("pkg: unsupported package format"))
+		})
+	})
+
+	Describe("Unpbzx", func() {
+		It("decompresses the chunks as they are read", func() {
+			data := []byte(strings.Repeat("pbzx payload ", 40))
+
+			r, err := Unpbzx(bytes.NewReader(makePbzx(data, 100)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(io.ReadAll(r)).To(Equal(data))
+		})
+
+		It("rejects chunks longer than the input", func() {
+			pbzx := makePbzx([]byte("payload"), 100)
+			binary.BigEndian.PutUint64(pbzx[20:], 1<<40)
+
+			r, err := Unpbzx(bytes.NewReader(pbzx))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = io.ReadAll(r)
+			Expect(err).To(MatchError("pbzx: chunk of 1099511627776 bytes exceeds the input"))
+		})
+
+		It("rejects chunks larger than the chunk size", func() {
+			pbzx := makePbzx([]byte(strings.Repeat("x", 200)), 200)
+			binary.BigEndian.PutUint64(pbzx[4:], 100)
+
+			r, err := Unpbzx(bytes.NewReader(pbzx))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = io.ReadAll(r)
+			Expect(err).To(MatchError("pbzx: chunk exceeds the chunk size of 100 bytes"))
+		})
+	})
+
+	Describe("NewDmgArchive", func() {
+		DescribeTable("reading HFS+ images",
+			func(kind uint32) {
+				ar, err := NewDmgArchive(makeUDIF(makeHFS(hfsItems), kind), nil)
+				Expect(err).NotTo(HaveOccurred())
+				expectDmgFiles(ar)
+			},
+			Entry("raw", uint32(chunkRaw)),
+			Entry("zlib", uint32(chunkZlib)),
+			Entry("ADC", uint32(chunkADC)),
+		)
+
+		It("reads the content of files", func() {
+			ar, err := NewDmgArchive(makeUDIF(makeHFS(hfsItems), chunkZlib), nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			for f, err := ar.Next(); err == nil; f, err = ar.Next() {
+				if f.Name == "Tool.app/Contents/MacOS/tool" {
+					data, err := ar.ReadAll()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(data)).To(Equal("#!/bin/sh\necho tool\n"))
+					return
+				}
+			}
+			Fail("tool not found")
+		})
+
+		It("reads images without a UDIF trailer", func() {
+			ar, err := NewDmgArchive(makeHFS(hfsItems), nil)
+			Expect(err).NotTo(HaveOccurred())
+			expectDmgFiles(ar)
+		})
+
+		It("fails for partitions larger than their chunks", func() {
+			partition := makeHFS(hfsItems)
+			_, err := NewDmgArchive(makeUDIFSectors(partition, chunkZlib, 1<<32, uint64(len(partition)/512)), nil)
+			Expect(err).To(MatchError(ContainSubstring("partition of 4294967296 sectors larger than its chunks")))
+		})
+
+		It("fails for oversized chunks", func() {
+			_, err := NewDmgArchive(makeUDIFSectors(makeHFS(hfsItems), chunkZlib, 1<<32, 1<<32), nil)
+			Expect(err).To(MatchError(ContainSubstring("chunk of 4294967296 sectors larger than 67108864 bytes")))
+		})
+
+		It("fails for APFS images", func() {
+			partition := make([]byte, 4096)
+			copy(partition[32:], "NXSB")
+			_, err := NewDmgArchive(makeUDIF(partition, chunkRaw), nil)
+			Expect(err).To(MatchError("dmg: APFS volumes are not supported"))
+		})
+
+		It("is detected by its trailer", func() {
+			Expect(DetectFormat(makeUDIF(makeHFS(hfsItems), chunkZlib))).To(Equal(".dmg"))
+			Expect(DetectFormat(makeXar(nil))).To(Equal(".pkg"))
+		})
+	})
+})
@@ -0,0 +1,418 @@
+package archives
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/permafrost-dev/zeget/lib/files"
+	"github.com/ulikunitz/xz/lzma"
+)
+
+const (
+	nsisSignature       = "\xef\xbe\xad\xdeNullsoftInst"
+	nsisFirstHeaderSize = 28
+	nsisEntrySize       = 28
+	nsisCompressed      = 0x80000000
+
+	// the largest header read, far above that of installers with thousands
+	// of files
+	nsisMaxHeaderSize = 16 << 20
+
+	// the indexes of the entries and strings in the block table of the header
+	nsisBlockEntries   = 2
+	nsisBlockStrings   = 3
+	nsisBlockLangTable = 4
+
+	// the opcodes of the instructions creating directories and files
+	nsisCreateDir   = 11
+	nsisExtractFile = 20
+)
+
+const (
+	nsisStored = iota
+	nsisDeflate
+	nsisLZMA
+)
+
+// the names of the built-in variables of NSIS, after $0-$9 and $R0-$R9
+var nsisVariables = []string{
+	"CMDLINE", "INSTDIR", "OUTDIR", "EXEDIR", "LANGUAGE", "TEMP", "PLUGINSDIR",
+	"EXEPATH", "EXEFILE", "HWNDPARENT", "_CLICK", "_OUTDIR",
+}
+
+// findNSIS returns the offset of the first header of an NSIS installer, which
+// is aligned to 512 bytes and follows the installer executable, or -1.
+func findNSIS(data []byte, overlay int) int {
+	for offset := overlay &^ 511; offset+nsisFirstHeaderSize <= len(data); offset += 512 {
+		if string(data[offset+4:offset+20]) == nsisSignature {
+			return offset
+		}
+	}
+
+	return -1
+}
+
+// nsisInstaller is the data of an NSIS installer, compressed as a whole
+// (solid) or block by block.
+type nsisInstaller struct {
+	method int
+	solid  bool
+	data   []byte
+	// stream decompresses a solid installer into data as far as its blocks
+	// are read
+	stream io.Reader
+	// blocks caches the decompressed blocks of other installers by offset
+	blocks map[int][]byte
+}
+
+// readNSIS returns the files an NSIS installer extracts, named after the
+// directories they are extracted to, relative to the installation directory.
+// The files are found by interpreting the instructions of the installer
+// script that set the output directory and extract files.
+func readNSIS(data []byte, overlay int) ([]entry, error) {
+	offset := findNSIS(data, overlay)
+	if offset < 0 {
+		return nil, fmt.Errorf("nsis: not an NSIS installer")
+	}
+
+	headerSize := binary.LittleEndian.Uint32(data[offset+20:])
+	if headerSize > nsisMaxHeaderSize {
+		return nil, fmt.Errorf("nsis: header of %d bytes larger than %d bytes", headerSize, nsisMaxHeaderSize)
+	}
+	end := offset + int(min(binary.LittleEndian.Uint32(data[offset+24:]), uint32(len(data)-offset)))
+	n := &nsisInstaller{data: data[offset+nsisFirstHeaderSize : max(end, offset+nsisFirstHeaderSize)], blocks: map[int][]byte{}}
+	if len(n.data) < 12 {
+		return nil, fmt.Errorf("nsis: truncated installer")
+	}
+
+	size := binary.LittleEndian.Uint32(n.data)
+	switch {
+	case size == headerSize:
+		n.method = nsisStored
+	case isNSISLzma(n.data):
+		n.method, n.solid = nsisLZMA, true
+	case size&nsisCompressed != 0 && isNSISLzma(n.data[4:]):
+		n.method = nsisLZMA
+	case size&nsisCompressed != 0:
+		n.method = nsisDeflate
+	default:
+		n.method, n.solid = nsisDeflate, true
+	}
+
+	if n.solid {
+		stream, err := n.decompressor(n.data, n.method)
+		if err != nil {
+			return nil, err
+		}
+		n.data, n.stream = nil, stream
+	}
+
+	header, err := n.block(0, int64(headerSize))
+	if err != nil {
+		return nil, fmt.Errorf("nsis: header: %w", err)
+	}
+	if int64(len(header)) > int64(headerSize) {
+		return nil, fmt.Errorf("nsis: header: block larger than %d bytes", headerSize)
+	}
+	dataStart := 4 + int(binary.LittleEndian.Uint32(n.data)&^nsisCompressed)
+
+	return n.files(header, dataStart)
+}
+
+// isNSISLzma reports whether data starts with the properties of an LZMA
+// stream compressed by NSIS, optionally preceded by a filter flag.
+func isNSISLzma(data []byte) bool {
+	return len(data) > 6 && (data[0] == 0x5d && data[5] == 0 ||
+		data[0] == 0 && data[1] == 0x5d && data[6] == 0)
+}
+
+// decompressor returns a reader decompressing a stream of the installer. The
+// end of an LZMA stream may only be known from its content.
+func (n *nsisInstaller) decompressor(data []byte, method int) (io.Reader, error) {
+	switch method {
+	case nsisDeflate:
+		return flate.NewReader(bytes.NewReader(data)), nil
+	case nsisLZMA:
+		if data[0] != 0x5d {
+			// a zero filter flag precedes the properties
+			data = data[1:]
+		}
+		header := make([]byte, 13)
+		copy(header, data[:5])
+		binary.LittleEndian.PutUint64(header[5:], ^uint64(0))
+
+		r, err := lzma.NewReader(io.MultiReader(bytes.NewReader(header), bytes.NewReader(data[5:])))
+		if err != nil {
+			return nil, fmt.Errorf("nsis: %w", err)
+		}
+		return r, nil
+	}
+
+	return bytes.NewReader(data), nil
+}
+
+// decompress decompresses a block of the installer, stopping after limit+1
+// bytes unless limit is negative.
+func (n *nsisInstaller) decompress(data []byte, method int, limit int64) ([]byte, error) {
+	r, err := n.decompressor(data, method)
+	if err != nil {
+		return nil, err
+	}
+	if limit >= 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+
+	out, err := io.ReadAll(r)
+	if err != nil && (!errors.Is(err, io.ErrUnexpectedEOF) || len(out) == 0) {
+		return nil, fmt.Errorf("nsis: %w", err)
+	}
+
+	return out, nil
+}
+
+// fill decompresses a solid installer up to end, or as far as it goes.
+func (n *nsisInstaller) fill(end int) error {
+	if n.stream == nil || end <= len(n.data) {
+		return nil
+	}
+
+	buf := bytes.NewBuffer(n.data)
+	_, err := io.CopyN(buf, n.stream, int64(end-len(n.data)))
+	n.data = buf.Bytes()
+
+	switch {
+	case err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF):
+		n.stream = nil
+	case err != nil:
+		return fmt.Errorf("nsis: %w", err)
+	}
+
+	return nil
+}
+
+// block returns the data of the block at an offset of the data, which starts
+// with its size and, unless the installer is solid, whether it is compressed.
+// Given a limit of zero or more, only the first limit+1 bytes of a larger
+// block are read.
+func (n *nsisInstaller) block(offset int, limit int64) ([]byte, error) {
+	if data, found := n.blocks[offset]; found {
+		return data, nil
+	}
+	if err := n.fill(offset + 4); err != nil {
+		return nil, err
+	}
+	if offset < 0 || offset+4 > len(n.data) {
+		return nil, fmt.Errorf("nsis: block beyond the end of the installer")
+	}
+
+	declared := binary.LittleEndian.Uint32(n.data[offset:])
+	compressed := declared&nsisCompressed != 0 && !n.solid
+	if !n.solid {
+		declared &^= nsisCompressed
+	}
+	size := int64(declared)
+	if !compressed && limit >= 0 {
+		size = min(size, limit+1)
+	}
+	if err := n.fill(offset + 4 + int(size)); err != nil {
+		return nil, err
+	}
+	if size > int64(len(n.data)-offset-4) {
+		return nil, fmt.Errorf("nsis: block beyond the end of the installer")
+	}
+
+	data := n.data[offset+4 : offset+4+int(size)]
+	if !compressed {
+		return data, nil
+	}
+
+	// blocks may be compressed even if the header is not
+	method := n.method
+	if method == nsisStored {
+		method = nsisDeflate
+		if isNSISLzma(data) {
+			method = nsisLZMA
+		}
+	}
+
+	data, err := n.decompress(data, method, limit)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 0 || int64(len(data)) <= limit {
+		n.blocks[offset] = data
+	}
+
+	return data, nil
+}
+
+// files interprets the instructions of the installer header.
+func (n *nsisInstaller) files(header []byte, dataStart int) ([]entry, error) {
+	if len(header) < 4+8*(nsisBlockLangTable+1) {
+		return nil, fmt.Errorf("nsis: truncated header")
+	}
+	blockOffset := func(i int) int {
+		return int(binary.LittleEndian.Uint32(header[4+8*i:]))
+	}
+
+	entries := blockOffset(nsisBlockEntries)
+	count := int(binary.LittleEndian.Uint32(header[8+8*nsisBlockEntries:]))
+	strs := blockOffset(nsisBlockStrings)
+	strsEnd := blockOffset(nsisBlockLangTable)
+	if strsEnd <= strs || strsEnd > len(header) {
+		strsEnd = len(header)
+	}
+	if entries < 0 || count < 0 || entries+count*nsisEntrySize > len(header) || strs < 0 || strs >= strsEnd {
+		return nil, fmt.Errorf("nsis: invalid header")
+	}
+
+	s := newNSISStrings(header[strs:strsEnd])
+
+	var result []entry
+	outDir := "$INSTDIR"
+
+	for i := 0; i < count; i++ {
+		e := header[entries+i*nsisEntrySize:]
+		param := func(j int) int32 {
+			return int32(binary.LittleEndian.Uint32(e[4+4*j:]))
+		}
+
+		switch binary.LittleEndian.Uint32(e) {
+		case nsisCreateDir:
+			// SetOutPath sets the output directory
+			if param(1) != 0 {
+				outDir = s.get(param(0))
+			}
+
+		case nsisExtractFile:
+			name := s.get(param(1))
+			if !strings.HasPrefix(name, "$") && !strings.HasPrefix(name, `\\`) && !strings.Contains(name, ":") {
+				name = outDir + `\` + name
+			}
+
+			offset := dataStart + int(param(2))
+			result = append(result, entry{
+				File: files.File{Name: nsisPath(name), Mode: 0o644, Type: files.TypeNormal},
+				read: func(limit int64) ([]byte, error) { return n.block(offset, limit) },
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// nsisPath converts a Windows path to a slash-separated path relative to
+// the installation directory.
+func nsisPath(name string) string {
+	name = strings.ReplaceAll(name, `\`, "/")
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "$INSTDIR"), "$OUTDIR")
+
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// nsisStrings decodes the strings of an installer, in which variables are
+// coded as special characters.
+type nsisStrings struct {
+	data    []byte
+	unicode bool
+	// the codes of NSIS 2, before Unicode support, are different
+	skip, variable, shell, lang uint16
+}
+
+func newNSISStrings(data []byte) *nsisStrings {
+	s := &nsisStrings{data: data, skip: 4, variable: 3, shell: 2, lang: 1}
+
+	// the first string is empty
+	s.unicode = len(data) >= 2 && data[0] == 0 && data[1] == 0
+	if !s.unicode && !bytes.ContainsAny(data, "\x01\x02\x03\x04") {
+		s.skip, s.variable, s.shell, s.lang = 252, 253, 254, 255
+	}
+
+	return s
+}
+
+func (s *nsisStrings) get(offset int32) string {
+	if offset < 0 {
+		// negative offsets refer to language strings
+		return "$(LangString)"
+	}
+
+	var units []uint16
+	if s.unicode {
+		for i := 2 * int(offset); i+1 < len(s.data); i += 2 {
+			u := binary.LittleEndian.Uint16(s.data[i:])
+			if u == 0 {
+				break
+			}
+			units = append(units, u)
+		}
+	} else {
+		for i := int(offset); i < len(s.data) && s.data[i] != 0; i++ {
+			units = append(units, uint16(s.data[i]))
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(units); i++ {
+		u := units[i]
+
+		if u != s.skip && u != s.variable && u != s.shell && u != s.lang {
+			if s.unicode && utf16.IsSurrogate(rune(u)) && i+1 < len(units) {
+				b.WriteRune(utf16.DecodeRune(rune(u), rune(units[i+1])))
+				i++
+			} else {
+				b.WriteRune(rune(u))
+			}
+			continue
+		}
+
+		// the skip code escapes the next character, other codes are followed
+		// by a value in two bytes
+		var value int
+		switch {
+		case u == s.skip && i+1 < len(units):
+			b.WriteRune(rune(units[i+1]))
+			i++
+			continue
+		case s.unicode && i+1 < len(units):
+			value = int(units[i+1] & 0x7fff)
+			i++
+		case !s.unicode && i+2 < len(units):
+			value = int(units[i+1]&0x7f) | int(units[i+2]&0x7f)<<7
+			i += 2
+		default:
+			continue
+		}
+
+		switch u {
+		case s.variable:
+			b.WriteString(nsisVariable(value))
+		case s.shell:
+			b.WriteString("$SHELL")
+		case s.lang:
+			b.WriteString("$(LangString)")
+		}
+	}
+
+	return b.String()
+}
+
+func nsisVariable(i int) string {
+	switch {
+	case i < 10:
+		return fmt.Sprintf("$%d", i)
+	case i < 20:
+		return fmt.Sprintf("$R%d", i-10)
+	case i-20 < len(nsisVariables):
+		return "$" + nsisVariables[i-20]
+	}
+
+	return fmt.Sprintf("$_%d_", i)
+}
@@ -0,0 +1,292 @@
+package archives_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/archives"
+	"github.com/permafrost-dev/zeget/lib/files"
+)
+
+type packageEntry struct {
+	name   string
+	mode   int64
+	target string
+	data   string
+}
+
+var packageEntries = []packageEntry{
+	{name: "./", mode: 0o040755},
+	{name: "./usr/", mode: 0o040755},
+	{name: "./usr/bin/", mode: 0o040755},
+	{name: "./usr/bin/tool", mode: 0o100755, data: "#!/bin/sh\necho tool\n"},
+	{name: "./usr/bin/tl", mode: 0o120777, target: "tool"},
+	{name: "./usr/share/doc/tool/README", mode: 0o100644, data: "readme"},
+}
+
+func makeTar(entries []packageEntry) []byte {
+	buf := new(bytes.Buffer)
+	w := tar.NewWriter(buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: e.mode & 0o7777, Size: int64(len(e.data))}
+		switch e.mode & 0o170000 {
+		case 0o040000:
+			hdr.Typeflag = tar.TypeDir
+		case 0o120000:
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = e.target
+		default:
+			hdr.Typeflag = tar.TypeReg
+		}
+		Expect(w.WriteHeader(hdr)).To(Succeed())
+		_, err := w.Write([]byte(e.data))
+		Expect(err).NotTo(HaveOccurred())
+	}
+	Expect(w.Close()).To(Succeed())
+
+	return buf.Bytes()
+}
+
+func makeCpio(entries []packageEntry) []byte {
+	buf := new(bytes.Buffer)
+	pad := func() {
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+	}
+	write := func(name string, mode int64, data string) {
+		fmt.Fprintf(buf, "070701%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x",
+			0, mode, 0, 0, 1, 0, len(data), 0, 0, 0, 0, len(name)+1, 0)
+		buf.WriteString(name + "\x00")
+		pad()
+		buf.WriteString(data)
+		pad()
+	}
+	for _, e := range entries {
+		data := e.data
+		if e.target != "" {
+			data = e.target
+		}
+		name := e.name
+		if len(name) > 2 && name[len(name)-1] == '/' {
+			name = name[:len(name)-1]
+		}
+		write(name, e.mode, data)
+	}
+	write("TRAILER!!!", 0, "")
+
+	return buf.Bytes()
+}
+
+func gzipped(data []byte) []byte {
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	_, err := w.Write(data)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(w.Close()).To(Succeed())
+
+	return buf.Bytes()
+}
+
+func makeAr(members map[string][]byte, order ...string) []byte {
+	buf := bytes.NewBufferString("!<arch>\n")
+	for _, name := range order {
+		data := members[name]
+		fmt.Fprintf(buf, "%-16s%-12d%-6d%-6d%-8s%-10d`\n", name+"/", 0, 0, 0, "100644", len(data))
+		buf.Write(data)
+		if len(data)%2 == 1 {
+			buf.WriteByte('\n')
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func makeRpmHeader(entries, store int) []byte {
+	buf := bytes.NewBuffer([]byte{0x8e, 0xad, 0xe8, 0x01, 0, 0, 0, 0})
+	Expect(binary.Write(buf, binary.BigEndian, uint32(entries))).To(Succeed())
+	Expect(binary.Write(buf, binary.BigEndian, uint32(store))).To(Succeed())
+	buf.Write(make([]byte, entries*16+store))
+
+	return buf.Bytes()
+}
+
+func makeRpm(payload []byte) []byte {
+	lead := make([]byte, 96)
+	copy(lead, []byte{0xed, 0xab, 0xee, 0xdb})
+
+	buf := bytes.NewBuffer(lead)
+	// a signature of 16+16+5 bytes is padded to 40 bytes
+	buf.Write(makeRpmHeader(1, 5))
+	buf.Write(make([]byte, 3))
+	buf.Write(makeRpmHeader(2, 7))
+	buf.Write(payload)
+
+	return buf.Bytes()
+}
+
+func listArchive(ar Archive) []files.File {
+	var result []files.File
+	for {
+		f, err := ar.Next()
+		if err == io.EOF {
+			return result
+		}
+		Expect(err).NotTo(HaveOccurred())
+		result = append(result, f)
+	}
+}
+
+func expectPackageFiles(ar Archive) {
+	var names []string
+	for {
+		f, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		Expect(err).NotTo(HaveOccurred())
+		names = append(names, f.Name)
+
+		switch f.Name {
+		case "usr/bin/tool":
+			Expect(f.Type).To(Equal(files.TypeNormal))
+			Expect(f.Mode.Perm()).To(Equal(fs.FileMode(0o755)))
+			data, err := ar.ReadAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("#!/bin/sh\necho tool\n"))
+		case "usr/bin/tl":
+			Expect(f.Type).To(Equal(files.TypeSymlink))
+			Expect(f.LinkName).To(Equal("tool"))
+		case "usr/bin/":
+			Expect(f.Dir()).To(BeTrue())
+		}
+	}
+
+	Expect(names).To(Equal([]string{"usr/", "usr/bin/", "usr/bin/tool", "usr/bin/tl", "usr/share/doc/tool/README"}))
+}
+
+var _ = Describe("Package archives", func() {
+	Describe("NewCpioArchive", func() {
+		It("reads newc archives", func() {
+			ar, err := NewCpioArchive(makeCpio(packageEntries), NoDecompress)
+			Expect(err).NotTo(HaveOccurred())
+			expectPackageFiles(ar)
+		})
+
+		It("skips unread file data", func() {
+			ar, err := NewCpioArchive(makeCpio(packageEntries), NoDecompress)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(listArchive(ar)).To(HaveLen(5))
+		})
+
+		It("rejects other cpio formats", func() {
+			ar, err := NewCpioArchive([]byte("070710"+string(make([]byte, 120))), NoDecompress)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = ar.Next()
+			Expect(err).To(MatchError(`cpio: unsupported format "070710"`))
+		})
+	})
+
+	Describe("NewDebArchive", func() {
+		for _, compression := range []string{"", ".gz"} {
+			compression := compression
+
+			It("reads the data"+compression+" member", func() {
+				data := makeTar(packageEntries)
+				if compression == ".gz" {
+					data = gzipped(data)
+				}
+				deb := makeAr(map[string][]byte{
+					"debian-binary":          []byte("2.0\n"),
+					"control.tar.gz":         gzipped(makeTar([]packageEntry{{name: "./control", mode: 0o100644, data: "Package: tool"}})),
+					"data.tar" + compression: data,
+				}, "debian-binary", "control.tar.gz", "data.tar"+compression)
+
+				ar, err := NewDebArchive(deb, nil)
+				Expect(err).NotTo(HaveOccurred())
+				expectPackageFiles(ar)
+			})
+		}
+
+		It("fails without a data member", func() {
+			deb := makeAr(map[string][]byte{"debian-binary": []byte("2.0\n")}, "debian-binary")
+			_, err := NewDebArchive(deb, nil)
+			Expect(err).To(MatchError("deb: no data.tar member found"))
+		})
+
+		It("fails for other files", func() {
+			_, err := NewDebArchive([]byte("not a package"), nil)
+			Expect(err).To(MatchError("deb: ar: not an ar archive"))
+		})
+	})
+
+	Describe("NewRpmArchive", func() {
+		It("reads an uncompressed payload", func() {
+			ar, err := NewRpmArchive(makeRpm(makeCpio(packageEntries)), nil)
+			Expect(err).NotTo(HaveOccurred())
+			expectPackageFiles(ar)
+		})
+
+		It("reads a gzip payload", func() {
+			ar, err := NewRpmArchive(makeRpm(gzipped(makeCpio(packageEntries))), nil)
+			Expect(err).NotTo(HaveOccurred())
+			expectPackageFiles(ar)
+		})
+
+		It("fails for truncated headers", func() {
+			rpm := makeRpm(nil)
+			_, err := NewRpmArchive(rpm[:120], nil)
+			Expect(err).To(MatchError(ContainSubstring("rpm: signature: truncated header")))
+		})
+
+		It("fails for other files", func() {
+			_, err := NewRpmArchive([]byte("not a package"), nil)
+			Expect(err).To(MatchError("rpm: not an rpm package"))
+		})
+	})
+
+	Describe("NewApkArchive", func() {
+		It("reads Alpine packages and skips their metadata", func() {
+			signature := makeTar([]packageEntry{{name: ".SIGN.RSA.key.rsa.pub", mode: 0o100644, data: "sig"}})
+			control := makeTar([]packageEntry{{name: ".PKGINFO", mode: 0o100644, data: "pkgname = tool"}})
+			data := makeTar(packageEntries)
+
+			// apk segments omit the end-of-archive blocks, except for the last
+			apk := append(gzipped(signature[:len(signature)-1024]), gzipped(control[:len(control)-1024])...)
+			apk = append(apk, gzipped(data)...)
+
+			ar, err := NewApkArchive(apk, nil)
+			Expect(err).NotTo(HaveOccurred())
+			expectPackageFiles(ar)
+		})
+
+		It("fails for other files", func() {
+			_, err := NewApkArchive([]byte("not a package"), nil)
+			Expect(err).To(MatchError("apk: unsupported package format"))
+		})
+	})
+
+	Describe("DetectDecompressor", func() {
+		It("returns the data of unknown formats as is", func() {
+			r, err := DetectDecompressor([]byte("plain"))(bytes.NewReader([]byte("plain")))
+			Expect(err).NotTo(HaveOccurred())
+			data, _ := io.ReadAll(r)
+			Expect(string(data)).To(Equal("plain"))
+		})
+
+		It("decompresses gzip data", func() {
+			data := gzipped([]byte("compressed"))
+			r, err := DetectDecompressor(data)(bytes.NewReader(data))
+			Expect(err).NotTo(HaveOccurred())
+			out, _ := io.ReadAll(r)
+			Expect(string(out)).To(Equal("compressed"))
+		})
+	})
+})
@@ -0,0 +1,456 @@
+package archives
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/permafrost-dev/zeget/lib/files"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+	"github.com/ulikunitz/xz/lzma"
+)
+
+var squashfsMagic = []byte("hsqs")
+
+const (
+	squashfsSuperblockSize = 96
+	squashfsMetadataSize   = 8192
+	squashfsUncompressed   = 1 << 24
+	squashfsNoFragment     = 0xffffffff
+	squashfsMaxDepth       = 256
+
+	squashfsGzip = 1
+	squashfsLzma = 2
+	squashfsLzo  = 3
+	squashfsXz   = 4
+	squashfsLz4  = 5
+	squashfsZstd = 6
+
+	squashfsDir        = 1
+	squashfsFile       = 2
+	squashfsSymlink    = 3
+	squashfsExtDir     = 8
+	squashfsExtFile    = 9
+	squashfsExtSymlink = 10
+)
+
+type squashfsFragment struct {
+	start uint64
+	size  uint32
+}
+
+// A squashfs is a SquashFS 4.0 file system, the format of the files of
+// AppImages and of many Linux live systems.
+type squashfs struct {
+	data       []byte
+	blockSize  uint32
+	compressor uint16
+	inodeTable uint64
+	dirTable   uint64
+	fragments  []squashfsFragment
+	entries    []entry
+	metadata   map[uint64]squashfsBlock
+	zstd       *zstd.Decoder
+}
+
+type squashfsBlock struct {
+	content []byte
+	next    uint64
+}
+
+// readSquashfs returns the files of a SquashFS file system, directories
+// before the files they contain.
+func readSquashfs(data []byte) ([]entry, error) {
+	if !bytes.HasPrefix(data, squashfsMagic) || len(data) < squashfsSuperblockSize {
+		return nil, fmt.Errorf("squashfs: not a SquashFS file system")
+	}
+	if major := binary.LittleEndian.Uint16(data[28:]); major != 4 {
+		return nil, fmt.Errorf("squashfs: unsupported version %d", major)
+	}
+
+	s := &squashfs{
+		data:       data,
+		blockSize:  binary.LittleEndian.Uint32(data[12:]),
+		compressor: binary.LittleEndian.Uint16(data[20:]),
+		inodeTable: binary.LittleEndian.Uint64(data[64:]),
+		dirTable:   binary.LittleEndian.Uint64(data[72:]),
+		metadata:   map[uint64]squashfsBlock{},
+	}
+	if s.blockSize < 4096 || s.blockSize > 1<<20 || s.blockSize&(s.blockSize-1) != 0 {
+		return nil, fmt.Errorf("squashfs: invalid block size %d", s.blockSize)
+	}
+
+	// the fragment table lists the metadata blocks of the fragment entries,
+	// 512 in each block
+	count := int(binary.LittleEndian.Uint32(data[16:]))
+	tableStart := binary.LittleEndian.Uint64(data[80:])
+	for i := 0; i < (count+511)/512; i++ {
+		offset := tableStart + 8*uint64(i)
+		if offset+8 > uint64(len(data)) {
+			return nil, fmt.Errorf("squashfs: truncated fragment table")
+		}
+
+		c := s.cursor(binary.LittleEndian.Uint64(data[offset:]), 0)
+		for j := 0; j < min(512, count-512*i); j++ {
+			b, err := c.read(16)
+			if err != nil {
+				return nil, fmt.Errorf("squashfs: fragment table: %w", err)
+			}
+			s.fragments = append(s.fragments, squashfsFragment{
+				start: binary.LittleEndian.Uint64(b),
+				size:  binary.LittleEndian.Uint32(b[8:]),
+			})
+		}
+	}
+
+	if err := s.walk(binary.LittleEndian.Uint64(data[32:]), "", 0); err != nil {
+		return nil, err
+	}
+
+	return s.entries, nil
+}
+
+// decompress decompresses a block of at most size bytes.
+func (s *squashfs) decompress(block []byte, size int) ([]byte, error) {
+	var r io.Reader
+	var err error
+
+	switch s.compressor {
+	case squashfsGzip:
+		r, err = zlib.NewReader(bytes.NewReader(block))
+	case squashfsLzma:
+		r, err = lzma.NewReader(bytes.NewReader(block))
+	case squashfsXz:
+		r, err = xz.NewReader(bytes.NewReader(block))
+	case squashfsLz4:
+		out := make([]byte, size)
+		n, err := lz4.UncompressBlock(block, out)
+		if err != nil {
+			return nil, err
+		}
+		return out[:n], nil
+	case squashfsZstd:
+		if s.zstd == nil {
+			if s.zstd, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1)); err != nil {
+				return nil, err
+			}
+		}
+		out, err := s.zstd.DecodeAll(block, make([]byte, 0, size))
+		if len(out) > size {
+			return nil, fmt.Errorf("block larger than %d bytes", size)
+		}
+		return out, err
+	case squashfsLzo:
+		return nil, fmt.Errorf("LZO compression is not supported")
+	default:
+		return nil, fmt.Errorf("unsupported compression type %d", s.compressor)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(io.LimitReader(r, int64(size)))
+}
+
+// metadataBlock returns the content of the metadata block at an offset of the
+// file system, and the offset of the next block. Metadata blocks start with
+// their size and whether they are compressed.
+func (s *squashfs) metadataBlock(offset uint64) ([]byte, uint64, error) {
+	if b, ok := s.metadata[offset]; ok {
+		return b.content, b.next, nil
+	}
+
+	if offset+2 > uint64(len(s.data)) {
+		return nil, 0, fmt.Errorf("metadata block beyond the end of the file system")
+	}
+
+	header := binary.LittleEndian.Uint16(s.data[offset:])
+	size := uint64(header & 0x7fff)
+	start := offset + 2
+	if start+size > uint64(len(s.data)) {
+		return nil, 0, fmt.Errorf("metadata block beyond the end of the file system")
+	}
+
+	block := s.data[start : start+size]
+	if header&0x8000 == 0 {
+		var err error
+		if block, err = s.decompress(block, squashfsMetadataSize); err != nil {
+			return nil, 0, err
+		}
+	}
+	s.metadata[offset] = squashfsBlock{content: block, next: start + size}
+
+	return block, start + size, nil
+}
+
+// A squashfsCursor reads metadata which may span several metadata blocks.
+type squashfsCursor struct {
+	s    *squashfs
+	next uint64
+	skip int
+	buf  []byte
+}
+
+// cursor returns a cursor at an offset of the content of the metadata block
+// at an offset of the file system.
+func (s *squashfs) cursor(block uint64, offset int) *squashfsCursor {
+	return &squashfsCursor{s: s, next: block, skip: offset}
+}
+
+func (c *squashfsCursor) read(n int) ([]byte, error) {
+	for len(c.buf) < c.skip+n {
+		block, next, err := c.s.metadataBlock(c.next)
+		if err != nil {
+			return nil, err
+		}
+		if len(block) == 0 {
+			return nil, fmt.Errorf("empty metadata block")
+		}
+		c.buf = append(c.buf, block...)
+		c.next = next
+	}
+
+	b := c.buf[c.skip : c.skip+n]
+	c.buf, c.skip = c.buf[c.skip+n:], 0
+
+	return b, nil
+}
+
+// walk adds the entry of the inode referenced by ref, and the entries of the
+// directory it may be, named after the path.
+func (s *squashfs) walk(ref uint64, name string, depth int) error {
+	if depth > squashfsMaxDepth {
+		return fmt.Errorf("squashfs: directories nested too deeply")
+	}
+
+	c := s.cursor(s.inodeTable+ref>>16, int(ref&0xffff))
+	header, err := c.read(16)
+	if err != nil {
+		return fmt.Errorf("squashfs: %s: %w", name, err)
+	}
+	kind := binary.LittleEndian.Uint16(header)
+	mode := fs.FileMode(binary.LittleEndian.Uint16(header[2:]) & 0o7777)
+
+	switch kind {
+	case squashfsDir, squashfsExtDir:
+		var block, size uint32
+		var offset uint16
+		if kind == squashfsDir {
+			b, err := c.read(16)
+			if err != nil {
+				return fmt.Errorf("squashfs: %s: %w", name, err)
+			}
+			block = binary.LittleEndian.Uint32(b)
+			size = uint32(binary.LittleEndian.Uint16(b[8:]))
+			offset = binary.LittleEndian.Uint16(b[10:])
+		} else {
+			b, err := c.read(24)
+			if err != nil {
+				return fmt.Errorf("squashfs: %s: %w", name, err)
+			}
+			size = binary.LittleEndian.Uint32(b[4:])
+			block = binary.LittleEndian.Uint32(b[8:])
+			offset = binary.LittleEndian.Uint16(b[18:])
+		}
+
+		if name != "" {
+			s.entries = append(s.entries, entry{File: files.File{Name: name + "/", Mode: mode | fs.ModeDir, Type: files.TypeDir}})
+		}
+
+		// the size includes the implicit . and .. entries
+		if size <= 3 {
+			return nil
+		}
+		return s.walkDir(s.cursor(s.dirTable+uint64(block), int(offset)), int(size)-3, name, depth)
+
+	case squashfsFile, squashfsExtFile:
+		var start, size uint64
+		var fragment, fragmentOffset uint32
+		if kind == squashfsFile {
+			b, err := c.read(16)
+			if err != nil {
+				return fmt.Errorf("squashfs: %s: %w", name, err)
+			}
+			start = uint64(binary.LittleEndian.Uint32(b))
+			fragment = binary.LittleEndian.Uint32(b[4:])
+			fragmentOffset = binary.LittleEndian.Uint32(b[8:])
+			size = uint64(binary.LittleEndian.Uint32(b[12:]))
+		} else {
+			b, err := c.read(40)
+			if err != nil {
+				return fmt.Errorf("squashfs: %s: %w", name, err)
+			}
+			start = binary.LittleEndian.Uint64(b)
+			size = binary.LittleEndian.Uint64(b[8:])
+			fragment = binary.LittleEndian.Uint32(b[28:])
+			fragmentOffset = binary.LittleEndian.Uint32(b[32:])
+		}
+
+		// the tail of the file may be stored in a fragment shared with other
+		// files instead of a block of its own
+		blocks := (size + uint64(s.blockSize) - 1) / uint64(s.blockSize)
+		if fragment != squashfsNoFragment {
+			blocks = size / uint64(s.blockSize)
+		}
+		if blocks > uint64(len(s.data)) {
+			return fmt.Errorf("squashfs: %s: invalid file size", name)
+		}
+		b, err := c.read(4 * int(blocks))
+		if err != nil {
+			return fmt.Errorf("squashfs: %s: %w", name, err)
+		}
+		sizes := make([]uint32, blocks)
+		for i := range sizes {
+			sizes[i] = binary.LittleEndian.Uint32(b[4*i:])
+		}
+
+		s.entries = append(s.entries, entry{
+			File: files.File{Name: name, Mode: mode, Type: files.TypeNormal},
+			read: func(limit int64) ([]byte, error) {
+				data, err := s.readFile(start, size, sizes, fragment, fragmentOffset, limit)
+				if err != nil {
+					return nil, fmt.Errorf("squashfs: %s: %w", name, err)
+				}
+				return data, nil
+			},
+		})
+
+	case squashfsSymlink, squashfsExtSymlink:
+		b, err := c.read(8)
+		if err != nil {
+			return fmt.Errorf("squashfs: %s: %w", name, err)
+		}
+		target, err := c.read(int(min(binary.LittleEndian.Uint32(b[4:]), 4096)))
+		if err != nil {
+			return fmt.Errorf("squashfs: %s: %w", name, err)
+		}
+
+		s.entries = append(s.entries, entry{File: files.File{
+			Name:     name,
+			LinkName: string(target),
+			Mode:     mode | fs.ModeSymlink,
+			Type:     files.TypeSymlink,
+		}})
+	}
+
+	// devices, pipes and sockets are left out
+	return nil
+}
+
+// walkDir walks the entries of a directory listing of size bytes. Listings
+// are split in runs of entries whose inodes are in the same metadata block.
+func (s *squashfs) walkDir(c *squashfsCursor, size int, dir string, depth int) error {
+	for size > 0 {
+		header, err := c.read(12)
+		if err != nil {
+			return fmt.Errorf("squashfs: %s: %w", dir, err)
+		}
+		count := int(binary.LittleEndian.Uint32(header)) + 1
+		block := uint64(binary.LittleEndian.Uint32(header[4:]))
+		size -= 12
+
+		if count > squashfsMetadataSize {
+			return fmt.Errorf("squashfs: %s: invalid directory", dir)
+		}
+
+		for i := 0; i < count && size > 0; i++ {
+			b, err := c.read(8)
+			if err != nil {
+				return fmt.Errorf("squashfs: %s: %w", dir, err)
+			}
+			offset := uint64(binary.LittleEndian.Uint16(b))
+			nameSize := int(binary.LittleEndian.Uint16(b[6:])) + 1
+			name, err := c.read(nameSize)
+			if err != nil {
+				return fmt.Errorf("squashfs: %s: %w", dir, err)
+			}
+			size -= 8 + nameSize
+
+			if err := s.walk(block<<16|offset, path.Join(dir, string(name)), depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// dataBlock returns the content of a data block, whose size is given with
+// whether it is stored uncompressed.
+func (s *squashfs) dataBlock(start uint64, size uint32) ([]byte, error) {
+	length := uint64(size &^ squashfsUncompressed)
+	if start+length > uint64(len(s.data)) || start+length < start {
+		return nil, fmt.Errorf("data block beyond the end of the file system")
+	}
+
+	block := s.data[start : start+length]
+	if size&squashfsUncompressed != 0 {
+		return block, nil
+	}
+
+	return s.decompress(block, int(s.blockSize))
+}
+
+// readFile returns the data of a file. Given a limit of zero or more, only
+// the first limit+1 bytes of a larger file are read.
+func (s *squashfs) readFile(start, size uint64, sizes []uint32, fragment, fragmentOffset uint32, limit int64) ([]byte, error) {
+	if size > uint64(len(sizes)+1)*uint64(s.blockSize) {
+		return nil, fmt.Errorf("invalid file size")
+	}
+	want := size
+	if limit >= 0 {
+		want = min(want, uint64(limit)+1)
+	}
+	data := make([]byte, 0, want)
+
+	for _, blockSize := range sizes {
+		if uint64(len(data)) > size {
+			return nil, fmt.Errorf("invalid block size")
+		}
+		if uint64(len(data)) >= want {
+			return data[:want], nil
+		}
+
+		// empty blocks are holes of sparse files
+		if blockSize == 0 {
+			data = append(data, make([]byte, min(uint64(s.blockSize), size-uint64(len(data))))...)
+			continue
+		}
+
+		block, err := s.dataBlock(start, blockSize)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, block...)
+		start += uint64(blockSize &^ squashfsUncompressed)
+	}
+
+	if fragment != squashfsNoFragment && uint64(len(data)) < want {
+		if int(fragment) >= len(s.fragments) {
+			return nil, fmt.Errorf("invalid fragment %d", fragment)
+		}
+		f := s.fragments[fragment]
+		block, err := s.dataBlock(f.start, f.size)
+		if err != nil {
+			return nil, err
+		}
+
+		tail := size - uint64(len(data))
+		if uint64(fragmentOffset)+tail > uint64(len(block)) {
+			return nil, fmt.Errorf("file beyond the end of its fragment")
+		}
+		data = append(data, block[fragmentOffset:uint64(fragmentOffset)+tail]...)
+	}
+
+	if uint64(len(data)) < want || want == size && uint64(len(data)) != size {
+		return nil, fmt.Errorf("truncated file")
+	}
+
+	return data[:want], nil
+}
@@ -0,0 +1,600 @@
+package archives_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"strings"
+	"unicode/utf16"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/archives"
+	"github.com/ulikunitz/xz/lzma"
+)
+
+type cabTestFile struct {
+	name string
+	data []byte
+	exec bool
+}
+
+const (
+	cabStored = 0x0000
+	cabMSZIP  = 0x0001
+	cabLZX    = 0x0f03
+)
+
+// lzxStored codes data as an uncompressed LZX block.
+func lzxStored(data []byte) []byte {
+	header := uint32(3)<<28 | uint32(len(data))<<4
+	buf := new(bytes.Buffer)
+	Expect(binary.Write(buf, binary.LittleEndian, uint16(header>>16))).To(Succeed())
+	Expect(binary.Write(buf, binary.LittleEndian, uint16(header))).To(Succeed())
+	Expect(binary.Write(buf, binary.LittleEndian, [3]uint32{1, 1, 1})).To(Succeed())
+	buf.Write(data)
+	if len(data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+
+	return buf.Bytes()
+}
+
+// makeCab builds a cabinet with the files in a single folder.
+func makeCab(entries []cabTestFile, kind uint16) []byte {
+	var folder []byte
+	for _, e := range entries {
+		folder = append(folder, e.data...)
+	}
+
+	// data blocks hold up to 32 KiB of the folder, LZX streams are split at
+	// arbitrary positions
+	var blocks [][2][]byte
+	stream := lzxStored(folder)
+	for i := 0; i < len(folder); i += 32768 {
+		block := folder[i:min(i+32768, len(folder))]
+		stored := block
+		switch kind {
+		case cabLZX:
+			stored = stream[min(i, len(stream)):min(i+32768, len(stream))]
+			if i+32768 >= len(folder) {
+				stored = stream[min(i, len(stream)):]
+			}
+		case cabMSZIP:
+			buf := bytes.NewBufferString("CK")
+			w, err := flate.NewWriterDict(buf, flate.BestCompression, folder[max(0, i-32768):i])
+			Expect(err).NotTo(HaveOccurred())
+			_, err = w.Write(block)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+			stored = buf.Bytes()
+		}
+		blocks = append(blocks, [2][]byte{stored, block})
+	}
+
+	fileList := new(bytes.Buffer)
+	offset := 0
+	for _, e := range entries {
+		var attrs uint16
+		if e.exec {
+			attrs = 0x40
+		}
+		Expect(binary.Write(fileList, binary.LittleEndian, []uint32{uint32(len(e.data)), uint32(offset)})).To(Succeed())
+		Expect(binary.Write(fileList, binary.LittleEndian, []uint16{0, 0, 0, attrs})).To(Succeed())
+		fileList.WriteString(e.name + "\x00")
+		offset += len(e.data)
+	}
+
+	filesOffset := 36 + 8
+	dataOffset := filesOffset + fileList.Len()
+	data := new(bytes.Buffer)
+	for _, b := range blocks {
+		Expect(binary.Write(data, binary.LittleEndian, uint32(0))).To(Succeed())
+		Expect(binary.Write(data, binary.LittleEndian, []uint16{uint16(len(b[0])), uint16(len(b[1]))})).To(Succeed())
+		data.Write(b[0])
+	}
+
+	buf := bytes.NewBufferString("MSCF")
+	Expect(binary.Write(buf, binary.LittleEndian, []uint32{
+		0, uint32(dataOffset + data.Len()), 0, uint32(filesOffset), 0,
+	})).To(Succeed())
+	buf.Write([]byte{3, 1})
+	Expect(binary.Write(buf, binary.LittleEndian, []uint16{1, uint16(len(entries)), 0, 0, 0})).To(Succeed())
+	Expect(binary.Write(buf, binary.LittleEndian, uint32(dataOffset))).To(Succeed())
+	Expect(binary.Write(buf, binary.LittleEndian, []uint16{uint16(len(blocks)), kind})).To(Succeed())
+	buf.Write(fileList.Bytes())
+	buf.Write(data.Bytes())
+
+	return buf.Bytes()
+}
+
+// makeCFB builds a compound file with the streams in the root storage, all
+// stored in regular sectors.
+func makeCFB(names []string, streams map[string][]byte) []byte {
+	const sectorSize = 512
+
+	dirSectors := (len(names) + 1 + 3) / 4
+	next := 1 + dirSectors
+	fat := []uint32{0xfffffffd}
+	for i := 1; i < next; i++ {
+		fat = append(fat, uint32(i+1))
+	}
+	fat[next-1] = 0xfffffffe
+
+	dir := make([]byte, dirSectors*sectorSize)
+	body := new(bytes.Buffer)
+	putEntry := func(i int, name string, kind byte, start, size uint32) {
+		e := dir[i*128:]
+		units := utf16.Encode([]rune(name))
+		for j, u := range units {
+			binary.LittleEndian.PutUint16(e[2*j:], u)
+		}
+		binary.LittleEndian.PutUint16(e[64:], uint16(2*len(units)+2))
+		e[66] = kind
+		binary.LittleEndian.PutUint32(e[68:], 0xffffffff)
+		binary.LittleEndian.PutUint32(e[72:], 0xffffffff)
+		binary.LittleEndian.PutUint32(e[76:], 0xffffffff)
+		binary.LittleEndian.PutUint32(e[116:], start)
+		binary.LittleEndian.PutUint32(e[120:], size)
+	}
+
+	putEntry(0, "Root Entry", 5, 0xfffffffe, 0)
+	binary.LittleEndian.PutUint32(dir[76:], 1)
+	for i, name := range names {
+		stream := streams[name]
+		sectors := (len(stream) + sectorSize - 1) / sectorSize
+		putEntry(i+1, name, 2, uint32(next), uint32(len(stream)))
+		if i+1 < len(names) {
+			binary.LittleEndian.PutUint32(dir[(i+1)*128+72:], uint32(i+2))
+		}
+
+		for j := 0; j < sectors; j++ {
+			fat = append(fat, uint32(next+j+1))
+		}
+		fat[len(fat)-1] = 0xfffffffe
+		next += sectors
+
+		padded := make([]byte, sectors*sectorSize)
+		copy(padded, stream)
+		body.Write(padded)
+	}
+	Expect(len(fat)).To(BeNumerically("<=", sectorSize/4))
+
+	header := make([]byte, sectorSize)
+	copy(header, "\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1")
+	binary.LittleEndian.PutUint16(header[24:], 0x3e)
+	binary.LittleEndian.PutUint16(header[26:], 3)
+	binary.LittleEndian.PutUint16(header[28:], 0xfffe)
+	binary.LittleEndian.PutUint16(header[30:], 9)
+	binary.LittleEndian.PutUint16(header[32:], 6)
+	binary.LittleEndian.PutUint32(header[44:], 1)
+	binary.LittleEndian.PutUint32(header[48:], 1)
+	binary.LittleEndian.PutUint32(header[60:], 0xfffffffe)
+	binary.LittleEndian.PutUint32(header[68:], 0xfffffffe)
+	for i := 76; i < sectorSize; i += 4 {
+		binary.LittleEndian.PutUint32(header[i:], 0xffffffff)
+	}
+	binary.LittleEndian.PutUint32(header[76:], 0)
+
+	fatSector := make([]byte, sectorSize)
+	for i := range fatSector {
+		fatSector[i] = 0xff
+	}
+	for i, v := range fat {
+		binary.LittleEndian.PutUint32(fatSector[4*i:], v)
+	}
+
+	out := append(header, fatSector...)
+	out = append(out, dir...)
+
+	return append(out, body.Bytes()...)
+}
+
+// msiEncodeName packs the name of a stream of an MSI database.
+func msiEncodeName(name string) string {
+	const chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz._"
+
+	var runes []rune
+	if table, ok := strings.CutPrefix(name, "!"); ok {
+		runes = append(runes, 0x4840)
+		name = table
+	}
+	for i := 0; i < len(name); i += 2 {
+		if i+1 < len(name) {
+			runes = append(runes, 0x3800+rune(strings.IndexByte(chars, name[i])+strings.IndexByte(chars, name[i+1])<<6))
+		} else {
+			runes = append(runes, 0x4800+rune(strings.IndexByte(chars, name[i])))
+		}
+	}
+
+	return string(runes)
+}
+
+type msiTable struct {
+	name    string
+	columns []string
+	rows    [][]any
+}
+
+// makeMsi builds an MSI database with the tables, whose columns are strings
+// unless their values are integers, and the streams.
+func makeMsi(tables []msiTable, streams map[string][]byte) []byte {
+	pool := []string{""}
+	ref := func(s string) uint16 {
+		if s == "" {
+			return 0
+		}
+		for i, p := range pool {
+			if p == s {
+				return uint16(i)
+			}
+		}
+		pool = append(pool, s)
+		return uint16(len(pool) - 1)
+	}
+
+	encode := func(rows [][]any) []byte {
+		buf := new(bytes.Buffer)
+		if len(rows) == 0 {
+			return nil
+		}
+		for c := range rows[0] {
+			for _, row := range rows {
+				var v uint16
+				switch value := row[c].(type) {
+				case string:
+					v = ref(value)
+				case int:
+					v = uint16(value + 0x8000)
+				}
+				Expect(binary.Write(buf, binary.LittleEndian, v)).To(Succeed())
+			}
+		}
+		return buf.Bytes()
+	}
+
+	all := map[string][]byte{}
+	var columns [][]any
+	for _, t := range tables {
+		for i, c := range t.columns {
+			kind := 0x1d48
+			if _, ok := t.rows[0][i].(int); ok {
+				kind = 0x1502
+			}
+			columns = append(columns, []any{t.name, i + 1, c, kind})
+		}
+		all[msiEncodeName("!"+t.name)] = encode(t.rows)
+	}
+	all[msiEncodeName("!_Columns")] = encode(columns)
+
+	names := []string{msiEncodeName("!_Columns")}
+	for _, t := range tables {
+		names = append(names, msiEncodeName("!"+t.name))
+	}
+	for name, data := range streams {
+		all[msiEncodeName(name)] = data
+		names = append(names, msiEncodeName(name))
+	}
+
+	poolData := new(bytes.Buffer)
+	strs := new(bytes.Buffer)
+	Expect(binary.Write(poolData, binary.LittleEndian, uint32(0))).To(Succeed())
+	for _, s := range pool[1:] {
+		Expect(binary.Write(poolData, binary.LittleEndian, []uint16{uint16(len(s)), 1})).To(Succeed())
+		strs.WriteString(s)
+	}
+	all[msiEncodeName("!_StringPool")] = poolData.Bytes()
+	all[msiEncodeName("!_StringData")] = strs.Bytes()
+	names = append(names, msiEncodeName("!_StringPool"), msiEncodeName("!_StringData"))
+
+	return makeCFB(names, all)
+}
+
+// makePE builds a Windows executable with a single section, followed by the
+// overlay.
+func makePE(overlay []byte) []byte {
+	exe := make([]byte, 1024)
+	copy(exe, "MZ")
+	binary.LittleEndian.PutUint32(exe[0x3c:], 0x40)
+	copy(exe[0x40:], "PE\x00\x00")
+	binary.LittleEndian.PutUint16(exe[0x44:], 0x8664)
+	binary.LittleEndian.PutUint16(exe[0x46:], 1)
+
+	section := exe[0x58:]
+	copy(section, ".text")
+	binary.LittleEndian.PutUint32(section[8:], 512)
+	binary.LittleEndian.PutUint32(section[12:], 0x1000)
+	binary.LittleEndian.PutUint32(section[16:], 512)
+	binary.LittleEndian.PutUint32(section[20:], 512)
+
+	return append(exe, overlay...)
+}
+
+// makeNSIS builds the data of a Unicode NSIS installer which extracts the
+// files to the directories, as a stored header followed by deflated blocks,
+// or as a solid LZMA stream.
+func makeNSIS(solid bool) []byte {
+	var strs []uint16
+	addString := func(s string) uint32 {
+		offset := uint32(len(strs))
+		if rest, ok := strings.CutPrefix(s, "$INSTDIR"); ok {
+			strs = append(strs, 3, 0x8000|21)
+			s = rest
+		}
+		strs = append(strs, utf16.Encode([]rune(s))...)
+		strs = append(strs, 0)
+		return offset
+	}
+	addString("")
+
+	blocks := new(bytes.Buffer)
+	addBlock := func(data []byte) uint32 {
+		offset := uint32(blocks.Len())
+		if solid {
+			Expect(binary.Write(blocks, binary.LittleEndian, uint32(len(data)))).To(Succeed())
+			blocks.Write(data)
+			return offset
+		}
+
+		compressed := new(bytes.Buffer)
+		w, err := flate.NewWriter(compressed, flate.BestCompression)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = w.Write(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Close()).To(Succeed())
+		Expect(binary.Write(blocks, binary.LittleEndian, uint32(compressed.Len())|0x80000000)).To(Succeed())
+		blocks.Write(compressed.Bytes())
+		return offset
+	}
+
+	var instructions [][7]uint32
+	instructions = append(instructions,
+		[7]uint32{11, addString(`$INSTDIR\bin`), 1},
+		[7]uint32{20, 0, addString("tool.exe"), addBlock([]byte("MZ tool"))},
+		[7]uint32{11, addString(`$INSTDIR`), 1},
+		[7]uint32{20, 0, addString("README.txt"), addBlock([]byte("readme"))},
+		[7]uint32{20, 0, addString(`$INSTDIR\doc\LICENSE`), addBlock([]byte("license"))},
+	)
+
+	entriesOffset := 4 + 8*8
+	stringsOffset := entriesOffset + 28*len(instructions)
+	header := new(bytes.Buffer)
+	Expect(binary.Write(header, binary.LittleEndian, uint32(0))).To(Succeed())
+	table := make([]uint32, 16)
+	table[4], table[5] = uint32(entriesOffset), uint32(len(instructions))
+	table[6] = uint32(stringsOffset)
+	table[8] = uint32(stringsOffset + 2*len(strs))
+	Expect(binary.Write(header, binary.LittleEndian, table)).To(Succeed())
+	Expect(binary.Write(header, binary.LittleEndian, instructions)).To(Succeed())
+	Expect(binary.Write(header, binary.LittleEndian, strs)).To(Succeed())
+
+	data := new(bytes.Buffer)
+	Expect(binary.Write(data, binary.LittleEndian, uint32(header.Len()))).To(Succeed())
+	data.Write(header.Bytes())
+	data.Write(blocks.Bytes())
+
+	stored := data.Bytes()
+	if solid {
+		compressed := new(bytes.Buffer)
+		w, err := lzma.WriterConfig{SizeInHeader: false, EOSMarker: true}.NewWriter(compressed)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = w.Write(stored)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Close()).To(Succeed())
+		// NSIS omits the size of the LZMA header
+		stored = append(compressed.Bytes()[:5:5], compressed.Bytes()[13:]...)
+	}
+
+	buf := new(bytes.Buffer)
+	Expect(binary.Write(buf, binary.LittleEndian, uint32(0))).To(Succeed())
+	buf.WriteString("\xef\xbe\xad\xdeNullsoftInst")
+	Expect(binary.Write(buf, binary.LittleEndian, []uint32{uint32(header.Len()), uint32(28 + len(stored))})).To(Succeed())
+	buf.Write(stored)
+
+	return buf.Bytes()
+}
+
+func readArchive(ar Archive) map[string]string {
+	found := map[string]string{}
+	for {
+		f, err := ar.Next()
+		if err == io.EOF {
+			return found
+		}
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := ar.ReadAll()
+		Expect(err).NotTo(HaveOccurred())
+		found[f.Name] = string(data)
+	}
+}
+
+var cabFiles = []cabTestFile{
+	{name: `bin\tool.exe`, data: bytes.Repeat([]byte("MZ tool binary "), 5000), exec: true},
+	{name: "README.txt", data: []byte("readme")},
+}
+
+var _ = Describe("Windows archives", func() {
+	Describe("NewCabArchive", func() {
+		DescribeTable("reading cabinets",
+			func(kind uint16) {
+				ar, err := NewCabArchive(makeCab(cabFiles, kind), nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				found := listArchive(ar)
+				Expect(found).To(HaveLen(2))
+				Expect(found[0].Name).To(Equal("bin/tool.exe"))
+				Expect(found[0].Mode.Perm()).To(Equal(fs.FileMode(0o755)))
+				Expect(found[1].Name).To(Equal("README.txt"))
+				Expect(found[1].Mode.Perm()).To(Equal(fs.FileMode(0o644)))
+
+				ar, err = NewCabArchive(makeCab(cabFiles, kind), nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(readArchive(ar)).To(Equal(map[string]string{
+					"bin/tool.exe": string(cabFiles[0].data),
+					"README.txt":   "readme",
+				}))
+			},
+			Entry("stored", uint16(cabStored)),
+			Entry("MSZIP", uint16(cabMSZIP)),
+			Entry("LZX", uint16(cabLZX)),
+		)
+
+		It("fails for Quantum compression", func() {
+			ar, err := NewCabArchive(makeCab(cabFiles, 0x0002), nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = ar.Next()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = ar.ReadAll()
+			Expect(err).To(MatchError("cab: Quantum compression is not supported"))
+		})
+
+		It("fails for other files", func() {
+			_, err := NewCabArchive([]byte("not a cabinet"), nil)
+			Expect(err).To(MatchError("cab: not a cabinet"))
+		})
+	})
+
+	Describe("NewMsiArchive", func() {
+		tables := []msiTable{
+			{name: "Directory", columns: []string{"Directory", "Directory_Parent", "DefaultDir"}, rows: [][]any{
+				{"TARGETDIR", "", "SourceDir"},
+				{"ProgramFilesFolder", "TARGETDIR", "."},
+				{"INSTALLDIR", "ProgramFilesFolder", "TOOL|Tool App"},
+				{"BINDIR", "INSTALLDIR", "bin"},
+			}},
+			{name: "Component", columns: []string{"Component", "Directory_"}, rows: [][]any{
+				{"Binaries", "BINDIR"},
+				{"Docs", "INSTALLDIR"},
+			}},
+			{name: "File", columns: []string{"File", "Component_", "FileName"}, rows: [][]any{
+				{"tool", "Binaries", "tool.exe"},
+				{"readme", "Docs", "README~1.TXT|README.txt"},
+			}},
+			{name: "Media", columns: []string{"DiskId", "LastSequence", "Cabinet"}, rows: [][]any{
+				{1, 2, "#tool.cab"},
+			}},
+		}
+
+		It("reads the files of embedded cabinets", func() {
+			cab := makeCab([]cabTestFile{
+				{name: "tool", data: []byte("MZ tool")},
+				{name: "readme", data: []byte("readme")},
+			}, cabMSZIP)
+
+			ar, err := NewMsiArchive(makeMsi(tables, map[string][]byte{"tool.cab": cab}), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(readArchive(ar)).To(Equal(map[string]string{
+				"Tool App/README.txt":   "readme",
+				"Tool App/bin/tool.exe": "MZ tool",
+			}))
+		})
+
+		It("fails without embedded cabinets", func() {
+			external := append([]msiTable{}, tables[:3]...)
+			external = append(external, msiTable{name: "Media", columns: []string{"DiskId", "Cabinet"}, rows: [][]any{
+				{1, "tool.cab"},
+			}})
+
+			_, err := NewMsiArchive(makeMsi(external, nil), nil)
+			Expect(err).To(MatchError("msi: no embedded cabinet found"))
+		})
+
+		It("fails for other files", func() {
+			_, err := NewMsiArchive([]byte("not a package"), nil)
+			Expect(err).To(MatchError("cfb: not a compound file"))
+		})
+	})
+
+	Describe("NewExeArchive", func() {
+		DescribeTable("reading NSIS installers",
+			func(solid bool) {
+				ar, err := NewExeArchive(makePE(makeNSIS(solid)), nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(readArchive(ar)).To(Equal(map[string]string{
+					"bin/tool.exe": "MZ tool",
+					"README.txt":   "readme",
+					"doc/LICENSE":  "license",
+				}))
+			},
+			Entry("with deflated blocks", false),
+			Entry("solid LZMA", true),
+		)
+
+		It("fails for a header larger than its declared size", func() {
+			nsis := makeNSIS(true)
+			binary.LittleEndian.PutUint32(nsis[20:], 8)
+
+			_, err := NewExeArchive(makePE(nsis), nil)
+			Expect(err).To(MatchError(ContainSubstring("block larger than 8 bytes")))
+		})
+
+		It("fails for a header larger than the largest header read", func() {
+			nsis := makeNSIS(true)
+			binary.LittleEndian.PutUint32(nsis[20:], 1<<31)
+
+			_, err := NewExeArchive(makePE(nsis), nil)
+			Expect(err).To(MatchError("exe: nsis: header of 2147483648 bytes larger than 16777216 bytes"))
+		})
+
+		It("reads self-extracting zip archives", func() {
+			buf := new(bytes.Buffer)
+			w := zip.NewWriter(buf)
+			f, err := w.Create("tool.exe")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.Write([]byte("MZ tool"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+
+			ar, err := NewExeArchive(makePE(buf.Bytes()), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(readArchive(ar)).To(Equal(map[string]string{"tool.exe": "MZ tool"}))
+		})
+
+		It("reads self-extracting 7z archives", func() {
+			ar, err := NewExeArchive(makePE(readFixture("tool.7z")), nil)
+			Expect(err).NotTo(HaveOccurred())
+			expectFixtureFiles(ar)
+		})
+
+		It("reads self-extracting cabinets", func() {
+			ar, err := NewExeArchive(makePE(makeCab(cabFiles, cabMSZIP)), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(readArchive(ar)).To(HaveKey("bin/tool.exe"))
+		})
+
+		It("fails for Inno Setup installers", func() {
+			_, err := NewExeArchive(makePE([]byte("Inno Setup Setup Data (6.2.0) (u)")), nil)
+			Expect(err).To(MatchError("exe: Inno Setup installers are not supported"))
+		})
+
+		It("ignores magic bytes in the executable itself", func() {
+			exe := makePE(nil)
+			copy(exe[600:], "Inno Setup Setup Data (")
+			copy(exe[700:], "7z\xbc\xaf\x27\x1c")
+			_, err := NewExeArchive(exe, nil)
+			Expect(err).To(MatchError("exe: no embedded archive found"))
+		})
+
+		It("fails for other files", func() {
+			_, err := NewExeArchive([]byte("MZ not an executable"), nil)
+			Expect(err).To(MatchError("exe: not a Windows executable"))
+		})
+	})
+
+	Describe("DetectFormat", func() {
+		It("detects Windows archives", func() {
+			Expect(DetectFormat(makeCab(cabFiles, cabStored))).To(Equal(".cab"))
+			Expect(DetectFormat(makeMsi(nil, nil))).To(Equal(".msi"))
+			Expect(DetectFormat(makePE(makeNSIS(false)))).To(Equal(".exe"))
+			Expect(DetectFormat(makePE(readFixture("tool.7z")))).To(Equal(".exe"))
+		})
+
+		It("returns nothing for plain executables", func() {
+			Expect(DetectFormat(makePE(nil))).To(BeEmpty())
+		})
+	})
+})
@@ -1,12 +1,25 @@
 package assets
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 type Asset struct {
-	Name        string    `json:"name"`
-	DownloadURL string    `json:"download_url"`
-	ReleaseDate time.Time `json:"release_date"`
-	Filters     []string  `json:"filters"`
+	Name            string    `json:"name"`
+	DownloadURL     string    `json:"download_url"`
+	APIURL          string    `json:"api_url"`
+	Size            int64     `json:"size"` // in bytes, 0 if unknown
+	ContentType     string    `json:"content_type"`
+	DownloadCount   int64     `json:"download_count"`
+	ReleaseTag      string    `json:"release_tag"`
+	Prerelease      bool      `json:"prerelease"`
+	ReleaseNotesURL string    `json:"release_notes_url"`
+	Checksum        string    `json:"checksum"`     // hex encoded sha256, if published alongside the asset
+	ChecksumURL     string    `json:"checksum_url"` // file holding the sha256 of the asset, alone or in a sha256sum style list
+	ReleaseDate     time.Time `json:"release_date"`
+	Filters         []string  `json:"filters"`
 }
 
 type AssetWrapper struct {
@@ -20,3 +33,44 @@ func NewAssetWrapper(assets []Asset) *AssetWrapper {
 		Asset:  nil,
 	}
 }
+
+// FormatSize returns a human readable representation of a size in bytes,
+// using 1024-based units.
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// Summary describes the known metadata of the asset, such as its size and
+// release, e.g. "12.3 MiB, v1.2.0, 1500 downloads". Unknown values are left out.
+func (a Asset) Summary() string {
+	parts := []string{}
+
+	if a.Size > 0 {
+		parts = append(parts, FormatSize(a.Size))
+	}
+
+	if a.ReleaseTag != "" {
+		tag := a.ReleaseTag
+		if a.Prerelease {
+			tag += " (pre-release)"
+		}
+		parts = append(parts, tag)
+	}
+
+	if a.DownloadCount > 0 {
+		parts = append(parts, fmt.Sprintf("%d downloads", a.DownloadCount))
+	}
+
+	return strings.Join(parts, ", ")
+}
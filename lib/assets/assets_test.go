@@ -13,4 +13,17 @@ var _ = Describe("lib/assets > Asset", func() {
 		Expect(aw.Assets[0].Name).To(Equal("one"))
 		Expect(aw.Assets[1].Name).To(Equal("two"))
 	})
+
+	It("should format sizes using 1024-based units", func() {
+		Expect(FormatSize(512)).To(Equal("512 B"))
+		Expect(FormatSize(1536)).To(Equal("1.5 KiB"))
+		Expect(FormatSize(12*1024*1024 + 300*1024)).To(Equal("12.3 MiB"))
+		Expect(FormatSize(3 * 1024 * 1024 * 1024)).To(Equal("3.0 GiB"))
+	})
+
+	It("should summarize the known metadata", func() {
+		Expect(Asset{Name: "one"}.Summary()).To(Equal(""))
+		Expect(Asset{Size: 2048, ReleaseTag: "v1.2.0", DownloadCount: 15}.Summary()).To(Equal("2.0 KiB, v1.2.0, 15 downloads"))
+		Expect(Asset{ReleaseTag: "v2.0.0-rc1", Prerelease: true}.Summary()).To(Equal("v2.0.0-rc1 (pre-release)"))
+	})
 })
@@ -0,0 +1,98 @@
+package credentials
+
+import (
+	"net/http"
+	"strings"
+)
+
+type Kind string
+
+const (
+	KindBearer Kind = "bearer"
+	KindBasic  Kind = "basic"
+	KindHeader Kind = "header"
+)
+
+// A Credential holds the authentication details for a single host. Host may
+// be an exact host name or a wildcard such as "*.example.com".
+type Credential struct {
+	Host     string
+	Kind     Kind
+	Token    string
+	Username string
+	Password string
+	Header   string // header name when Kind is KindHeader
+	Source   string // where the credential was found, for verbose output
+}
+
+// NewBearerCredential returns a credential that sends "Authorization: Bearer <token>".
+func NewBearerCredential(host string, token string) *Credential {
+	return &Credential{Host: host, Kind: KindBearer, Token: token}
+}
+
+// NewBasicCredential returns a credential that uses HTTP basic authentication.
+func NewBasicCredential(host string, username string, password string) *Credential {
+	return &Credential{Host: host, Kind: KindBasic, Username: username, Password: password}
+}
+
+// NewHeaderCredential returns a credential that sends the token in a custom header.
+func NewHeaderCredential(host string, header string, token string) *Credential {
+	return &Credential{Host: host, Kind: KindHeader, Header: header, Token: token}
+}
+
+// Matches returns true if the credential applies to the given host. Ports are
+// ignored unless the credential host includes one.
+func (c *Credential) Matches(host string) bool {
+	host = strings.ToLower(host)
+	pattern := strings.ToLower(c.Host)
+
+	if !strings.Contains(pattern, ":") {
+		host = stripPort(host)
+	}
+
+	if suffix, found := strings.CutPrefix(pattern, "*."); found {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+
+	return host == pattern
+}
+
+// IsValid returns true if the credential carries enough data to be applied.
+func (c *Credential) IsValid() bool {
+	switch c.Kind {
+	case KindBasic:
+		return c.Username != ""
+	case KindHeader:
+		return c.Header != "" && c.Token != ""
+	default:
+		return c.Token != ""
+	}
+}
+
+// Apply sets the authentication headers for this credential on the request.
+func (c *Credential) Apply(req *http.Request) *http.Request {
+	switch c.Kind {
+	case KindBasic:
+		req.SetBasicAuth(c.Username, c.Password)
+	case KindHeader:
+		req.Header.Set(c.Header, c.Token)
+	default:
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	return req
+}
+
+func stripPort(host string) string {
+	if strings.HasPrefix(host, "[") {
+		if idx := strings.Index(host, "]"); idx >= 0 {
+			return host[:idx+1]
+		}
+	}
+
+	if idx := strings.LastIndex(host, ":"); idx >= 0 && strings.Count(host, ":") == 1 {
+		return host[:idx]
+	}
+
+	return host
+}
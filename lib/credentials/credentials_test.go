@@ -0,0 +1,73 @@
+package credentials_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/permafrost-dev/zeget/lib/credentials"
+)
+
+var _ = Describe("Credential", func() {
+	Describe("Matches", func() {
+		It("should match an exact host, ignoring case and port", func() {
+			c := NewBearerCredential("Artifactory.corp", "abc")
+
+			Expect(c.Matches("artifactory.corp")).To(BeTrue())
+			Expect(c.Matches("artifactory.corp:8443")).To(BeTrue())
+			Expect(c.Matches("other.corp")).To(BeFalse())
+		})
+
+		It("should match wildcard hosts", func() {
+			c := NewBearerCredential("*.corp", "abc")
+
+			Expect(c.Matches("artifactory.corp")).To(BeTrue())
+			Expect(c.Matches("corp")).To(BeFalse())
+		})
+
+		It("should require the port when the credential specifies one", func() {
+			c := NewBearerCredential("artifactory.corp:8443", "abc")
+
+			Expect(c.Matches("artifactory.corp:8443")).To(BeTrue())
+			Expect(c.Matches("artifactory.corp")).To(BeFalse())
+		})
+	})
+
+	Describe("Apply", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			req, _ = http.NewRequest("GET", "https://artifactory.corp/file", nil)
+		})
+
+		It("should set a bearer token", func() {
+			NewBearerCredential("artifactory.corp", "abc").Apply(req)
+			Expect(req.Header.Get("Authorization")).To(Equal("Bearer abc"))
+		})
+
+		It("should set basic auth", func() {
+			NewBasicCredential("artifactory.corp", "user", "pass").Apply(req)
+
+			user, pass, ok := req.BasicAuth()
+			Expect(ok).To(BeTrue())
+			Expect(user).To(Equal("user"))
+			Expect(pass).To(Equal("pass"))
+		})
+
+		It("should set a custom header", func() {
+			NewHeaderCredential("artifactory.corp", "X-JFrog-Art-Api", "abc").Apply(req)
+			Expect(req.Header.Get("X-JFrog-Art-Api")).To(Equal("abc"))
+			Expect(req.Header.Get("Authorization")).To(BeEmpty())
+		})
+	})
+
+	Describe("IsValid", func() {
+		It("should validate required values by kind", func() {
+			Expect(NewBearerCredential("h", "").IsValid()).To(BeFalse())
+			Expect(NewBasicCredential("h", "", "p").IsValid()).To(BeFalse())
+			Expect(NewHeaderCredential("h", "", "t").IsValid()).To(BeFalse())
+			Expect(NewHeaderCredential("h", "X-Token", "t").IsValid()).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,174 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrTokenNotFound = errors.New("token not found")
+
+// A TokenSource is a single location a GitHub token may be read from.
+type TokenSource struct {
+	Name    string
+	Resolve func() (string, error)
+}
+
+// TokenReporter is called for every source consulted while resolving a token.
+type TokenReporter func(source string, found bool, err error)
+
+// ResolveToken returns the first non-empty token found in 'sources', along
+// with the name of the source it came from.
+func ResolveToken(sources []TokenSource, report TokenReporter) (string, string, error) {
+	for _, source := range sources {
+		token, err := source.Resolve()
+		token = strings.TrimSpace(token)
+
+		if report != nil {
+			report(source.Name, err == nil && token != "", err)
+		}
+
+		if err == nil && token != "" {
+			return token, source.Name, nil
+		}
+	}
+
+	return "", "", ErrTokenNotFound
+}
+
+// GhConfigDir returns the configuration directory used by the GitHub CLI.
+func GhConfigDir() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh")
+	}
+
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("AppData"); dir != "" {
+			return filepath.Join(dir, "GitHub CLI")
+		}
+	}
+
+	homePath, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(homePath, ".config", "gh")
+}
+
+type ghHost struct {
+	OauthToken string `yaml:"oauth_token"`
+	User       string `yaml:"user"`
+	Users      map[string]struct {
+		OauthToken string `yaml:"oauth_token"`
+	} `yaml:"users"`
+}
+
+// ParseGhHosts returns the oauth token for 'host' from the contents of the
+// GitHub CLI hosts.yml file. Tokens stored in the system keyring are not
+// visible in hosts.yml; use `token_command = "gh auth token"` for those.
+func ParseGhHosts(data []byte, host string) (string, error) {
+	hosts := map[string]ghHost{}
+
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return "", fmt.Errorf("parse gh hosts.yml: %w", err)
+	}
+
+	entry, found := hosts[host]
+	if !found {
+		return "", nil
+	}
+
+	if entry.OauthToken != "" {
+		return entry.OauthToken, nil
+	}
+
+	if user, found := entry.Users[entry.User]; found {
+		return user.OauthToken, nil
+	}
+
+	return "", nil
+}
+
+// GhHostsToken reads the token for 'host' from the GitHub CLI hosts.yml file.
+// A missing file is not an error.
+func GhHostsToken(host string) (string, error) {
+	dir := GhConfigDir()
+	if dir == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "hosts.yml"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return ParseGhHosts(data, host)
+}
+
+// GitCredentialToken asks git's configured credential helpers for the
+// password stored for 'host', without ever prompting the user.
+func GitCredentialToken(runner HelperRunner, host string) (string, error) {
+	if runner == nil {
+		return "", nil
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", nil
+	}
+
+	out, err := runner("git credential fill", HelperRequest(host))
+	if err != nil {
+		return "", err
+	}
+
+	if c := ParseHelperResponse(host, out); c != nil {
+		return c.Password + c.Token, nil
+	}
+
+	return "", nil
+}
+
+// RunGitCredentialFill runs `git credential fill` with terminal prompts disabled.
+func RunGitCredentialFill(command string, stdin string) ([]byte, error) {
+	return runHelper(command, stdin, []string{"GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never"})
+}
+
+// RunTokenCommand runs 'command' through the system shell and returns its
+// trimmed output, e.g. `gh auth token` or `op read op://vault/github/token`.
+func RunTokenCommand(command string) (string, error) {
+	if strings.TrimSpace(command) == "" {
+		return "", nil
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("token command: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
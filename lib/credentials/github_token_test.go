@@ -0,0 +1,110 @@
+package credentials_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/permafrost-dev/zeget/lib/credentials"
+)
+
+var _ = Describe("GitHub token resolution", func() {
+	source := func(name string, token string, err error) TokenSource {
+		return TokenSource{Name: name, Resolve: func() (string, error) { return token, err }}
+	}
+
+	Describe("ResolveToken", func() {
+		It("should return the first token found and report each source", func() {
+			reported := []string{}
+
+			token, from, err := ResolveToken([]TokenSource{
+				source("empty", "", nil),
+				source("broken", "", errors.New("boom")),
+				source("env", " abc\n", nil),
+				source("never", "def", nil),
+			}, func(name string, found bool, err error) {
+				reported = append(reported, name)
+			})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token).To(Equal("abc"))
+			Expect(from).To(Equal("env"))
+			Expect(reported).To(Equal([]string{"empty", "broken", "env"}))
+		})
+
+		It("should return an error when no source has a token", func() {
+			_, _, err := ResolveToken([]TokenSource{source("empty", "", nil)}, nil)
+			Expect(err).To(MatchError(ErrTokenNotFound))
+		})
+	})
+
+	Describe("ParseGhHosts", func() {
+		It("should read a plain oauth token", func() {
+			token, err := ParseGhHosts([]byte("github.com:\n    user: octocat\n    oauth_token: gho_abc\n    git_protocol: https\n"), "github.com")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token).To(Equal("gho_abc"))
+		})
+
+		It("should read the token of the active user in multi-account configs", func() {
+			token, err := ParseGhHosts([]byte(`github.com:
+    git_protocol: https
+    users:
+        octocat:
+            oauth_token: gho_user
+        other:
+            oauth_token: gho_other
+    user: octocat
+`), "github.com")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token).To(Equal("gho_user"))
+		})
+
+		It("should return nothing for unknown hosts", func() {
+			token, err := ParseGhHosts([]byte("github.com:\n    oauth_token: gho_abc\n"), "ghe.corp")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token).To(BeEmpty())
+		})
+
+		It("should return an error for invalid yaml", func() {
+			_, err := ParseGhHosts([]byte("github.com: [\n"), "github.com")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("GitCredentialToken", func() {
+		It("should return the password from the helper", func() {
+			token, err := GitCredentialToken(func(command string, stdin string) ([]byte, error) {
+				Expect(stdin).To(ContainSubstring("host=github.com"))
+				return []byte("username=x-access-token\npassword=ghp_abc\n"), nil
+			}, "github.com")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token).To(Equal("ghp_abc"))
+		})
+	})
+
+	Describe("RunTokenCommand", func() {
+		It("should return the trimmed command output", func() {
+			token, err := RunTokenCommand("echo ghp_cmd")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token).To(Equal("ghp_cmd"))
+		})
+
+		It("should do nothing for an empty command", func() {
+			token, err := RunTokenCommand("")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token).To(BeEmpty())
+		})
+
+		It("should return an error when the command fails", func() {
+			_, err := RunTokenCommand("exit 3")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
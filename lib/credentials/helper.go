@@ -0,0 +1,80 @@
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// HelperRunner executes a credential helper command with the given stdin and
+// returns its stdout.
+type HelperRunner func(command string, stdin string) ([]byte, error)
+
+// RunHelperCommand runs a credential helper command. The command is split on
+// whitespace, so "git credential fill" and "my-helper get" are both valid.
+func RunHelperCommand(command string, stdin string) ([]byte, error) {
+	return runHelper(command, stdin, nil)
+}
+
+func runHelper(command string, stdin string, env []string) ([]byte, error) {
+	parts := strings.Fields(command)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("empty credential helper command")
+	}
+
+	cmd := exec.Command(parts[0], parts[1:]...)
+	cmd.Stdin = strings.NewReader(stdin)
+
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential helper %s: %w: %s", parts[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
+
+// HelperRequest returns the stdin sent to a credential helper, using the git
+// credential helper protocol.
+func HelperRequest(host string) string {
+	return fmt.Sprintf("protocol=https\nhost=%s\n\n", host)
+}
+
+// ParseHelperResponse parses the "key=value" output of a git style credential
+// helper. A password without a username is treated as a bearer token.
+func ParseHelperResponse(host string, output []byte) *Credential {
+	values := map[string]string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if found {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	if values["password"] == "" {
+		return nil
+	}
+
+	if values["username"] == "" {
+		return &Credential{Host: host, Kind: KindBearer, Token: values["password"], Source: "credential helper"}
+	}
+
+	return &Credential{
+		Host:     host,
+		Kind:     KindBasic,
+		Username: values["username"],
+		Password: values["password"],
+		Source:   "credential helper",
+	}
+}
@@ -0,0 +1,40 @@
+package credentials_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/permafrost-dev/zeget/lib/credentials"
+)
+
+var _ = Describe("Helper", func() {
+	It("should build a git credential request", func() {
+		Expect(HelperRequest("example.com")).To(Equal("protocol=https\nhost=example.com\n\n"))
+	})
+
+	It("should parse a username and password", func() {
+		c := ParseHelperResponse("example.com", []byte("protocol=https\nhost=example.com\nusername=bob\npassword=secret\n"))
+
+		Expect(c).NotTo(BeNil())
+		Expect(c.Kind).To(Equal(KindBasic))
+		Expect(c.Username).To(Equal("bob"))
+		Expect(c.Password).To(Equal("secret"))
+	})
+
+	It("should treat a lone password as a bearer token", func() {
+		c := ParseHelperResponse("example.com", []byte("password=tok\n"))
+
+		Expect(c).NotTo(BeNil())
+		Expect(c.Kind).To(Equal(KindBearer))
+		Expect(c.Token).To(Equal("tok"))
+	})
+
+	It("should return nil without a password", func() {
+		Expect(ParseHelperResponse("example.com", []byte("username=bob\n"))).To(BeNil())
+	})
+
+	It("should return an error for an empty command", func() {
+		_, err := RunHelperCommand("  ", "")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,108 @@
+package credentials
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NetrcFilename returns the location of the user's netrc file, honouring the
+// NETRC environment variable.
+func NetrcFilename() string {
+	if fn := os.Getenv("NETRC"); fn != "" {
+		return fn
+	}
+
+	homePath, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	if filepath.Separator == '\\' {
+		return filepath.Join(homePath, "_netrc")
+	}
+
+	return filepath.Join(homePath, ".netrc")
+}
+
+// ParseNetrc parses the contents of a netrc file into basic auth credentials.
+// Only the "machine", "login" and "password" tokens are used; "default" entries
+// and macro definitions are ignored.
+func ParseNetrc(data string) []*Credential {
+	result := []*Credential{}
+
+	var current *Credential
+	var inMacro bool
+
+	flush := func() {
+		if current != nil && current.Host != "" && current.Username != "" {
+			result = append(result, current)
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// a macro definition ends at the first empty line
+		if inMacro {
+			if strings.TrimSpace(line) == "" {
+				inMacro = false
+			}
+			continue
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			value := ""
+			if i+1 < len(fields) {
+				value = fields[i+1]
+			}
+
+			switch fields[i] {
+			case "machine":
+				flush()
+				current = &Credential{Host: value, Kind: KindBasic, Source: "netrc"}
+				i++
+			case "default":
+				flush()
+			case "login":
+				if current != nil {
+					current.Username = value
+				}
+				i++
+			case "password":
+				if current != nil {
+					current.Password = value
+				}
+				i++
+			case "account":
+				i++
+			case "macdef":
+				flush()
+				inMacro = true
+				i = len(fields)
+			}
+		}
+	}
+
+	flush()
+
+	return result
+}
+
+// LoadNetrc reads and parses the netrc file at the given path.
+func LoadNetrc(filename string) ([]*Credential, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseNetrc(string(data)), nil
+}
@@ -0,0 +1,41 @@
+package credentials_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/permafrost-dev/zeget/lib/credentials"
+)
+
+var _ = Describe("Netrc", func() {
+	It("should parse machines spread over multiple lines", func() {
+		creds := ParseNetrc(`
+# comment
+machine artifactory.corp
+  login builder
+  password s3cret
+
+machine example.com login alice password wonderland
+`)
+
+		Expect(creds).To(HaveLen(2))
+		Expect(creds[0].Host).To(Equal("artifactory.corp"))
+		Expect(creds[0].Username).To(Equal("builder"))
+		Expect(creds[0].Password).To(Equal("s3cret"))
+		Expect(creds[0].Kind).To(Equal(KindBasic))
+		Expect(creds[1].Host).To(Equal("example.com"))
+		Expect(creds[1].Password).To(Equal("wonderland"))
+	})
+
+	It("should skip default entries and macros", func() {
+		creds := ParseNetrc(`machine a.com login a password b
+macdef init
+machine fake.com login x password y
+
+default login anonymous password none
+`)
+
+		Expect(creds).To(HaveLen(1))
+		Expect(creds[0].Host).To(Equal("a.com"))
+	})
+})
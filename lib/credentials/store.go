@@ -0,0 +1,159 @@
+package credentials
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// A Store resolves credentials for a host. Sources are consulted in order:
+// explicitly configured credentials, environment variables, the netrc file and
+// finally the credential helper command, if any.
+type Store struct {
+	Helper      string
+	RunHelper   HelperRunner
+	Getenv      func(key string) string
+	credentials []*Credential
+	netrc       []*Credential
+	helperCache map[string]*Credential
+	helperMutex sync.Mutex
+}
+
+func NewStore() *Store {
+	return &Store{
+		RunHelper:   RunHelperCommand,
+		Getenv:      os.Getenv,
+		credentials: []*Credential{},
+		netrc:       []*Credential{},
+		helperCache: map[string]*Credential{},
+	}
+}
+
+// Add registers an explicitly configured credential.
+func (s *Store) Add(c *Credential) *Store {
+	if c.Source == "" {
+		c.Source = "config"
+	}
+
+	s.credentials = append(s.credentials, c)
+	return s
+}
+
+// Override registers a credential that takes precedence over all others for
+// its host, e.g. a short-lived registry token obtained at runtime.
+func (s *Store) Override(c *Credential) *Store {
+	if c.Source == "" {
+		c.Source = "runtime"
+	}
+
+	s.credentials = append([]*Credential{c}, s.credentials...)
+	return s
+}
+
+// SetHelper sets the credential helper command.
+func (s *Store) SetHelper(command string) *Store {
+	s.Helper = strings.TrimSpace(command)
+	return s
+}
+
+// LoadNetrc reads credentials from the given netrc file. A missing file is
+// not an error.
+func (s *Store) LoadNetrc(filename string) error {
+	if filename == "" {
+		return nil
+	}
+
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return nil
+	}
+
+	creds, err := LoadNetrc(filename)
+	if err != nil {
+		return err
+	}
+
+	s.netrc = creds
+	return nil
+}
+
+// Lookup returns the credential for the given host, or nil if none is found.
+func (s *Store) Lookup(host string) *Credential {
+	if s == nil || host == "" {
+		return nil
+	}
+
+	for _, c := range s.credentials {
+		if c.Matches(host) && c.IsValid() {
+			return c
+		}
+	}
+
+	if c := s.lookupEnvironment(host); c != nil {
+		return c
+	}
+
+	for _, c := range s.netrc {
+		if c.Matches(host) && c.IsValid() {
+			return c
+		}
+	}
+
+	return s.lookupHelper(host)
+}
+
+var envNamePattern = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// EnvironmentKey returns the suffix used for per-host environment variables,
+// e.g. "artifactory.corp:8443" becomes "ARTIFACTORY_CORP_8443".
+func EnvironmentKey(host string) string {
+	return strings.Trim(envNamePattern.ReplaceAllString(strings.ToUpper(host), "_"), "_")
+}
+
+// lookupEnvironment checks ZEGET_TOKEN_<HOST>, or ZEGET_USERNAME_<HOST> and
+// ZEGET_PASSWORD_<HOST>.
+func (s *Store) lookupEnvironment(host string) *Credential {
+	if s.Getenv == nil {
+		return nil
+	}
+
+	key := EnvironmentKey(stripPort(host))
+
+	if token := s.Getenv("ZEGET_TOKEN_" + key); token != "" {
+		return &Credential{Host: host, Kind: KindBearer, Token: token, Source: "ZEGET_TOKEN_" + key}
+	}
+
+	if username := s.Getenv("ZEGET_USERNAME_" + key); username != "" {
+		return &Credential{
+			Host:     host,
+			Kind:     KindBasic,
+			Username: username,
+			Password: s.Getenv("ZEGET_PASSWORD_" + key),
+			Source:   "ZEGET_USERNAME_" + key,
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) lookupHelper(host string) *Credential {
+	if s.Helper == "" || s.RunHelper == nil {
+		return nil
+	}
+
+	s.helperMutex.Lock()
+	defer s.helperMutex.Unlock()
+
+	if c, found := s.helperCache[host]; found {
+		return c
+	}
+
+	var result *Credential
+	if out, err := s.RunHelper(s.Helper, HelperRequest(host)); err == nil {
+		result = ParseHelperResponse(host, out)
+	}
+
+	s.helperCache[host] = result
+
+	return result
+}
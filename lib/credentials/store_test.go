@@ -0,0 +1,88 @@
+package credentials_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/permafrost-dev/zeget/lib/credentials"
+)
+
+var _ = Describe("Store", func() {
+	var (
+		store *Store
+		env   map[string]string
+		calls int
+	)
+
+	BeforeEach(func() {
+		env = map[string]string{}
+		calls = 0
+
+		store = NewStore()
+		store.Getenv = func(key string) string { return env[key] }
+		store.RunHelper = func(command string, stdin string) ([]byte, error) {
+			calls++
+			return []byte("username=helper\npassword=from-helper\n"), nil
+		}
+	})
+
+	It("should return nil when nothing matches", func() {
+		Expect(store.Lookup("example.com")).To(BeNil())
+	})
+
+	It("should prefer configured credentials", func() {
+		env["ZEGET_TOKEN_EXAMPLE_COM"] = "from-env"
+		store.Add(NewBearerCredential("example.com", "from-config"))
+
+		Expect(store.Lookup("example.com").Token).To(Equal("from-config"))
+	})
+
+	It("should prefer overriding credentials", func() {
+		store.Add(NewBasicCredential("ghcr.io", "user", "pass"))
+		store.Override(NewBearerCredential("ghcr.io", "registry-token"))
+
+		Expect(store.Lookup("ghcr.io").Token).To(Equal("registry-token"))
+		Expect(store.Lookup("ghcr.io").Source).To(Equal("runtime"))
+	})
+
+	It("should read tokens and basic auth from the environment", func() {
+		env["ZEGET_TOKEN_EXAMPLE_COM"] = "from-env"
+		env["ZEGET_USERNAME_ARTIFACTORY_CORP"] = "user"
+		env["ZEGET_PASSWORD_ARTIFACTORY_CORP"] = "pass"
+
+		Expect(store.Lookup("example.com").Token).To(Equal("from-env"))
+		Expect(store.Lookup("artifactory.corp:8443").Username).To(Equal("user"))
+		Expect(store.Lookup("artifactory.corp").Password).To(Equal("pass"))
+	})
+
+	It("should read credentials from a netrc file", func() {
+		fn := filepath.Join(GinkgoT().TempDir(), ".netrc")
+		Expect(os.WriteFile(fn, []byte("machine example.com login bob password builder\n"), 0600)).To(Succeed())
+		Expect(store.LoadNetrc(fn)).To(Succeed())
+
+		c := store.Lookup("example.com")
+		Expect(c).NotTo(BeNil())
+		Expect(c.Username).To(Equal("bob"))
+		Expect(c.Source).To(Equal("netrc"))
+	})
+
+	It("should ignore a missing netrc file", func() {
+		Expect(store.LoadNetrc(filepath.Join(GinkgoT().TempDir(), "missing"))).To(Succeed())
+	})
+
+	It("should call the credential helper once per host", func() {
+		store.SetHelper("my-helper get")
+
+		Expect(store.Lookup("example.com").Password).To(Equal("from-helper"))
+		Expect(store.Lookup("example.com").Password).To(Equal("from-helper"))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("should build environment keys from host names", func() {
+		Expect(EnvironmentKey("artifactory.corp:8443")).To(Equal("ARTIFACTORY_CORP_8443"))
+		Expect(EnvironmentKey("dl-cdn.example.com")).To(Equal("DL_CDN_EXAMPLE_COM"))
+	})
+})
@@ -86,6 +86,28 @@ var _ = Describe("Cache", func() {
 			_, exists := newCache.Get("test")
 			Expect(exists).To(BeTrue())
 		})
+
+		It("should persist the release metadata of the assets", func() {
+			asset := assets.Asset{
+				Name:            "tool_linux_amd64.tar.gz",
+				DownloadURL:     "https://example.com/tool_linux_amd64.tar.gz",
+				Size:            2048,
+				ContentType:     "application/gzip",
+				DownloadCount:   15,
+				ReleaseTag:      "v1.2.0",
+				Prerelease:      true,
+				ReleaseNotesURL: "https://github.com/owner/tool/releases/tag/v1.2.0",
+			}
+			testEntry.Assets = []assets.Asset{asset}
+			cache.Set("test", testEntry)
+			cache.SaveToFile()
+
+			newCache := NewCache(filename)
+			Expect(newCache.LoadFromFile()).To(Succeed())
+			entry, exists := newCache.Get("test")
+			Expect(exists).To(BeTrue())
+			Expect(entry.Assets).To(Equal([]assets.Asset{asset}))
+		})
 	})
 
 	Describe("PurgeExpired", func() {
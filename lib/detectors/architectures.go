@@ -1,19 +1,44 @@
 package detectors
 
-import "regexp"
+import (
+	"maps"
+	"regexp"
+	"strings"
+)
 
 // An Arch represents a system architecture, such as amd64, i386, arm or others.
 type Arch struct {
 	name  string
 	regex *regexp.Regexp
+	anti  *regexp.Regexp
+
+	// the GOARCH names of architectures whose builds also run on this one,
+	// best first
+	compatible []string
 }
 
 // Match returns true if this architecture is likely supported by the given
 // archive name.
 func (a *Arch) Match(s string) bool {
+	if a.anti != nil && a.anti.MatchString(s) {
+		return false
+	}
+
 	return a.regex.MatchString(s)
 }
 
+// MatchCompatible returns the first compatible architecture that matches the
+// given archive name and its position in the list of compatible ones, or -1.
+func (a *Arch) MatchCompatible(s string) (*Arch, int) {
+	for i, name := range a.compatible {
+		if c, ok := goarchmap[name]; ok && c.Match(s) {
+			return &c, i
+		}
+	}
+
+	return nil, -1
+}
+
 var (
 	ArchAMD64 = Arch{
 		name:  "amd64",
@@ -21,11 +46,21 @@ var (
 	}
 	ArchI386 = Arch{
 		name:  "386",
-		regex: regexp.MustCompile(`(?i)(x32|amd32|x86(-|_)?32|i?386)`),
+		regex: regexp.MustCompile(`(?i)(x32|amd32|x86(-|_)?32|i?386|i[56]86|(^|[^0-9])32-?bit)`),
+	}
+	ArchArmV5 = Arch{
+		name:  "armv5",
+		regex: regexp.MustCompile(`(?i)(armv5|arm5|armel)`),
 	}
 	ArchArm = Arch{
-		name:  "arm",
-		regex: regexp.MustCompile(`(?i)(arm32|armv6|arm\b)`),
+		name:       "arm",
+		regex:      regexp.MustCompile(`(?i)(arm32|armv6|arm6\b|arm\b)`),
+		compatible: []string{"armv5"},
+	}
+	ArchArmV7 = Arch{
+		name:       "armv7",
+		regex:      regexp.MustCompile(`(?i)(armv7|arm7|armhf)`),
+		compatible: []string{"arm", "armv5"},
 	}
 	ArchArm64 = Arch{
 		name:  "arm64",
@@ -35,13 +70,76 @@ var (
 		name:  "riscv64",
 		regex: regexp.MustCompile(`(?i)(riscv64)`),
 	}
+	ArchPPC64 = Arch{
+		name:  "ppc64",
+		regex: regexp.MustCompile(`(?i)(ppc64|powerpc64)([^l]|$)`),
+	}
+	ArchPPC64LE = Arch{
+		name:  "ppc64le",
+		regex: regexp.MustCompile(`(?i)(ppc64le|ppc64el|powerpc64le)`),
+	}
+	ArchS390X = Arch{
+		name:  "s390x",
+		regex: regexp.MustCompile(`(?i)(s390x)`),
+	}
+	ArchMIPS = Arch{
+		name:  "mips",
+		regex: regexp.MustCompile(`(?i)mips([^a-z0-9]|$)`),
+	}
+	ArchMIPSLE = Arch{
+		name:  "mipsle",
+		regex: regexp.MustCompile(`(?i)(mipsle|mipsel)`),
+	}
+	ArchMIPS64 = Arch{
+		name:  "mips64",
+		regex: regexp.MustCompile(`(?i)mips64([^a-z0-9]|$)`),
+	}
+	ArchMIPS64LE = Arch{
+		name:  "mips64le",
+		regex: regexp.MustCompile(`(?i)(mips64le|mips64el)`),
+	}
+	ArchLoong64 = Arch{
+		name:  "loong64",
+		regex: regexp.MustCompile(`(?i)(loong64|loongarch64)`),
+	}
 )
 
-// a map from GOARCH values to internal architecture matchers
-var goarchmap = map[string]Arch{
-	"amd64":   ArchAMD64,
-	"386":     ArchI386,
-	"arm":     ArchArm,
-	"arm64":   ArchArm64,
-	"riscv64": ArchRiscv64,
+// the built-in architecture matchers, keyed by GOARCH values (and GOARM levels
+// of arm)
+var builtinArch = map[string]Arch{
+	"amd64":    ArchAMD64,
+	"386":      ArchI386,
+	"arm":      ArchArm,
+	"armv5":    ArchArmV5,
+	"armel":    ArchArmV5,
+	"armv6":    ArchArm,
+	"armv7":    ArchArmV7,
+	"armhf":    ArchArmV7,
+	"arm64":    ArchArm64,
+	"riscv64":  ArchRiscv64,
+	"ppc64":    ArchPPC64,
+	"ppc64le":  ArchPPC64LE,
+	"s390x":    ArchS390X,
+	"mips":     ArchMIPS,
+	"mipsle":   ArchMIPSLE,
+	"mips64":   ArchMIPS64,
+	"mips64le": ArchMIPS64LE,
+	"loong64":  ArchLoong64,
+}
+
+// a map from GOARCH values to internal architecture matchers, the built-in
+// ones extended by user-defined rules (see ApplyRules)
+var goarchmap = maps.Clone(builtinArch)
+
+// BaseArch returns the GOARCH of an architecture name that may include a level,
+// such as "armv7" or "amd64v3".
+func BaseArch(name string) string {
+	switch {
+	case strings.HasPrefix(name, "amd64v"):
+		return "amd64"
+	case name == "armv5", name == "armel", name == "armv6", name == "armv7", name == "armhf":
+		return "arm"
+	}
+
+	return name
 }
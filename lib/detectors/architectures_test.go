@@ -0,0 +1,77 @@
+package detectors_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/assets"
+	. "github.com/permafrost-dev/zeget/lib/detectors"
+)
+
+var _ = Describe("Arch", func() {
+	DescribeTable("matches asset names",
+		func(arch Arch, name string, want bool) {
+			Expect(arch.Match(name)).To(Equal(want))
+		},
+		Entry(nil, ArchI386, "tool_Linux_32-bit.tar.gz", true),
+		Entry(nil, ArchI386, "tool-i686-unknown-linux-gnu.tar.gz", true),
+		Entry(nil, ArchArm, "tool-arm-unknown-linux-gnueabihf.tar.gz", true),
+		Entry(nil, ArchArm, "tool-linux-arm64.tar.gz", false),
+		Entry(nil, ArchArm, "tool-linux-armv7.tar.gz", false),
+		Entry(nil, ArchArmV5, "tool_linux_armel.deb", true),
+		Entry(nil, ArchArmV7, "tool_linux_armhf.deb", true),
+		Entry(nil, ArchArmV7, "tool-armv7-unknown-linux-musleabihf.tar.gz", true),
+		Entry(nil, ArchPPC64, "tool-powerpc64-unknown-linux-gnu.tar.gz", true),
+		Entry(nil, ArchPPC64, "tool_linux_ppc64le.tar.gz", false),
+		Entry(nil, ArchPPC64LE, "tool_linux_ppc64le.tar.gz", true),
+		Entry(nil, ArchS390X, "tool-s390x-unknown-linux-gnu.tar.gz", true),
+		Entry(nil, ArchMIPS, "tool_linux_mips_softfloat.tar.gz", true),
+		Entry(nil, ArchMIPS, "tool_linux_mipsle.tar.gz", false),
+		Entry(nil, ArchMIPS, "tool_linux_mips64.tar.gz", false),
+		Entry(nil, ArchMIPSLE, "tool-mipsel-unknown-linux-musl.tar.gz", true),
+		Entry(nil, ArchMIPS64, "tool_linux_mips64.tar.gz", true),
+		Entry(nil, ArchMIPS64, "tool_linux_mips64le.tar.gz", false),
+		Entry(nil, ArchMIPS64LE, "tool_linux_mips64le.tar.gz", true),
+		Entry(nil, ArchLoong64, "tool-loongarch64-unknown-linux-gnu.tar.gz", true),
+	)
+
+	It("runs builds for older arm levels", func() {
+		arch, i := ArchArmV7.MatchCompatible("tool_linux_armv6.tar.gz")
+		Expect(arch).To(Equal(&ArchArm))
+		Expect(i).To(Equal(0))
+
+		arch, i = ArchArmV7.MatchCompatible("tool_linux_armel.tar.gz")
+		Expect(arch).To(Equal(&ArchArmV5))
+		Expect(i).To(Equal(1))
+
+		arch, _ = ArchArm.MatchCompatible("tool_linux_armv7.tar.gz")
+		Expect(arch).To(BeNil())
+	})
+
+	It("returns the GOARCH of arch levels", func() {
+		Expect(BaseArch("armv7")).To(Equal("arm"))
+		Expect(BaseArch("armel")).To(Equal("arm"))
+		Expect(BaseArch("amd64v3")).To(Equal("amd64"))
+		Expect(BaseArch("ppc64le")).To(Equal("ppc64le"))
+	})
+
+	Describe("NewSystemDetector", func() {
+		It("accepts x86-64 microarchitecture levels", func() {
+			detector, err := NewSystemDetector("linux", "amd64v3")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(detector.Arch).To(Equal(ArchAMD64))
+			Expect(detector.Level).To(Equal(3))
+		})
+
+		It("rejects unknown levels", func() {
+			_, err := NewSystemDetector("linux", "amd64v9")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("never selects builds for a higher cpu level", func() {
+			detector, _ := NewSystemDetector("linux", "amd64v2")
+			score := detector.Score(Asset{Name: "tool-linux-x86_64-v3.tar.gz"})
+			Expect(score.Total).To(BeNumerically("<", 0))
+			Expect(score.String()).To(ContainSubstring("requires cpu level v3"))
+		})
+	})
+})
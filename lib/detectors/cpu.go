@@ -0,0 +1,28 @@
+package detectors
+
+import (
+	"runtime"
+
+	"golang.org/x/sys/cpu"
+)
+
+// HostAMD64Level returns the x86-64 microarchitecture level (as in GOAMD64)
+// supported by the CPU, or 0 when not running on amd64.
+func HostAMD64Level() int {
+	if runtime.GOARCH != "amd64" {
+		return 0
+	}
+
+	x := cpu.X86
+
+	switch {
+	case !(x.HasCX16 && x.HasPOPCNT && x.HasSSE3 && x.HasSSSE3 && x.HasSSE41 && x.HasSSE42):
+		return 1
+	case !(x.HasAVX && x.HasAVX2 && x.HasBMI1 && x.HasBMI2 && x.HasFMA && x.HasOSXSAVE):
+		return 2
+	case !(x.HasAVX512F && x.HasAVX512BW && x.HasAVX512CD && x.HasAVX512DQ && x.HasAVX512VL):
+		return 3
+	}
+
+	return 4
+}
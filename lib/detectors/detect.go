@@ -17,6 +17,11 @@ type Detector interface {
 	Detect(assets []Asset) (DetectionResult, error)
 }
 
+// An Explainer reports how a detector rates each asset.
+type Explainer interface {
+	Explain(assets []Asset) []Score
+}
+
 // Determine the appropriate detector. If the --system is 'all', we use an
 // AllDetector, which will just return all assets. Otherwise we use the
 // --system pair provided by the user, or the runtime.GOOS/runtime.GOARCH
@@ -26,13 +31,24 @@ func DetermineCorrectDetector(opts *appflags.Flags, ignoredPatterns []string, sy
 		system, _ = NewSystemDetector(runtime.GOOS, runtime.GOARCH)
 	}
 
-	detector = system
+	libc := opts.Libc
 
 	if len(opts.System) > 2 && opts.System != "all" && strings.Contains(opts.System, "/") {
 		split := strings.Split(opts.System, "/")
-		detector, err = NewSystemDetector(split[0], split[1])
+		if system, err = NewSystemDetector(split[0], split[1]); err != nil {
+			return nil, err
+		}
+		if len(split) > 2 {
+			libc = split[2]
+		}
+	}
+
+	if system, err = forHost(system, libc); err != nil {
+		return nil, err
 	}
 
+	detector = system
+
 	if opts.System == "all" {
 		detector = &AllDetector{}
 	}
@@ -73,6 +89,38 @@ func DetermineCorrectDetector(opts *appflags.Flags, ignoredPatterns []string, sy
 	return detector, err
 }
 
+// forHost returns the detector targeting the given libc. Without a libc, the
+// libc of the host is used when the detector targets the host OS, and its CPU
+// level when it targets the host architecture.
+func forHost(system *SystemDetector, libc string) (*SystemDetector, error) {
+	if system == nil {
+		return system, nil
+	}
+
+	if libc != "" {
+		parsed, err := ParseLibc(libc)
+		if err != nil {
+			return nil, err
+		}
+
+		system = system.WithLibc(parsed)
+	}
+
+	if system.Os.Name != runtime.GOOS {
+		return system, nil
+	}
+
+	if system.Libc == LibcUnknown {
+		system = system.WithLibc(HostLibc())
+	}
+
+	if system.Level == 0 && system.Arch.name == runtime.GOARCH {
+		system = system.WithLevel(HostAMD64Level())
+	}
+
+	return system, nil
+}
+
 func GetPatternDetectors(ignoredPatterns []string, system *SystemDetector) (detector *DetectorChain, err error) {
 	detectors := make([]Detector, 0)
 
@@ -58,3 +58,12 @@ func (dc *DetectorChain) Detect(assets []Asset) (DetectionResult, error) {
 
 	return detected, fmt.Errorf("%d candidates found for asset chain", len(assets))
 }
+
+// Explain returns the scores given by the system detector, if it explains them.
+func (dc *DetectorChain) Explain(assets []Asset) []Score {
+	if e, ok := dc.System.(Explainer); ok {
+		return e.Explain(assets)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package detectors
+
+import (
+	"debug/elf"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// A Libc is the C library a Linux system uses, which decides whether
+// dynamically linked glibc builds can run on it.
+type Libc string
+
+const (
+	LibcUnknown Libc = ""
+	LibcGNU     Libc = "gnu"
+	LibcMusl    Libc = "musl"
+)
+
+// ParseLibc returns the libc for the given name: "gnu" (or "glibc") or "musl".
+func ParseLibc(name string) (Libc, error) {
+	switch strings.ToLower(name) {
+	case "gnu", "glibc":
+		return LibcGNU, nil
+	case "musl":
+		return LibcMusl, nil
+	}
+
+	return LibcUnknown, fmt.Errorf("unsupported target libc: %s", name)
+}
+
+// A LibcProbe detects the libc of a Linux system from the ELF interpreter of
+// /bin/sh, the presence of the musl dynamic loader, or the output of
+// `ldd --version`, in that order.
+type LibcProbe struct {
+	Root       string // the root of the file system, "/" if empty
+	LddVersion func() ([]byte, error)
+}
+
+func (p LibcProbe) Detect() Libc {
+	root := p.Root
+	if root == "" {
+		root = "/"
+	}
+
+	if interp := elfInterpreter(filepath.Join(root, "bin", "sh")); interp != "" {
+		switch {
+		case strings.Contains(interp, "musl"):
+			return LibcMusl
+		case strings.Contains(interp, "ld-linux"):
+			return LibcGNU
+		}
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(root, "lib", "ld-musl-*")); len(matches) > 0 {
+		return LibcMusl
+	}
+
+	if p.LddVersion == nil {
+		return LibcUnknown
+	}
+
+	// musl's ldd prints its version to stderr and exits with an error
+	out, _ := p.LddVersion()
+	version := strings.ToLower(string(out))
+
+	switch {
+	case strings.Contains(version, "musl"):
+		return LibcMusl
+	case strings.Contains(version, "glibc"), strings.Contains(version, "gnu libc"), strings.Contains(version, "gnu c library"):
+		return LibcGNU
+	}
+
+	return LibcUnknown
+}
+
+// elfInterpreter returns the program interpreter of the ELF file, if any.
+func elfInterpreter(name string) string {
+	f, err := elf.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+
+		data := make([]byte, prog.Filesz)
+		if _, err := prog.ReadAt(data, 0); err != nil {
+			return ""
+		}
+
+		return strings.TrimRight(string(data), "\x00")
+	}
+
+	return ""
+}
+
+var hostLibc = sync.OnceValue(func() Libc {
+	if runtime.GOOS != "linux" {
+		return LibcUnknown
+	}
+
+	return LibcProbe{
+		LddVersion: func() ([]byte, error) {
+			return exec.Command("ldd", "--version").CombinedOutput()
+		},
+	}.Detect()
+})
+
+// HostLibc returns the libc of the current system, or LibcUnknown when it is
+// not Linux or the libc cannot be determined.
+func HostLibc() Libc {
+	return hostLibc()
+}
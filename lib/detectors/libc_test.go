@@ -0,0 +1,167 @@
+package detectors_test
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/permafrost-dev/zeget/lib/appflags"
+	. "github.com/permafrost-dev/zeget/lib/assets"
+	. "github.com/permafrost-dev/zeget/lib/detectors"
+)
+
+// writeELF writes a minimal 64-bit ELF file that only has a program interpreter.
+func writeELF(name string, interp string) {
+	headerSize := uint64(unsafe.Sizeof(elf.Header64{}))
+	progSize := uint64(unsafe.Sizeof(elf.Prog64{}))
+	data := append([]byte(interp), 0)
+
+	header := elf.Header64{
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(elf.EM_X86_64),
+		Version:   uint32(elf.EV_CURRENT),
+		Phoff:     headerSize,
+		Ehsize:    uint16(headerSize),
+		Phentsize: uint16(progSize),
+		Phnum:     1,
+	}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	prog := elf.Prog64{
+		Type:   uint32(elf.PT_INTERP),
+		Flags:  uint32(elf.PF_R),
+		Off:    headerSize + progSize,
+		Filesz: uint64(len(data)),
+		Memsz:  uint64(len(data)),
+		Align:  1,
+	}
+
+	buf := &bytes.Buffer{}
+	Expect(binary.Write(buf, binary.LittleEndian, header)).To(Succeed())
+	Expect(binary.Write(buf, binary.LittleEndian, prog)).To(Succeed())
+	buf.Write(data)
+
+	Expect(os.MkdirAll(filepath.Dir(name), 0o755)).To(Succeed())
+	Expect(os.WriteFile(name, buf.Bytes(), 0o755)).To(Succeed())
+}
+
+func lddOutput(out string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		return []byte(out), nil
+	}
+}
+
+var _ = Describe("Libc", func() {
+	Describe("ParseLibc", func() {
+		It("parses the supported libc names", func() {
+			Expect(ParseLibc("gnu")).To(Equal(LibcGNU))
+			Expect(ParseLibc("glibc")).To(Equal(LibcGNU))
+			Expect(ParseLibc("MUSL")).To(Equal(LibcMusl))
+		})
+
+		It("rejects unknown libc names", func() {
+			_, err := ParseLibc("bionic")
+			Expect(err).To(MatchError("unsupported target libc: bionic"))
+		})
+	})
+
+	Describe("LibcProbe", func() {
+		var root string
+
+		BeforeEach(func() {
+			root = GinkgoT().TempDir()
+		})
+
+		It("reads the ELF interpreter of /bin/sh", func() {
+			writeELF(filepath.Join(root, "bin", "sh"), "/lib/ld-musl-x86_64.so.1")
+			Expect(LibcProbe{Root: root}.Detect()).To(Equal(LibcMusl))
+
+			writeELF(filepath.Join(root, "bin", "sh"), "/lib64/ld-linux-x86-64.so.2")
+			Expect(LibcProbe{Root: root}.Detect()).To(Equal(LibcGNU))
+		})
+
+		It("prefers the interpreter of /bin/sh over an installed musl loader", func() {
+			writeELF(filepath.Join(root, "bin", "sh"), "/lib64/ld-linux-x86-64.so.2")
+			writeELF(filepath.Join(root, "lib", "ld-musl-x86_64.so.1"), "")
+			Expect(LibcProbe{Root: root}.Detect()).To(Equal(LibcGNU))
+		})
+
+		It("looks for the musl dynamic loader", func() {
+			Expect(os.MkdirAll(filepath.Join(root, "lib"), 0o755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(root, "lib", "ld-musl-aarch64.so.1"), nil, 0o755)).To(Succeed())
+			Expect(LibcProbe{Root: root}.Detect()).To(Equal(LibcMusl))
+		})
+
+		DescribeTable("falls back to the output of ldd --version",
+			func(out string, want Libc) {
+				Expect(LibcProbe{Root: root, LddVersion: lddOutput(out)}.Detect()).To(Equal(want))
+			},
+			Entry("musl", "musl libc (x86_64)\nVersion 1.2.4\n", LibcMusl),
+			Entry("glibc", "ldd (GNU libc) 2.38\nCopyright (C) 2023 Free Software Foundation, Inc.\n", LibcGNU),
+			Entry("ubuntu", "ldd (Ubuntu GLIBC 2.35-0ubuntu3.6) 2.35\n", LibcGNU),
+			Entry("unknown", "ldd: command not found\n", LibcUnknown),
+		)
+
+		It("returns an unknown libc when nothing is found", func() {
+			failing := func() ([]byte, error) { return nil, errors.New("not found") }
+			Expect(LibcProbe{Root: root, LddVersion: failing}.Detect()).To(Equal(LibcUnknown))
+		})
+	})
+
+	Describe("SystemDetector", func() {
+		assets := []Asset{
+			{Name: "tool-x86_64-unknown-linux-gnu.tar.gz"},
+			{Name: "tool-linux-amd64.tar.gz"},
+			{Name: "tool-aarch64-unknown-linux-musl.tar.gz"},
+		}
+
+		It("avoids glibc builds on musl systems", func() {
+			detector, _ := NewSystemDetector("linux", "amd64")
+			result, err := detector.WithLibc(LibcMusl).Detect(assets)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Asset.Name).To(Equal("tool-linux-amd64.tar.gz"))
+		})
+
+		It("prefers glibc builds on glibc systems", func() {
+			detector, _ := NewSystemDetector("linux", "amd64")
+			result, err := detector.WithLibc(LibcGNU).Detect(assets)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Asset.Name).To(Equal("tool-x86_64-unknown-linux-gnu.tar.gz"))
+		})
+
+		It("still selects a glibc build when it is the only one", func() {
+			detector, _ := NewSystemDetector("linux", "arm64")
+			result, err := detector.WithLibc(LibcMusl).Detect(corpusAssets("BurntSushi/ripgrep@14.1.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Asset.Name).To(Equal("ripgrep-14.1.0-aarch64-unknown-linux-gnu.tar.gz"))
+		})
+	})
+
+	Describe("DetermineCorrectDetector", func() {
+		It("reads the libc from the system", func() {
+			detector, err := DetermineCorrectDetector(&appflags.Flags{System: "linux/amd64/musl"}, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(detector.(*SystemDetector).Libc).To(Equal(LibcMusl))
+		})
+
+		It("uses the configured libc", func() {
+			detector, err := DetermineCorrectDetector(&appflags.Flags{System: "linux/arm64", Libc: "glibc"}, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(detector.(*SystemDetector).Libc).To(Equal(LibcGNU))
+		})
+
+		It("rejects an unsupported libc", func() {
+			_, err := DetermineCorrectDetector(&appflags.Flags{System: "linux/amd64/bionic"}, nil, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
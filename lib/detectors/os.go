@@ -1,6 +1,9 @@
 package detectors
 
-import "regexp"
+import (
+	"maps"
+	"regexp"
+)
 
 // An OS represents a target operating system.
 type OS struct {
@@ -29,11 +32,11 @@ var (
 	}
 	OSWindows = OS{
 		Name:  "windows",
-		Regex: regexp.MustCompile(`(?i)([^r]win|windows)`),
+		Regex: regexp.MustCompile(`(?i)([^r]win|windows|\.exe$|\.msi$)`),
 	}
 	OSLinux = OS{
 		Name:     "linux",
-		Regex:    regexp.MustCompile(`(?i)(linux|ubuntu)`),
+		Regex:    regexp.MustCompile(`(?i)(linux|ubuntu|\.deb$|\.rpm$)`),
 		Anti:     regexp.MustCompile(`(?i)(android)`),
 		Priority: regexp.MustCompile(`\.appimage$`),
 	}
@@ -67,8 +70,8 @@ var (
 	}
 )
 
-// a map of GOOS values to internal OS matchers
-var goosmap = map[string]OS{
+// the built-in OS matchers, keyed by GOOS values
+var builtinOS = map[string]OS{
 	"darwin":  OSDarwin,
 	"windows": OSWindows,
 	"linux":   OSLinux,
@@ -80,3 +83,7 @@ var goosmap = map[string]OS{
 	"solaris": OSSolaris,
 	"plan9":   OSPlan9,
 }
+
+// a map of GOOS values to internal OS matchers, the built-in ones extended by
+// user-defined rules (see ApplyRules)
+var goosmap = maps.Clone(builtinOS)
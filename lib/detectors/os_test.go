@@ -52,6 +52,12 @@ var _ = Describe("OS", func() {
 				_, priority := osLinux.Match("app.appimage")
 				Expect(priority).To(BeTrue())
 			})
+
+			It("should match Windows installers without a system name", func() {
+				Expect(OSWindows.Match("tool-1.0-x64.msi")).To(BeTrue())
+				Expect(OSWindows.Match("tool-setup.exe")).To(BeTrue())
+				Expect(OSWindows.Match("tool-linux-amd64")).To(BeFalse())
+			})
 		})
 	})
 })
@@ -0,0 +1,137 @@
+package detectors
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A Rule extends the matching of an OS or architecture with additional
+// regular expressions, which are case-insensitive.
+type Rule struct {
+	Match    []string `toml:"match"`
+	Anti     []string `toml:"anti"`
+	Priority []string `toml:"priority"`
+}
+
+// Rules are user-defined OS and architecture rules, keyed by GOOS and GOARCH
+// names. Rules for names that are not built in define a new OS or
+// architecture and require a match regex.
+type Rules struct {
+	OS   map[string]Rule `toml:"os"`
+	Arch map[string]Rule `toml:"arch"`
+}
+
+// ApplyRules rebuilds the OS and architecture matchers from the built-in ones,
+// extended by each set of rules in order. Without rules, the built-in
+// matchers are restored. Nothing is changed if a rule is invalid.
+func ApplyRules(rules ...Rules) error {
+	oses := maps.Clone(builtinOS)
+	arches := maps.Clone(builtinArch)
+
+	for _, r := range rules {
+		for _, name := range sortedKeys(r.OS) {
+			if err := applyOSRule(oses, name, r.OS[name]); err != nil {
+				return fmt.Errorf("detectors.os.%s: %w", name, err)
+			}
+		}
+
+		for _, name := range sortedKeys(r.Arch) {
+			if err := applyArchRule(arches, name, r.Arch[name]); err != nil {
+				return fmt.Errorf("detectors.arch.%s: %w", name, err)
+			}
+		}
+	}
+
+	goosmap, goarchmap = oses, arches
+
+	return nil
+}
+
+func applyOSRule(oses map[string]OS, name string, rule Rule) (err error) {
+	os, ok := oses[name]
+	if !ok {
+		if len(rule.Match) == 0 {
+			return fmt.Errorf("unknown OS, a match regex is required")
+		}
+		os = OS{Name: name}
+	}
+
+	if os.Regex, err = extendRegex(os.Regex, "match", rule.Match); err != nil {
+		return err
+	}
+	if os.Anti, err = extendRegex(os.Anti, "anti", rule.Anti); err != nil {
+		return err
+	}
+	if os.Priority, err = extendRegex(os.Priority, "priority", rule.Priority); err != nil {
+		return err
+	}
+
+	oses[name] = os
+
+	return nil
+}
+
+func applyArchRule(arches map[string]Arch, name string, rule Rule) (err error) {
+	if len(rule.Priority) > 0 {
+		return fmt.Errorf("priority regexes are only supported for operating systems")
+	}
+
+	if _, ok := arches[name]; !ok {
+		if len(rule.Match) == 0 {
+			return fmt.Errorf("unknown architecture, a match regex is required")
+		}
+		arches[name] = Arch{name: name}
+	}
+
+	// aliases such as "armhf" share the rules of the architecture they name
+	for key, arch := range arches {
+		if key != name && arch.name != name {
+			continue
+		}
+
+		if arch.regex, err = extendRegex(arch.regex, "match", rule.Match); err != nil {
+			return err
+		}
+		if arch.anti, err = extendRegex(arch.anti, "anti", rule.Anti); err != nil {
+			return err
+		}
+
+		arches[key] = arch
+	}
+
+	return nil
+}
+
+// extendRegex returns a regex matching either re or any of the patterns.
+func extendRegex(re *regexp.Regexp, kind string, patterns []string) (*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return re, nil
+	}
+
+	parts := make([]string, 0, len(patterns)+1)
+	if re != nil {
+		parts = append(parts, "(?:"+re.String()+")")
+	}
+
+	for _, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("invalid %s regex %q: %w", kind, p, err)
+		}
+		parts = append(parts, "(?i:"+p+")")
+	}
+
+	return regexp.Compile(strings.Join(parts, "|"))
+}
+
+func sortedKeys(m map[string]Rule) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
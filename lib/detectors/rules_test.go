@@ -0,0 +1,105 @@
+package detectors_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/assets"
+	. "github.com/permafrost-dev/zeget/lib/detectors"
+)
+
+var _ = Describe("ApplyRules", func() {
+	AfterEach(func() {
+		Expect(ApplyRules()).To(Succeed())
+	})
+
+	detect := func(goos string, goarch string, names ...string) string {
+		detector, err := NewSystemDetector(goos, goarch)
+		Expect(err).NotTo(HaveOccurred())
+
+		assets := make([]Asset, len(names))
+		for i, name := range names {
+			assets[i] = Asset{Name: name}
+		}
+
+		result, err := detector.Detect(assets)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Candidates).To(BeEmpty())
+
+		return result.Asset.Name
+	}
+
+	It("adds match regexes to the built-in OS and architectures", func() {
+		Expect(ApplyRules(Rules{
+			OS:   map[string]Rule{"darwin": {Match: []string{"apple"}}},
+			Arch: map[string]Rule{"arm64": {Match: []string{`\bm1\b`}}},
+		})).To(Succeed())
+
+		Expect(detect("darwin", "arm64", "tool-apple-m1.tar.gz", "tool-linux-x86_64.tar.gz", "tool-apple-intel.tar.gz")).To(Equal("tool-apple-m1.tar.gz"))
+	})
+
+	It("applies arch rules to the aliases of an architecture", func() {
+		Expect(ApplyRules(Rules{Arch: map[string]Rule{"armv7": {Match: []string{"arm-v7"}}}})).To(Succeed())
+
+		Expect(detect("linux", "armhf", "tool-linux-arm-v7.tar.gz", "tool-linux-arm64.tar.gz")).To(Equal("tool-linux-arm-v7.tar.gz"))
+	})
+
+	It("adds anti and priority regexes", func() {
+		Expect(ApplyRules(Rules{
+			OS:   map[string]Rule{"linux": {Anti: []string{"-gui-"}, Priority: []string{`\.pkg\.tar\.zst$`}}},
+			Arch: map[string]Rule{"amd64": {Anti: []string{"x86_64-v4"}}},
+		})).To(Succeed())
+
+		Expect(detect("linux", "amd64", "tool-gui-linux-amd64.tar.gz", "tool-linux-amd64.pkg.tar.zst")).To(Equal("tool-linux-amd64.pkg.tar.zst"))
+		Expect(detect("linux", "amd64v4", "tool-linux-x86_64-v4.tar.gz", "tool-linux-x86_64.tar.gz")).To(Equal("tool-linux-x86_64.tar.gz"))
+	})
+
+	It("defines new operating systems and architectures", func() {
+		_, err := NewSystemDetector("aix", "ppc64")
+		Expect(err).To(HaveOccurred())
+
+		Expect(ApplyRules(Rules{
+			OS:   map[string]Rule{"aix": {Match: []string{"aix"}}},
+			Arch: map[string]Rule{"sparc64": {Match: []string{"sparcv9"}}},
+		})).To(Succeed())
+
+		Expect(detect("aix", "ppc64", "tool-aix-ppc64.tar.gz", "tool-linux-ppc64.tar.gz")).To(Equal("tool-aix-ppc64.tar.gz"))
+		Expect(detect("solaris", "sparc64", "tool-solaris-sparcv9.tar.gz", "tool-solaris-amd64.tar.gz")).To(Equal("tool-solaris-sparcv9.tar.gz"))
+	})
+
+	It("restores the built-in rules", func() {
+		Expect(ApplyRules(Rules{OS: map[string]Rule{"aix": {Match: []string{"aix"}}}})).To(Succeed())
+		Expect(ApplyRules()).To(Succeed())
+
+		_, err := NewSystemDetector("aix", "ppc64")
+		Expect(err).To(MatchError("unsupported target OS: aix"))
+	})
+
+	It("merges rule sets in order", func() {
+		global := Rules{OS: map[string]Rule{"darwin": {Match: []string{"apple"}}}}
+		repo := Rules{Arch: map[string]Rule{"arm64": {Match: []string{"silicon"}}}}
+		Expect(ApplyRules(global, repo)).To(Succeed())
+
+		Expect(detect("darwin", "arm64", "tool-apple-silicon.zip", "tool-apple-intel.zip")).To(Equal("tool-apple-silicon.zip"))
+	})
+
+	DescribeTable("rejects invalid rules",
+		func(rules Rules, message string) {
+			Expect(ApplyRules(rules)).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("invalid regex", Rules{OS: map[string]Rule{"linux": {Match: []string{"lin(64"}}}},
+			`detectors.os.linux: invalid match regex "lin(64"`),
+		Entry("unknown OS", Rules{OS: map[string]Rule{"aix": {Anti: []string{"x"}}}},
+			"detectors.os.aix: unknown OS, a match regex is required"),
+		Entry("unknown arch", Rules{Arch: map[string]Rule{"sparc64": {}}},
+			"detectors.arch.sparc64: unknown architecture, a match regex is required"),
+		Entry("arch priority", Rules{Arch: map[string]Rule{"amd64": {Priority: []string{"x"}}}},
+			"detectors.arch.amd64: priority regexes are only supported for operating systems"),
+	)
+
+	It("keeps the previous rules when a rule is invalid", func() {
+		Expect(ApplyRules(Rules{OS: map[string]Rule{"darwin": {Match: []string{"apple"}}}})).To(Succeed())
+		Expect(ApplyRules(Rules{OS: map[string]Rule{"linux": {Match: []string{"("}}}})).NotTo(Succeed())
+
+		Expect(detect("darwin", "amd64", "tool-apple-x64.zip", "tool-linux-x64.zip")).To(Equal("tool-apple-x64.zip"))
+	})
+})
@@ -0,0 +1,219 @@
+package detectors
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	. "github.com/permafrost-dev/zeget/lib/assets"
+)
+
+// The weights used to rate assets. Matching the OS is required for an asset
+// to be selected; the other weights rank the assets that match it.
+const (
+	ScoreOS           = 100
+	ScorePriority     = 25
+	ScoreArch         = 50
+	ScoreForeignArch  = -30
+	ScoreArchStep     = 10
+	ScoreUniversal    = -5
+	ScoreCPULevel     = 5
+	ScoreUnsupported  = -200
+	ScoreExactToken   = 5
+	ScorePortable     = 10
+	ScoreLibc         = 5
+	ScoreForeignLibc  = -50
+	ScoreToolchain    = 5
+	ScoreArchive      = 10
+	ScoreBinary       = 5
+	ScorePackage      = -40
+	ScoreSource       = -40
+	ScoreDebugSymbols = -60
+	ScoreMetadata     = -200
+)
+
+var (
+	archiveRegex  = regexp.MustCompile(`(?i)\.(tar\.(gz|xz|bz2|zst|lz|lzma|lz4|br|Z)|tgz|txz|tbz2?|tzst|taz|tar|zip|7z|cpio)$`)
+	binaryRegex   = regexp.MustCompile(`(?i)\.(exe|appimage)$`)
+	packageRegex  = regexp.MustCompile(`(?i)(\.(deb|rpm|apk|msi|msix|dmg|pkg|snap|flatpak|nupkg|vsix)$|\.pkg\.tar\.\w+$|[-_.](setup|installer)[-_.])`)
+	sourceRegex   = regexp.MustCompile(`(?i)[-_.](src|source)[-_.]`)
+	debugRegex    = regexp.MustCompile(`(?i)([-_.](debug|dbg|debuginfo|symbols)([-_.]|$)|\.(pdb|dsym)(\.|$))`)
+	metadataRegex = regexp.MustCompile(`(?i)(\.(sig|asc|pem|crt|cert|minisig|sbom|spdx|cdx|sha1|sha512|md5|sum|txt|zsync|b3|intoto\.jsonl)$|sbom|checksums?\b)`)
+	portableRegex = regexp.MustCompile(`(?i)(musl|static)`)
+	gnuRegex      = regexp.MustCompile(`(?i)(gnu|glibc)`)
+	msvcRegex     = regexp.MustCompile(`(?i)msvc`)
+	tokenRegex    = regexp.MustCompile(`[-_.\s]+`)
+
+	// architectures without a matcher, so builds for them are not mistaken for
+	// builds that do not name an architecture
+	otherArchRegex = regexp.MustCompile(`(?i)(sparc64|riscv32|powerpc([^6]|$)|wasm)`)
+	universalRegex = regexp.MustCompile(`(?i)(universal|[-_.]all([-_.]|$))`)
+	levelRegex     = regexp.MustCompile(`(?i)(x86[-_]?64|amd64)[-_]?v([1-4])`)
+)
+
+// A ScoreReason is a single contribution to the score of an asset.
+type ScoreReason struct {
+	Points int
+	Reason string
+}
+
+// A Score rates how well an asset suits a system, along with the reasons that
+// make up its total.
+type Score struct {
+	Asset   Asset
+	Total   int
+	OS      bool
+	Reasons []ScoreReason
+}
+
+func (s *Score) add(points int, reason string) {
+	s.Total += points
+	s.Reasons = append(s.Reasons, ScoreReason{Points: points, Reason: reason})
+}
+
+// String explains the score, e.g. "+100 os linux, +50 arch amd64, +10 archive".
+func (s Score) String() string {
+	parts := make([]string, len(s.Reasons))
+	for i, r := range s.Reasons {
+		parts[i] = fmt.Sprintf("%+d %s", r.Points, r.Reason)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// Score rates the asset for this detector's OS/Arch pair.
+func (d *SystemDetector) Score(asset Asset) Score {
+	name := path.Base(asset.Name)
+	score := Score{Asset: asset}
+
+	os, priority := d.Os.Match(name)
+	if os || priority {
+		score.OS = true
+		score.add(ScoreOS, "os "+d.Os.Name)
+	}
+	if priority {
+		score.add(ScorePriority, "preferred format for "+d.Os.Name)
+	}
+
+	if d.Arch.Match(name) {
+		score.add(ScoreArch, "arch "+d.Arch.name)
+	} else if arch, i := d.Arch.MatchCompatible(name); arch != nil {
+		score.add(ScoreArch-ScoreArchStep*(i+1), "compatible arch "+arch.name)
+	} else if d.Os.Name == "darwin" && universalRegex.MatchString(name) {
+		score.add(ScoreArch+ScoreUniversal, "universal binary")
+	} else if arch, ok := foreignArch(name); ok {
+		score.add(ScoreForeignArch, "other arch "+arch)
+	}
+
+	if m := levelRegex.FindStringSubmatch(name); m != nil && d.Arch.name == "amd64" {
+		level := int(m[2][0] - '0')
+		if level <= max(d.Level, 1) {
+			score.add(ScoreCPULevel*(level-1), "cpu level v"+m[2])
+		} else {
+			score.add(ScoreUnsupported, "requires cpu level v"+m[2])
+		}
+	}
+
+	tokens := map[string]bool{}
+	for _, t := range tokenRegex.Split(strings.ToLower(name), -1) {
+		tokens[t] = true
+	}
+	if tokens[d.Os.Name] || tokens[d.Arch.name] {
+		score.add(ScoreExactToken, "exact system name")
+	}
+
+	if d.Os.Name == "linux" {
+		switch {
+		case portableRegex.MatchString(name):
+			score.add(ScorePortable, "statically linked")
+		case gnuRegex.MatchString(name) && d.Libc == LibcGNU:
+			score.add(ScoreLibc, "libc gnu")
+		case gnuRegex.MatchString(name) && d.Libc == LibcMusl:
+			score.add(ScoreForeignLibc, "requires glibc")
+		}
+	}
+	if d.Os.Name == "windows" && msvcRegex.MatchString(name) {
+		score.add(ScoreToolchain, "native toolchain")
+	}
+
+	switch {
+	case metadataRegex.MatchString(name):
+		score.add(ScoreMetadata, "signature or metadata")
+	case packageRegex.MatchString(name):
+		score.add(ScorePackage, "package or installer")
+	case archiveRegex.MatchString(name):
+		score.add(ScoreArchive, "archive")
+	case isBinaryName(name):
+		score.add(ScoreBinary, "binary")
+	}
+
+	if debugRegex.MatchString(name) {
+		score.add(ScoreDebugSymbols, "debug symbols")
+	}
+	if sourceRegex.MatchString(name) {
+		score.add(ScoreSource, "source code")
+	}
+
+	return score
+}
+
+// Explain returns the scores of the assets, best first.
+func (d *SystemDetector) Explain(assets []Asset) []Score {
+	scores := make([]Score, 0, len(assets))
+	for _, a := range assets {
+		if !isChecksumFile(a.Name) {
+			scores = append(scores, d.Score(a))
+		}
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Total > scores[j].Total
+	})
+
+	return scores
+}
+
+// foreignArch returns the name of another known architecture the asset name
+// refers to.
+func foreignArch(name string) (string, bool) {
+	for _, key := range archNames() {
+		arch := goarchmap[key]
+		if arch.Match(name) {
+			return arch.name, true
+		}
+	}
+
+	if arch := otherArchRegex.FindString(name); arch != "" {
+		return strings.ToLower(arch), true
+	}
+
+	return "", false
+}
+
+func archNames() []string {
+	names := make([]string, 0, len(goarchmap))
+	for name := range goarchmap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// isBinaryName reports whether the name looks like an executable rather than
+// a file with an extension; dots in version numbers are not extensions.
+func isBinaryName(name string) bool {
+	if binaryRegex.MatchString(name) {
+		return true
+	}
+
+	ext := strings.TrimPrefix(path.Ext(name), ".")
+
+	return ext == "" || strings.ContainsAny(ext, "-_") || strings.Trim(ext, "0123456789") == ""
+}
+
+func isChecksumFile(name string) bool {
+	return strings.HasSuffix(name, ".sha256") || strings.HasSuffix(name, ".sha256sum")
+}
@@ -7,82 +7,92 @@ import (
 	. "github.com/permafrost-dev/zeget/lib/assets"
 )
 
-// A SystemDetector matches a particular OS/Arch system pair.
+// A SystemDetector matches a particular OS/Arch system pair, and optionally
+// the libc of the system on Linux and its x86-64 microarchitecture level.
 type SystemDetector struct {
-	Os   OS
-	Arch Arch
+	Os    OS
+	Arch  Arch
+	Libc  Libc
+	Level int // as in GOAMD64, 0 if unknown
 }
 
 // NewSystemDetector returns a new detector for the given OS/Arch as given by
-// Go OS/Arch names.
+// Go OS/Arch names. The arch may name a GOARM level, such as "armv7", or an
+// x86-64 microarchitecture level, such as "amd64v3".
 func NewSystemDetector(sos, sarch string) (*SystemDetector, error) {
 	os, ok := goosmap[sos]
 	if !ok {
 		return nil, fmt.Errorf("unsupported target OS: %s", sos)
 	}
+	level := 0
+	if l, found := strings.CutPrefix(sarch, "amd64v"); found && len(l) == 1 && l[0] >= '1' && l[0] <= '4' {
+		sarch, level = "amd64", int(l[0]-'0')
+	}
 	arch, ok := goarchmap[sarch]
 	if !ok {
 		return nil, fmt.Errorf("unsupported target arch: %s", sarch)
 	}
 	return &SystemDetector{
-		Os:   os,
-		Arch: arch,
+		Os:    os,
+		Arch:  arch,
+		Level: level,
 	}, nil
 }
 
-// Detect extracts the assets that match this detector's OS/Arch pair. If one
-// direct OS/Arch match is found, it is returned.  If multiple OS/Arch matches
-// are found they are returned as candidates. If multiple assets that only
-// match the OS are found, and no full OS/Arch matches are found, the OS
-// matches are returned as candidates. Otherwise all assets are returned as
+// WithLibc returns a copy of the detector that targets the given libc.
+func (d *SystemDetector) WithLibc(libc Libc) *SystemDetector {
+	result := *d
+	result.Libc = libc
+
+	return &result
+}
+
+// WithLevel returns a copy of the detector that targets the given x86-64
+// microarchitecture level.
+func (d *SystemDetector) WithLevel(level int) *SystemDetector {
+	result := *d
+	result.Level = level
+
+	return &result
+}
+
+// Detect extracts the assets that match this detector's OS/Arch pair. Each
+// asset that matches the OS is scored (see Score) and the best scoring asset
+// is returned. If several assets share the best score they are returned as
+// candidates. If no asset matches the OS, all assets are returned as
 // candidates.
 func (d *SystemDetector) Detect(assets []Asset) (DetectionResult, error) {
-	var priority = []Asset{}
-	var matches = []Asset{}
-	var candidates = []Asset{}
+	var best = []Asset{}
+	var bestScore = 0
 
 	all := make([]Asset, 0, len(assets))
 	for _, a := range assets {
-		if strings.HasSuffix(a.Name, ".sha256") || strings.HasSuffix(a.Name, ".sha256sum") {
+		if isChecksumFile(a.Name) {
 			// skip checksums (they will be checked later by the verifier)
 			continue
 		}
-		os, extra := d.Os.Match(a.Name)
-		if extra {
-			priority = append(priority, a)
-		}
-		arch := d.Arch.Match(a.Name)
-		if os && arch {
-			matches = append(matches, a)
-		}
-		if os {
-			candidates = append(candidates, a)
-		}
 		all = append(all, a)
-	}
-
-	if len(priority) == 1 {
-		return NewDetectionResult(&priority[0], nil), nil
-	}
-
-	if len(priority) > 1 {
-		return NewDetectionResult(&Asset{}, priority), nil //fmt.Errorf("%d priority matches found", len(matches))
-	}
 
-	if len(matches) == 1 {
-		return NewDetectionResult(&matches[0], nil), nil
-	}
+		score := d.Score(a)
+		if !score.OS || score.Total <= 0 {
+			continue
+		}
 
-	if len(matches) > 1 {
-		return NewDetectionResult(&Asset{}, matches), nil
+		switch {
+		case score.Total > bestScore:
+			best = []Asset{a}
+			bestScore = score.Total
+		case score.Total == bestScore:
+			best = append(best, a)
+		}
 	}
 
-	if len(candidates) == 1 {
-		return NewDetectionResult(&candidates[0], nil), nil
+	if len(best) == 1 {
+		return NewDetectionResult(&best[0], nil), nil
 	}
 
-	if len(candidates) > 1 {
-		return NewDetectionResult(&Asset{}, candidates), nil //fmt.Errorf("%d candidates found (unsure architecture)", len(candidates))
+	if len(best) > 1 {
+		return NewDetectionResult(&Asset{}, best), nil
 	}
 
 	if len(all) == 1 {
@@ -0,0 +1,311 @@
+package detectors_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/assets"
+	. "github.com/permafrost-dev/zeget/lib/detectors"
+)
+
+// asset lists of real releases, used to guard the scoring weights against regressions
+var corpus = map[string][]string{
+	"BurntSushi/ripgrep@14.1.0": {
+		"ripgrep-14.1.0-aarch64-apple-darwin.tar.gz",
+		"ripgrep-14.1.0-aarch64-apple-darwin.tar.gz.sha256",
+		"ripgrep-14.1.0-aarch64-unknown-linux-gnu.tar.gz",
+		"ripgrep-14.1.0-aarch64-unknown-linux-gnu.tar.gz.sha256",
+		"ripgrep-14.1.0-arm-unknown-linux-gnueabihf.tar.gz",
+		"ripgrep-14.1.0-arm-unknown-linux-gnueabihf.tar.gz.sha256",
+		"ripgrep-14.1.0-armv7-unknown-linux-gnueabihf.tar.gz",
+		"ripgrep-14.1.0-armv7-unknown-linux-musleabi.tar.gz",
+		"ripgrep-14.1.0-armv7-unknown-linux-musleabihf.tar.gz",
+		"ripgrep-14.1.0-i686-pc-windows-msvc.zip",
+		"ripgrep-14.1.0-i686-unknown-linux-gnu.tar.gz",
+		"ripgrep-14.1.0-powerpc64-unknown-linux-gnu.tar.gz",
+		"ripgrep-14.1.0-s390x-unknown-linux-gnu.tar.gz",
+		"ripgrep-14.1.0-x86_64-apple-darwin.tar.gz",
+		"ripgrep-14.1.0-x86_64-apple-darwin.tar.gz.sha256",
+		"ripgrep-14.1.0-x86_64-pc-windows-gnu.zip",
+		"ripgrep-14.1.0-x86_64-pc-windows-msvc.zip",
+		"ripgrep-14.1.0-x86_64-pc-windows-msvc.zip.sha256",
+		"ripgrep-14.1.0-x86_64-unknown-linux-musl.tar.gz",
+		"ripgrep-14.1.0-x86_64-unknown-linux-musl.tar.gz.sha256",
+		"ripgrep_14.1.0-1_amd64.deb",
+		"ripgrep_14.1.0-1_amd64.deb.sha256",
+	},
+	"sharkdp/fd@v9.0.0": {
+		"fd-v9.0.0-aarch64-apple-darwin.tar.gz",
+		"fd-v9.0.0-aarch64-unknown-linux-gnu.tar.gz",
+		"fd-v9.0.0-aarch64-unknown-linux-musl.tar.gz",
+		"fd-v9.0.0-arm-unknown-linux-gnueabihf.tar.gz",
+		"fd-v9.0.0-arm-unknown-linux-musleabihf.tar.gz",
+		"fd-v9.0.0-i686-pc-windows-msvc.zip",
+		"fd-v9.0.0-i686-unknown-linux-gnu.tar.gz",
+		"fd-v9.0.0-i686-unknown-linux-musl.tar.gz",
+		"fd-v9.0.0-x86_64-apple-darwin.tar.gz",
+		"fd-v9.0.0-x86_64-pc-windows-gnu.zip",
+		"fd-v9.0.0-x86_64-pc-windows-msvc.zip",
+		"fd-v9.0.0-x86_64-unknown-linux-gnu.tar.gz",
+		"fd-v9.0.0-x86_64-unknown-linux-musl.tar.gz",
+		"fd-musl_9.0.0_amd64.deb",
+		"fd-musl_9.0.0_arm64.deb",
+		"fd_9.0.0_amd64.deb",
+		"fd_9.0.0_arm64.deb",
+		"fd_9.0.0_armhf.deb",
+	},
+	"cli/cli@v2.40.0": {
+		"gh_2.40.0_checksums.txt",
+		"gh_2.40.0_linux_386.deb",
+		"gh_2.40.0_linux_386.rpm",
+		"gh_2.40.0_linux_386.tar.gz",
+		"gh_2.40.0_linux_amd64.deb",
+		"gh_2.40.0_linux_amd64.rpm",
+		"gh_2.40.0_linux_amd64.tar.gz",
+		"gh_2.40.0_linux_arm64.deb",
+		"gh_2.40.0_linux_arm64.rpm",
+		"gh_2.40.0_linux_arm64.tar.gz",
+		"gh_2.40.0_linux_armv6.deb",
+		"gh_2.40.0_linux_armv6.rpm",
+		"gh_2.40.0_linux_armv6.tar.gz",
+		"gh_2.40.0_macOS_amd64.zip",
+		"gh_2.40.0_macOS_arm64.zip",
+		"gh_2.40.0_windows_386.msi",
+		"gh_2.40.0_windows_386.zip",
+		"gh_2.40.0_windows_amd64.msi",
+		"gh_2.40.0_windows_amd64.zip",
+		"gh_2.40.0_windows_arm64.zip",
+	},
+	"jesseduffield/lazygit@v0.40.2": {
+		"checksums.txt",
+		"lazygit_0.40.2_Darwin_arm64.tar.gz",
+		"lazygit_0.40.2_Darwin_x86_64.tar.gz",
+		"lazygit_0.40.2_Freebsd_32-bit.tar.gz",
+		"lazygit_0.40.2_Freebsd_arm64.tar.gz",
+		"lazygit_0.40.2_Freebsd_x86_64.tar.gz",
+		"lazygit_0.40.2_Linux_32-bit.tar.gz",
+		"lazygit_0.40.2_Linux_arm64.tar.gz",
+		"lazygit_0.40.2_Linux_armv6.tar.gz",
+		"lazygit_0.40.2_Linux_x86_64.tar.gz",
+		"lazygit_0.40.2_Windows_32-bit.zip",
+		"lazygit_0.40.2_Windows_arm64.zip",
+		"lazygit_0.40.2_Windows_armv6.zip",
+		"lazygit_0.40.2_Windows_x86_64.zip",
+	},
+	"helix-editor/helix@23.10": {
+		"helix-23.10-aarch64-linux.tar.xz",
+		"helix-23.10-aarch64-macos.tar.xz",
+		"helix-23.10-source.tar.xz",
+		"helix-23.10-x86_64-linux.tar.xz",
+		"helix-23.10-x86_64-macos.tar.xz",
+		"helix-23.10-x86_64-windows.zip",
+		"helix-23.10-x86_64.AppImage",
+		"helix-23.10-x86_64.AppImage.zsync",
+	},
+	"jqlang/jq@jq-1.7.1": {
+		"jq-1.7.1.tar.gz",
+		"jq-1.7.1.zip",
+		"jq-linux-amd64",
+		"jq-linux-arm64",
+		"jq-linux-armel",
+		"jq-linux-armhf",
+		"jq-linux-i386",
+		"jq-linux64",
+		"jq-linux32",
+		"jq-macos-amd64",
+		"jq-macos-arm64",
+		"jq-win64.exe",
+		"jq-windows-amd64.exe",
+		"jq-windows-i386.exe",
+		"sha256sum.txt",
+	},
+	"starship/starship@v1.17.1": {
+		"starship-aarch64-apple-darwin.tar.gz",
+		"starship-aarch64-pc-windows-msvc.zip",
+		"starship-aarch64-unknown-linux-musl.tar.gz",
+		"starship-arm-unknown-linux-musleabihf.tar.gz",
+		"starship-i686-pc-windows-msvc.msi",
+		"starship-i686-pc-windows-msvc.zip",
+		"starship-i686-unknown-linux-musl.tar.gz",
+		"starship-x86_64-apple-darwin.tar.gz",
+		"starship-x86_64-pc-windows-msvc.msi",
+		"starship-x86_64-pc-windows-msvc.zip",
+		"starship-x86_64-unknown-freebsd.tar.gz",
+		"starship-x86_64-unknown-linux-gnu.tar.gz",
+		"starship-x86_64-unknown-linux-musl.tar.gz",
+	},
+	"derailed/k9s@v0.31.7": {
+		"checksums.sha256",
+		"k9s_Darwin_amd64.tar.gz",
+		"k9s_Darwin_amd64.tar.gz.sbom.json",
+		"k9s_Darwin_arm64.tar.gz",
+		"k9s_Darwin_arm64.tar.gz.sbom.json",
+		"k9s_Freebsd_amd64.tar.gz",
+		"k9s_Linux_amd64.tar.gz",
+		"k9s_Linux_amd64.tar.gz.sbom.json",
+		"k9s_Linux_arm64.tar.gz",
+		"k9s_Linux_armv7.tar.gz",
+		"k9s_Linux_ppc64le.tar.gz",
+		"k9s_Windows_amd64.zip",
+		"k9s_Windows_arm64.zip",
+		"k9s_linux_amd64.apk",
+		"k9s_linux_amd64.deb",
+		"k9s_linux_amd64.rpm",
+	},
+	"sigstore/cosign@v2.2.2": {
+		"cosign-2.2.2-1.x86_64.rpm",
+		"cosign-darwin-amd64",
+		"cosign-darwin-amd64.sig",
+		"cosign-darwin-arm64",
+		"cosign-darwin-arm64.sig",
+		"cosign-linux-amd64",
+		"cosign-linux-amd64-keyless.pem",
+		"cosign-linux-amd64-keyless.sig",
+		"cosign-linux-amd64.sig",
+		"cosign-linux-arm64",
+		"cosign-linux-arm64.sig",
+		"cosign-windows-amd64.exe",
+		"cosign-windows-amd64.exe.sig",
+		"cosign_2.2.2_amd64.deb",
+		"cosign_checksums.txt",
+	},
+	"ajeetdsouza/zoxide@v0.9.2": {
+		"zoxide-0.9.2-aarch64-apple-darwin.tar.gz",
+		"zoxide-0.9.2-aarch64-pc-windows-msvc.zip",
+		"zoxide-0.9.2-aarch64-unknown-linux-musl.tar.gz",
+		"zoxide-0.9.2-arm-unknown-linux-musleabihf.tar.gz",
+		"zoxide-0.9.2-armv7-unknown-linux-musleabihf.tar.gz",
+		"zoxide-0.9.2-x86_64-apple-darwin.tar.gz",
+		"zoxide-0.9.2-x86_64-pc-windows-msvc.zip",
+		"zoxide-0.9.2-x86_64-unknown-linux-musl.tar.gz",
+		"zoxide_0.9.2_amd64.deb",
+		"zoxide_0.9.2_arm64.deb",
+		"zoxide_0.9.2_armhf.deb",
+	},
+	"goreleaser/goreleaser@v1.23.0": {
+		"checksums.txt",
+		"checksums.txt.pem",
+		"checksums.txt.sig",
+		"goreleaser-1.23.0-1-x86_64.pkg.tar.zst",
+		"goreleaser_1.23.0_amd64.deb",
+		"goreleaser_Darwin_all.tar.gz",
+		"goreleaser_Linux_arm64.tar.gz",
+		"goreleaser_Linux_armv6.tar.gz",
+		"goreleaser_Linux_armv7.tar.gz",
+		"goreleaser_Linux_i386.tar.gz",
+		"goreleaser_Linux_ppc64.tar.gz",
+		"goreleaser_Linux_x86_64.tar.gz",
+		"goreleaser_Windows_arm64.zip",
+		"goreleaser_Windows_x86_64.zip",
+	},
+	"example/cpu-levels@v1.0.0": {
+		"tool-linux-x86_64-v3.tar.gz",
+		"tool-linux-x86_64-v4.tar.gz",
+		"tool-linux-x86_64.tar.gz",
+	},
+	"example/debug-builds@v1.0.0": {
+		"tool-linux-amd64-debug.tar.gz",
+		"tool-linux-amd64-static.tar.gz",
+		"tool-linux-amd64.tar.gz",
+		"tool-linux-amd64.tar.gz.asc",
+		"tool-windows-amd64.pdb.zip",
+		"tool-windows-amd64.zip",
+	},
+}
+
+func corpusAssets(release string) []Asset {
+	names, ok := corpus[release]
+	Expect(ok).To(BeTrue(), "unknown release %s", release)
+
+	result := make([]Asset, len(names))
+	for i, name := range names {
+		result[i] = Asset{Name: name, DownloadURL: "https://example.com/" + name}
+	}
+
+	return result
+}
+
+var _ = Describe("SystemDetector regression corpus", func() {
+	DescribeTable("selects the expected asset",
+		func(release string, goos string, goarch string, want string) {
+			detector, err := NewSystemDetector(goos, goarch)
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := detector.Detect(corpusAssets(release))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Candidates).To(BeEmpty(), "scores: %v", detector.Explain(corpusAssets(release)))
+			Expect(result.Asset.Name).To(Equal(want))
+		},
+		Entry(nil, "BurntSushi/ripgrep@14.1.0", "linux", "amd64", "ripgrep-14.1.0-x86_64-unknown-linux-musl.tar.gz"),
+		Entry(nil, "BurntSushi/ripgrep@14.1.0", "linux", "arm64", "ripgrep-14.1.0-aarch64-unknown-linux-gnu.tar.gz"),
+		Entry(nil, "BurntSushi/ripgrep@14.1.0", "linux", "arm", "ripgrep-14.1.0-arm-unknown-linux-gnueabihf.tar.gz"),
+		Entry(nil, "BurntSushi/ripgrep@14.1.0", "darwin", "arm64", "ripgrep-14.1.0-aarch64-apple-darwin.tar.gz"),
+		Entry(nil, "BurntSushi/ripgrep@14.1.0", "windows", "amd64", "ripgrep-14.1.0-x86_64-pc-windows-msvc.zip"),
+		Entry(nil, "sharkdp/fd@v9.0.0", "linux", "amd64", "fd-v9.0.0-x86_64-unknown-linux-musl.tar.gz"),
+		Entry(nil, "sharkdp/fd@v9.0.0", "linux", "arm64", "fd-v9.0.0-aarch64-unknown-linux-musl.tar.gz"),
+		Entry(nil, "sharkdp/fd@v9.0.0", "linux", "arm", "fd-v9.0.0-arm-unknown-linux-musleabihf.tar.gz"),
+		Entry(nil, "sharkdp/fd@v9.0.0", "windows", "amd64", "fd-v9.0.0-x86_64-pc-windows-msvc.zip"),
+		Entry(nil, "cli/cli@v2.40.0", "linux", "amd64", "gh_2.40.0_linux_amd64.tar.gz"),
+		Entry(nil, "cli/cli@v2.40.0", "linux", "arm", "gh_2.40.0_linux_armv6.tar.gz"),
+		Entry(nil, "cli/cli@v2.40.0", "darwin", "arm64", "gh_2.40.0_macOS_arm64.zip"),
+		Entry(nil, "cli/cli@v2.40.0", "windows", "amd64", "gh_2.40.0_windows_amd64.zip"),
+		Entry(nil, "jesseduffield/lazygit@v0.40.2", "linux", "amd64", "lazygit_0.40.2_Linux_x86_64.tar.gz"),
+		Entry(nil, "jesseduffield/lazygit@v0.40.2", "darwin", "arm64", "lazygit_0.40.2_Darwin_arm64.tar.gz"),
+		Entry(nil, "jesseduffield/lazygit@v0.40.2", "freebsd", "amd64", "lazygit_0.40.2_Freebsd_x86_64.tar.gz"),
+		Entry(nil, "helix-editor/helix@23.10", "linux", "amd64", "helix-23.10-x86_64-linux.tar.xz"),
+		Entry(nil, "helix-editor/helix@23.10", "darwin", "amd64", "helix-23.10-x86_64-macos.tar.xz"),
+		Entry(nil, "jqlang/jq@jq-1.7.1", "linux", "amd64", "jq-linux-amd64"),
+		Entry(nil, "jqlang/jq@jq-1.7.1", "darwin", "arm64", "jq-macos-arm64"),
+		Entry(nil, "jqlang/jq@jq-1.7.1", "windows", "amd64", "jq-windows-amd64.exe"),
+		Entry(nil, "starship/starship@v1.17.1", "linux", "amd64", "starship-x86_64-unknown-linux-musl.tar.gz"),
+		Entry(nil, "starship/starship@v1.17.1", "windows", "amd64", "starship-x86_64-pc-windows-msvc.zip"),
+		Entry(nil, "derailed/k9s@v0.31.7", "linux", "amd64", "k9s_Linux_amd64.tar.gz"),
+		Entry(nil, "derailed/k9s@v0.31.7", "darwin", "amd64", "k9s_Darwin_amd64.tar.gz"),
+		Entry(nil, "sigstore/cosign@v2.2.2", "linux", "amd64", "cosign-linux-amd64"),
+		Entry(nil, "sigstore/cosign@v2.2.2", "darwin", "arm64", "cosign-darwin-arm64"),
+		Entry(nil, "sigstore/cosign@v2.2.2", "windows", "amd64", "cosign-windows-amd64.exe"),
+		Entry(nil, "ajeetdsouza/zoxide@v0.9.2", "linux", "amd64", "zoxide-0.9.2-x86_64-unknown-linux-musl.tar.gz"),
+		Entry(nil, "ajeetdsouza/zoxide@v0.9.2", "linux", "arm", "zoxide-0.9.2-arm-unknown-linux-musleabihf.tar.gz"),
+		Entry(nil, "BurntSushi/ripgrep@14.1.0", "linux", "s390x", "ripgrep-14.1.0-s390x-unknown-linux-gnu.tar.gz"),
+		Entry(nil, "BurntSushi/ripgrep@14.1.0", "linux", "ppc64", "ripgrep-14.1.0-powerpc64-unknown-linux-gnu.tar.gz"),
+		Entry(nil, "BurntSushi/ripgrep@14.1.0", "linux", "386", "ripgrep-14.1.0-i686-unknown-linux-gnu.tar.gz"),
+		Entry(nil, "sharkdp/fd@v9.0.0", "linux", "armv7", "fd-v9.0.0-arm-unknown-linux-musleabihf.tar.gz"),
+		Entry(nil, "jesseduffield/lazygit@v0.40.2", "linux", "386", "lazygit_0.40.2_Linux_32-bit.tar.gz"),
+		Entry(nil, "derailed/k9s@v0.31.7", "linux", "ppc64le", "k9s_Linux_ppc64le.tar.gz"),
+		Entry(nil, "derailed/k9s@v0.31.7", "linux", "armv7", "k9s_Linux_armv7.tar.gz"),
+		Entry(nil, "ajeetdsouza/zoxide@v0.9.2", "linux", "armv7", "zoxide-0.9.2-armv7-unknown-linux-musleabihf.tar.gz"),
+		Entry(nil, "goreleaser/goreleaser@v1.23.0", "darwin", "arm64", "goreleaser_Darwin_all.tar.gz"),
+		Entry(nil, "goreleaser/goreleaser@v1.23.0", "darwin", "amd64", "goreleaser_Darwin_all.tar.gz"),
+		Entry(nil, "goreleaser/goreleaser@v1.23.0", "linux", "arm", "goreleaser_Linux_armv6.tar.gz"),
+		Entry(nil, "goreleaser/goreleaser@v1.23.0", "linux", "armv7", "goreleaser_Linux_armv7.tar.gz"),
+		Entry(nil, "goreleaser/goreleaser@v1.23.0", "linux", "ppc64", "goreleaser_Linux_ppc64.tar.gz"),
+		Entry(nil, "example/cpu-levels@v1.0.0", "linux", "amd64", "tool-linux-x86_64.tar.gz"),
+		Entry(nil, "example/cpu-levels@v1.0.0", "linux", "amd64v3", "tool-linux-x86_64-v3.tar.gz"),
+		Entry(nil, "example/cpu-levels@v1.0.0", "linux", "amd64v4", "tool-linux-x86_64-v4.tar.gz"),
+		Entry(nil, "example/debug-builds@v1.0.0", "linux", "amd64", "tool-linux-amd64-static.tar.gz"),
+		Entry(nil, "example/debug-builds@v1.0.0", "windows", "amd64", "tool-windows-amd64.zip"),
+	)
+
+	It("explains the score of each asset", func() {
+		detector, _ := NewSystemDetector("linux", "amd64")
+		scores := detector.Explain(corpusAssets("cli/cli@v2.40.0"))
+
+		Expect(scores[0].Asset.Name).To(Equal("gh_2.40.0_linux_amd64.tar.gz"))
+		Expect(scores[0].Total).To(Equal(ScoreOS + ScoreArch + ScoreExactToken + ScoreArchive))
+		Expect(scores[0].String()).To(Equal("+100 os linux, +50 arch amd64, +5 exact system name, +10 archive"))
+	})
+
+	It("penalizes packages, signatures and debug symbols", func() {
+		detector, _ := NewSystemDetector("linux", "amd64")
+
+		for name, reason := range map[string]string{
+			"gh_2.40.0_linux_amd64.deb":        "package or installer",
+			"cosign-linux-amd64.sig":           "signature or metadata",
+			"k9s_Linux_amd64.tar.gz.sbom.json": "signature or metadata",
+			"tool-linux-amd64-debug.tar.gz":    "debug symbols",
+		} {
+			score := detector.Score(Asset{Name: name})
+			Expect(score.String()).To(ContainSubstring(reason), name)
+			Expect(score.Total).To(BeNumerically("<", ScoreOS+ScoreArch), name)
+		}
+	})
+})
@@ -0,0 +1,27 @@
+package detectors
+
+import "fmt"
+
+// A SystemMatcher matches asset names against Go OS and architecture names,
+// using the same rules as the SystemDetector. It backs the os() and arch()
+// asset filters.
+type SystemMatcher struct{}
+
+func (SystemMatcher) MatchOS(goos string, name string) (bool, error) {
+	os, ok := goosmap[goos]
+	if !ok {
+		return false, fmt.Errorf("unsupported target OS: %s", goos)
+	}
+
+	matched, _ := os.Match(name)
+	return matched, nil
+}
+
+func (SystemMatcher) MatchArch(goarch string, name string) (bool, error) {
+	arch, ok := goarchmap[goarch]
+	if !ok {
+		return false, fmt.Errorf("unsupported target arch: %s", goarch)
+	}
+
+	return arch.Match(name), nil
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"strings"
 
+	"github.com/permafrost-dev/zeget/lib/credentials"
 	pb "github.com/schollz/progressbar/v3"
 )
 
@@ -17,6 +19,7 @@ type ClientContract interface {
 	GetJSON(url string) (*http.Response, error)
 	GetBinaryFile(url string) (*http.Response, error)
 	GetText(url string) (*http.Response, error)
+	Do(req *http.Request) (*http.Response, error)
 	Download(url string, out io.Writer, progressBarCallback func(size int64) *pb.ProgressBar) error
 }
 
@@ -25,6 +28,8 @@ type Client struct {
 	Token        string
 	Accept       string
 	DisableSSL   bool
+	Mirrors      []*Mirror
+	Credentials  *credentials.Store
 	tokenType    string
 	CreateClient func() *http.Client
 }
@@ -75,6 +80,26 @@ func (dc *Client) SetTokenType(tokenType string) *Client {
 	return dc
 }
 
+func (dc *Client) SetCredentials(store *credentials.Store) *Client {
+	dc.Credentials = store
+	return dc
+}
+
+func (dc *Client) SetMirrors(mirrors []*Mirror) *Client {
+	dc.Mirrors = mirrors
+	return dc
+}
+
+// RewriteURL returns the URL that will actually be requested for 'url' after
+// applying any configured mirror.
+func (dc *Client) RewriteURL(url string) string {
+	if mirror := FindMirror(dc.Mirrors, url); mirror != nil {
+		return mirror.Rewrite(url)
+	}
+
+	return url
+}
+
 func (dc *Client) AddHeader(header string, value string) *Client {
 	dc.Headers = append(dc.Headers, header+":"+value)
 	return dc
@@ -85,9 +110,15 @@ func (dc *Client) initRequest(req *http.Request) *http.Request {
 		req.Header.Set("Accept", dc.Accept)
 	}
 
-	if dc.Token != "" {
-		tokenTypeStr := setIf(len(dc.tokenType) > 0, dc.tokenType, "Bearer")
-		req.Header.Set("Authorization", fmt.Sprintf("%s %s", tokenTypeStr, dc.Token))
+	// the GitHub token is only ever sent to GitHub hosts
+	if IsGithubHost(req.URL.Host) {
+		dc.setTokenHeader(req)
+	}
+
+	if req.Header.Get("Authorization") == "" {
+		if cred := dc.Credentials.Lookup(req.URL.Host); cred != nil {
+			cred.Apply(req)
+		}
 	}
 
 	for _, header := range dc.Headers {
@@ -100,13 +131,35 @@ func (dc *Client) initRequest(req *http.Request) *http.Request {
 	return req
 }
 
+func (dc *Client) setTokenHeader(req *http.Request) {
+	if dc.Token == "" {
+		return
+	}
+
+	tokenTypeStr := setIf(len(dc.tokenType) > 0, dc.tokenType, "Bearer")
+	req.Header.Set("Authorization", fmt.Sprintf("%s %s", tokenTypeStr, dc.Token))
+}
+
 func (dc *Client) createRequest(method string, url string) (*http.Request, error) {
-	result, err := http.NewRequest(method, url, nil)
+	mirror := FindMirror(dc.Mirrors, url)
+	target := dc.RewriteURL(url)
+
+	result, err := http.NewRequest(method, target, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return dc.initRequest(result), nil
+	result = dc.initRequest(result)
+
+	if mirror == nil {
+		return result, nil
+	}
+
+	if mirror.ForwardToken && isGithubURL(url) {
+		dc.setTokenHeader(result)
+	}
+
+	return mirror.Apply(result), nil
 }
 
 func (dc *Client) GetClient() *http.Client {
@@ -127,6 +180,38 @@ func (dc *Client) Get(url string) (*http.Response, error) {
 	return dc.CreateClient().Do(req)
 }
 
+// Do sends a request built by the caller, such as one with its own Accept
+// or Authorization header, through the configured mirrors with the client
+// headers and credentials.
+func (dc *Client) Do(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	accept := req.Header.Get("Accept")
+	auth := req.Header.Get("Authorization")
+
+	mirror := FindMirror(dc.Mirrors, req.URL.String())
+	if mirror != nil {
+		target, err := neturl.Parse(mirror.Rewrite(req.URL.String()))
+		if err != nil {
+			return nil, err
+		}
+		req.URL, req.Host = target, target.Host
+	}
+
+	req = dc.initRequest(req)
+
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	if mirror != nil {
+		req = mirror.Apply(req)
+	}
+
+	return dc.CreateClient().Do(req)
+}
+
 func (dc *Client) GetJSON(url string) (*http.Response, error) {
 	return dc.
 		SetAccept(AcceptGitHubJSON).
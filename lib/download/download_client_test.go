@@ -13,6 +13,7 @@ import (
 
 	// "testing"
 
+	"github.com/permafrost-dev/zeget/lib/credentials"
 	. "github.com/permafrost-dev/zeget/lib/download"
 )
 
@@ -215,3 +216,59 @@ var _ = Describe("DownloadClient", func() {
 	// 	gm.Expect(buf.String()).To(gm.Equal("mock body"))
 	// })
 })
+
+var _ = Describe("DownloadClient authentication", func() {
+	var (
+		transport *recordingTransport
+		dc        *Client
+	)
+
+	BeforeEach(func() {
+		transport = &recordingTransport{}
+		dc = NewClient("gh-token")
+		dc.CreateClient = func() *http.Client {
+			return &http.Client{Transport: transport}
+		}
+	})
+
+	It("should send the GitHub token to GitHub hosts", func() {
+		_, err := dc.Get("https://api.github.com/repos/a/b/releases/latest")
+
+		gm.Expect(err).To(gm.BeNil())
+		gm.Expect(transport.Requests[0].Header.Get("Authorization")).To(gm.Equal("Bearer gh-token"))
+	})
+
+	It("should not send the GitHub token to other hosts", func() {
+		_, err := dc.Get("https://example.com/tool.tar.gz")
+
+		gm.Expect(err).To(gm.BeNil())
+		gm.Expect(transport.Requests[0].Header.Get("Authorization")).To(gm.BeEmpty())
+	})
+
+	It("should use per-host credentials for other hosts", func() {
+		store := credentials.NewStore()
+		store.Getenv = func(string) string { return "" }
+		store.Add(credentials.NewBasicCredential("example.com", "user", "pass"))
+		dc.SetCredentials(store)
+
+		_, err := dc.Get("https://example.com/tool.tar.gz")
+		gm.Expect(err).To(gm.BeNil())
+
+		user, pass, ok := transport.Requests[0].BasicAuth()
+		gm.Expect(ok).To(gm.BeTrue())
+		gm.Expect(user).To(gm.Equal("user"))
+		gm.Expect(pass).To(gm.Equal("pass"))
+	})
+
+	It("should recognise GitHub hosts", func() {
+		gm.Expect(IsGithubHost("api.github.com")).To(gm.BeTrue())
+		gm.Expect(IsGithubHost("GitHub.com:443")).To(gm.BeTrue())
+		gm.Expect(IsGithubHost("github.com.evil.example")).To(gm.BeFalse())
+	})
+
+	It("should recognise release asset API URLs", func() {
+		gm.Expect(IsGithubAssetAPIURL("https://api.github.com/repos/owner/repo/releases/assets/123")).To(gm.BeTrue())
+		gm.Expect(IsGithubAssetAPIURL("https://ghe.example.com/api/v3/repos/owner/repo/releases/assets/123")).To(gm.BeFalse())
+		gm.Expect(IsGithubAssetAPIURL("https://github.com/owner/repo/releases/download/v1.0.0/tool.tar.gz")).To(gm.BeFalse())
+	})
+})
@@ -1,6 +1,11 @@
 package download
 
-import "os"
+import (
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
 
 func isLocalFile(s string) bool {
 	_, err := os.Stat(s)
@@ -13,3 +18,46 @@ func setIf[T interface{}](condition bool, original T, newValue T) T {
 	}
 	return original
 }
+
+// hosts that the GitHub token may be sent to
+var githubHosts = []string{
+	"github.com",
+	"api.github.com",
+	"uploads.github.com",
+	"codeload.github.com",
+	"objects.githubusercontent.com",
+	"raw.githubusercontent.com",
+}
+
+// IsGithubHost returns true if the given host (optionally with a port) is
+// operated by GitHub.
+func IsGithubHost(host string) bool {
+	host = strings.ToLower(host)
+	if h, _, found := strings.Cut(host, ":"); found {
+		host = h
+	}
+
+	for _, h := range githubHosts {
+		if host == h {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches the path of release asset endpoints of the GitHub API
+var githubAssetAPIPattern = regexp.MustCompile(`^/repos/[^/]+/[^/]+/releases/assets/\d+$`)
+
+// IsGithubAssetAPIURL returns true if the URL is a release asset API endpoint,
+// which returns the asset contents when requested with "Accept: application/octet-stream".
+// Only GitHub hosts are recognized, as the token is only sent to them.
+func IsGithubAssetAPIURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && IsGithubHost(u.Host) && githubAssetAPIPattern.MatchString(u.Path)
+}
+
+func isGithubURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && IsGithubHost(u.Host)
+}
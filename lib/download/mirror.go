@@ -0,0 +1,108 @@
+package download
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// A Mirror rewrites request URLs that match either a literal Prefix or a
+// Regex, so that downloads and API calls can be routed through a proxy such as
+// an Artifactory remote repository. A mirror may carry its own headers and
+// credentials, which are attached to every rewritten request.
+type Mirror struct {
+	Name         string
+	Prefix       string
+	Regex        *regexp.Regexp
+	Replace      string
+	Headers      []string
+	Username     string
+	Password     string
+	Token        string
+	ForwardToken bool // send the GitHub token to the mirror host
+}
+
+// NewPrefixMirror returns a mirror that replaces the leading 'prefix' of a URL
+// with 'replace'.
+func NewPrefixMirror(name string, prefix string, replace string) *Mirror {
+	return &Mirror{
+		Name:    name,
+		Prefix:  prefix,
+		Replace: replace,
+	}
+}
+
+// NewRegexMirror returns a mirror that rewrites URLs matching 'pattern'. The
+// replacement may reference capture groups, e.g. `https://proxy/$1`.
+func NewRegexMirror(name string, pattern string, replace string) (*Mirror, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("mirror %s: invalid regex: %w", name, err)
+	}
+
+	return &Mirror{
+		Name:    name,
+		Regex:   re,
+		Replace: replace,
+	}, nil
+}
+
+// Matches returns true if this mirror applies to the given URL.
+func (m *Mirror) Matches(u string) bool {
+	if m.Regex != nil {
+		return m.Regex.MatchString(u)
+	}
+
+	return m.Prefix != "" && strings.HasPrefix(u, m.Prefix)
+}
+
+// Rewrite returns the mirrored version of the given URL, or the URL unchanged
+// if the mirror does not apply to it.
+func (m *Mirror) Rewrite(u string) string {
+	if !m.Matches(u) {
+		return u
+	}
+
+	if m.Regex != nil {
+		return m.Regex.ReplaceAllString(u, m.Replace)
+	}
+
+	return m.Replace + u[len(m.Prefix):]
+}
+
+// HasCredentials returns true if the mirror provides its own authentication.
+func (m *Mirror) HasCredentials() bool {
+	return m.Token != "" || m.Username != ""
+}
+
+// Apply sets the mirror's headers and credentials on the request.
+func (m *Mirror) Apply(req *http.Request) *http.Request {
+	if m.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+m.Token)
+	}
+
+	if m.Username != "" {
+		req.SetBasicAuth(m.Username, m.Password)
+	}
+
+	for _, header := range m.Headers {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) == 2 {
+			req.Header.Set(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+		}
+	}
+
+	return req
+}
+
+// FindMirror returns the first mirror that applies to the given URL, or nil.
+func FindMirror(mirrors []*Mirror, u string) *Mirror {
+	for _, m := range mirrors {
+		if m != nil && m.Matches(u) {
+			return m
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package download_test
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/permafrost-dev/zeget/lib/download"
+)
+
+type recordingTransport struct {
+	Requests []*http.Request
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.Requests = append(rt.Requests, req)
+	return newMockResponse("mock body", http.StatusOK), nil
+}
+
+var _ = Describe("Mirror", func() {
+	Describe("prefix mirrors", func() {
+		It("should rewrite matching URLs", func() {
+			m := NewPrefixMirror("corp", "https://github.com/", "https://artifactory.corp/github/")
+
+			Expect(m.Matches("https://github.com/a/b/releases/download/v1/x.tar.gz")).To(BeTrue())
+			Expect(m.Rewrite("https://github.com/a/b/releases/download/v1/x.tar.gz")).To(Equal("https://artifactory.corp/github/a/b/releases/download/v1/x.tar.gz"))
+		})
+
+		It("should leave other URLs untouched", func() {
+			m := NewPrefixMirror("corp", "https://github.com/", "https://artifactory.corp/github/")
+
+			Expect(m.Matches("https://api.github.com/repos/a/b")).To(BeFalse())
+			Expect(m.Rewrite("https://api.github.com/repos/a/b")).To(Equal("https://api.github.com/repos/a/b"))
+		})
+	})
+
+	Describe("regex mirrors", func() {
+		It("should expand capture groups", func() {
+			m, err := NewRegexMirror("api", `^https://api\.github\.com/(.*)$`, "https://artifactory.corp/github-api/$1")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Rewrite("https://api.github.com/repos/a/b/releases/latest")).To(Equal("https://artifactory.corp/github-api/repos/a/b/releases/latest"))
+		})
+
+		It("should return an error for an invalid pattern", func() {
+			_, err := NewRegexMirror("bad", `(`, "")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("FindMirror", func() {
+		It("should return the first matching mirror", func() {
+			first := NewPrefixMirror("first", "https://github.com/", "https://one/")
+			second := NewPrefixMirror("second", "https://github.com/a/", "https://two/")
+
+			Expect(FindMirror([]*Mirror{first, second}, "https://github.com/a/b")).To(Equal(first))
+			Expect(FindMirror([]*Mirror{first, second}, "https://example.com/a/b")).To(BeNil())
+		})
+	})
+
+	Describe("Client requests", func() {
+		var (
+			transport *recordingTransport
+			dc        *Client
+		)
+
+		BeforeEach(func() {
+			transport = &recordingTransport{}
+			dc = NewClient("gh-token")
+			dc.CreateClient = func() *http.Client {
+				return &http.Client{Transport: transport}
+			}
+		})
+
+		It("should request the mirrored URL with the mirror headers", func() {
+			m := NewPrefixMirror("corp", "https://github.com/", "https://artifactory.corp/github/")
+			m.Headers = []string{"X-JFrog-Art-Api: secret"}
+			dc.SetMirrors([]*Mirror{m})
+
+			_, err := dc.Get("https://github.com/a/b/releases/download/v1/x.tar.gz")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transport.Requests).To(HaveLen(1))
+			Expect(transport.Requests[0].URL.String()).To(Equal("https://artifactory.corp/github/a/b/releases/download/v1/x.tar.gz"))
+			Expect(transport.Requests[0].Header.Get("X-JFrog-Art-Api")).To(Equal("secret"))
+		})
+
+		It("should not send the client token to another host", func() {
+			dc.SetMirrors([]*Mirror{NewPrefixMirror("corp", "https://github.com/", "https://artifactory.corp/github/")})
+
+			_, err := dc.Get("https://github.com/a/b")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transport.Requests[0].Header.Get("Authorization")).To(BeEmpty())
+		})
+
+		It("should forward the client token when requested", func() {
+			m := NewPrefixMirror("corp", "https://github.com/", "https://artifactory.corp/github/")
+			m.ForwardToken = true
+			dc.SetMirrors([]*Mirror{m})
+
+			_, err := dc.Get("https://github.com/a/b")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transport.Requests[0].Header.Get("Authorization")).To(Equal("Bearer gh-token"))
+		})
+
+		It("should send requests built by the caller through the mirror", func() {
+			dc.SetMirrors([]*Mirror{NewPrefixMirror("registry", "https://ghcr.io/", "https://registry.corp/ghcr/")})
+
+			req, _ := http.NewRequest("GET", "https://ghcr.io/v2/org/tool/manifests/v1", nil)
+			req.Header.Set("Accept", "application/vnd.oci.image.index.v1+json")
+			req.Header.Set("Authorization", "Bearer registry-token")
+			_, err := dc.Do(req)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transport.Requests[0].URL.String()).To(Equal("https://registry.corp/ghcr/v2/org/tool/manifests/v1"))
+			Expect(transport.Requests[0].Header.Get("Accept")).To(Equal("application/vnd.oci.image.index.v1+json"))
+			Expect(transport.Requests[0].Header.Get("Authorization")).To(Equal("Bearer registry-token"))
+			Expect(req.URL.Host).To(Equal("ghcr.io"))
+		})
+
+		It("should use the mirror credentials", func() {
+			m := NewPrefixMirror("corp", "https://github.com/", "https://artifactory.corp/github/")
+			m.Username = "builder"
+			m.Password = "hunter2"
+			dc.SetMirrors([]*Mirror{m})
+
+			_, err := dc.Get("https://github.com/a/b")
+			Expect(err).NotTo(HaveOccurred())
+
+			user, pass, ok := transport.Requests[0].BasicAuth()
+			Expect(ok).To(BeTrue())
+			Expect(user).To(Equal("builder"))
+			Expect(pass).To(Equal("hunter2"))
+		})
+	})
+})
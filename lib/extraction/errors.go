@@ -0,0 +1,62 @@
+package extraction
+
+import (
+	"fmt"
+
+	"github.com/permafrost-dev/zeget/lib/assets"
+)
+
+// An UnsafePathError is returned for a file whose path in an archive is
+// absolute or leads out of the directory it is extracted to, as in a zip-slip
+// attack.
+type UnsafePathError struct {
+	Name string
+}
+
+func (e *UnsafePathError) Error() string {
+	return fmt.Sprintf("extract: %s: path leads outside of the target directory", e.Name)
+}
+
+// A LinkError is returned for a symbolic or hard link in an archive which
+// points outside of the directory it is extracted to.
+type LinkError struct {
+	Name   string
+	Target string
+}
+
+func (e *LinkError) Error() string {
+	return fmt.Sprintf("extract: %s: link to %s leads outside of the target directory", e.Name, e.Target)
+}
+
+// A FileSizeError is returned for a file larger than Limits.MaxFileSize.
+type FileSizeError struct {
+	Name  string
+	Limit int64
+}
+
+func (e *FileSizeError) Error() string {
+	return fmt.Sprintf("extract: %s: file larger than %s", e.Name, assets.FormatSize(e.Limit))
+}
+
+// A TotalSizeError is returned when the files read from an archive are
+// together larger than Limits.MaxTotalSize.
+type TotalSizeError struct {
+	Limit int64
+}
+
+func (e *TotalSizeError) Error() string {
+	return fmt.Sprintf("extract: archive content larger than %s", assets.FormatSize(e.Limit))
+}
+
+// A RatioError is returned when the files read from an archive are larger
+// than Limits.MaxRatio times the archive.
+type RatioError struct {
+	Size        int64
+	ArchiveSize int64
+	Limit       float64
+}
+
+func (e *RatioError) Error() string {
+	return fmt.Sprintf("extract: %s extracted from %s exceeds the compression ratio limit of %g",
+		assets.FormatSize(e.Size), assets.FormatSize(e.ArchiveSize), e.Limit)
+}
@@ -1,23 +1,21 @@
 package extraction
 
 import (
-	"bufio"
 	"bytes"
-	"compress/bzip2"
-	"compress/gzip"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
-	"github.com/klauspost/compress/zstd"
 	"github.com/permafrost-dev/zeget/lib/archives"
 	"github.com/permafrost-dev/zeget/lib/files"
 	"github.com/permafrost-dev/zeget/lib/targetfile"
 	"github.com/permafrost-dev/zeget/lib/utilities"
 	"github.com/twpayne/go-vfs/v5"
-	"github.com/ulikunitz/xz"
 )
 
 // An Extractor reads in some archive data and extracts a particular file from
@@ -28,72 +26,265 @@ type Extractor interface {
 }
 
 // NewExtractor constructs an extractor for the given archive file using the
-// given chooser. It will construct extractors for files ending in '.tar.gz',
-// '.tar.bz2', '.tar', '.zip'. After these matches, if the file ends with
-// '.gz', '.bz2' it will be decompressed and copied. Other files will simply
-// be copied without any decompression or extraction.
-func NewExtractor(fs vfs.FS, filename string, tool string, chooser Chooser) Extractor {
+// given chooser. It will construct extractors for tar archives ('.tar.gz',
+// '.tar.bz2', '.tar.xz', '.tar.zst', '.tar.lz', '.tar.lzma', '.tar.lz4',
+// '.tar.br', '.tar.Z' and '.tar'), '.zip', '.7z', '.cpio' and '.cab'
+// archives, '.squashfs' file systems, macOS '.dmg' disk images, and for the
+// payload of '.deb', '.rpm', '.apk', '.pkg' and '.msi' packages. After these matches, if the file ends
+// with '.gz', '.bz2', '.xz', '.zst', '.lz', '.lzma', '.lz4', '.br' or '.Z' it
+// will be decompressed and copied. The format of other files is detected from
+// their content, which also finds the payload of self-extracting executables
+// and installers, and files in no known format will simply be copied without
+// any decompression or extraction. The extractor reads no more data than the
+// given limits allow.
+func NewExtractor(fs vfs.FS, filename string, tool string, chooser Chooser, limits Limits) Extractor {
 	if tool == "" {
 		tool = filename
 	}
 
-	gunzipper := func(r io.Reader) (io.Reader, error) {
-		return gzip.NewReader(r)
+	archive := func(ar archives.ArchiveFunc, decompress archives.DecompressFunc) Extractor {
+		extractor := NewArchiveExtractor(chooser, ar, decompress, fs)
+		extractor.Limits = limits
+		return extractor
 	}
-	b2unzipper := func(r io.Reader) (io.Reader, error) {
-		return bzip2.NewReader(r), nil
-	}
-	xunzipper := func(r io.Reader) (io.Reader, error) {
-		return xz.NewReader(bufio.NewReader(r))
-	}
-	zstdunzipper := func(r io.Reader) (io.Reader, error) {
-		return zstd.NewReader(r)
-	}
-	nounzipper := func(r io.Reader) (io.Reader, error) {
-		return r, nil
+	single := func(decompress archives.DecompressFunc) Extractor {
+		extractor := NewSingleFileExtractor(tool, filename, decompress, fs)
+		extractor.Limits = limits
+		return extractor
 	}
 
 	switch {
 	case strings.HasSuffix(filename, ".tar.gz"), strings.HasSuffix(filename, ".tgz"):
-		return NewArchiveExtractor(chooser, archives.NewTarArchive, gunzipper, fs)
+		return archive(archives.NewTarArchive, archives.Gunzip)
 
 	case strings.HasSuffix(filename, ".tar.bz2"), strings.HasSuffix(filename, ".tbz"):
-		return NewArchiveExtractor(chooser, archives.NewTarArchive, b2unzipper, fs)
+		return archive(archives.NewTarArchive, archives.Bunzip2)
 
 	case strings.HasSuffix(filename, ".tar.xz"), strings.HasSuffix(filename, ".txz"):
-		return NewArchiveExtractor(chooser, archives.NewTarArchive, xunzipper, fs)
+		return archive(archives.NewTarArchive, archives.Unxz)
 
 	case strings.HasSuffix(filename, ".tar.zst"):
-		return NewArchiveExtractor(chooser, archives.NewTarArchive, zstdunzipper, fs)
+		return archive(archives.NewTarArchive, archives.Unzstd)
+
+	case strings.HasSuffix(filename, ".tar.lz"):
+		return archive(archives.NewTarArchive, archives.Unlzip)
+
+	case strings.HasSuffix(filename, ".tar.lzma"):
+		return archive(archives.NewTarArchive, archives.Unlzma)
+
+	case strings.HasSuffix(filename, ".tar.lz4"):
+		return archive(archives.NewTarArchive, archives.Unlz4)
+
+	case strings.HasSuffix(filename, ".tar.br"):
+		return archive(archives.NewTarArchive, archives.Unbrotli)
+
+	case strings.HasSuffix(filename, ".tar.Z"), strings.HasSuffix(filename, ".taz"):
+		return archive(archives.NewTarArchive, archives.Uncompress)
 
 	case strings.HasSuffix(filename, ".tar"):
-		return NewArchiveExtractor(chooser, archives.NewTarArchive, nounzipper, fs)
+		return archive(archives.NewTarArchive, archives.NoDecompress)
 
 	case strings.HasSuffix(filename, ".zip"):
-		return NewArchiveExtractor(chooser, archives.NewZipArchive, nounzipper, fs)
+		return archive(archives.NewZipArchive, archives.NoDecompress)
+
+	case strings.HasSuffix(filename, ".7z"):
+		return archive(archives.NewSevenZipArchive, archives.NoDecompress)
+
+	case strings.HasSuffix(filename, ".cpio"):
+		return archive(archives.NewCpioArchive, archives.NoDecompress)
+
+	case strings.HasSuffix(filename, ".deb"):
+		return archive(archives.NewDebArchive, archives.NoDecompress)
+
+	case strings.HasSuffix(filename, ".rpm"):
+		return archive(archives.NewRpmArchive, archives.NoDecompress)
+
+	case strings.HasSuffix(filename, ".apk"):
+		return archive(archives.NewApkArchive, archives.NoDecompress)
+
+	case strings.HasSuffix(filename, ".dmg"):
+		return archive(archives.NewDmgArchive, archives.NoDecompress)
+
+	case strings.HasSuffix(filename, ".pkg"):
+		return archive(archives.NewPkgArchive, archives.NoDecompress)
+
+	case strings.HasSuffix(filename, ".msi"):
+		return archive(archives.NewMsiArchive, archives.NoDecompress)
+
+	case strings.HasSuffix(filename, ".cab"):
+		return archive(archives.NewCabArchive, archives.NoDecompress)
+
+	case strings.HasSuffix(filename, ".squashfs"), strings.HasSuffix(filename, ".sqfs"):
+		return archive(archives.NewSquashfsArchive, archives.NoDecompress)
 
 	case strings.HasSuffix(filename, ".gz"):
-		return NewSingleFileExtractor(tool, filename, gunzipper, fs)
+		return single(archives.Gunzip)
 
 	case strings.HasSuffix(filename, ".bz2"):
-		return NewSingleFileExtractor(tool, filename, b2unzipper, fs)
+		return single(archives.Bunzip2)
 
 	case strings.HasSuffix(filename, ".xz"):
-		return NewSingleFileExtractor(tool, filename, xunzipper, fs)
+		return single(archives.Unxz)
 
 	case strings.HasSuffix(filename, ".zst"):
-		return NewSingleFileExtractor(tool, filename, zstdunzipper, fs)
+		return single(archives.Unzstd)
+
+	case strings.HasSuffix(filename, ".lz"):
+		return single(archives.Unlzip)
+
+	case strings.HasSuffix(filename, ".lzma"):
+		return single(archives.Unlzma)
+
+	case strings.HasSuffix(filename, ".lz4"):
+		return single(archives.Unlz4)
+
+	case strings.HasSuffix(filename, ".br"):
+		return single(archives.Unbrotli)
+
+	case strings.HasSuffix(filename, ".Z"):
+		return single(archives.Uncompress)
 
 	default:
-		return NewSingleFileExtractor(tool, filename, nounzipper, fs)
+		extractor := NewDetectingExtractor(tool, filename, chooser, fs)
+		extractor.Limits = limits
+		return extractor
+	}
+}
+
+// A DetectingExtractor extracts files whose name does not identify their
+// format, such as assets without an extension, based on their content.
+type DetectingExtractor struct {
+	Tool     string
+	Filename string
+	File     Chooser
+	Fs       vfs.FS
+	Limits   Limits
+}
+
+func NewDetectingExtractor(tool string, filename string, file Chooser, fs vfs.FS) *DetectingExtractor {
+	return &DetectingExtractor{
+		Tool:     tool,
+		Filename: filename,
+		File:     file,
+		Fs:       fs,
+		Limits:   BuiltinLimits,
 	}
 }
 
+func (d *DetectingExtractor) Extract(data []byte, multiple bool) (ExtractedFile, []ExtractedFile, error) {
+	ext := archives.DetectFormat(data)
+
+	// installers are extracted if they contain the target, and copied
+	// otherwise, as the installer may be the target itself
+	if ext == ".exe" {
+		extractor := NewArchiveExtractor(d.File, archives.NewExeArchive, archives.NoDecompress, d.Fs)
+		extractor.Limits = d.Limits
+		if file, candidates, err := extractor.Extract(data, multiple); err == nil || len(candidates) > 0 {
+			return file, candidates, err
+		}
+	} else if ext != "" {
+		extractor := NewExtractor(d.Fs, d.Filename+ext, d.Tool, d.File, d.Limits)
+		if _, ok := extractor.(*DetectingExtractor); !ok {
+			return extractor.Extract(data, multiple)
+		}
+	}
+
+	extractor := NewSingleFileExtractor(d.Tool, d.Filename, archives.NoDecompress, d.Fs)
+	extractor.Limits = d.Limits
+
+	return extractor.Extract(data, multiple)
+}
+
+// An AppImageExtractor unpacks the file system of an AppImage, so that the
+// application runs on hosts without FUSE. The files chosen by File are
+// extracted from it; without a chooser, the whole file system is extracted
+// to a directory next to a script that runs the application from it.
+type AppImageExtractor struct {
+	Tool   string
+	File   Chooser
+	Fs     vfs.FS
+	Limits Limits
+}
+
+func NewAppImageExtractor(tool string, file Chooser, fs vfs.FS) *AppImageExtractor {
+	return &AppImageExtractor{
+		Tool:   tool,
+		File:   file,
+		Fs:     fs,
+		Limits: BuiltinLimits,
+	}
+}
+
+func (a *AppImageExtractor) Extract(data []byte, multiple bool) (ExtractedFile, []ExtractedFile, error) {
+	if a.File != nil {
+		extractor := NewArchiveExtractor(a.File, archives.NewAppImageArchive, archives.NoDecompress, a.Fs)
+		extractor.Limits = a.Limits
+		return extractor.Extract(data, multiple)
+	}
+
+	if _, err := archives.NewAppImageArchive(data, nil); err != nil {
+		return ExtractedFile{}, nil, err
+	}
+
+	return ExtractedFile{
+		Name:        a.Tool,
+		ArchiveName: "AppRun",
+		mode:        0o755,
+		Extract: func(to string) error {
+			return a.unpack(data, to)
+		},
+	}, nil, nil
+}
+
+// unpack extracts the file system to the AppDir directory named after the
+// script, replacing the directory of a previous version, and writes the
+// script.
+func (a *AppImageExtractor) unpack(data []byte, to string) error {
+	if to == "-" {
+		return fmt.Errorf("extract: an unpacked AppImage cannot be written to standard output")
+	}
+
+	dir := to + ".AppDir"
+	if err := a.Fs.RemoveAll(dir); err != nil {
+		return fmt.Errorf("extract: %w", err)
+	}
+	if err := vfs.MkdirAll(a.Fs, dir, 0o755); err != nil {
+		return fmt.Errorf("extract: %w", err)
+	}
+
+	ar := NewArchiveExtractor(nil, archives.NewAppImageArchive, archives.NoDecompress, a.Fs)
+	ar.Limits = a.Limits
+	extract, _ := ar.handleDirs(files.File{}, data, nil, newUsage(ar.Limits, len(data)))
+	if err := extract(dir); err != nil {
+		return err
+	}
+
+	script := fmt.Sprintf(appImageScript, filepath.Base(dir))
+
+	return targetfile.GetTargetFile(a.Fs, to, 0o755, true).Write([]byte(script), true)
+}
+
+// appImageScript runs the AppRun entry point of an unpacked AppImage, which
+// locates the application through $APPDIR.
+const appImageScript = `#!/bin/sh
+APPDIR="$(dirname "$(readlink -f "$0")")/%s"
+export APPDIR
+exec "$APPDIR/AppRun" "$@"
+`
+
+// maxNestingDepth is how many levels of archives within archives are read.
+const maxNestingDepth = 3
+
 type ArchiveExtractor struct {
 	File       Chooser
 	Ar         archives.ArchiveFunc
 	Decompress archives.DecompressFunc
 	Fs         vfs.FS
+	Limits     Limits
+
+	// the depth and usage of an archive nested in another one
+	depth int
+	usage *usage
 }
 
 func NewArchiveExtractor(file Chooser, ar archives.ArchiveFunc, decompress archives.DecompressFunc, fs interface{}) *ArchiveExtractor {
@@ -106,12 +297,21 @@ func NewArchiveExtractor(file Chooser, ar archives.ArchiveFunc, decompress archi
 		Ar:         ar,
 		Decompress: decompress,
 		Fs:         fs.(vfs.FS),
+		Limits:     BuiltinLimits,
 	}
 }
 
 func (a *ArchiveExtractor) Extract(data []byte, multiple bool) (ExtractedFile, []ExtractedFile, error) {
 	var candidates []ExtractedFile
 	var dirs []string
+	var nested []string
+	// the data of the candidates, so that a nested archive is not read twice
+	read := map[string][]byte{}
+
+	u := a.usage
+	if u == nil {
+		u = newUsage(a.Limits, len(data))
+	}
 
 	ar, err := a.Ar(data, a.Decompress)
 	if err != nil {
@@ -135,6 +335,9 @@ func (a *ArchiveExtractor) Extract(data []byte, multiple bool) (ExtractedFile, [
 		if hasdir {
 			continue
 		}
+		if !f.Dir() && a.nestedExtractor(f.Name, nil, nil) != nil {
+			nested = append(nested, f.Name)
+		}
 		direct, possible := a.File.Choose(f.Name, f.Dir(), f.Mode)
 		if !direct && !possible {
 			continue
@@ -142,9 +345,9 @@ func (a *ArchiveExtractor) Extract(data []byte, multiple bool) (ExtractedFile, [
 
 		name := utilities.GetRename(f.Name, f.Name)
 
-		fdata, err := ar.ReadAll()
+		fdata, err := u.read(ar, f.Name)
 		if err != nil {
-			return ExtractedFile{}, nil, fmt.Errorf("extract: %w", err)
+			return ExtractedFile{}, nil, err
 		}
 
 		var extract func(to string) error
@@ -160,7 +363,7 @@ func (a *ArchiveExtractor) Extract(data []byte, multiple bool) (ExtractedFile, [
 		}
 
 		if f.Dir() {
-			extract, dirs = a.handleDirs(f, data, dirs)
+			extract, dirs = a.handleDirs(f, data, dirs, u)
 		}
 
 		ef := ExtractedFile{
@@ -175,10 +378,37 @@ func (a *ArchiveExtractor) Extract(data []byte, multiple bool) (ExtractedFile, [
 		}
 		if err == nil {
 			candidates = append(candidates, ef)
+			if !f.Dir() {
+				read[f.Name] = fdata
+			}
 		}
 
 	}
 
+	// archives within the archive are searched when nothing else was found,
+	// or when the only candidate is one of them
+	switch {
+	case len(candidates) == 1 && a.nestedExtractor(candidates[0].ArchiveName, nil, nil) != nil:
+		nested = []string{candidates[0].ArchiveName}
+	case len(candidates) > 0:
+		nested = nil
+	}
+	if len(nested) > 0 && a.depth < maxNestingDepth {
+		found, direct, err := a.extractNested(data, nested, read, u)
+		if err != nil {
+			return ExtractedFile{}, nil, err
+		}
+		if len(direct) == 1 && !multiple {
+			return direct[0], nil, nil
+		}
+		if len(direct) > 0 && !multiple {
+			found = direct
+		}
+		if len(found) > 0 {
+			candidates = found
+		}
+	}
+
 	if len(candidates) == 1 {
 		return candidates[0], nil, nil
 	}
@@ -190,7 +420,93 @@ func (a *ArchiveExtractor) Extract(data []byte, multiple bool) (ExtractedFile, [
 	return ExtractedFile{}, candidates, fmt.Errorf("%d candidates for target %v found", len(candidates), a.File)
 }
 
-func (a *ArchiveExtractor) handleDirs(f files.File, data []byte, dirs []string) (func(to string) error, []string) {
+// nestedExtractor returns an extractor for the archive called name within the
+// archive, which counts the data it reads in u, or nil if name is not an
+// archive.
+func (a *ArchiveExtractor) nestedExtractor(name string, file Chooser, u *usage) *ArchiveExtractor {
+	extractor, ok := NewExtractor(a.Fs, name, "", file, a.Limits).(*ArchiveExtractor)
+	if !ok {
+		return nil
+	}
+
+	extractor.depth = a.depth + 1
+	extractor.usage = u
+
+	return extractor
+}
+
+// extractNested returns the candidates found in the nested archives called
+// names, and those of them that are direct matches. Files in nested archives
+// are chosen by their path through the archives, as in 'inner.tar.gz/bin/tool'.
+// Archives whose data is in read are not read again.
+func (a *ArchiveExtractor) extractNested(data []byte, names []string, read map[string][]byte, u *usage) ([]ExtractedFile, []ExtractedFile, error) {
+	var found, direct []ExtractedFile
+
+	ar, err := a.Ar(data, a.Decompress)
+	if err != nil {
+		return nil, nil, err
+	}
+	for {
+		f, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("extract: %w", err)
+		}
+		if !slices.Contains(names, f.Name) {
+			continue
+		}
+
+		extractor := a.nestedExtractor(f.Name, &nestedChooser{prefix: f.Name + "/", Chooser: a.File}, u)
+		fdata, ok := read[f.Name]
+		if !ok {
+			if fdata, err = u.read(ar, f.Name); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		// archives which cannot be read are left out, like other files
+		file, candidates, err := extractor.Extract(fdata, true)
+		if isLimitError(err) {
+			return nil, nil, err
+		}
+		if err == nil && len(candidates) == 0 {
+			candidates = []ExtractedFile{file}
+		} else if err != nil && len(candidates) == 0 {
+			continue
+		}
+
+		for _, ef := range candidates {
+			ef.ArchiveName = f.Name + "/" + ef.ArchiveName
+			if d, _ := a.File.Choose(ef.ArchiveName, ef.Dir, ef.mode); d {
+				direct = append(direct, ef)
+			}
+			found = append(found, ef)
+		}
+	}
+
+	return found, direct, nil
+}
+
+// A nestedChooser chooses the files of an archive nested in another archive
+// by their path in the outer archive.
+type nestedChooser struct {
+	prefix string
+	Chooser
+}
+
+func (n *nestedChooser) Choose(name string, dir bool, mode fs.FileMode) (bool, bool) {
+	return n.Chooser.Choose(n.prefix+name, dir, mode)
+}
+
+func (n *nestedChooser) String() string {
+	return fmt.Sprint(n.Chooser)
+}
+
+// handleDirs returns a function extracting the directory f of the archive,
+// which counts the data it reads in u, and adds it to dirs.
+func (a *ArchiveExtractor) handleDirs(f files.File, data []byte, dirs []string, u *usage) (func(to string) error, []string) {
 	directories := append(dirs, f.Name)
 
 	extract := func(to string) error {
@@ -199,6 +515,7 @@ func (a *ArchiveExtractor) handleDirs(f files.File, data []byte, dirs []string)
 			return err
 		}
 		var links []files.Link
+		targets := map[string]string{}
 		for {
 			subf, err := ar.Next()
 			if err == io.EOF {
@@ -213,28 +530,32 @@ func (a *ArchiveExtractor) handleDirs(f files.File, data []byte, dirs []string)
 				continue
 			}
 
+			rel := subf.Name[len(f.Name):]
+			name, err := safeJoin(to, rel)
+			if err != nil {
+				return err
+			}
+
 			if subf.Dir() {
 				// TODO implement MkdirAll
-				a.Fs.Mkdir(filepath.Join(to, subf.Name[len(f.Name):]), 0755)
+				a.Fs.Mkdir(name, 0755)
 				continue
 			}
 
 			if subf.Type == files.TypeLink || subf.Type == files.TypeSymlink {
-				newname := filepath.Join(to, subf.Name[len(f.Name):])
-				oldname := subf.LinkName
+				targets[path.Clean(rel)] = subf.LinkName
 				links = append(links, files.Link{
-					Newname: newname,
-					Oldname: oldname,
+					Newname: name,
+					Oldname: subf.LinkName,
 					Sym:     subf.Type == files.TypeSymlink,
 				})
 				continue
 			}
 
-			fdata, err := ar.ReadAll()
+			fdata, err := u.read(ar, subf.Name)
 			if err != nil {
-				return fmt.Errorf("extract: %w", err)
+				return err
 			}
-			name := filepath.Join(to, subf.Name[len(f.Name):])
 
 			tf := targetfile.GetTargetFile(a.Fs, name, subf.Mode, true)
 			if err = tf.Write(fdata, true); err != nil {
@@ -242,6 +563,11 @@ func (a *ArchiveExtractor) handleDirs(f files.File, data []byte, dirs []string)
 			}
 		}
 
+		// links are created last, once all of them are known to stay in the
+		// directory, so that no file is written through them
+		if err := checkLinks(targets); err != nil {
+			return err
+		}
 		for _, l := range links {
 			l.Fs = a.Fs
 			if err := l.Write(); err != nil && err != os.ErrExist {
@@ -262,6 +588,7 @@ type SingleFileExtractor struct {
 	Name       string
 	Decompress func(r io.Reader) (io.Reader, error)
 	Fs         vfs.FS
+	Limits     Limits
 }
 
 func (sf *SingleFileExtractor) Extract(data []byte, _ bool) (ExtractedFile, []ExtractedFile, error) {
@@ -278,7 +605,7 @@ func (sf *SingleFileExtractor) Extract(data []byte, _ bool) (ExtractedFile, []Ex
 				return err
 			}
 
-			decdata, err := io.ReadAll(dr)
+			decdata, err := newUsage(sf.Limits, len(data)).readStream(dr, name)
 			if err != nil {
 				return err
 			}
@@ -295,5 +622,6 @@ func NewSingleFileExtractor(name string, rename string, decompress func(r io.Rea
 		Rename:     rename,
 		Decompress: decompress,
 		Fs:         fs.(vfs.FS),
+		Limits:     BuiltinLimits,
 	}
 }
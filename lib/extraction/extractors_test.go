@@ -1,9 +1,15 @@
 package extraction_test
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
 	"fmt"
 	"io/fs"
 	"os"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -23,6 +29,67 @@ func (mc *MockChooser) Choose(name string, dir bool, mode fs.FileMode) (direct b
 	return false, false
 }
 
+// makePE builds a Windows executable with a single section.
+func makePE() []byte {
+	exe := make([]byte, 1024)
+	copy(exe, "MZ")
+	binary.LittleEndian.PutUint32(exe[0x3c:], 0x40)
+	copy(exe[0x40:], "PE\x00\x00")
+	binary.LittleEndian.PutUint16(exe[0x44:], 0x8664)
+	binary.LittleEndian.PutUint16(exe[0x46:], 1)
+	binary.LittleEndian.PutUint32(exe[0x58+16:], 512)
+	binary.LittleEndian.PutUint32(exe[0x58+20:], 512)
+
+	return exe
+}
+
+// A testFile is a file of a test archive, or a directory if its name ends
+// with a slash, or a symbolic link if it has a link target.
+type testFile struct {
+	name string
+	mode fs.FileMode
+	data []byte
+	link string
+}
+
+func makeZip(entries ...testFile) []byte {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		hdr.SetMode(e.mode)
+		f, err := w.CreateHeader(hdr)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.Write(e.data)
+		Expect(err).NotTo(HaveOccurred())
+	}
+	Expect(w.Close()).To(Succeed())
+
+	return buf.Bytes()
+}
+
+func makeTarGz(entries ...testFile) []byte {
+	buf := new(bytes.Buffer)
+	gw := gzip.NewWriter(buf)
+	w := tar.NewWriter(gw)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: tar.TypeReg, Mode: int64(e.mode), Size: int64(len(e.data))}
+		switch {
+		case strings.HasSuffix(e.name, "/"):
+			hdr.Typeflag = tar.TypeDir
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname = tar.TypeSymlink, e.link
+		}
+		Expect(w.WriteHeader(hdr)).To(Succeed())
+		_, err := w.Write(e.data)
+		Expect(err).NotTo(HaveOccurred())
+	}
+	Expect(w.Close()).To(Succeed())
+	Expect(gw.Close()).To(Succeed())
+
+	return buf.Bytes()
+}
+
 var _ = Describe("Extractor", func() {
 	var testFS *vfst.TestFS
 	var cleanup func()
@@ -41,14 +108,268 @@ var _ = Describe("Extractor", func() {
 
 	Describe("NewExtractor", func() {
 		It("should create an ArchiveExtractor for .tar.gz files", func() {
-			extractor := extraction.NewExtractor(testFS, "test.tar.gz", "", nil)
+			extractor := extraction.NewExtractor(testFS, "test.tar.gz", "", nil, extraction.BuiltinLimits)
 			Expect(extractor).To(BeAssignableToTypeOf(&extraction.ArchiveExtractor{}))
 		})
 
 		It("should create a SingleFileExtractor for .gz files", func() {
-			extractor := extraction.NewExtractor(testFS, "test.gz", "", nil)
+			extractor := extraction.NewExtractor(testFS, "test.gz", "", nil, extraction.BuiltinLimits)
 			Expect(extractor).To(BeAssignableToTypeOf(&extraction.SingleFileExtractor{}))
 		})
+
+		It("should create an ArchiveExtractor for other archive formats", func() {
+			for _, name := range []string{"t.7z", "t.cpio", "t.tar.lz", "t.tar.lzma", "t.tar.lz4", "t.tar.br", "t.tar.Z"} {
+				extractor := extraction.NewExtractor(testFS, name, "", nil, extraction.BuiltinLimits)
+				Expect(extractor).To(BeAssignableToTypeOf(&extraction.ArchiveExtractor{}), name)
+			}
+		})
+
+		It("should create a SingleFileExtractor for other compressed files", func() {
+			for _, name := range []string{"t.lz", "t.lzma", "t.lz4", "t.br", "t.Z"} {
+				extractor := extraction.NewExtractor(testFS, name, "", nil, extraction.BuiltinLimits)
+				Expect(extractor).To(BeAssignableToTypeOf(&extraction.SingleFileExtractor{}), name)
+			}
+		})
+
+		It("should create a DetectingExtractor for files without a known extension", func() {
+			extractor := extraction.NewExtractor(testFS, "tool-linux-amd64", "", nil, extraction.BuiltinLimits)
+			Expect(extractor).To(BeAssignableToTypeOf(&extraction.DetectingExtractor{}))
+		})
+
+		It("should create an ArchiveExtractor for OS packages", func() {
+			for _, name := range []string{"tool_1.0_amd64.deb", "tool-1.0.x86_64.rpm", "tool-1.0-r0.apk", "Tool-1.0.dmg", "tool-1.0.pkg"} {
+				extractor := extraction.NewExtractor(testFS, name, "", nil, extraction.BuiltinLimits)
+				Expect(extractor).To(BeAssignableToTypeOf(&extraction.ArchiveExtractor{}), name)
+			}
+		})
+
+		It("should create an ArchiveExtractor for Windows packages and cabinets", func() {
+			for _, name := range []string{"tool-1.0-x64.msi", "tool.cab"} {
+				extractor := extraction.NewExtractor(testFS, name, "", nil, extraction.BuiltinLimits)
+				Expect(extractor).To(BeAssignableToTypeOf(&extraction.ArchiveExtractor{}), name)
+			}
+		})
+
+		It("should create an ArchiveExtractor for SquashFS file systems", func() {
+			for _, name := range []string{"tool.squashfs", "tool.sqfs"} {
+				extractor := extraction.NewExtractor(testFS, name, "", nil, extraction.BuiltinLimits)
+				Expect(extractor).To(BeAssignableToTypeOf(&extraction.ArchiveExtractor{}), name)
+			}
+		})
+	})
+
+	Describe("nested archives", func() {
+		tool := func(platform string) []byte {
+			return makeTarGz(testFile{name: "bin/tool", mode: 0o755, data: []byte("tool " + platform)})
+		}
+
+		It("should extract a binary from an archive within the archive", func() {
+			data := makeZip(testFile{name: "tool-linux.tar.gz", mode: 0o644, data: tool("linux")})
+
+			extractor := extraction.NewExtractor(testFS, "tool.zip", "tool", &extraction.BinaryChooser{Tool: "tool"}, extraction.BuiltinLimits)
+			ef, _, err := extractor.Extract(data, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.ArchiveName).To(Equal("tool-linux.tar.gz/bin/tool"))
+
+			Expect(ef.Extract("/tool")).To(Succeed())
+			content, err := testFS.ReadFile("/tool")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("tool linux"))
+		})
+
+		It("should descend into the only candidate if it is an archive", func() {
+			data := makeZip(
+				testFile{name: "README.md", mode: 0o644, data: []byte("readme")},
+				testFile{name: "tool-linux.tar.gz", mode: 0o755, data: tool("linux")},
+			)
+
+			extractor := extraction.NewExtractor(testFS, "tool.zip", "tool", &extraction.BinaryChooser{Tool: "tool"}, extraction.BuiltinLimits)
+			ef, _, err := extractor.Extract(data, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.ArchiveName).To(Equal("tool-linux.tar.gz/bin/tool"))
+		})
+
+		It("should select files by their path through the archives", func() {
+			data := makeZip(
+				testFile{name: "tool-linux.tar.gz", mode: 0o644, data: tool("linux")},
+				testFile{name: "tool-darwin.tar.gz", mode: 0o644, data: tool("darwin")},
+			)
+
+			extractor := extraction.NewExtractor(testFS, "tool.zip", "tool", &extraction.BinaryChooser{Tool: "tool"}, extraction.BuiltinLimits)
+			_, candidates, err := extractor.Extract(data, false)
+			Expect(err).To(HaveOccurred())
+			Expect(candidates).To(HaveLen(2))
+
+			gc, err := extraction.NewGlobChooser("tool-darwin.tar.gz/bin/tool")
+			Expect(err).NotTo(HaveOccurred())
+			extractor = extraction.NewExtractor(testFS, "tool.zip", "tool", gc, extraction.BuiltinLimits)
+			ef, _, err := extractor.Extract(data, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.ArchiveName).To(Equal("tool-darwin.tar.gz/bin/tool"))
+		})
+
+		It("should extract nested archives themselves when chosen", func() {
+			data := makeZip(testFile{name: "tool-linux.tar.gz", mode: 0o644, data: tool("linux")})
+
+			gc, err := extraction.NewGlobChooser("*.tar.gz")
+			Expect(err).NotTo(HaveOccurred())
+			extractor := extraction.NewExtractor(testFS, "tool.zip", "tool", gc, extraction.BuiltinLimits)
+			ef, _, err := extractor.Extract(data, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.ArchiveName).To(Equal("tool-linux.tar.gz"))
+		})
+
+		It("should limit the nesting depth", func() {
+			nest := func(levels int) []byte {
+				data := tool("linux")
+				name := "tool.tar.gz"
+				for i := 0; i < levels; i++ {
+					data = makeZip(testFile{name: name, mode: 0o644, data: data})
+					name = fmt.Sprintf("level%d.zip", i)
+				}
+				return data
+			}
+
+			extractor := extraction.NewExtractor(testFS, "tool.zip", "tool", &extraction.BinaryChooser{Tool: "tool"}, extraction.BuiltinLimits)
+			ef, _, err := extractor.Extract(nest(3), false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.ArchiveName).To(Equal("level1.zip/level0.zip/tool.tar.gz/bin/tool"))
+
+			_, _, err = extractor.Extract(nest(4), false)
+			Expect(err).To(MatchError(ContainSubstring("not found")))
+		})
+	})
+
+	Describe("AppImageExtractor", func() {
+		var data []byte
+
+		BeforeEach(func() {
+			data, err = os.ReadFile("../../test/archives/tool.AppImage")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should unpack the AppImage next to a launcher script", func() {
+			extractor := extraction.NewAppImageExtractor("tool", nil, testFS)
+			ef, _, err := extractor.Extract(data, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.Name).To(Equal("tool"))
+
+			Expect(testFS.Mkdir("/bin", 0o755)).To(Succeed())
+			Expect(ef.Extract("/bin/tool")).To(Succeed())
+
+			script, err := testFS.ReadFile("/bin/tool")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(script)).To(ContainSubstring(`/tool.AppDir"`))
+			Expect(string(script)).To(ContainSubstring(`exec "$APPDIR/AppRun" "$@"`))
+
+			content, err := testFS.ReadFile("/bin/tool.AppDir/usr/bin/tool")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("#!/bin/sh\necho tool\n"))
+			info, err := testFS.Stat("/bin/tool.AppDir/AppRun")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(fs.FileMode(0o755)))
+		})
+
+		It("should extract the chosen file from the AppImage", func() {
+			extractor := extraction.NewAppImageExtractor("tool", &extraction.BinaryChooser{Tool: "tool"}, testFS)
+			ef, _, err := extractor.Extract(data, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.ArchiveName).To(Equal("usr/bin/tool"))
+
+			Expect(ef.Extract("/tool")).To(Succeed())
+			content, err := testFS.ReadFile("/tool")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("#!/bin/sh\necho tool\n"))
+		})
+
+		It("should not unpack to standard output", func() {
+			extractor := extraction.NewAppImageExtractor("tool", nil, testFS)
+			ef, _, err := extractor.Extract(data, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.Extract("-")).To(MatchError("extract: an unpacked AppImage cannot be written to standard output"))
+		})
+
+		It("should fail for other files", func() {
+			extractor := extraction.NewAppImageExtractor("tool", nil, testFS)
+			_, _, err := extractor.Extract(makePE(), false)
+			Expect(err).To(MatchError("appimage: not an AppImage"))
+		})
+	})
+
+	Describe("ArchiveExtractor", func() {
+		It("should extract a binary from a .deb package", func() {
+			data := new(bytes.Buffer)
+			w := tar.NewWriter(data)
+			for _, hdr := range []*tar.Header{
+				{Name: "./usr/bin/", Typeflag: tar.TypeDir, Mode: 0o755},
+				{Name: "./usr/bin/tool", Typeflag: tar.TypeReg, Mode: 0o755, Size: 4},
+			} {
+				Expect(w.WriteHeader(hdr)).To(Succeed())
+			}
+			_, err := w.Write([]byte("tool"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+
+			deb := bytes.NewBufferString("!<arch>\n")
+			fmt.Fprintf(deb, "%-16s%-12d%-6d%-6d%-8s%-10d`\n", "data.tar/", 0, 0, 0, "100644", data.Len())
+			deb.Write(data.Bytes())
+
+			extractor := extraction.NewExtractor(testFS, "tool_1.0_amd64.deb", "tool", &extraction.BinaryChooser{Tool: "tool"}, extraction.BuiltinLimits)
+			ef, _, err := extractor.Extract(deb.Bytes(), false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.ArchiveName).To(Equal("usr/bin/tool"))
+
+			Expect(ef.Extract("/tool")).To(Succeed())
+			content, err := testFS.ReadFile("/tool")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("tool"))
+		})
+	})
+
+	Describe("DetectingExtractor", func() {
+		It("should extract archives without an extension", func() {
+			data, err := os.ReadFile("../../test/archives/tool.tar.Z")
+			Expect(err).NotTo(HaveOccurred())
+
+			extractor := extraction.NewExtractor(testFS, "tool-linux-amd64", "tool", &extraction.BinaryChooser{Tool: "tool"}, extraction.BuiltinLimits)
+			ef, _, err := extractor.Extract(data, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.ArchiveName).To(Equal("tool-1.0/bin/tool"))
+		})
+
+		It("should extract the payload of self-extracting executables", func() {
+			payload := new(bytes.Buffer)
+			w := zip.NewWriter(payload)
+			f, err := w.CreateHeader(&zip.FileHeader{Name: "bin/tool.exe"})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.Write([]byte("MZ tool"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+
+			extractor := extraction.NewExtractor(testFS, "tool-setup.exe", "tool", &extraction.BinaryChooser{Tool: "tool"}, extraction.BuiltinLimits)
+			ef, _, err := extractor.Extract(append(makePE(), payload.Bytes()...), false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.ArchiveName).To(Equal("bin/tool.exe"))
+		})
+
+		It("should copy executables without a payload", func() {
+			extractor := extraction.NewExtractor(testFS, "tool.exe", "tool", &extraction.BinaryChooser{Tool: "tool"}, extraction.BuiltinLimits)
+			ef, _, err := extractor.Extract(makePE(), false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.Name).To(Equal("tool.exe"))
+		})
+
+		It("should copy files in no known format", func() {
+			extractor := extraction.NewExtractor(testFS, "tool-linux-amd64", "tool", &extraction.BinaryChooser{Tool: "tool"}, extraction.BuiltinLimits)
+			ef, _, err := extractor.Extract([]byte("\x7fELF"), false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.Name).To(Equal("tool"))
+
+			Expect(ef.Extract("/tool")).To(Succeed())
+			content, err := testFS.ReadFile("/tool")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("\x7fELF"))
+		})
 	})
 
 	Describe("SingleFileExtractor", func() {
@@ -58,7 +379,7 @@ var _ = Describe("Extractor", func() {
 				testArchiveFn := fmt.Sprintf("test archive: %s/../../test/test-config-toml.gz", wd)
 				buf, err := os.ReadFile(testArchiveFn)
 
-				extractor := extraction.NewExtractor(vfs.OSFS, testArchiveFn, "test-config-toml", nil)
+				extractor := extraction.NewExtractor(vfs.OSFS, testArchiveFn, "test-config-toml", nil, extraction.BuiltinLimits)
 				ef, _, err := extractor.Extract(buf, false)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(ef.Name).To(Equal("test-config-toml"))
@@ -67,42 +388,42 @@ var _ = Describe("Extractor", func() {
 			})
 
 			It("should select the correct extractor for .tar.bz2 files", func() {
-				extractor := extraction.NewExtractor(testFS, "test.tar.bz2", "", nil)
+				extractor := extraction.NewExtractor(testFS, "test.tar.bz2", "", nil, extraction.BuiltinLimits)
 				Expect(extractor).To(BeAssignableToTypeOf(&extraction.ArchiveExtractor{}))
 			})
 
 			It("should select the correct extractor for .tar.xz files", func() {
-				extractor := extraction.NewExtractor(testFS, "test.tar.xz", "", nil)
+				extractor := extraction.NewExtractor(testFS, "test.tar.xz", "", nil, extraction.BuiltinLimits)
 				Expect(extractor).To(BeAssignableToTypeOf(&extraction.ArchiveExtractor{}))
 			})
 
 			It("should select the correct extractor for .tar.zst files", func() {
-				extractor := extraction.NewExtractor(testFS, "test.tar.zst", "", nil)
+				extractor := extraction.NewExtractor(testFS, "test.tar.zst", "", nil, extraction.BuiltinLimits)
 				Expect(extractor).To(BeAssignableToTypeOf(&extraction.ArchiveExtractor{}))
 			})
 
 			It("should select the correct extractor for .tar files", func() {
-				extractor := extraction.NewExtractor(testFS, "test.tar", "", nil)
+				extractor := extraction.NewExtractor(testFS, "test.tar", "", nil, extraction.BuiltinLimits)
 				Expect(extractor).To(BeAssignableToTypeOf(&extraction.ArchiveExtractor{}))
 			})
 
 			It("should select the correct extractor for .zip files", func() {
-				extractor := extraction.NewExtractor(testFS, "test.zip", "", nil)
+				extractor := extraction.NewExtractor(testFS, "test.zip", "", nil, extraction.BuiltinLimits)
 				Expect(extractor).To(BeAssignableToTypeOf(&extraction.ArchiveExtractor{}))
 			})
 
 			It("should select the correct extractor for .bz2 files", func() {
-				extractor := extraction.NewExtractor(testFS, "test.bz2", "", nil)
+				extractor := extraction.NewExtractor(testFS, "test.bz2", "", nil, extraction.BuiltinLimits)
 				Expect(extractor).To(BeAssignableToTypeOf(&extraction.SingleFileExtractor{}))
 			})
 
 			It("should select the correct extractor for .xz files", func() {
-				extractor := extraction.NewExtractor(testFS, "test.xz", "", nil)
+				extractor := extraction.NewExtractor(testFS, "test.xz", "", nil, extraction.BuiltinLimits)
 				Expect(extractor).To(BeAssignableToTypeOf(&extraction.SingleFileExtractor{}))
 			})
 
 			It("should select the correct extractor for .zst files", func() {
-				extractor := extraction.NewExtractor(testFS, "test.zst", "", nil)
+				extractor := extraction.NewExtractor(testFS, "test.zst", "", nil, extraction.BuiltinLimits)
 				Expect(extractor).To(BeAssignableToTypeOf(&extraction.SingleFileExtractor{}))
 			})
 		})
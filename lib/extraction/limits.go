@@ -0,0 +1,175 @@
+package extraction
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/permafrost-dev/zeget/lib/archives"
+)
+
+// Limits bound the data extracted from an archive or compressed file, which
+// guards against decompression bombs. A limit of zero is not enforced.
+type Limits struct {
+	// MaxFileSize is the size of the largest file read.
+	MaxFileSize int64
+	// MaxTotalSize is the size of all files read from an archive together,
+	// including archives nested in it.
+	MaxTotalSize int64
+	// MaxRatio is how many times larger than the archive the files read from
+	// it may be. It only applies once more than ratioThreshold bytes are read,
+	// as small files compress well.
+	MaxRatio float64
+}
+
+// BuiltinLimits are the limits used unless configured otherwise.
+var BuiltinLimits = Limits{
+	MaxFileSize:  1 << 30,
+	MaxTotalSize: 4 << 30,
+	MaxRatio:     100,
+}
+
+const ratioThreshold = 16 << 20
+
+// usage tracks the data read from an archive, and from the archives nested in
+// it, against the limits.
+type usage struct {
+	limits Limits
+	size   int64
+	total  int64
+}
+
+func newUsage(limits Limits, size int) *usage {
+	return &usage{limits: limits, size: int64(size)}
+}
+
+// add accounts for n bytes read from the file called name.
+func (u *usage) add(name string, n int64) error {
+	if u.limits.MaxFileSize > 0 && n > u.limits.MaxFileSize {
+		return &FileSizeError{Name: name, Limit: u.limits.MaxFileSize}
+	}
+
+	u.total += n
+	if u.limits.MaxTotalSize > 0 && u.total > u.limits.MaxTotalSize {
+		return &TotalSizeError{Limit: u.limits.MaxTotalSize}
+	}
+	if u.limits.MaxRatio > 0 && u.total > ratioThreshold && float64(u.total) > u.limits.MaxRatio*float64(u.size) {
+		return &RatioError{Size: u.total, ArchiveSize: u.size, Limit: u.limits.MaxRatio}
+	}
+
+	return nil
+}
+
+// isLimitError reports whether err is due to one of the limits.
+func isLimitError(err error) bool {
+	var fileErr *FileSizeError
+	var totalErr *TotalSizeError
+	var ratioErr *RatioError
+
+	return errors.As(err, &fileErr) || errors.As(err, &totalErr) || errors.As(err, &ratioErr)
+}
+
+// read reads the current file of an archive, without reading more than the
+// size limit of a file into memory where the archive allows.
+func (u *usage) read(ar archives.Archive, name string) ([]byte, error) {
+	var data []byte
+	var err error
+	if u.limits.MaxFileSize > 0 {
+		data, err = archives.ReadAtMost(ar, u.limits.MaxFileSize)
+	} else {
+		data, err = ar.ReadAll()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("extract: %w", err)
+	}
+
+	return data, u.add(name, int64(len(data)))
+}
+
+// readStream reads a decompressed file like read.
+func (u *usage) readStream(r io.Reader, name string) ([]byte, error) {
+	if u.limits.MaxFileSize > 0 {
+		r = io.LimitReader(r, u.limits.MaxFileSize+1)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, u.add(name, int64(len(data)))
+}
+
+// safeJoin returns the path of the file called name in an archive once
+// extracted to the directory to, or an error if it would be outside of it.
+func safeJoin(to, name string) (string, error) {
+	name = strings.TrimSuffix(name, "/")
+	if name == "" {
+		return to, nil
+	}
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", &UnsafePathError{Name: name}
+	}
+
+	return filepath.Join(to, filepath.FromSlash(name)), nil
+}
+
+// checkLinks returns an error if one of the links extracted from an archive,
+// by name, points outside of the directory it is extracted to. Links are
+// created as symbolic links relative to their directory, and are resolved
+// through each other, as a link may lead out through another one.
+func checkLinks(links map[string]string) error {
+	names := make([]string, 0, len(links))
+	for name := range links {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		target := links[name]
+		if path.IsAbs(target) || filepath.IsAbs(target) || !confined(links, path.Dir(name)+"/"+target) {
+			return &LinkError{Name: name, Target: target}
+		}
+	}
+
+	return nil
+}
+
+// confined reports whether a path stays within the directory it is relative
+// to once the links in it are resolved.
+func confined(links map[string]string, name string) bool {
+	parts := strings.Split(name, "/")
+	var dir []string
+
+	for hops := 0; len(parts) > 0; {
+		part := parts[0]
+		parts = parts[1:]
+
+		switch part {
+		case "", ".":
+		case "..":
+			if len(dir) == 0 {
+				return false
+			}
+			dir = dir[:len(dir)-1]
+		default:
+			target, ok := links[path.Join(path.Join(dir...), part)]
+			if !ok {
+				dir = append(dir, part)
+				continue
+			}
+
+			// links which loop are not followed
+			if hops++; hops > 40 || path.IsAbs(target) {
+				return false
+			}
+			parts = append(strings.Split(target, "/"), parts...)
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,238 @@
+package extraction_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/permafrost-dev/zeget/lib/archives"
+	"github.com/permafrost-dev/zeget/lib/extraction"
+	"github.com/twpayne/go-vfs/v5/vfst"
+)
+
+var _ = Describe("Extraction safety", func() {
+	var testFS *vfst.TestFS
+	var cleanup func()
+	var err error
+
+	BeforeEach(func() {
+		testFS, cleanup, err = vfst.NewTestFS(map[string]any{"/out": &vfst.Dir{Perm: 0o755}})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		cleanup()
+	})
+
+	// extractDir extracts the tool directory of an archive to /out.
+	extractDir := func(name string, data []byte) error {
+		gc, err := extraction.NewGlobChooser("tool")
+		Expect(err).NotTo(HaveOccurred())
+
+		ef, _, err := extraction.NewExtractor(testFS, name, "tool", gc, extraction.BuiltinLimits).Extract(data, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ef.Dir).To(BeTrue())
+
+		return ef.Extract("/out")
+	}
+
+	exists := func(name string) bool {
+		_, err := testFS.Lstat(name)
+		return err == nil
+	}
+
+	zeros := make([]byte, 20<<20)
+
+	Describe("paths", func() {
+		It("should reject files leading out of the target directory in tarballs", func() {
+			err := extractDir("tool.tar.gz", makeTarGz(
+				testFile{name: "tool/", mode: 0o755},
+				testFile{name: "tool/../../evil", mode: 0o644, data: []byte("evil")},
+			))
+
+			var pathErr *extraction.UnsafePathError
+			Expect(err).To(BeAssignableToTypeOf(pathErr))
+			Expect(err).To(MatchError(&extraction.UnsafePathError{Name: "../../evil"}))
+			Expect(exists("/evil")).To(BeFalse())
+		})
+
+		It("should reject files leading out of the target directory in zip files", func() {
+			err := extractDir("tool.zip", makeZip(
+				testFile{name: "tool/", mode: 0o755 | fs.ModeDir},
+				testFile{name: "tool/bin/../../../evil", mode: 0o644, data: []byte("evil")},
+			))
+
+			Expect(err).To(MatchError(&extraction.UnsafePathError{Name: "bin/../../../evil"}))
+			Expect(exists("/evil")).To(BeFalse())
+		})
+
+		It("should reject absolute paths", func() {
+			err := extractDir("tool.tar.gz", makeTarGz(
+				testFile{name: "tool/", mode: 0o755},
+				testFile{name: "tool//etc/evil", mode: 0o644, data: []byte("evil")},
+			))
+
+			Expect(err).To(MatchError(&extraction.UnsafePathError{Name: "/etc/evil"}))
+		})
+	})
+
+	Describe("links", func() {
+		DescribeTable("rejecting links leading out of the target directory",
+			func(links ...testFile) {
+				entries := append([]testFile{
+					{name: "tool/", mode: 0o755},
+					{name: "tool/bin/tool", mode: 0o755, data: []byte("tool")},
+				}, links...)
+
+				err := extractDir("tool.tar.gz", makeTarGz(entries...))
+
+				var linkErr *extraction.LinkError
+				Expect(err).To(BeAssignableToTypeOf(linkErr))
+				Expect(exists("/out/evil")).To(BeFalse())
+
+				// files are still extracted, but no link is created
+				Expect(exists("/out/bin/tool")).To(BeTrue())
+			},
+			Entry("relative", testFile{name: "tool/evil", link: "../../etc/passwd"}),
+			Entry("absolute", testFile{name: "tool/evil", link: "/etc/passwd"}),
+			Entry("through another link",
+				testFile{name: "tool/bin/up", link: ".."},
+				testFile{name: "tool/evil", link: "bin/up/../.."}),
+			Entry("looping", testFile{name: "tool/evil", link: "evil/x"}),
+		)
+
+		It("should create links within the target directory", func() {
+			err := extractDir("tool.tar.gz", makeTarGz(
+				testFile{name: "tool/", mode: 0o755},
+				testFile{name: "tool/bin/tool", mode: 0o755, data: []byte("tool")},
+				testFile{name: "tool/lib/tool", link: "../bin/tool"},
+				testFile{name: "tool/current", link: "."},
+			))
+			Expect(err).NotTo(HaveOccurred())
+
+			target, err := testFS.Readlink("/out/lib/tool")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(target).To(Equal("../bin/tool"))
+		})
+	})
+
+	Describe("limits", func() {
+		It("should reject files larger than the file size limit", func() {
+			extractor := extraction.NewArchiveExtractor(&extraction.BinaryChooser{Tool: "tool"}, archives.NewTarArchive, archives.Gunzip, testFS)
+			extractor.Limits = extraction.Limits{MaxFileSize: 8}
+
+			_, _, err := extractor.Extract(makeTarGz(testFile{name: "bin/tool", mode: 0o755, data: bytes.Repeat([]byte("x"), 100)}), false)
+			Expect(err).To(MatchError(&extraction.FileSizeError{Name: "bin/tool", Limit: 8}))
+		})
+
+		It("should reject archives larger than the total size limit", func() {
+			gc, err := extraction.NewGlobChooser("tool")
+			Expect(err).NotTo(HaveOccurred())
+			extractor := extraction.NewArchiveExtractor(gc, archives.NewTarArchive, archives.Gunzip, testFS)
+			extractor.Limits = extraction.Limits{MaxTotalSize: 100}
+
+			ef, _, err := extractor.Extract(makeTarGz(
+				testFile{name: "tool/", mode: 0o755},
+				testFile{name: "tool/a", mode: 0o644, data: bytes.Repeat([]byte("a"), 60)},
+				testFile{name: "tool/b", mode: 0o644, data: bytes.Repeat([]byte("b"), 60)},
+			), false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.Extract("/out")).To(MatchError(&extraction.TotalSizeError{Limit: 100}))
+		})
+
+		It("should count nested archives in the total size", func() {
+			extractor := extraction.NewArchiveExtractor(&extraction.BinaryChooser{Tool: "tool"}, archives.NewZipArchive, archives.NoDecompress, testFS)
+			extractor.Limits = extraction.Limits{MaxTotalSize: 1000}
+
+			inner := makeTarGz(testFile{name: "bin/tool", mode: 0o755, data: bytes.Repeat([]byte("x"), 1000)})
+			_, _, err := extractor.Extract(makeZip(testFile{name: "tool.tar.gz", mode: 0o644, data: inner}), false)
+			Expect(err).To(MatchError(&extraction.TotalSizeError{Limit: 1000}))
+		})
+
+		It("should count directories of nested archives in the total size", func() {
+			inner := makeTarGz(
+				testFile{name: "tool/", mode: 0o755},
+				testFile{name: "tool/a", mode: 0o644, data: bytes.Repeat([]byte("a"), 60)},
+				testFile{name: "tool/b", mode: 0o644, data: bytes.Repeat([]byte("b"), 60)},
+			)
+			gc, err := extraction.NewGlobChooser("inner.tar.gz/tool")
+			Expect(err).NotTo(HaveOccurred())
+			limits := extraction.Limits{MaxTotalSize: int64(len(inner)) + 100}
+
+			ef, _, err := extraction.NewExtractor(testFS, "tool.zip", "tool", gc, limits).Extract(makeZip(testFile{name: "inner.tar.gz", mode: 0o644, data: inner}), false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.Dir).To(BeTrue())
+			Expect(ef.Extract("/out")).To(MatchError(&extraction.TotalSizeError{Limit: limits.MaxTotalSize}))
+		})
+
+		It("should count a nested archive which is the only candidate once", func() {
+			inner := makeTarGz(testFile{name: "bin/tool", mode: 0o755, data: bytes.Repeat([]byte("x"), 100)})
+			limits := extraction.Limits{MaxTotalSize: int64(len(inner)) + 100}
+
+			ef, _, err := extraction.NewExtractor(testFS, "tool.zip", "tool", &extraction.BinaryChooser{Tool: "tool"}, limits).Extract(makeZip(
+				testFile{name: "README.md", mode: 0o644},
+				testFile{name: "tool-linux.tar.gz", mode: 0o755, data: inner},
+			), false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ef.ArchiveName).To(Equal("tool-linux.tar.gz/bin/tool"))
+		})
+
+		It("should limit files of disk images without decoding them whole", func() {
+			// a 4 GiB file in a partition of zeros, stored in under 2 KiB
+			data, err := os.ReadFile("../../test/archives/bomb.dmg")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = extraction.NewExtractor(testFS, "tool.dmg", "tool", &extraction.BinaryChooser{Tool: "tool"}, extraction.Limits{MaxFileSize: 1 << 20}).Extract(data, false)
+			Expect(err).To(MatchError(&extraction.FileSizeError{Name: "tool", Limit: 1 << 20}))
+		})
+
+		It("should reject disk images declaring partitions larger than their data", func() {
+			data, err := os.ReadFile("../../test/archives/sectors.dmg")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, err = extraction.NewExtractor(testFS, "tool", "tool", &extraction.BinaryChooser{Tool: "tool"}, extraction.BuiltinLimits).Extract(data, false)
+			Expect(err).To(MatchError(ContainSubstring("partition of 4294967296 sectors larger than its chunks")))
+		})
+
+		It("should use the limits passed to NewExtractor", func() {
+			data := makeTarGz(testFile{name: "bin/tool", mode: 0o755, data: bytes.Repeat([]byte("x"), 100)})
+
+			_, _, err := extraction.NewExtractor(testFS, "tool.tar.gz", "tool", &extraction.BinaryChooser{Tool: "tool"}, extraction.Limits{MaxFileSize: 8}).Extract(data, false)
+			Expect(err).To(MatchError(&extraction.FileSizeError{Name: "bin/tool", Limit: 8}))
+
+			_, _, err = extraction.NewExtractor(testFS, "tool", "tool", &extraction.BinaryChooser{Tool: "tool"}, extraction.Limits{MaxFileSize: 8}).Extract(data, false)
+			Expect(err).To(MatchError(&extraction.FileSizeError{Name: "bin/tool", Limit: 8}))
+		})
+
+		It("should reject archives beyond the compression ratio limit", func() {
+			data := makeZip(testFile{name: "tool", mode: 0o755, data: zeros})
+
+			_, _, err := extraction.NewExtractor(testFS, "tool.zip", "tool", &extraction.BinaryChooser{Tool: "tool"}, extraction.BuiltinLimits).Extract(data, false)
+			var ratioErr *extraction.RatioError
+			Expect(err).To(BeAssignableToTypeOf(ratioErr))
+
+			extractor := extraction.NewArchiveExtractor(&extraction.BinaryChooser{Tool: "tool"}, archives.NewZipArchive, archives.NoDecompress, testFS)
+			extractor.Limits = extraction.Limits{}
+			_, _, err = extractor.Extract(data, false)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should limit compressed files", func() {
+			buf := new(bytes.Buffer)
+			w := gzip.NewWriter(buf)
+			_, err := w.Write(zeros)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+
+			ef, _, err := extraction.NewExtractor(testFS, "tool.gz", "tool", nil, extraction.BuiltinLimits).Extract(buf.Bytes(), false)
+			Expect(err).NotTo(HaveOccurred())
+
+			var ratioErr *extraction.RatioError
+			Expect(ef.Extract("/tool")).To(BeAssignableToTypeOf(ratioErr))
+			Expect(exists("/tool")).To(BeFalse())
+		})
+	})
+})
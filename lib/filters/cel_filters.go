@@ -1,92 +1,117 @@
 package filters
 
 import (
-	"context"
 	"fmt"
-	"log"
+	"reflect"
+	"sync"
 
 	"github.com/google/cel-go/cel"
-	"github.com/google/cel-go/common/types"
-	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+	"github.com/permafrost-dev/zeget/lib/assets"
 )
 
-func ParseCELFilter(expression string) (*cel.Program, error) {
-	makeFetch := func(ctx any) cel.EnvOption {
-		fn := func(arg ref.Val) ref.Val {
-			return types.NewErr("stateful context not bound")
-		}
-		if ctx != nil {
-			fn = func(resource ref.Val) ref.Val {
-				return types.DefaultTypeAdapter.NativeToValue(
-					ctx.(context.Context).Value(string(resource.(types.String))),
-				)
-			}
-		}
-		return cel.Function("fetch",
-			cel.Overload("fetch_string",
-				[]*cel.Type{cel.StringType}, cel.StringType,
-				cel.UnaryBinding(fn),
-			),
-		)
-	}
-
-	// var adapter types.Adapter
+// A CELFilter selects assets with a CEL expression such as
+//
+//	name.endsWith(".tar.gz") && size < 50000000 && !release.prerelease
+//
+// Expressions can use the asset's `name`, `size`, `content_type` and
+// `download_count`, the `release.tag` and `release.prerelease` of its release,
+// and the target `os` and `arch` (empty for --system all).
+type CELFilter struct {
+	Expression string
+	program    cel.Program
+	err        error
+}
 
-	// // Define the slice of strings
-	// elements := []string{"apple", "banana", "cherry"}
+// celRelease is the release of an asset, typed so that unknown fields are
+// rejected when an expression is checked.
+type celRelease struct {
+	Tag        string `cel:"tag"`
+	Prerelease bool   `cel:"prerelease"`
+}
 
-	env, err := cel.NewEnv(
+var celEnvironment = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		ext.NativeTypes(reflect.TypeOf(celRelease{}), ext.ParseStructTags(true)),
 		cel.Variable("name", cel.StringType),
-		cel.Variable("group", cel.StringType),
-		cel.Variable("i", cel.StringType),
-		cel.Variable("you", cel.StringType),
-		cel.Variable("assets", cel.ListType(cel.StringType)),
-		// Function to generate a greeting from one person to another: i.greet(you)
-		cel.Function("greet",
-			cel.MemberOverload("string_greet_string", []*cel.Type{cel.StringType, cel.StringType}, cel.StringType,
-				cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
-					return types.String(fmt.Sprintf("Hello %s! Nice to meet you, I'm %s.\n", rhs, lhs))
-				}),
-			),
-		),
-		makeFetch(nil),
+		cel.Variable("size", cel.IntType),
+		cel.Variable("content_type", cel.StringType),
+		cel.Variable("download_count", cel.IntType),
+		cel.Variable("release", cel.ObjectType("filters.celRelease")),
+		cel.Variable("os", cel.StringType),
+		cel.Variable("arch", cel.StringType),
 	)
+})
 
+// NewCELFilter compiles and type-checks expression, which must evaluate to a bool.
+func NewCELFilter(expression string) (*CELFilter, error) {
+	env, err := celEnvironment()
 	if err != nil {
-		log.Fatalf("environment creation error: %s", err)
+		return nil, fmt.Errorf("create CEL environment: %w", err)
 	}
 
-	ast, issues := env.Compile(`assets.filter(asset, asset.startsWith("a")).map(a, a.size())`)
+	ast, issues := env.Compile(expression)
 	if issues != nil && issues.Err() != nil {
-		log.Fatalf("type-check error: %s", issues.Err())
+		return nil, fmt.Errorf("invalid asset expression '%s': %w", expression, issues.Err())
+	}
+
+	if t := ast.OutputType(); !t.IsExactType(cel.BoolType) && !t.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("invalid asset expression '%s': must evaluate to a bool, not %s", expression, t)
 	}
 
-	prg, err := env.Program(ast)
+	program, err := env.Program(ast)
 	if err != nil {
-		log.Fatalf("program construction error: %s", err)
+		return nil, fmt.Errorf("invalid asset expression '%s': %w", expression, err)
 	}
 
-	return &prg, nil
+	return &CELFilter{Expression: expression, program: program}, nil
 }
 
-func CELTest() {
-	prg, err := ParseCELFilter(`assets.some(asset, asset.startsWith("a"))`)
+// Match evaluates the expression for an asset and the target system.
+func (f *CELFilter) Match(item assets.Asset, goos string, goarch string) (bool, error) {
+	out, _, err := f.program.Eval(map[string]interface{}{
+		"name":           item.Name,
+		"size":           item.Size,
+		"content_type":   item.ContentType,
+		"download_count": item.DownloadCount,
+		"release": celRelease{
+			Tag:        item.ReleaseTag,
+			Prerelease: item.Prerelease,
+		},
+		"os":   goos,
+		"arch": goarch,
+	})
 	if err != nil {
-		log.Fatalf("error parsing CEL filter: %s", err)
+		return false, fmt.Errorf("evaluate asset expression '%s' for %s: %w", f.Expression, item.Name, err)
 	}
 
-	// Evaluate the program in a context.
-	out, details, err := (*prg).Eval(map[string]interface{}{
-		"name":   "/groups/abc",
-		"group":  "abc",
-		"i":      "CEL",
-		"assets": []string{"abc", "def", "ghi", "aaadd"},
-		"you":    func() ref.Val { return types.String("world") },
-	})
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("asset expression '%s' returned %v for %s instead of a bool", f.Expression, out.Value(), item.Name)
+	}
 
-	if err != nil {
-		log.Fatalf("error evaluating program: %s", err)
+	return result, nil
+}
+
+// Filter returns the expression as a filter for the given target system.
+// Assets the expression cannot be evaluated for do not pass, and the first
+// error is reported by Err.
+func (f *CELFilter) Filter(goos string, goarch string) *Filter {
+	return &Filter{
+		Name: "asset-expr",
+		Handler: func(item assets.Asset, _ []string) bool {
+			matched, err := f.Match(item, goos, goarch)
+			if err != nil && f.err == nil {
+				f.err = err
+			}
+			return err == nil && matched
+		},
+		Action:     FilterActionInclude,
+		Definition: f.Expression,
 	}
+}
 
-	log.Printf("result: %v, details: %v", out, details)
+// Err returns the first error evaluating the expression as a filter.
+func (f *CELFilter) Err() error {
+	return f.err
 }
@@ -0,0 +1,87 @@
+package filters_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/permafrost-dev/zeget/lib/assets"
+	. "github.com/permafrost-dev/zeget/lib/filters"
+)
+
+var _ = Describe("CELFilter", func() {
+	asset := assets.Asset{
+		Name:          "tool_1.2.0_linux_amd64.tar.gz",
+		Size:          12 << 20,
+		ContentType:   "application/gzip",
+		DownloadCount: 1500,
+		ReleaseTag:    "v1.2.0",
+		Prerelease:    false,
+	}
+
+	DescribeTable("evaluating expressions",
+		func(expression string, expected bool) {
+			filter, err := NewCELFilter(expression)
+			Expect(err).NotTo(HaveOccurred())
+
+			matched, err := filter.Match(asset, "linux", "amd64")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matched).To(Equal(expected))
+		},
+		Entry("name functions", `name.endsWith(".tar.gz") && name.contains(os)`, true),
+		Entry("name regular expressions", `name.matches("_(darwin|windows)_")`, false),
+		Entry("size", `size < 10 * 1024 * 1024`, false),
+		Entry("content type", `content_type == "application/gzip"`, true),
+		Entry("download count", `download_count >= 1000`, true),
+		Entry("release tag", `release.tag.startsWith("v1.")`, true),
+		Entry("prerelease", `!release.prerelease`, true),
+		Entry("target system", `os == "linux" && arch in ["amd64", "arm64"]`, true),
+		Entry("bool fields as results", `release.prerelease`, false),
+	)
+
+	DescribeTable("reporting invalid expressions",
+		func(expression string, message string) {
+			_, err := NewCELFilter(expression)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("syntax errors", `name.endsWith(".zip"`, "Syntax error"),
+		Entry("unknown variables", `nme == "tool"`, "undeclared reference to 'nme'"),
+		Entry("type errors", `size == "big"`, "no matching overload"),
+		Entry("non-bool results", `size + 1`, "must evaluate to a bool, not int"),
+		Entry("unknown release fields", `release.tagg == "v1"`, "undefined field 'tagg'"),
+		Entry("release field types", `release.prerelease == "yes"`, "no matching overload"),
+	)
+
+	It("should report runtime errors", func() {
+		filter, err := NewCELFilter(`size / 0 == 1`)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = filter.Match(asset, "linux", "amd64")
+		Expect(err).To(MatchError(ContainSubstring("division by zero")))
+	})
+
+	It("should reject non-bool dynamic results at runtime", func() {
+		filter, err := NewCELFilter(`dyn(release.tag)`)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = filter.Match(asset, "linux", "amd64")
+		Expect(err).To(MatchError(ContainSubstring("instead of a bool")))
+	})
+
+	It("should act as a filter for the target system", func() {
+		filter, err := NewCELFilter(`name.contains(arch)`)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(filter.Filter("linux", "amd64").Apply(asset)).To(BeTrue())
+		Expect(filter.Filter("linux", "arm64").Apply(asset)).To(BeFalse())
+		Expect(filter.Filter("linux", "arm64").Definition).To(Equal(`name.contains(arch)`))
+		Expect(filter.Err()).NotTo(HaveOccurred())
+	})
+
+	It("should keep the first error of the filter", func() {
+		filter, err := NewCELFilter(`size / (download_count - 1500) == 1`)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(filter.Filter("linux", "amd64").Apply(asset)).To(BeFalse())
+		Expect(filter.Err()).To(MatchError(ContainSubstring("division by zero")))
+	})
+})
@@ -0,0 +1,124 @@
+package filters_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/permafrost-dev/zeget/lib/assets"
+	"github.com/permafrost-dev/zeget/lib/detectors"
+	. "github.com/permafrost-dev/zeget/lib/filters"
+)
+
+var releaseAssets = []assets.Asset{
+	{Name: "tool_1.0.0_linux_amd64.tar.gz", Size: 5 << 20},
+	{Name: "tool_1.0.0_linux_amd64_musl.tar.gz", Size: 4 << 20},
+	{Name: "tool_1.0.0_linux_arm64.tar.gz", Size: 5 << 20},
+	{Name: "tool_1.0.0_darwin_arm64.zip", Size: 6 << 20},
+	{Name: "tool_1.0.0_windows_amd64.zip", Size: 7 << 20},
+	{Name: "tool_1.0.0_x86_64.deb", Size: 80 << 20},
+	{Name: "checksums, signed.txt"},
+}
+
+func applyDefinitions(definitions string) []string {
+	filters, err := NewParser().WithSystemMatcher(detectors.SystemMatcher{}).ParseDefinitions(definitions)
+	Expect(err).NotTo(HaveOccurred())
+
+	filter := AllOf(filters...)
+	names := []string{}
+	for _, a := range releaseAssets {
+		if filter.Apply(a) {
+			names = append(names, a.Name)
+		}
+	}
+
+	return names
+}
+
+var _ = Describe("Filter expressions", func() {
+	DescribeTable("selecting assets",
+		func(definitions string, expected []string) {
+			Expect(applyDefinitions(definitions)).To(Equal(expected))
+		},
+		Entry("contains", "contains(musl)",
+			[]string{"tool_1.0.0_linux_amd64_musl.tar.gz"}),
+		Entry("has is an alias of contains", "has(DARWIN)",
+			[]string{"tool_1.0.0_darwin_arm64.zip"}),
+		Entry("any matches substrings and globs", "any(musl, *.deb)",
+			[]string{"tool_1.0.0_linux_amd64_musl.tar.gz", "tool_1.0.0_x86_64.deb"}),
+		Entry("all requires every pattern", "all(linux, *.tar.gz, arm64)",
+			[]string{"tool_1.0.0_linux_arm64.tar.gz"}),
+		Entry("none excludes matches", "none(linux, *.txt)",
+			[]string{"tool_1.0.0_darwin_arm64.zip", "tool_1.0.0_windows_amd64.zip", "tool_1.0.0_x86_64.deb"}),
+		Entry("glob matches whole names", "glob(*_arm64.*)",
+			[]string{"tool_1.0.0_linux_arm64.tar.gz", "tool_1.0.0_darwin_arm64.zip"}),
+		Entry("regex", `regex("^tool_[\d.]+_(darwin|windows)_")`,
+			[]string{"tool_1.0.0_darwin_arm64.zip", "tool_1.0.0_windows_amd64.zip"}),
+		Entry("ext matches multi-part extensions", "ext(.tar.gz, deb)",
+			[]string{"tool_1.0.0_linux_amd64.tar.gz", "tool_1.0.0_linux_amd64_musl.tar.gz", "tool_1.0.0_linux_arm64.tar.gz", "tool_1.0.0_x86_64.deb"}),
+		Entry("size ranges", "size(>4MB, <=6M)",
+			[]string{"tool_1.0.0_linux_amd64.tar.gz", "tool_1.0.0_linux_arm64.tar.gz", "tool_1.0.0_darwin_arm64.zip"}),
+		Entry("os and arch", "os(linux) and arch(amd64)",
+			[]string{"tool_1.0.0_linux_amd64.tar.gz", "tool_1.0.0_linux_amd64_musl.tar.gz", "tool_1.0.0_x86_64.deb"}),
+		Entry("arch aliases", "arch(amd64) and ext(.deb)",
+			[]string{"tool_1.0.0_x86_64.deb"}),
+		Entry("not", "not contains(tool)",
+			[]string{"checksums, signed.txt"}),
+		Entry("and binds tighter than or", "contains(darwin) or contains(linux) and contains(musl)",
+			[]string{"tool_1.0.0_linux_amd64_musl.tar.gz", "tool_1.0.0_darwin_arm64.zip"}),
+		Entry("parentheses", "(contains(darwin) || contains(linux)) && !contains(musl) && ext(zip)",
+			[]string{"tool_1.0.0_darwin_arm64.zip"}),
+		Entry("quoted arguments with commas", `contains("checksums, signed")`,
+			[]string{"checksums, signed.txt"}),
+		Entry("escaped quotes", `not contains('it\'s') and contains('.deb')`,
+			[]string{"tool_1.0.0_x86_64.deb"}),
+		Entry("semicolons combine filters with and", "os(linux); not contains(musl); ext(.tar.gz)",
+			[]string{"tool_1.0.0_linux_amd64.tar.gz", "tool_1.0.0_linux_arm64.tar.gz"}),
+		Entry("not with parentheses", "not(contains(tool))",
+			[]string{"checksums, signed.txt"}),
+	)
+
+	It("should not match assets of unknown size", func() {
+		filter, err := NewParser().ParseDefinition("size(<1GB)")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filter.Apply(assets.Asset{Name: "unknown"})).To(BeFalse())
+	})
+
+	It("should keep the source text as the definition", func() {
+		filter, err := NewParser().ParseDefinition(`  ext(.zip)  or contains( "a,b" )`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filter.Definition).To(Equal(`(ext(.zip)) or (contains( "a,b" ))`))
+	})
+
+	DescribeTable("reporting parse errors",
+		func(definition string, message string) {
+			_, err := NewParser().WithSystemMatcher(detectors.SystemMatcher{}).ParseDefinitions(definition)
+			Expect(err).To(BeAssignableToTypeOf(&ParseError{}))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("unknown functions", "exe(.zip)", "unknown filter function 'exe'"),
+		Entry("missing arguments", "contains()", "contains() requires at least one argument"),
+		Entry("empty arguments", "contains(a,,b)", "contains() has an empty argument"),
+		Entry("missing parentheses", "contains musl", "expected '(' after 'contains'"),
+		Entry("unclosed calls", "contains(musl", "expected ')'"),
+		Entry("unclosed groups", "(contains(musl)", "expected ')'"),
+		Entry("unterminated strings", `contains("musl)`, "unterminated string"),
+		Entry("dangling operators", "contains(musl) and", "expected a filter function"),
+		Entry("trailing input", "contains(musl) contains(x)", "unexpected 'c'"),
+		Entry("keywords without word boundaries", "contains(musl) orcontains(x)", "unexpected 'o'"),
+		Entry("invalid regular expressions", "regex([)", "regex()"),
+		Entry("invalid globs", "glob('[')", "glob()"),
+		Entry("invalid sizes", "size(big)", "invalid size condition 'big'"),
+		Entry("unknown systems", "os(beos)", "unsupported target OS: beos"),
+		Entry("unknown architectures", "arch(vax)", "unsupported target arch: vax"),
+	)
+
+	It("should report the position of errors", func() {
+		_, err := NewParser().ParseDefinition("ext(.zip) and nope(x)")
+		Expect(err).To(MatchError("invalid filter 'ext(.zip) and nope(x)' at position 15: unknown filter function 'nope'"))
+	})
+
+	It("should require a system matcher for os() and arch()", func() {
+		_, err := NewParser().ParseDefinition("os(linux)")
+		Expect(err).To(MatchError(ContainSubstring("os() is not available")))
+	})
+})
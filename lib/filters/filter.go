@@ -6,13 +6,14 @@ import (
 	"github.com/permafrost-dev/zeget/lib/assets"
 )
 
+// A FilterHandler reports whether an asset matches the filter arguments.
 type FilterHandler func(assets.Asset, []string) bool
 
 type FilterAction byte
 
 const (
-	FilterActionInclude FilterAction = iota
-	FilterActionExclude FilterAction = iota
+	FilterActionInclude FilterAction = iota // keep the assets the handler matches
+	FilterActionExclude FilterAction = iota // remove the assets the handler matches
 )
 
 type Filter struct {
@@ -33,11 +34,66 @@ func NewFilter(name string, handler FilterHandler, action FilterAction, args ...
 	}
 }
 
+// Apply returns true if the asset passes the filter.
 func (f *Filter) Apply(item assets.Asset) bool {
-	return f.Handler(item, f.Args)
+	matched := f.Handler(item, f.Args)
+
+	if f.Action == FilterActionExclude {
+		return !matched
+	}
+
+	return matched
 }
 
 func (f *Filter) WithArgs(args ...string) *Filter {
 	f.Args = args
 	return f
 }
+
+// AllOf returns a filter that passes assets passing all of the given filters.
+func AllOf(filters ...*Filter) *Filter {
+	return composite("and", filters, func(item assets.Asset) bool {
+		for _, f := range filters {
+			if !f.Apply(item) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// AnyOf returns a filter that passes assets passing any of the given filters.
+func AnyOf(filters ...*Filter) *Filter {
+	return composite("or", filters, func(item assets.Asset) bool {
+		for _, f := range filters {
+			if f.Apply(item) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Negate returns a filter that passes the assets the given filter does not.
+func Negate(filter *Filter) *Filter {
+	return &Filter{
+		Name:       "not",
+		Handler:    func(item assets.Asset, _ []string) bool { return filter.Apply(item) },
+		Action:     FilterActionExclude,
+		Definition: "not " + filter.Definition,
+	}
+}
+
+func composite(name string, filters []*Filter, apply func(assets.Asset) bool) *Filter {
+	definitions := make([]string, len(filters))
+	for i, f := range filters {
+		definitions[i] = "(" + f.Definition + ")"
+	}
+
+	return &Filter{
+		Name:       name,
+		Handler:    func(item assets.Asset, _ []string) bool { return apply(item) },
+		Action:     FilterActionInclude,
+		Definition: strings.Join(definitions, " "+name+" "),
+	}
+}
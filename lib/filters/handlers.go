@@ -1,23 +1,62 @@
 package filters
 
 import (
-	"path"
+	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/gobwas/glob"
 	"github.com/permafrost-dev/zeget/lib/assets"
 )
 
 var FilterMap = map[string]*Filter{
-	"all":  NewFilter("all", allHandler, FilterActionInclude),
-	"any":  NewFilter("any", anyHandler, FilterActionInclude),
-	"ext":  NewFilter("ext", extensionHandler, FilterActionInclude),
-	"none": NewFilter("none", noneHandler, FilterActionExclude),
-	"has":  NewFilter("has", hasHandler, FilterActionInclude),
+	"all":      NewFilter("all", allHandler, FilterActionInclude),
+	"any":      NewFilter("any", anyHandler, FilterActionInclude),
+	"contains": NewFilter("contains", containsHandler, FilterActionInclude),
+	"ext":      NewFilter("ext", extensionHandler, FilterActionInclude),
+	"glob":     NewFilter("glob", globHandler, FilterActionInclude),
+	"has":      NewFilter("has", containsHandler, FilterActionInclude),
+	"none":     NewFilter("none", anyHandler, FilterActionExclude),
+	"regex":    NewFilter("regex", regexHandler, FilterActionInclude),
+	"size":     NewFilter("size", sizeHandler, FilterActionInclude),
+}
+
+// validators check filter arguments while parsing, so that mistakes are
+// reported instead of silently matching nothing
+var validators = map[string]func(arg string) error{
+	"glob": func(arg string) error {
+		_, err := glob.Compile(arg)
+		return err
+	},
+	"regex": func(arg string) error {
+		_, err := regexp.Compile(arg)
+		return err
+	},
+	"size": func(arg string) error {
+		_, _, err := parseSizeCondition(arg)
+		return err
+	},
+}
+
+// matchPattern matches a glob pattern against the whole name, or a plain
+// string anywhere in the name, ignoring case.
+func matchPattern(name string, pattern string) bool {
+	if strings.ContainsAny(pattern, "*?[{") {
+		return globMatch(name, pattern)
+	}
+
+	return strings.Contains(strings.ToLower(name), strings.ToLower(pattern))
+}
+
+func globMatch(name string, pattern string) bool {
+	g, err := glob.Compile(strings.ToLower(pattern))
+	return err == nil && g.Match(strings.ToLower(name))
 }
 
 var anyHandler FilterHandler = func(item assets.Asset, args []string) bool {
 	for _, arg := range args {
-		if strings.EqualFold(item.Name, arg) {
+		if matchPattern(item.Name, arg) {
 			return true
 		}
 	}
@@ -27,7 +66,7 @@ var anyHandler FilterHandler = func(item assets.Asset, args []string) bool {
 
 var allHandler FilterHandler = func(item assets.Asset, args []string) bool {
 	for _, arg := range args {
-		if !strings.EqualFold(item.Name, arg) {
+		if !matchPattern(item.Name, arg) {
 			return false
 		}
 	}
@@ -35,34 +74,114 @@ var allHandler FilterHandler = func(item assets.Asset, args []string) bool {
 	return true
 }
 
-var hasHandler FilterHandler = func(item assets.Asset, args []string) bool {
+var containsHandler FilterHandler = func(item assets.Asset, args []string) bool {
 	for _, arg := range args {
-		if !strings.EqualFold(item.Name, arg) {
-			return false
+		if strings.Contains(strings.ToLower(item.Name), strings.ToLower(arg)) {
+			return true
 		}
 	}
 
-	return true
+	return false
 }
 
-var noneHandler FilterHandler = func(item assets.Asset, args []string) bool {
+var globHandler FilterHandler = func(item assets.Asset, args []string) bool {
 	for _, arg := range args {
-		if strings.EqualFold(item.Name, arg) {
-			return false
+		if globMatch(item.Name, arg) {
+			return true
 		}
 	}
 
-	return true
+	return false
+}
+
+var regexHandler FilterHandler = func(item assets.Asset, args []string) bool {
+	for _, arg := range args {
+		if re, err := regexp.Compile(arg); err == nil && re.MatchString(item.Name) {
+			return true
+		}
+	}
+
+	return false
 }
 
 var extensionHandler FilterHandler = func(item assets.Asset, args []string) bool {
-	extension := path.Ext(item.Name)
+	name := strings.ToLower(item.Name)
 
 	for _, arg := range args {
-		if strings.EqualFold(extension, arg) {
+		extension := strings.ToLower(arg)
+		if !strings.HasPrefix(extension, ".") {
+			extension = "." + extension
+		}
+		if strings.HasSuffix(name, extension) {
 			return true
 		}
 	}
 
 	return false
 }
+
+// sizeHandler matches assets whose size satisfies all conditions; assets of
+// unknown size never match.
+var sizeHandler FilterHandler = func(item assets.Asset, args []string) bool {
+	if item.Size <= 0 {
+		return false
+	}
+
+	for _, arg := range args {
+		op, size, err := parseSizeCondition(arg)
+		if err != nil {
+			return false
+		}
+
+		var ok bool
+		switch op {
+		case "<":
+			ok = item.Size < size
+		case "<=":
+			ok = item.Size <= size
+		case ">":
+			ok = item.Size > size
+		case ">=":
+			ok = item.Size >= size
+		default:
+			ok = item.Size == size
+		}
+
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+var sizeConditionPattern = regexp.MustCompile(`(?i)^(<=|>=|<|>|==|=)?\s*(\d+(?:\.\d+)?)\s*([kmgt]?)(i?b)?$`)
+
+var sizeUnits = map[string]float64{"": 1, "k": 1 << 10, "m": 1 << 20, "g": 1 << 30, "t": 1 << 40}
+
+// ParseSize parses sizes such as "10MB", "512k" or "1024". Units are powers
+// of 1024.
+func ParseSize(s string) (int64, error) {
+	op, size, err := parseSizeCondition(s)
+	if err != nil || op != "" {
+		return 0, fmt.Errorf("invalid size '%s', expected e.g. '10MB'", s)
+	}
+
+	return size, nil
+}
+
+// parseSizeCondition parses conditions such as ">10MB", "<=512k" or "1024".
+// Units are powers of 1024.
+func parseSizeCondition(s string) (string, int64, error) {
+	m := sizeConditionPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return "", 0, fmt.Errorf("invalid size condition '%s', expected e.g. '>10MB'", s)
+	}
+
+	value, err := strconv.ParseFloat(m[2], 64)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return m[1], int64(value * sizeUnits[strings.ToLower(m[3])]), nil
+}
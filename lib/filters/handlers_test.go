@@ -37,10 +37,10 @@ var _ = Describe("Filters", func() {
 			Expect(hasHandler(asset1, []string{"file2.exe"})).To(BeFalse())
 		})
 
-		It("noneHandler should return true if no arguments match the asset name", func() {
-			Expect(noneHandler(asset1, []string{"file2.exe"})).To(BeTrue())
-			Expect(noneHandler(asset2, []string{"file1.txt"})).To(BeTrue())
-			Expect(noneHandler(asset1, []string{"file1.txt"})).To(BeFalse())
+		It("noneHandler should match the assets that the none filter excludes", func() {
+			Expect(noneHandler(asset1, []string{"file2.exe"})).To(BeFalse())
+			Expect(noneHandler(asset1, []string{"file1.txt"})).To(BeTrue())
+			Expect(FilterMap["none"].Action).To(Equal(FilterActionExclude))
 		})
 
 		It("extensionHandler should return true if the extension matches the asset name", func() {
@@ -50,4 +50,15 @@ var _ = Describe("Filters", func() {
 		})
 	})
 
+	It("ParseSize should parse sizes with units", func() {
+		Expect(ParseSize("1024")).To(Equal(int64(1024)))
+		Expect(ParseSize("512k")).To(Equal(int64(512 << 10)))
+		Expect(ParseSize("1.5 GiB")).To(Equal(int64(3 << 29)))
+
+		_, err := ParseSize(">10MB")
+		Expect(err).To(HaveOccurred())
+		_, err = ParseSize("ten")
+		Expect(err).To(HaveOccurred())
+	})
+
 })
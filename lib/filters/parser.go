@@ -1,55 +1,361 @@
 package filters
 
 import (
-	"regexp"
+	"fmt"
 	"strings"
+	"unicode"
+
+	"github.com/permafrost-dev/zeget/lib/assets"
 )
 
+// A SystemMatcher reports whether an asset name is meant for a GOOS or GOARCH
+// value; it is used by the os() and arch() filters.
+type SystemMatcher interface {
+	MatchOS(goos string, name string) (bool, error)
+	MatchArch(goarch string, name string) (bool, error)
+}
+
+// A ParseError describes an invalid filter definition.
+type ParseError struct {
+	Definition string
+	Position   int
+	Message    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid filter '%s' at position %d: %s", e.Definition, e.Position+1, e.Message)
+}
+
+// A Parser parses filter expressions such as
+//
+//	ext(.tar.gz, .zip) and not (contains(musl) or size(>50MB))
+//
+// Expressions combine filter functions with `and` (`&&`), `or` (`||`), `not`
+// (`!`) and parentheses. Arguments may be quoted with single or double quotes
+// to include commas, parentheses or spaces.
 type Parser struct {
+	Systems SystemMatcher
 }
 
 func NewParser() *Parser {
 	return &Parser{}
 }
 
-func (p *Parser) ParseDefinitions(definitions string) []*Filter {
-	defs := strings.Split(definitions, ";")
+// WithSystemMatcher sets the matcher used by the os() and arch() filters.
+func (p *Parser) WithSystemMatcher(systems SystemMatcher) *Parser {
+	p.Systems = systems
+	return p
+}
 
+// ParseDefinitions parses filter expressions separated by semicolons. An
+// asset must pass all of the returned filters.
+func (p *Parser) ParseDefinitions(definitions string) ([]*Filter, error) {
+	s := &scanner{parser: p, input: []rune(definitions)}
 	filters := make([]*Filter, 0)
 
-	for _, def := range defs {
-		filter := p.ParseDefinition(def)
-		if filter != nil {
-			filters = append(filters, filter)
+	for {
+		s.skipSpace()
+		if s.done() {
+			return filters, nil
+		}
+		if s.consume(";") {
+			continue
+		}
+
+		filter, err := s.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+
+		s.skipSpace()
+		if !s.done() && !s.consume(";") {
+			return nil, s.errorf("unexpected '%c'", s.peek())
 		}
 	}
+}
+
+// ParseDefinition parses a single filter expression, like "any(abc,def)" or
+// "not ext(.deb)".
+func (p *Parser) ParseDefinition(definition string) (*Filter, error) {
+	s := &scanner{parser: p, input: []rune(definition)}
 
-	return filters
+	filter, err := s.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.skipSpace(); !s.done() {
+		return nil, s.errorf("unexpected '%c'", s.peek())
+	}
+
+	return filter, nil
+}
+
+type scanner struct {
+	parser *Parser
+	input  []rune
+	pos    int
+}
+
+func (s *scanner) errorf(format string, args ...interface{}) error {
+	return &ParseError{Definition: string(s.input), Position: s.pos, Message: fmt.Sprintf(format, args...)}
 }
 
-// ParseDefinition parses a string definition of a filter, like "always(abc,def)" or "never(.deb)"
-// and returns a Filter struct with the appropriate values set.
-func (p *Parser) ParseDefinition(definition string) *Filter {
-	re := regexp.MustCompile(`([a-zA-Z]+)\((.*)\)`)
-	matches := re.FindStringSubmatch(definition)
+func (s *scanner) done() bool {
+	return s.pos >= len(s.input)
+}
 
-	if len(matches) < 3 {
-		return nil
+func (s *scanner) peek() rune {
+	if s.done() {
+		return 0
 	}
+	return s.input[s.pos]
+}
 
-	name := matches[1]
-	argStr := matches[2]
-	args := strings.Split(argStr, ",")
+func (s *scanner) skipSpace() {
+	for !s.done() && unicode.IsSpace(s.peek()) {
+		s.pos++
+	}
+}
 
-	if FilterMap[name] == nil {
-		return nil
+func (s *scanner) consume(token string) bool {
+	s.skipSpace()
+	if strings.HasPrefix(string(s.input[s.pos:]), token) {
+		s.pos += len([]rune(token))
+		return true
+	}
+	return false
+}
+
+// consumeKeyword consumes a keyword that is not followed by other word characters.
+func (s *scanner) consumeKeyword(keyword string) bool {
+	start := s.pos
+	if s.readIdent() == keyword {
+		return true
+	}
+	s.pos = start
+	return false
+}
+
+func (s *scanner) readIdent() string {
+	s.skipSpace()
+	start := s.pos
+	for !s.done() && (unicode.IsLetter(s.peek()) || unicode.IsDigit(s.peek()) || s.peek() == '_') {
+		s.pos++
+	}
+	return string(s.input[start:s.pos])
+}
+
+func (s *scanner) parseOr() (*Filter, error) {
+	left, err := s.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for s.consumeKeyword("or") || s.consume("||") {
+		right, err := s.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = AnyOf(left, right)
+	}
+
+	return left, nil
+}
+
+func (s *scanner) parseAnd() (*Filter, error) {
+	left, err := s.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for s.consumeKeyword("and") || s.consume("&&") {
+		right, err := s.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = AllOf(left, right)
+	}
+
+	return left, nil
+}
+
+func (s *scanner) parseUnary() (*Filter, error) {
+	if s.consumeKeyword("not") || s.consume("!") {
+		filter, err := s.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Negate(filter), nil
+	}
+
+	return s.parsePrimary()
+}
+
+func (s *scanner) parsePrimary() (*Filter, error) {
+	if s.consume("(") {
+		filter, err := s.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !s.consume(")") {
+			return nil, s.errorf("expected ')'")
+		}
+		return filter, nil
+	}
+
+	start := s.pos
+	name := s.readIdent()
+	if name == "" {
+		if s.done() {
+			return nil, s.errorf("expected a filter function")
+		}
+		return nil, s.errorf("expected a filter function, found '%c'", s.peek())
+	}
+
+	if !s.consume("(") {
+		return nil, s.errorf("expected '(' after '%s'", name)
+	}
+
+	args, err := s.parseArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := s.parser.function(name, args)
+	if err != nil {
+		s.pos = start
+		return nil, s.errorf("%v", err)
+	}
+	filter.Definition = strings.TrimSpace(string(s.input[start:s.pos]))
+
+	return filter, nil
+}
+
+func (s *scanner) parseArgs() ([]string, error) {
+	args := []string{}
+
+	if s.consume(")") {
+		return args, nil
+	}
+
+	for {
+		arg, err := s.parseArg()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		if s.consume(",") {
+			continue
+		}
+		if s.consume(")") {
+			return args, nil
+		}
+		if s.done() {
+			return nil, s.errorf("expected ')'")
+		}
+		return nil, s.errorf("expected ',' or ')', found '%c'", s.peek())
+	}
+}
+
+func (s *scanner) parseArg() (string, error) {
+	s.skipSpace()
+
+	if quote := s.peek(); quote == '"' || quote == '\'' {
+		start := s.pos
+		s.pos++
+
+		var arg strings.Builder
+		for !s.done() {
+			c := s.input[s.pos]
+			s.pos++
+
+			switch {
+			case c == '\\' && !s.done() && (s.peek() == quote || s.peek() == '\\'):
+				arg.WriteRune(s.input[s.pos])
+				s.pos++
+			case c == quote:
+				return arg.String(), nil
+			default:
+				arg.WriteRune(c)
+			}
+		}
+
+		s.pos = start
+		return "", s.errorf("unterminated string")
+	}
+
+	start := s.pos
+	for !s.done() && !strings.ContainsRune(",()'\";", s.peek()) {
+		s.pos++
+	}
+
+	return strings.TrimSpace(string(s.input[start:s.pos])), nil
+}
+
+// function creates the filter for a function call.
+func (p *Parser) function(name string, args []string) (*Filter, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s() requires at least one argument", name)
+	}
+
+	for _, arg := range args {
+		if arg == "" {
+			return nil, fmt.Errorf("%s() has an empty argument", name)
+		}
+	}
+
+	if name == "os" || name == "arch" {
+		return p.systemFilter(name, args)
+	}
+
+	base := FilterMap[name]
+	if base == nil {
+		return nil, fmt.Errorf("unknown filter function '%s'", name)
+	}
+
+	if validate := validators[name]; validate != nil {
+		for _, arg := range args {
+			if err := validate(arg); err != nil {
+				return nil, fmt.Errorf("%s(): %v", name, err)
+			}
+		}
 	}
 
 	return &Filter{
-		Name:       FilterMap[name].Name,
-		Handler:    FilterMap[name].Handler,
-		Action:     FilterMap[name].Action,
-		Args:       args,
-		Definition: definition,
+		Name:    base.Name,
+		Handler: base.Handler,
+		Action:  base.Action,
+		Args:    args,
+	}, nil
+}
+
+func (p *Parser) systemFilter(name string, args []string) (*Filter, error) {
+	if p.Systems == nil {
+		return nil, fmt.Errorf("%s() is not available", name)
+	}
+
+	match := p.Systems.MatchOS
+	if name == "arch" {
+		match = p.Systems.MatchArch
 	}
+
+	for _, arg := range args {
+		if _, err := match(arg, ""); err != nil {
+			return nil, fmt.Errorf("%s(): %v", name, err)
+		}
+	}
+
+	handler := func(item assets.Asset, args []string) bool {
+		for _, arg := range args {
+			if ok, _ := match(arg, item.Name); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	return NewFilter(name, handler, FilterActionInclude, args...), nil
 }
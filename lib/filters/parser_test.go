@@ -46,8 +46,9 @@ var _ = Describe("Filters", func() {
 
 		It("should parse multiple definitions", func() {
 			definitions := "all(file1.txt);none(file2.exe)"
-			filters := parser.ParseDefinitions(definitions)
+			filters, err := parser.ParseDefinitions(definitions)
 
+			Expect(err).NotTo(HaveOccurred())
 			Expect(filters).To(HaveLen(2))
 			Expect(filters[0].Name).To(Equal("all"))
 			Expect(filters[1].Name).To(Equal("none"))
@@ -55,17 +56,19 @@ var _ = Describe("Filters", func() {
 
 		It("should parse a single definition", func() {
 			definition := "all(file1.txt)"
-			filter := parser.ParseDefinition(definition)
+			filter, err := parser.ParseDefinition(definition)
 
+			Expect(err).NotTo(HaveOccurred())
 			Expect(filter).NotTo(BeNil())
 			Expect(filter.Name).To(Equal("all"))
 			Expect(filter.Args).To(Equal([]string{"file1.txt"}))
 		})
 
-		It("should return nil for invalid definitions", func() {
+		It("should return an error for invalid definitions", func() {
 			definition := "invalid()"
-			filter := parser.ParseDefinition(definition)
+			filter, err := parser.ParseDefinition(definition)
 
+			Expect(err).To(HaveOccurred())
 			Expect(filter).To(BeNil())
 		})
 	})
@@ -0,0 +1,103 @@
+package finders
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	. "github.com/permafrost-dev/zeget/lib/assets"
+	"github.com/permafrost-dev/zeget/lib/download"
+	"github.com/permafrost-dev/zeget/lib/github"
+	"github.com/permafrost-dev/zeget/lib/utilities"
+)
+
+// A GithubActionsFinder finds the artifacts of the latest successful run of a
+// workflow, optionally restricted to a branch. Downloading artifacts requires
+// a GitHub token, even for public repositories.
+type GithubActionsFinder struct {
+	Finder
+
+	Repo     string
+	Workflow string
+	Branch   string
+}
+
+func NewGithubActionsFinder(ref *utilities.ActionsReference) *GithubActionsFinder {
+	return &GithubActionsFinder{
+		Repo:     ref.Repository.String(),
+		Workflow: ref.Workflow,
+		Branch:   ref.Branch,
+	}
+}
+
+func (f GithubActionsFinder) Find(client download.ClientContract) *FindResult {
+	run, err := f.LatestRun(client)
+	if err != nil {
+		return NewInvalidFindResult(err)
+	}
+
+	var list github.ArtifactList
+	artifactsURL := fmt.Sprintf("https://api.github.com/repos/%s/actions/runs/%d/artifacts?per_page=100", f.Repo, run.ID)
+	if err := getGithubJSON(client, artifactsURL, &list); err != nil {
+		return NewInvalidFindResult(err)
+	}
+
+	assets := make([]Asset, 0, len(list.Artifacts))
+	for _, a := range list.Artifacts {
+		if !a.Expired {
+			assets = append(assets, a.CopyToNewAsset())
+		}
+	}
+
+	if len(assets) == 0 {
+		return NewInvalidFindResult(fmt.Errorf("workflow run %d of %s has no unexpired artifacts", run.ID, f.Workflow))
+	}
+
+	return NewFindResult(assets, nil)
+}
+
+// LatestRun returns the most recent successful run of the workflow.
+func (f GithubActionsFinder) LatestRun(client download.ClientContract) (*github.WorkflowRun, error) {
+	query := url.Values{"status": {"success"}, "per_page": {"1"}}
+	if f.Branch != "" {
+		query.Set("branch", f.Branch)
+	}
+
+	var runs github.WorkflowRunList
+	runsURL := fmt.Sprintf("https://api.github.com/repos/%s/actions/workflows/%s/runs?%s", f.Repo, url.PathEscape(f.Workflow), query.Encode())
+	if err := getGithubJSON(client, runsURL, &runs); err != nil {
+		return nil, err
+	}
+
+	if len(runs.WorkflowRuns) == 0 {
+		return nil, fmt.Errorf("no successful runs found for workflow %s in %s", f.Workflow, f.Repo)
+	}
+
+	return &runs.WorkflowRuns[0], nil
+}
+
+func getGithubJSON(client download.ClientContract, url string, v interface{}) error {
+	resp, err := client.GetJSON(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &github.Error{
+			Status: resp.Status,
+			Code:   resp.StatusCode,
+			Body:   body,
+			URL:    url,
+		}
+	}
+
+	return json.Unmarshal(body, v)
+}
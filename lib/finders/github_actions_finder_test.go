@@ -0,0 +1,52 @@
+package finders_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/finders"
+	"github.com/permafrost-dev/zeget/lib/github"
+	. "github.com/permafrost-dev/zeget/lib/mockhttp"
+)
+
+var _ = Describe("GithubActionsFinder", func() {
+	var (
+		client HTTPClient
+		finder *GithubActionsFinder
+	)
+
+	BeforeEach(func() {
+		client = NewMockHTTPClient()
+		finder = &GithubActionsFinder{Repo: "owner/repo", Workflow: "build.yml", Branch: "main"}
+	})
+
+	AfterEach(func() {
+		client.Reset()
+	})
+
+	It("should return the unexpired artifacts of the latest successful run", func() {
+		client.AddJSONResponse("https://api.github.com/repos/owner/repo/actions/workflows/build.yml/runs", `{"total_count": 1, "workflow_runs": [{"id": 42, "head_branch": "main", "conclusion": "success"}]}`, 200)
+		client.AddJSONResponse("https://api.github.com/repos/owner/repo/actions/runs/42/artifacts", `{"total_count": 2, "artifacts": [
+			{"name": "tool-linux-amd64", "archive_download_url": "https://api.github.com/repos/owner/repo/actions/artifacts/1/zip", "expired": false},
+			{"name": "tool-old", "archive_download_url": "https://api.github.com/repos/owner/repo/actions/artifacts/2/zip", "expired": true}
+		]}`, 200)
+
+		result := finder.Find(client)
+
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets).To(HaveLen(1))
+		Expect(result.Assets[0].Name).To(Equal("tool-linux-amd64.zip"))
+		Expect(result.Assets[0].DownloadURL).To(Equal("https://api.github.com/repos/owner/repo/actions/artifacts/1/zip"))
+	})
+
+	It("should return an error when the workflow has no successful runs", func() {
+		client.AddJSONResponse("https://api.github.com/repos/owner/repo/actions/workflows/build.yml/runs", `{"total_count": 0, "workflow_runs": []}`, 200)
+
+		result := finder.Find(client)
+		Expect(result.Error).To(MatchError(ContainSubstring("no successful runs")))
+	})
+
+	It("should return a github error for failed requests", func() {
+		result := finder.Find(client)
+		Expect(result.Error).To(BeAssignableToTypeOf(&github.Error{}))
+	})
+})
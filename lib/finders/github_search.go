@@ -0,0 +1,107 @@
+package finders
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	. "github.com/permafrost-dev/zeget/lib/assets"
+	"github.com/permafrost-dev/zeget/lib/download"
+	"github.com/permafrost-dev/zeget/lib/errors"
+	"github.com/permafrost-dev/zeget/lib/github"
+)
+
+// A GithubSearch looks up repositories by name. Results are ranked first by
+// whether their latest release has an asset for the current system, then by stars.
+type GithubSearch struct {
+	Query string
+	Limit int                // number of repositories to rank, defaults to 10
+	Match func([]Asset) bool // reports if the assets contain one for the current system
+}
+
+type GithubSearchResult struct {
+	Repository github.Repository
+	Release    *github.Release // nil if the repository has no releases
+	Matches    bool
+}
+
+func (r GithubSearchResult) String() string {
+	result := fmt.Sprintf("%s (%d stars)", r.Repository.FullName, r.Repository.Stars)
+	if r.Release == nil {
+		result += " - no releases"
+	} else if !r.Matches {
+		result += " - no assets for this system"
+	}
+	if r.Repository.Description != "" {
+		result += ": " + r.Repository.Description
+	}
+
+	return result
+}
+
+func (s GithubSearch) Search(client download.ClientContract) ([]GithubSearchResult, error) {
+	query := strings.TrimSpace(s.Query)
+	if !github.IsValidSearchQuery(query) {
+		return nil, errors.InvalidGithubSearchQueryError{SearchQuery: s.Query}
+	}
+
+	limit := s.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	var found github.RepositorySearchResult
+	searchURL := fmt.Sprintf("https://api.github.com/search/repositories?q=%s&sort=stars&order=desc&per_page=%d", url.QueryEscape(query+" in:name"), limit)
+	if err := getGithubJSON(client, searchURL, &found); err != nil {
+		return nil, err
+	}
+
+	results := []GithubSearchResult{}
+	for _, repo := range found.Items {
+		if repo.Archived {
+			continue
+		}
+
+		result := GithubSearchResult{Repository: repo}
+
+		var rel github.Release
+		if err := getGithubJSON(client, fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", repo.FullName), &rel); err == nil {
+			rel.ProcessReleaseAssets()
+			result.Release = &rel
+			result.Matches = s.matches(rel)
+		}
+
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		return nil, errors.NoGithubSearchResultsError{SearchQuery: s.Query}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Matches != results[j].Matches {
+			return results[i].Matches
+		}
+		return results[i].Repository.Stars > results[j].Repository.Stars
+	})
+
+	return results, nil
+}
+
+func (s GithubSearch) matches(rel github.Release) bool {
+	if len(rel.Assets) == 0 {
+		return false
+	}
+
+	if s.Match == nil {
+		return true
+	}
+
+	assets := make([]Asset, 0, len(rel.Assets))
+	for _, a := range rel.Assets {
+		assets = append(assets, a.CopyToNewAsset())
+	}
+
+	return s.Match(assets)
+}
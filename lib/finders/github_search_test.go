@@ -0,0 +1,83 @@
+package finders_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/assets"
+	"github.com/permafrost-dev/zeget/lib/errors"
+	. "github.com/permafrost-dev/zeget/lib/finders"
+	. "github.com/permafrost-dev/zeget/lib/mockhttp"
+)
+
+var _ = Describe("GithubSearch", func() {
+	var client HTTPClient
+
+	linuxAssets := func(assets []Asset) bool {
+		for _, a := range assets {
+			if strings.Contains(a.Name, "linux") {
+				return true
+			}
+		}
+		return false
+	}
+
+	BeforeEach(func() {
+		client = NewMockHTTPClient()
+	})
+
+	AfterEach(func() {
+		client.Reset()
+	})
+
+	It("should rank repositories with matching assets first, then by stars", func() {
+		client.AddJSONResponse("https://api.github.com/search/repositories", `{"total_count": 4, "items": [
+			{"full_name": "popular/tool", "stargazers_count": 5000},
+			{"full_name": "small/tool", "stargazers_count": 10},
+			{"full_name": "other/tool", "stargazers_count": 300, "description": "another tool"},
+			{"full_name": "old/tool", "stargazers_count": 9000, "archived": true}
+		]}`, 200)
+		client.AddJSONResponse("https://api.github.com/repos/popular/tool/releases/latest", `{"tag_name": "v1.0.0", "assets": [{"name": "tool_darwin_arm64.tar.gz"}]}`, 200)
+		client.AddJSONResponse("https://api.github.com/repos/small/tool/releases/latest", `{"tag_name": "v0.1.0", "assets": [{"name": "tool_linux_amd64.tar.gz"}]}`, 200)
+		client.AddJSONResponse("https://api.github.com/repos/other/tool/releases/latest", `{"tag_name": "v2.0.0", "assets": [{"name": "tool-linux-x86_64.zip"}]}`, 200)
+
+		results, err := GithubSearch{Query: "tool", Match: linuxAssets}.Search(client)
+		Expect(err).NotTo(HaveOccurred())
+
+		names := []string{}
+		for _, r := range results {
+			names = append(names, r.Repository.FullName)
+		}
+		Expect(names).To(Equal([]string{"other/tool", "small/tool", "popular/tool"}))
+		Expect(results[0].String()).To(Equal("other/tool (300 stars): another tool"))
+		Expect(results[2].String()).To(Equal("popular/tool (5000 stars) - no assets for this system"))
+	})
+
+	It("should keep repositories without releases at the end", func() {
+		client.AddJSONResponse("https://api.github.com/search/repositories", `{"total_count": 2, "items": [
+			{"full_name": "no/releases", "stargazers_count": 100},
+			{"full_name": "has/releases", "stargazers_count": 1}
+		]}`, 200)
+		client.AddJSONResponse("https://api.github.com/repos/no/releases/releases/latest", `{"message": "Not Found"}`, 404)
+		client.AddJSONResponse("https://api.github.com/repos/has/releases/releases/latest", `{"tag_name": "v1.0.0", "assets": [{"name": "tool_linux_amd64"}]}`, 200)
+
+		results, err := GithubSearch{Query: "tool", Match: linuxAssets}.Search(client)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results[0].Repository.FullName).To(Equal("has/releases"))
+		Expect(results[1].Release).To(BeNil())
+		Expect(results[1].String()).To(Equal("no/releases (100 stars) - no releases"))
+	})
+
+	It("should return an error when nothing is found", func() {
+		client.AddJSONResponse("https://api.github.com/search/repositories", `{"total_count": 0, "items": []}`, 200)
+
+		_, err := GithubSearch{Query: "nothing"}.Search(client)
+		Expect(err).To(BeAssignableToTypeOf(errors.NoGithubSearchResultsError{}))
+	})
+
+	It("should reject invalid queries", func() {
+		_, err := GithubSearch{Query: "tool stars:>10"}.Search(client)
+		Expect(err).To(BeAssignableToTypeOf(errors.InvalidGithubSearchQueryError{}))
+	})
+})
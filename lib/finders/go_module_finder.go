@@ -0,0 +1,95 @@
+package finders
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/permafrost-dev/zeget/lib/download"
+	"github.com/permafrost-dev/zeget/lib/goproxy"
+	"github.com/permafrost-dev/zeget/lib/utilities"
+)
+
+// A GoModuleFinder resolves a Go module version through the module proxies
+// configured by GOPROXY and then finds the release assets for that version in
+// the module's GitHub repository. Package paths inside a module, as accepted
+// by 'go install', are resolved to the enclosing module.
+type GoModuleFinder struct {
+	Finder
+
+	Module     string
+	Version    string
+	Prerelease bool
+	MinTime    time.Time
+	Getenv     func(string) string
+}
+
+func NewGoModuleFinder(ref *goproxy.Reference, prerelease bool, minTime time.Time) *GoModuleFinder {
+	return &GoModuleFinder{
+		Module:     ref.Module,
+		Version:    ref.Version,
+		Prerelease: prerelease,
+		MinTime:    minTime,
+		Getenv:     os.Getenv,
+	}
+}
+
+func (f GoModuleFinder) Find(client download.ClientContract) *FindResult {
+	module, info, err := f.Resolve(client)
+	if err != nil && !errors.Is(err, goproxy.ErrDirect) {
+		return NewInvalidFindResult(err)
+	}
+
+	repo, subdir, err := goproxy.GithubRepository(client, module, info)
+	if err != nil {
+		return NewInvalidFindResult(err)
+	}
+
+	ref, err := utilities.ParseRepositoryReference(repo)
+	if err != nil {
+		return NewInvalidFindResult(err)
+	}
+
+	// without a proxy neither the version nor the module, which may be a
+	// package in it, can be resolved, so rely on the GitHub releases instead
+	tag := "latest"
+	if info != nil {
+		tag = "tags/" + info.Tag(subdir)
+	} else if f.Version != "latest" {
+		tag = "tags/" + f.Version
+	}
+
+	return NewGithubAssetFinder(ref, tag, f.Prerelease, f.MinTime).Find(client)
+}
+
+// Resolve returns the module containing f.Module and the info for the
+// requested version. The info is nil if the module is not fetched through a
+// proxy, in which case the error is goproxy.ErrDirect.
+func (f GoModuleFinder) Resolve(client download.ClientContract) (string, *goproxy.Info, error) {
+	getenv := f.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+
+	var lastErr error
+
+	for module := f.Module; strings.Contains(module, "/"); module = path.Dir(module) {
+		resolver := goproxy.NewResolver(client, goproxy.Proxies(module, getenv))
+		resolver.SumDB = goproxy.SumDB(module, getenv)
+		resolver.Prerelease = f.Prerelease
+
+		// only a module which is not found may be a package of the enclosing module
+		info, err := resolver.Resolve(module, f.Version)
+		if err == nil || !goproxy.IsNotFound(err) {
+			return module, info, err
+		}
+
+		if lastErr == nil {
+			lastErr = err
+		}
+	}
+
+	return f.Module, nil, lastErr
+}
@@ -0,0 +1,89 @@
+package finders_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/finders"
+	"github.com/permafrost-dev/zeget/lib/goproxy"
+	. "github.com/permafrost-dev/zeget/lib/mockhttp"
+)
+
+var _ = Describe("GoModuleFinder", func() {
+	var (
+		client HTTPClient
+		env    map[string]string
+	)
+
+	newFinder := func(target string) *GoModuleFinder {
+		ref, err := goproxy.ParseReference(target)
+		Expect(err).NotTo(HaveOccurred())
+
+		finder := NewGoModuleFinder(ref, false, time.Time{})
+		finder.Getenv = func(key string) string { return env[key] }
+
+		return finder
+	}
+
+	BeforeEach(func() {
+		client = NewMockHTTPClient()
+		env = map[string]string{"GOPROXY": "https://proxy.test", "GOSUMDB": "sum.test"}
+
+		client.AddJSONResponse("https://api.github.com/repos/owner/tool/releases/tags/v1.2.0", `{"tag_name": "v1.2.0", "assets": [{"name": "tool_linux_amd64.tar.gz", "browser_download_url": "https://github.com/owner/tool/releases/download/v1.2.0/tool_linux_amd64.tar.gz"}], "created_at": "2024-01-01T00:00:00Z"}`, 200)
+		client.AddJSONResponse("https://api.github.com/repos/owner/tool/releases/latest", `{"tag_name": "v1.3.0", "assets": [{"name": "tool_linux_amd64.tar.gz", "browser_download_url": "https://github.com/owner/tool/releases/download/v1.3.0/tool_linux_amd64.tar.gz"}], "created_at": "2024-02-01T00:00:00Z"}`, 200)
+	})
+
+	AfterEach(func() {
+		client.Reset()
+	})
+
+	It("should find the release assets for the version resolved by the proxy", func() {
+		client.AddJSONResponse("https://proxy.test/example.com/tool/@v/list", "v1.1.0\nv1.2.0\n", 200)
+		client.AddJSONResponse("https://proxy.test/example.com/tool/@v/v1.2.0.info", `{"Version": "v1.2.0", "Origin": {"VCS": "git", "URL": "https://github.com/owner/tool"}}`, 200)
+		client.AddJSONResponse("https://sum.test/lookup/example.com/tool@v1.2.0", "example.com/tool v1.2.0 h1:abc=", 200)
+
+		result := newFinder("go:example.com/tool/cmd/tool").Find(client)
+
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets[0].DownloadURL).To(ContainSubstring("/v1.2.0/"))
+	})
+
+	It("should resolve package paths to the enclosing module", func() {
+		client.AddJSONResponse("https://proxy.test/example.com/tool/@v/v1.2.0.info", `{"Version": "v1.2.0", "Origin": {"URL": "https://github.com/owner/tool"}}`, 200)
+		client.AddJSONResponse("https://sum.test/lookup/example.com/tool@v1.2.0", "example.com/tool v1.2.0 h1:abc=", 200)
+
+		module, info, err := newFinder("go:example.com/tool/cmd/tool@v1.2.0").Resolve(client)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(module).To(Equal("example.com/tool"))
+		Expect(info.Version).To(Equal("v1.2.0"))
+	})
+
+	It("should find the release tagged for a module in a subdirectory", func() {
+		env["GONOSUMDB"] = "example.com"
+		client.AddJSONResponse("https://proxy.test/example.com/tool/gen/@v/v1.2.0.info", `{"Version": "v1.2.0", "Origin": {"URL": "https://github.com/owner/tool", "Subdir": "gen"}}`, 200)
+		client.AddJSONResponse("https://api.github.com/repos/owner/tool/releases/tags/gen/v1.2.0", `{"tag_name": "gen/v1.2.0", "assets": [{"name": "gen_linux_amd64.tar.gz", "browser_download_url": "https://github.com/owner/tool/releases/download/gen/v1.2.0/gen_linux_amd64.tar.gz"}], "created_at": "2024-01-01T00:00:00Z"}`, 200)
+
+		result := newFinder("go:example.com/tool/gen@v1.2.0").Find(client)
+
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets[0].DownloadURL).To(ContainSubstring("/gen/v1.2.0/"))
+	})
+
+	It("should not resolve a module whose version is missing from the checksum database", func() {
+		client.AddJSONResponse("https://proxy.test/example.com/tool/@v/v1.2.0.info", `{"Version": "v1.2.0", "Origin": {"URL": "https://github.com/owner/tool"}}`, 200)
+
+		_, _, err := newFinder("go:example.com/tool@v1.2.0").Resolve(client)
+		Expect(err).To(MatchError(ContainSubstring("checksum database lookup failed")))
+	})
+
+	It("should use the latest GitHub release for private modules", func() {
+		env["GOPRIVATE"] = "github.com/owner"
+
+		result := newFinder("go:github.com/owner/tool").Find(client)
+
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets[0].DownloadURL).To(ContainSubstring("/v1.3.0/"))
+	})
+})
@@ -0,0 +1,346 @@
+package finders
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/blang/semver"
+	. "github.com/permafrost-dev/zeget/lib/assets"
+	"github.com/permafrost-dev/zeget/lib/download"
+	"github.com/permafrost-dev/zeget/lib/jsonpath"
+)
+
+// An IndexFinder scrapes assets from an HTML index page or a JSON manifest.
+// Assets are extracted either with a regular expression using the named
+// groups "url", "name" and "version", or with a JSONPath selecting one item
+// per asset and the fields holding its name, URL, version and checksum.
+// Instead of an index, DownloadURL may point at a single asset directly.
+//
+// URLs may contain the placeholders {{version}}, {{os}}, {{arch}} and {{exe}};
+// ChecksumURL may also use {{name}} and {{url}} of each asset. When a version
+// is needed but "latest" is requested, it is read from VersionURL.
+type IndexFinder struct {
+	Finder
+
+	URL           string
+	DownloadURL   string
+	VersionURL    string
+	ChecksumURL   string
+	Regex         *regexp.Regexp
+	JSONPath      string
+	NameField     string
+	URLField      string
+	VersionField  string
+	ChecksumField string
+
+	Version    string // "latest" or empty selects the highest version
+	Prerelease bool
+	OS         string
+	Arch       string
+}
+
+// IndexAsset is a single entry extracted from an index.
+type IndexAsset struct {
+	Name     string
+	URL      string
+	Version  string
+	Checksum string
+}
+
+func (f IndexFinder) Find(client download.ClientContract) *FindResult {
+	version, err := f.ResolveVersion(client)
+	if err != nil {
+		return NewInvalidFindResult(err)
+	}
+
+	var entries []IndexAsset
+
+	if f.DownloadURL != "" {
+		entries = []IndexAsset{{URL: f.expand(f.DownloadURL, version, nil), Version: version}}
+		entries[0].Name = path.Base(entries[0].URL)
+	} else {
+		if entries, err = f.scrape(client, version); err != nil {
+			return NewInvalidFindResult(err)
+		}
+	}
+
+	entries = f.SelectVersion(entries, version)
+	if len(entries) == 0 {
+		return NewInvalidFindResult(fmt.Errorf("no assets found in %s for version %s", f.URL, version))
+	}
+
+	return NewFindResult(f.toAssets(entries), nil)
+}
+
+func (f IndexFinder) scrape(client download.ClientContract, version string) ([]IndexAsset, error) {
+	indexURL := f.expand(f.URL, version, nil)
+
+	body, err := f.fetch(client, indexURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Extract(indexURL, body)
+}
+
+func (f IndexFinder) toAssets(entries []IndexAsset) []Asset {
+	result := make([]Asset, 0, len(entries))
+
+	for _, e := range entries {
+		asset := Asset{Name: e.Name, DownloadURL: e.URL, Checksum: e.Checksum}
+		if f.ChecksumURL != "" {
+			asset.ChecksumURL = f.expand(f.ChecksumURL, e.Version, &e)
+		}
+
+		result = append(result, asset)
+	}
+
+	return result
+}
+
+func (f IndexFinder) version() string {
+	if f.Version == "" {
+		return "latest"
+	}
+
+	return f.Version
+}
+
+func (f IndexFinder) needsVersion() bool {
+	return strings.Contains(f.URL, "{{version}}") || strings.Contains(f.DownloadURL, "{{version}}")
+}
+
+// ResolveVersion returns the requested version without a leading "v", reading
+// the latest version from VersionURL if the URLs require a version.
+func (f IndexFinder) ResolveVersion(client download.ClientContract) (string, error) {
+	version := f.version()
+	if version != "latest" || !f.needsVersion() {
+		return strings.TrimPrefix(version, "v"), nil
+	}
+
+	if f.VersionURL == "" {
+		return "", fmt.Errorf("%s requires a version, use --tag", orDefault(f.DownloadURL, f.URL))
+	}
+
+	body, err := f.fetch(client, f.expand(f.VersionURL, "", nil))
+	if err != nil {
+		return "", err
+	}
+
+	version = strings.TrimPrefix(strings.TrimSpace(string(body)), "v")
+	if version == "" {
+		return "", fmt.Errorf("no version found at %s", f.VersionURL)
+	}
+
+	return version, nil
+}
+
+func (f IndexFinder) expand(template string, version string, entry *IndexAsset) string {
+	goos := orDefault(f.OS, runtime.GOOS)
+	exe := ""
+	if goos == "windows" {
+		exe = ".exe"
+	}
+
+	replacements := []string{
+		"{{version}}", version,
+		"{{os}}", goos,
+		"{{arch}}", orDefault(f.Arch, runtime.GOARCH),
+		"{{exe}}", exe,
+	}
+
+	if entry != nil {
+		replacements = append(replacements, "{{name}}", entry.Name, "{{url}}", entry.URL)
+	}
+
+	return strings.NewReplacer(replacements...).Replace(template)
+}
+
+func (f IndexFinder) fetch(client download.ClientContract, indexURL string) ([]byte, error) {
+	resp, err := client.GetText(indexURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s: %s", indexURL, resp.Status)
+	}
+
+	return body, nil
+}
+
+// Extract returns the entries listed in the index body. Relative URLs are
+// resolved against the index URL.
+func (f IndexFinder) Extract(indexURL string, body []byte) ([]IndexAsset, error) {
+	var entries []IndexAsset
+	var err error
+
+	switch {
+	case f.JSONPath != "":
+		entries, err = f.extractJSON(body)
+	case f.Regex != nil:
+		entries = f.extractRegex(body)
+	default:
+		return nil, fmt.Errorf("index %s: either a regex or a JSONPath is required", f.URL)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	base, _ := url.Parse(indexURL)
+	seen := map[string]bool{}
+	result := make([]IndexAsset, 0, len(entries))
+
+	for _, e := range entries {
+		if e.URL == "" && e.Name != "" {
+			e.URL = e.Name
+		}
+
+		if e.URL == "" {
+			continue
+		}
+
+		if u, err := url.Parse(e.URL); err == nil && base != nil {
+			e.URL = base.ResolveReference(u).String()
+		}
+
+		if e.Name == "" {
+			e.Name = path.Base(e.URL)
+		}
+
+		if !seen[e.URL] {
+			seen[e.URL] = true
+			result = append(result, e)
+		}
+	}
+
+	return result, nil
+}
+
+func (f IndexFinder) extractRegex(body []byte) []IndexAsset {
+	result := []IndexAsset{}
+
+	for _, m := range f.Regex.FindAllSubmatch(body, -1) {
+		entry := IndexAsset{}
+
+		for i, name := range f.Regex.SubexpNames() {
+			switch name {
+			case "name":
+				entry.Name = string(m[i])
+			case "url":
+				entry.URL = string(m[i])
+			case "version":
+				entry.Version = string(m[i])
+			case "checksum":
+				entry.Checksum = string(m[i])
+			}
+		}
+
+		// without named groups, the first group (or the whole match) is the URL
+		if entry.URL == "" && entry.Name == "" {
+			entry.URL = string(m[len(m)-1])
+		}
+
+		result = append(result, entry)
+	}
+
+	return result
+}
+
+func (f IndexFinder) extractJSON(body []byte) ([]IndexAsset, error) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("index %s: %w", f.URL, err)
+	}
+
+	items, err := jsonpath.Query(doc, f.JSONPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]IndexAsset, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			result = append(result, IndexAsset{URL: s})
+			continue
+		}
+
+		entry := IndexAsset{
+			Name:    jsonpath.GetString(item, orDefault(f.NameField, "name")),
+			URL:     jsonpath.GetString(item, orDefault(f.URLField, "url")),
+			Version: jsonpath.GetString(item, orDefault(f.VersionField, "version")),
+		}
+
+		if f.ChecksumField != "" {
+			entry.Checksum = jsonpath.GetString(item, f.ChecksumField)
+		}
+
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
+// leading letters such as "v" or "go" are not part of the version number
+var versionPrefix = regexp.MustCompile(`^[A-Za-z]+`)
+
+func parseVersion(s string) (semver.Version, error) {
+	return semver.ParseTolerant(versionPrefix.ReplaceAllString(s, ""))
+}
+
+func sameVersion(a string, b string) bool {
+	return versionPrefix.ReplaceAllString(a, "") == versionPrefix.ReplaceAllString(b, "")
+}
+
+// SelectVersion keeps the entries of the requested version, or of the highest
+// version when the latest is requested. Entries without a version are kept.
+func (f IndexFinder) SelectVersion(entries []IndexAsset, want string) []IndexAsset {
+	if want == "latest" {
+		var latest *semver.Version
+		for _, e := range entries {
+			v, err := parseVersion(e.Version)
+			if err != nil || (len(v.Pre) > 0 && !f.Prerelease) {
+				continue
+			}
+			if latest == nil || v.GT(*latest) {
+				latest = &v
+				want = e.Version
+			}
+		}
+
+		if latest == nil {
+			return entries
+		}
+	}
+
+	result := []IndexAsset{}
+	for _, e := range entries {
+		if e.Version == "" || sameVersion(e.Version, want) {
+			result = append(result, e)
+		}
+	}
+
+	return result
+}
+
+func orDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
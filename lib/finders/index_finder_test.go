@@ -0,0 +1,138 @@
+package finders_test
+
+import (
+	"regexp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/finders"
+	. "github.com/permafrost-dev/zeget/lib/mockhttp"
+)
+
+var _ = Describe("IndexFinder", func() {
+	var client HTTPClient
+
+	BeforeEach(func() {
+		client = NewMockHTTPClient()
+
+		client.AddJSONResponse("https://releases.example.com/tool/", `<html><body>
+			<a href="/tool/1.2.0/tool_1.2.0_linux_amd64.zip">tool_1.2.0_linux_amd64.zip</a>
+			<a href="/tool/1.10.0/tool_1.10.0_linux_amd64.zip">tool_1.10.0_linux_amd64.zip</a>
+			<a href="/tool/1.10.0/tool_1.10.0_darwin_arm64.zip">tool_1.10.0_darwin_arm64.zip</a>
+			<a href="/tool/1.11.0-beta1/tool_1.11.0-beta1_linux_amd64.zip">tool_1.11.0-beta1_linux_amd64.zip</a>
+		</body></html>`, 200)
+
+		client.AddJSONResponse("https://releases.example.com/tool/index.json", `{"versions": {
+			"1.2.0": {"version": "1.2.0", "builds": [{"filename": "tool_1.2.0_linux_amd64.zip", "url": "https://releases.example.com/tool/1.2.0/tool_1.2.0_linux_amd64.zip"}]},
+			"1.10.0": {"version": "1.10.0", "builds": [{"filename": "tool_1.10.0_linux_amd64.zip", "url": "https://releases.example.com/tool/1.10.0/tool_1.10.0_linux_amd64.zip"}]}
+		}}`, 200)
+	})
+
+	AfterEach(func() {
+		client.Reset()
+	})
+
+	regexFinder := func(version string) IndexFinder {
+		return IndexFinder{
+			URL:     "https://releases.example.com/tool/",
+			Regex:   regexp.MustCompile(`href="(?P<url>[^"]+/(?P<name>tool_(?P<version>[^_]+)_[^"/]+\.zip))"`),
+			Version: version,
+		}
+	}
+
+	It("should extract assets of the latest stable version with a regex", func() {
+		result := regexFinder("latest").Find(client)
+
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets).To(HaveLen(2))
+		Expect(result.Assets[0].Name).To(Equal("tool_1.10.0_linux_amd64.zip"))
+		Expect(result.Assets[0].DownloadURL).To(Equal("https://releases.example.com/tool/1.10.0/tool_1.10.0_linux_amd64.zip"))
+	})
+
+	It("should select a specific version", func() {
+		result := regexFinder("v1.2.0").Find(client)
+
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets).To(HaveLen(1))
+		Expect(result.Assets[0].Name).To(Equal("tool_1.2.0_linux_amd64.zip"))
+	})
+
+	It("should include prereleases when requested", func() {
+		finder := regexFinder("")
+		finder.Prerelease = true
+
+		result := finder.Find(client)
+		Expect(result.Assets[0].Name).To(ContainSubstring("1.11.0-beta1"))
+	})
+
+	It("should extract assets with a JSONPath", func() {
+		result := IndexFinder{
+			URL:       "https://releases.example.com/tool/index.json",
+			JSONPath:  "$.versions.*.builds[*]",
+			NameField: "filename",
+		}.Find(client)
+
+		// the build items carry no version, so all of them are kept
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets).To(HaveLen(2))
+
+		result = IndexFinder{
+			URL:       "https://releases.example.com/tool/index.json",
+			JSONPath:  "$.versions.*",
+			NameField: "builds[0].filename",
+			URLField:  "builds[0].url",
+		}.Find(client)
+
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets).To(HaveLen(1))
+		Expect(result.Assets[0].Name).To(Equal("tool_1.10.0_linux_amd64.zip"))
+	})
+
+	It("should expand URL placeholders", func() {
+		client.AddJSONResponse("https://example.com/1.0.0/linux/amd64/tool", "", 200)
+
+		result := IndexFinder{DownloadURL: "https://example.com/{{version}}/{{os}}/{{arch}}/tool{{exe}}", Version: "v1.0.0", OS: "linux", Arch: "amd64"}.Find(client)
+
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets[0].DownloadURL).To(Equal("https://example.com/1.0.0/linux/amd64/tool"))
+
+		result = IndexFinder{URL: "https://example.com/{{version}}/"}.Find(client)
+		Expect(result.Error).To(MatchError(ContainSubstring("requires a version")))
+	})
+
+	It("should resolve the latest version from the version URL", func() {
+		client.AddJSONResponse("https://example.com/stable.txt", "v1.31.0\n", 200)
+
+		result := IndexFinder{
+			DownloadURL: "https://example.com/v{{version}}/bin/{{os}}/{{arch}}/tool{{exe}}",
+			VersionURL:  "https://example.com/stable.txt",
+			ChecksumURL: "{{url}}.sha256",
+			OS:          "windows",
+			Arch:        "amd64",
+		}.Find(client)
+
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets).To(HaveLen(1))
+		Expect(result.Assets[0].Name).To(Equal("tool.exe"))
+		Expect(result.Assets[0].DownloadURL).To(Equal("https://example.com/v1.31.0/bin/windows/amd64/tool.exe"))
+		Expect(result.Assets[0].ChecksumURL).To(Equal("https://example.com/v1.31.0/bin/windows/amd64/tool.exe.sha256"))
+	})
+
+	It("should point all assets at a single checksum list", func() {
+		finder := regexFinder("latest")
+		finder.ChecksumURL = "https://releases.example.com/tool/{{version}}/SHA256SUMS"
+
+		result := finder.Find(client)
+
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets).To(HaveLen(2))
+		for _, asset := range result.Assets {
+			Expect(asset.ChecksumURL).To(Equal("https://releases.example.com/tool/1.10.0/SHA256SUMS"))
+		}
+	})
+
+	It("should require a regex or a JSONPath", func() {
+		result := IndexFinder{URL: "https://releases.example.com/tool/"}.Find(client)
+		Expect(result.Error).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,110 @@
+package finders
+
+import (
+	"fmt"
+
+	. "github.com/permafrost-dev/zeget/lib/assets"
+	"github.com/permafrost-dev/zeget/lib/credentials"
+	"github.com/permafrost-dev/zeget/lib/download"
+	"github.com/permafrost-dev/zeget/lib/oci"
+)
+
+// An OCIFinder finds the layers of an artifact or image in an OCI registry.
+// For multi-platform indexes only the manifests for OS/Arch are used; leave
+// them empty to list the layers of every platform.
+type OCIFinder struct {
+	Finder
+
+	Reference   *oci.Reference
+	OS          string
+	Arch        string
+	Credentials *credentials.Store
+}
+
+func NewOCIFinder(ref *oci.Reference, os string, arch string, store *credentials.Store) *OCIFinder {
+	return &OCIFinder{
+		Reference:   ref,
+		OS:          os,
+		Arch:        arch,
+		Credentials: store,
+	}
+}
+
+func (f OCIFinder) Find(client download.ClientContract) *FindResult {
+	// registry requests go through the download client for its mirrors, headers and TLS settings
+	var httpClient oci.Doer
+	if client != nil {
+		httpClient = client
+	}
+
+	registry := oci.NewClient(httpClient, f.Credentials)
+
+	manifest, err := registry.GetManifest(f.Reference, f.Reference.Ref())
+	if err != nil {
+		return NewInvalidFindResult(err)
+	}
+
+	assets := []Asset{}
+
+	if !manifest.IsIndex() {
+		assets = f.layerAssets(manifest, nil)
+	}
+
+	for _, desc := range manifest.Manifests {
+		if !desc.Platform.Matches(f.OS, f.Arch) {
+			continue
+		}
+
+		platformManifest, err := registry.GetManifest(f.Reference, desc.Digest)
+		if err != nil {
+			return NewInvalidFindResult(err)
+		}
+
+		assets = append(assets, f.layerAssets(platformManifest, desc.Platform)...)
+	}
+
+	if len(assets) == 0 {
+		return NewInvalidFindResult(fmt.Errorf("no layers found in %s for %s/%s", f.Reference, f.OS, f.Arch))
+	}
+
+	// blobs are downloaded by the regular download client, which needs the registry token as well
+	if token := registry.Token(f.Reference.Registry); token != "" && f.Credentials != nil {
+		f.Credentials.Override(&credentials.Credential{
+			Host:   f.Reference.Registry,
+			Kind:   credentials.KindBearer,
+			Token:  token,
+			Source: "oci registry",
+		})
+	}
+
+	return NewFindResult(assets, nil)
+}
+
+// layerAssets names layers by their title annotation, or after the repository
+// and platform so that the system detector can still tell them apart.
+func (f OCIFinder) layerAssets(manifest *oci.Manifest, platform *oci.Platform) []Asset {
+	result := make([]Asset, 0, len(manifest.Layers))
+
+	for i, layer := range manifest.Layers {
+		name := layer.Title()
+
+		if name == "" {
+			name = f.Reference.Name()
+			if platform != nil {
+				name += "_" + platform.OS + "_" + platform.Architecture
+			}
+			if len(manifest.Layers) > 1 {
+				name += fmt.Sprintf("_%d", i+1)
+			}
+			name += layer.Extension()
+		}
+
+		result = append(result, Asset{
+			Name:        name,
+			DownloadURL: f.Reference.BlobURL(layer.Digest),
+			Size:        layer.Size,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,141 @@
+package finders_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/permafrost-dev/zeget/lib/credentials"
+	"github.com/permafrost-dev/zeget/lib/download"
+	. "github.com/permafrost-dev/zeget/lib/finders"
+	"github.com/permafrost-dev/zeget/lib/oci"
+	pb "github.com/schollz/progressbar/v3"
+)
+
+// a minimal stand-in for a registry that requires anonymous token auth, like
+// ghcr.io, which records the X-Registry header of each request
+func newTestRegistry(headers *[]string) *httptest.Server {
+	var server *httptest.Server
+
+	index := `{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.index.v1+json", "manifests": [
+		{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:linux", "platform": {"os": "linux", "architecture": "amd64"}},
+		{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:darwin", "platform": {"os": "darwin", "architecture": "arm64"}}
+	]}`
+	manifests := map[string]string{
+		"v1":            index,
+		"sha256:linux":  `{"schemaVersion": 2, "layers": [{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": "sha256:layer-linux"}]}`,
+		"sha256:darwin": `{"schemaVersion": 2, "layers": [{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": "sha256:layer-darwin"}]}`,
+		"artifact":      `{"schemaVersion": 2, "layers": [{"mediaType": "application/octet-stream", "digest": "sha256:file", "annotations": {"org.opencontainers.image.title": "tool_linux_amd64.tar.gz"}}]}`,
+	}
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*headers = append(*headers, r.Header.Get("X-Registry"))
+
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, `{"token": "anonymous"}`)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer anonymous" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if reference, found := strings.CutPrefix(r.URL.Path, "/v2/org/tool/manifests/"); found {
+			fmt.Fprint(w, manifests[reference])
+			return
+		}
+
+		if digest, found := strings.CutPrefix(r.URL.Path, "/v2/org/tool/blobs/"); found {
+			fmt.Fprint(w, "contents of "+digest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	return server
+}
+
+var _ = Describe("OCIFinder", func() {
+	var (
+		server  *httptest.Server
+		store   *credentials.Store
+		client  *download.Client
+		headers []string
+	)
+
+	reference := func(tag string) *oci.Reference {
+		ref, err := oci.ParseReference("oci://" + strings.TrimPrefix(server.URL, "http://") + "/org/tool:" + tag)
+		Expect(err).NotTo(HaveOccurred())
+		return ref
+	}
+
+	BeforeEach(func() {
+		headers = nil
+		server = newTestRegistry(&headers)
+		store = credentials.NewStore()
+		store.Getenv = func(string) string { return "" }
+		client = download.NewClient("").SetCredentials(store)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should select the layer for the requested platform from an index", func() {
+		result := NewOCIFinder(reference("v1"), "linux", "amd64", store).Find(client)
+
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets).To(HaveLen(1))
+		Expect(result.Assets[0].Name).To(Equal("tool_linux_amd64.tar.gz"))
+		Expect(result.Assets[0].DownloadURL).To(HaveSuffix("/v2/org/tool/blobs/sha256:layer-linux"))
+	})
+
+	It("should list all platforms when no platform is given", func() {
+		result := NewOCIFinder(reference("v1"), "", "", store).Find(client)
+
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets).To(HaveLen(2))
+	})
+
+	It("should name artifact layers after their title annotation", func() {
+		result := NewOCIFinder(reference("artifact"), "linux", "amd64", store).Find(client)
+
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets[0].Name).To(Equal("tool_linux_amd64.tar.gz"))
+	})
+
+	It("should make the registry token available for downloading blobs", func() {
+		result := NewOCIFinder(reference("v1"), "linux", "amd64", store).Find(client)
+		Expect(result.Error).NotTo(HaveOccurred())
+
+		buf := &bytes.Buffer{}
+		Expect(client.Download(result.Assets[0].DownloadURL, buf, func(size int64) *pb.ProgressBar { return pb.DefaultBytesSilent(size) })).To(Succeed())
+		Expect(buf.String()).To(Equal("contents of sha256:layer-linux"))
+	})
+
+	It("should send registry requests through the client mirrors and headers", func() {
+		client.SetMirrors([]*download.Mirror{download.NewPrefixMirror("registry", "https://registry.example/", server.URL+"/")})
+		client.AddHeader("X-Registry", "mirrored")
+		ref, err := oci.ParseReference("oci://registry.example/org/tool:artifact")
+		Expect(err).NotTo(HaveOccurred())
+
+		result := NewOCIFinder(ref, "linux", "amd64", store).Find(client)
+
+		Expect(result.Error).NotTo(HaveOccurred())
+		Expect(result.Assets[0].Name).To(Equal("tool_linux_amd64.tar.gz"))
+		Expect(headers).To(HaveEach("mirrored"))
+	})
+
+	It("should return an error when no platform matches", func() {
+		result := NewOCIFinder(reference("v1"), "windows", "386", store).Find(client)
+		Expect(result.Error).To(MatchError(ContainSubstring("no layers found")))
+	})
+})
@@ -0,0 +1,46 @@
+package github
+
+import (
+	"time"
+
+	"github.com/permafrost-dev/zeget/lib/assets"
+)
+
+// A WorkflowRun matches the parts of Github's workflow run API json used by zeget.
+type WorkflowRun struct {
+	ID         int64     `json:"id"`
+	HeadBranch string    `json:"head_branch"`
+	HeadSHA    string    `json:"head_sha"`
+	Conclusion string    `json:"conclusion"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type WorkflowRunList struct {
+	TotalCount   int           `json:"total_count"`
+	WorkflowRuns []WorkflowRun `json:"workflow_runs"`
+}
+
+// An Artifact is a file produced by a workflow run; it is always downloaded as a zip archive.
+type Artifact struct {
+	ID                 int64     `json:"id"`
+	Name               string    `json:"name"`
+	SizeInBytes        int64     `json:"size_in_bytes"`
+	ArchiveDownloadURL string    `json:"archive_download_url"`
+	Expired            bool      `json:"expired"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+type ArtifactList struct {
+	TotalCount int        `json:"total_count"`
+	Artifacts  []Artifact `json:"artifacts"`
+}
+
+func (a *Artifact) CopyToNewAsset() assets.Asset {
+	return assets.Asset{
+		Name:        a.Name + ".zip",
+		DownloadURL: a.ArchiveDownloadURL,
+		Size:        a.SizeInBytes,
+		ReleaseDate: a.CreatedAt,
+	}
+}
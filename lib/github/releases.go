@@ -21,6 +21,7 @@ type Release struct {
 	Assets      []ReleaseAsset `json:"assets"`
 	Prerelease  bool           `json:"prerelease"`
 	Tag         string         `json:"tag_name"`
+	HTMLURL     string         `json:"html_url"`
 	CreatedAt   time.Time      `json:"created_at"`
 	PublishedAt time.Time      `json:"published_at"`
 }
@@ -34,8 +35,15 @@ func (r *Release) ProcessReleaseAssets() {
 
 func (ra *ReleaseAsset) CopyToNewAsset() assets.Asset {
 	return assets.Asset{
-		Name:        ra.Name,
-		DownloadURL: ra.DownloadURL,
-		ReleaseDate: ra.Release.PublishedAt,
+		Name:            ra.Name,
+		DownloadURL:     ra.DownloadURL,
+		APIURL:          ra.URL,
+		Size:            ra.Size,
+		ContentType:     ra.ContentType,
+		DownloadCount:   ra.DownloadCount,
+		ReleaseTag:      ra.Release.Tag,
+		Prerelease:      ra.Release.Prerelease,
+		ReleaseNotesURL: ra.Release.HTMLURL,
+		ReleaseDate:     ra.Release.PublishedAt,
 	}
 }
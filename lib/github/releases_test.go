@@ -36,6 +36,7 @@ var _ = Describe("Release", func() {
 			},
 			Prerelease:  false,
 			Tag:         "v1.0.0",
+			HTMLURL:     "https://github.com/owner/repo/releases/tag/v1.0.0",
 			CreatedAt:   time.Now(),
 			PublishedAt: time.Now(),
 		}
@@ -62,6 +63,7 @@ var _ = Describe("ReleaseAsset", func() {
 			Assets:      nil,
 			Prerelease:  false,
 			Tag:         "v1.0.0",
+			HTMLURL:     "https://github.com/owner/repo/releases/tag/v1.0.0",
 			CreatedAt:   time.Now(),
 			PublishedAt: time.Now(),
 		}
@@ -81,9 +83,16 @@ var _ = Describe("ReleaseAsset", func() {
 		It("should correctly copy ReleaseAsset to Asset", func() {
 			copiedAsset := releaseAsset.CopyToNewAsset()
 			Expect(copiedAsset).To(Equal(assets.Asset{
-				Name:        releaseAsset.Name,
-				DownloadURL: releaseAsset.DownloadURL,
-				ReleaseDate: releaseAsset.Release.PublishedAt,
+				Name:            releaseAsset.Name,
+				DownloadURL:     releaseAsset.DownloadURL,
+				APIURL:          releaseAsset.URL,
+				Size:            releaseAsset.Size,
+				ContentType:     releaseAsset.ContentType,
+				DownloadCount:   releaseAsset.DownloadCount,
+				ReleaseTag:      "v1.0.0",
+				Prerelease:      false,
+				ReleaseNotesURL: "https://github.com/owner/repo/releases/tag/v1.0.0",
+				ReleaseDate:     releaseAsset.Release.PublishedAt,
 			}))
 		})
 	})
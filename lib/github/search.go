@@ -0,0 +1,25 @@
+package github
+
+import "regexp"
+
+// A Repository matches the parts of Github's repository search API json used by zeget.
+type Repository struct {
+	FullName    string `json:"full_name"`
+	Description string `json:"description"`
+	HTMLURL     string `json:"html_url"`
+	Stars       int    `json:"stargazers_count"`
+	Archived    bool   `json:"archived"`
+}
+
+type RepositorySearchResult struct {
+	TotalCount int          `json:"total_count"`
+	Items      []Repository `json:"items"`
+}
+
+var searchQueryPattern = regexp.MustCompile(`^[\w.\- ]+$`)
+
+// IsValidSearchQuery returns true if the query only contains letters, digits,
+// spaces, dots, dashes and underscores.
+func IsValidSearchQuery(query string) bool {
+	return searchQueryPattern.MatchString(query)
+}
@@ -0,0 +1,20 @@
+package github_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/github"
+)
+
+var _ = Describe("IsValidSearchQuery", func() {
+	DescribeTable("validating search queries",
+		func(query string, expected bool) {
+			Expect(IsValidSearchQuery(query)).To(Equal(expected))
+		},
+		Entry("a tool name", "ripgrep", true),
+		Entry("several words", "json cli_tool v2.0", true),
+		Entry("an empty query", "", false),
+		Entry("search qualifiers", "stars:>100", false),
+		Entry("a repository reference", "owner/repo", false),
+	)
+})
@@ -0,0 +1,346 @@
+package goproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/permafrost-dev/zeget/lib/download"
+)
+
+const DefaultProxy = "https://proxy.golang.org,direct"
+
+const DefaultSumDB = "https://sum.golang.org"
+
+// ErrDirect is returned when a module must not be fetched through a proxy,
+// because of GOPROXY=direct or a GONOPROXY/GOPRIVATE match.
+var ErrDirect = errors.New("module is not available through a proxy")
+
+// An Origin describes where a module version came from; proxies include it in .info responses.
+type Origin struct {
+	VCS    string `json:"VCS"`
+	URL    string `json:"URL"`
+	Subdir string `json:"Subdir"`
+	Ref    string `json:"Ref"`
+	Hash   string `json:"Hash"`
+}
+
+type Info struct {
+	Version string    `json:"Version"`
+	Time    time.Time `json:"Time"`
+	Origin  *Origin   `json:"Origin"`
+}
+
+// Tag returns the git tag of the version for a module in the given
+// subdirectory of its repository, preferring the tag recorded in the origin.
+func (info *Info) Tag(subdir string) string {
+	if info.Origin != nil {
+		if tag, found := strings.CutPrefix(info.Origin.Ref, "refs/tags/"); found {
+			return tag
+		}
+	}
+
+	return TagName(subdir, info.Version)
+}
+
+// TagName returns the git tag of a version for a module in the given
+// subdirectory of its repository, which is prefixed with the subdirectory.
+func TagName(subdir string, version string) string {
+	if subdir == "" {
+		return version
+	}
+
+	return subdir + "/" + version
+}
+
+// A Proxy is an entry of GOPROXY. The next entry is only tried when the
+// module is not found, unless FallbackOnError is set because the entry is
+// followed by "|" rather than ",".
+type Proxy struct {
+	URL             string
+	FallbackOnError bool
+}
+
+// Proxies returns the proxies to query for the module, honouring GOPROXY,
+// GONOPROXY and GOPRIVATE. The special entry "direct" is kept; "off" ends the list.
+func Proxies(module string, getenv func(string) string) []Proxy {
+	noProxy := getenv("GONOPROXY")
+	if noProxy == "" {
+		noProxy = getenv("GOPRIVATE")
+	}
+
+	if MatchPatterns(noProxy, module) {
+		return []Proxy{{URL: "direct"}}
+	}
+
+	value := getenv("GOPROXY")
+	if value == "" {
+		value = DefaultProxy
+	}
+
+	result := []Proxy{}
+	for value != "" {
+		end := strings.IndexAny(value, ",|")
+		if end < 0 {
+			end = len(value)
+		}
+
+		proxy := strings.TrimSuffix(strings.TrimSpace(value[:end]), "/")
+		fallback := end < len(value) && value[end] == '|'
+		value = value[min(end+1, len(value)):]
+
+		if proxy == "off" {
+			break
+		}
+		if proxy != "" {
+			result = append(result, Proxy{URL: proxy, FallbackOnError: fallback})
+		}
+	}
+
+	return result
+}
+
+// SumDB returns the URL of the checksum database to look up versions of the
+// module in, honouring GOSUMDB, GONOSUMDB and GOPRIVATE, or an empty string
+// if the module is not checked.
+func SumDB(module string, getenv func(string) string) string {
+	noSumDB := getenv("GONOSUMDB")
+	if noSumDB == "" {
+		noSumDB = getenv("GOPRIVATE")
+	}
+
+	if MatchPatterns(noSumDB, module) {
+		return ""
+	}
+
+	// "name[+key] [url]"
+	fields := strings.Fields(getenv("GOSUMDB"))
+	switch {
+	case len(fields) == 0:
+		return DefaultSumDB
+	case fields[0] == "off":
+		return ""
+	case len(fields) > 1:
+		return strings.TrimSuffix(fields[1], "/")
+	}
+
+	name, _, _ := strings.Cut(fields[0], "+")
+	return "https://" + name
+}
+
+// A StatusError is returned for an unsuccessful response of a proxy.
+type StatusError struct {
+	URL        string
+	Status     string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.URL, e.Status, e.Body)
+}
+
+// IsNotFound reports whether err is a "not found" response of a proxy, after
+// which the next proxy is tried.
+func IsNotFound(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone)
+}
+
+// A Resolver looks up module versions through a list of module proxies, and
+// checks that they are recorded in the checksum database at SumDB if set.
+type Resolver struct {
+	Client     download.ClientContract
+	Proxies    []Proxy
+	SumDB      string
+	Prerelease bool
+}
+
+func NewResolver(client download.ClientContract, proxies []Proxy) *Resolver {
+	return &Resolver{Client: client, Proxies: proxies}
+}
+
+// Resolve returns the info for the given version of the module, where
+// "latest" resolves to the latest release, or the latest prerelease as well
+// if r.Prerelease is set. Proxies are tried in turn as GOPROXY specifies.
+func (r *Resolver) Resolve(module string, version string) (*Info, error) {
+	var err error = ErrDirect
+
+	for _, proxy := range r.Proxies {
+		if proxy.URL == "direct" {
+			return nil, ErrDirect
+		}
+
+		var info *Info
+		if version == "latest" {
+			info, err = r.latest(proxy.URL, module)
+		} else {
+			info, err = r.info(proxy.URL, module, version)
+		}
+
+		if err == nil {
+			return info, r.checkSum(module, info.Version)
+		}
+
+		if !proxy.FallbackOnError && !IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+func (r *Resolver) latest(proxy string, module string) (*Info, error) {
+	versions, err := r.List(proxy, module)
+	if err == nil && len(versions) > 0 {
+		return r.info(proxy, module, versions[len(versions)-1])
+	}
+
+	info := &Info{}
+	if err := r.getJSON(fmt.Sprintf("%s/%s/@latest", proxy, EscapePath(module)), info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// checkSum returns an error if the version of the module is not recorded in
+// the checksum database.
+func (r *Resolver) checkSum(module string, version string) error {
+	if r.SumDB == "" {
+		return nil
+	}
+
+	if _, err := r.get(fmt.Sprintf("%s/lookup/%s@%s", r.SumDB, EscapePath(module), EscapePath(version))); err != nil {
+		return fmt.Errorf("%s@%s: checksum database lookup failed: %w", module, version, err)
+	}
+
+	return nil
+}
+
+func (r *Resolver) info(proxy string, module string, version string) (*Info, error) {
+	info := &Info{}
+	if err := r.getJSON(fmt.Sprintf("%s/%s/@v/%s.info", proxy, EscapePath(module), EscapePath(version)), info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// List returns the released versions known to the proxy, sorted ascending.
+// Prereleases are only included if r.Prerelease is set.
+func (r *Resolver) List(proxy string, module string) ([]string, error) {
+	body, err := r.get(fmt.Sprintf("%s/%s/@v/list", proxy, EscapePath(module)))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := semver.Versions{}
+	originals := map[string]string{}
+
+	for _, line := range strings.Fields(string(body)) {
+		v, err := semver.ParseTolerant(line)
+		if err != nil || (len(v.Pre) > 0 && !r.Prerelease) {
+			continue
+		}
+
+		versions = append(versions, v)
+		originals[v.String()] = line
+	}
+
+	semver.Sort(versions)
+
+	result := make([]string, 0, len(versions))
+	for _, v := range versions {
+		result = append(result, originals[v.String()])
+	}
+
+	return result, nil
+}
+
+func (r *Resolver) getJSON(url string, v interface{}) error {
+	body, err := r.get(url)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+func (r *Resolver) get(url string) ([]byte, error) {
+	resp, err := r.Client.GetJSON(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: url, Status: resp.Status, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+
+	return body, nil
+}
+
+var (
+	githubRepoPattern = regexp.MustCompile(`^(?:https?://)?github\.com/([\w.\-]+)/([\w.\-]+?)(?:\.git)?(?:/|$)`)
+	goImportPattern   = regexp.MustCompile(`<meta\s+name="go-import"\s+content="([^"]+)"`)
+	majorPattern      = regexp.MustCompile(`(^|/)v[2-9]\d*$`)
+)
+
+// subdir returns the subdirectory of the repository at root that the module
+// is in, as used to prefix its tags. A major version suffix is not part of it.
+func subdir(module string, root string) string {
+	dir := strings.TrimPrefix(strings.TrimPrefix(module, root), "/")
+	return majorPattern.ReplaceAllString(dir, "")
+}
+
+// GithubRepository returns the "owner/repo" GitHub repository hosting the
+// module, taken from the version origin, the module path itself or the
+// module's go-import meta tag, and the subdirectory of the repository the
+// module is in.
+func GithubRepository(client download.ClientContract, module string, info *Info) (string, string, error) {
+	if info != nil && info.Origin != nil {
+		if m := githubRepoPattern.FindStringSubmatch(info.Origin.URL); m != nil {
+			return m[1] + "/" + m[2], info.Origin.Subdir, nil
+		}
+	}
+
+	if m := githubRepoPattern.FindStringSubmatch(module); m != nil {
+		return m[1] + "/" + m[2], subdir(module, strings.TrimSuffix(m[0], "/")), nil
+	}
+
+	resp, err := client.GetText("https://" + module + "?go-get=1")
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, m := range goImportPattern.FindAllStringSubmatch(string(body), -1) {
+		fields := strings.Fields(m[1])
+		if len(fields) != 3 || !strings.HasPrefix(module+"/", fields[0]+"/") {
+			continue
+		}
+
+		if repo := githubRepoPattern.FindStringSubmatch(fields[2]); repo != nil {
+			return repo[1] + "/" + repo[2], subdir(module, fields[0]), nil
+		}
+	}
+
+	return "", "", fmt.Errorf("module %s is not hosted on GitHub", module)
+}
@@ -0,0 +1,190 @@
+package goproxy_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/permafrost-dev/zeget/lib/goproxy"
+	"github.com/permafrost-dev/zeget/lib/mockhttp"
+)
+
+var _ = Describe("Proxy", func() {
+	var (
+		client mockhttp.HTTPClient
+		env    map[string]string
+	)
+
+	getenv := func(key string) string { return env[key] }
+
+	BeforeEach(func() {
+		client = mockhttp.NewMockHTTPClient()
+		env = map[string]string{}
+	})
+
+	Describe("Proxies", func() {
+		It("should default to proxy.golang.org", func() {
+			Expect(Proxies("example.com/tool", getenv)).To(Equal([]Proxy{{URL: "https://proxy.golang.org"}, {URL: "direct"}}))
+		})
+
+		It("should read GOPROXY and stop at off", func() {
+			env["GOPROXY"] = "https://goproxy.corp/|https://proxy.golang.org,off,direct"
+			Expect(Proxies("example.com/tool", getenv)).To(Equal([]Proxy{
+				{URL: "https://goproxy.corp", FallbackOnError: true},
+				{URL: "https://proxy.golang.org"},
+			}))
+		})
+
+		It("should bypass proxies for private modules", func() {
+			env["GOPRIVATE"] = "example.com/private"
+			Expect(Proxies("example.com/private/tool", getenv)).To(Equal([]Proxy{{URL: "direct"}}))
+
+			env["GONOPROXY"] = "example.com/other"
+			Expect(Proxies("example.com/private/tool", getenv)).NotTo(Equal([]Proxy{{URL: "direct"}}))
+		})
+	})
+
+	Describe("SumDB", func() {
+		It("should default to sum.golang.org", func() {
+			Expect(SumDB("example.com/tool", getenv)).To(Equal("https://sum.golang.org"))
+		})
+
+		It("should read GOSUMDB", func() {
+			env["GOSUMDB"] = "sum.corp+abcdef"
+			Expect(SumDB("example.com/tool", getenv)).To(Equal("https://sum.corp"))
+
+			env["GOSUMDB"] = "sum.golang.org https://sumdb.corp/sum.golang.org/"
+			Expect(SumDB("example.com/tool", getenv)).To(Equal("https://sumdb.corp/sum.golang.org"))
+
+			env["GOSUMDB"] = "off"
+			Expect(SumDB("example.com/tool", getenv)).To(BeEmpty())
+		})
+
+		It("should skip modules matching GONOSUMDB or GOPRIVATE", func() {
+			env["GOPRIVATE"] = "example.com/private"
+			Expect(SumDB("example.com/private/tool", getenv)).To(BeEmpty())
+
+			env["GONOSUMDB"] = "example.com/other"
+			Expect(SumDB("example.com/private/tool", getenv)).NotTo(BeEmpty())
+			Expect(SumDB("example.com/other/tool", getenv)).To(BeEmpty())
+		})
+	})
+
+	Describe("Resolver", func() {
+		It("should resolve the latest release from the version list", func() {
+			client.AddJSONResponse("https://proxy.test/example.com/tool/@v/list", "v1.2.0\nv1.10.0\nv1.11.0-rc.1\nv1.9.0\n", 200)
+			client.AddJSONResponse("https://proxy.test/example.com/tool/@v/v1.10.0.info", `{"Version": "v1.10.0", "Origin": {"VCS": "git", "URL": "https://github.com/owner/tool"}}`, 200)
+
+			info, err := NewResolver(&client, []Proxy{{URL: "https://proxy.test"}}).Resolve("example.com/tool", "latest")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Version).To(Equal("v1.10.0"))
+			Expect(info.Origin.URL).To(Equal("https://github.com/owner/tool"))
+		})
+
+		It("should fall back to @latest when there are no tagged versions", func() {
+			client.AddJSONResponse("https://proxy.test/example.com/tool/@v/list", "", 200)
+			client.AddJSONResponse("https://proxy.test/example.com/tool/@latest", `{"Version": "v0.0.0-20240101000000-abcdef123456"}`, 200)
+
+			info, err := NewResolver(&client, []Proxy{{URL: "https://proxy.test"}}).Resolve("example.com/tool", "latest")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Version).To(Equal("v0.0.0-20240101000000-abcdef123456"))
+		})
+
+		It("should include prereleases when requested", func() {
+			client.AddJSONResponse("https://proxy.test/example.com/tool/@v/list", "v1.2.0\nv1.11.0-rc.1\nv1.9.0\n", 200)
+			client.AddJSONResponse("https://proxy.test/example.com/tool/@v/v1.11.0-rc.1.info", `{"Version": "v1.11.0-rc.1"}`, 200)
+
+			resolver := NewResolver(&client, []Proxy{{URL: "https://proxy.test"}})
+			resolver.Prerelease = true
+			info, err := resolver.Resolve("example.com/tool", "latest")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Version).To(Equal("v1.11.0-rc.1"))
+		})
+
+		It("should try the next proxy when the module is not found", func() {
+			client.AddJSONResponse("https://second.test/example.com/tool/@v/v1.0.0.info", `{"Version": "v1.0.0"}`, 200)
+
+			info, err := NewResolver(&client, []Proxy{{URL: "https://first.test"}, {URL: "https://second.test"}}).Resolve("example.com/tool", "v1.0.0")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Version).To(Equal("v1.0.0"))
+		})
+
+		It("should only try the next proxy after other errors when separated by a pipe", func() {
+			client.AddJSONResponse("https://first.test/example.com/tool/@v/v1.0.0.info", "", 500)
+			client.AddJSONResponse("https://second.test/example.com/tool/@v/v1.0.0.info", `{"Version": "v1.0.0"}`, 200)
+
+			_, err := NewResolver(&client, []Proxy{{URL: "https://first.test"}, {URL: "https://second.test"}}).Resolve("example.com/tool", "v1.0.0")
+			Expect(err).To(HaveOccurred())
+
+			info, err := NewResolver(&client, []Proxy{{URL: "https://first.test", FallbackOnError: true}, {URL: "https://second.test"}}).Resolve("example.com/tool", "v1.0.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Version).To(Equal("v1.0.0"))
+		})
+
+		It("should look up the version in the checksum database", func() {
+			client.AddJSONResponse("https://proxy.test/example.com/tool/@v/v1.0.0.info", `{"Version": "v1.0.0"}`, 200)
+			resolver := NewResolver(&client, []Proxy{{URL: "https://proxy.test"}})
+			resolver.SumDB = "https://sum.test"
+
+			_, err := resolver.Resolve("example.com/tool", "v1.0.0")
+			Expect(err).To(MatchError(ContainSubstring("checksum database lookup failed")))
+
+			client.AddJSONResponse("https://sum.test/lookup/example.com/tool@v1.0.0", "123\nexample.com/tool v1.0.0 h1:abc=\n", 200)
+			info, err := resolver.Resolve("example.com/tool", "v1.0.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Version).To(Equal("v1.0.0"))
+		})
+
+		It("should report direct modules", func() {
+			_, err := NewResolver(&client, []Proxy{{URL: "direct"}}).Resolve("example.com/tool", "latest")
+			Expect(err).To(MatchError(ErrDirect))
+		})
+	})
+
+	Describe("Info", func() {
+		It("should prefix the tag with the subdirectory of the module", func() {
+			Expect((&Info{Version: "v1.2.0"}).Tag("")).To(Equal("v1.2.0"))
+			Expect((&Info{Version: "v1.2.0"}).Tag("tools/gen")).To(Equal("tools/gen/v1.2.0"))
+			Expect((&Info{Version: "v1.2.0", Origin: &Origin{Ref: "refs/tags/gen/v1.2.0"}}).Tag("")).To(Equal("gen/v1.2.0"))
+		})
+	})
+
+	Describe("GithubRepository", func() {
+		It("should use the origin or the module path", func() {
+			repo, subdir, err := GithubRepository(&client, "example.com/tool", &Info{Origin: &Origin{URL: "https://github.com/owner/tool.git", Subdir: "tool"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo).To(Equal("owner/tool"))
+			Expect(subdir).To(Equal("tool"))
+
+			repo, subdir, err = GithubRepository(&client, "github.com/owner/tool/tools/gen/v2", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo).To(Equal("owner/tool"))
+			Expect(subdir).To(Equal("tools/gen"))
+		})
+
+		It("should follow go-import meta tags", func() {
+			client.AddJSONResponse("https://example.com/tool", `<html><head><meta name="go-import" content="example.com/tool git https://github.com/owner/tool"></head></html>`, 200)
+
+			repo, subdir, err := GithubRepository(&client, "example.com/tool", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo).To(Equal("owner/tool"))
+			Expect(subdir).To(BeEmpty())
+
+			client.AddJSONResponse("https://example.com/tool/gen/v3", `<meta name="go-import" content="example.com/tool git https://github.com/owner/tool">`, 200)
+
+			_, subdir, err = GithubRepository(&client, "example.com/tool/gen/v3", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(subdir).To(Equal("gen"))
+		})
+
+		It("should return an error for modules hosted elsewhere", func() {
+			client.AddJSONResponse("https://example.com/tool", `<meta name="go-import" content="example.com/tool git https://gitlab.com/owner/tool">`, 200)
+
+			_, _, err := GithubRepository(&client, "example.com/tool", nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
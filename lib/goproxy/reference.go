@@ -0,0 +1,93 @@
+package goproxy
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const scheme = "go:"
+
+// A Reference names a Go module and optional version, given as "go:module/path[@version]".
+type Reference struct {
+	Module  string
+	Version string
+}
+
+// IsReference returns true if s uses the go: target syntax.
+func IsReference(s string) bool {
+	return strings.HasPrefix(s, scheme)
+}
+
+func ParseReference(s string) (*Reference, error) {
+	module, version, _ := strings.Cut(strings.TrimPrefix(s, scheme), "@")
+	module = strings.Trim(module, "/")
+
+	if !IsReference(s) || !strings.Contains(module, "/") {
+		return nil, fmt.Errorf("invalid Go module reference, expected go:module/path[@version]: %s", s)
+	}
+
+	if version == "" {
+		version = "latest"
+	}
+
+	return &Reference{Module: module, Version: version}, nil
+}
+
+var majorVersionSuffix = regexp.MustCompile(`^v\d+$`)
+
+// ToolName returns the name of the binary built from the module, which is its
+// last path element ignoring a major version suffix, e.g. "tool" for "example.com/tool/v2".
+func (r *Reference) ToolName() string {
+	name := path.Base(r.Module)
+	if majorVersionSuffix.MatchString(name) {
+		name = path.Base(path.Dir(r.Module))
+	}
+
+	return name
+}
+
+func (r *Reference) String() string {
+	return scheme + r.Module + "@" + r.Version
+}
+
+// EscapePath escapes a module path or version for use in proxy URLs, where
+// upper case letters are written as "!" followed by the lower case letter.
+func EscapePath(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// MatchPatterns reports whether the module path matches one of the comma
+// separated glob patterns, as used by GOPRIVATE and GONOPROXY. A pattern
+// matches a path prefix, so "example.com/org" matches "example.com/org/tool".
+func MatchPatterns(patterns string, module string) bool {
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSuffix(strings.TrimSpace(pattern), "/")
+		if pattern == "" {
+			continue
+		}
+
+		elements := strings.Count(pattern, "/") + 1
+		parts := strings.Split(module, "/")
+		if len(parts) < elements {
+			continue
+		}
+
+		if matched, _ := path.Match(pattern, strings.Join(parts[:elements], "/")); matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,44 @@
+package goproxy_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/permafrost-dev/zeget/lib/goproxy"
+)
+
+var _ = Describe("Reference", func() {
+	It("should parse module and version", func() {
+		ref, err := ParseReference("go:golang.org/x/tools/cmd/goimports@v0.26.0")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ref.Module).To(Equal("golang.org/x/tools/cmd/goimports"))
+		Expect(ref.Version).To(Equal("v0.26.0"))
+		Expect(ref.ToolName()).To(Equal("goimports"))
+	})
+
+	It("should default to the latest version", func() {
+		ref, err := ParseReference("go:github.com/owner/tool/v2")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ref.Version).To(Equal("latest"))
+		Expect(ref.ToolName()).To(Equal("tool"))
+	})
+
+	It("should reject invalid references", func() {
+		_, err := ParseReference("go:tool")
+		Expect(err).To(HaveOccurred())
+		Expect(IsReference("owner/repo")).To(BeFalse())
+	})
+
+	It("should escape upper case letters", func() {
+		Expect(EscapePath("github.com/BurntSushi/toml")).To(Equal("github.com/!burnt!sushi/toml"))
+	})
+
+	It("should match GOPRIVATE style patterns against path prefixes", func() {
+		Expect(MatchPatterns("example.com/org,*.corp.example", "example.com/org/tool")).To(BeTrue())
+		Expect(MatchPatterns("example.com/org,*.corp.example", "git.corp.example/tool")).To(BeTrue())
+		Expect(MatchPatterns("example.com/org", "example.com/other/tool")).To(BeFalse())
+		Expect(MatchPatterns("", "example.com/org/tool")).To(BeFalse())
+	})
+})
@@ -0,0 +1,269 @@
+// Package jsonpath implements the subset of JSONPath needed to pick values out
+// of vendor release manifests: `$`, `.key`, `['key']`, `[n]`, `[*]`, `.*`,
+// recursive descent with `..key` and filters like `[?(@.kind == 'archive')]`.
+package jsonpath
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type step struct {
+	key       string
+	index     int
+	wildcard  bool
+	isIndex   bool
+	recursive bool
+	filter    *filter
+}
+
+// a filter keeps the array items whose field compares equal (or not) to a value
+type filter struct {
+	path   string
+	value  string
+	negate bool
+}
+
+func (f *filter) matches(item interface{}) bool {
+	return (GetString(item, f.path) == f.value) != f.negate
+}
+
+// Query returns all values in doc, as decoded by encoding/json, matched by path.
+func Query(doc interface{}, path string) ([]interface{}, error) {
+	steps, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := []interface{}{doc}
+	for _, s := range steps {
+		next := []interface{}{}
+		for _, value := range current {
+			if s.recursive {
+				for _, v := range descendants(value) {
+					next = append(next, s.apply(v)...)
+				}
+				continue
+			}
+			next = append(next, s.apply(value)...)
+		}
+		current = next
+	}
+
+	return current, nil
+}
+
+// Get returns the first value matched by path, or nil.
+func Get(doc interface{}, path string) interface{} {
+	values, err := Query(doc, path)
+	if err != nil || len(values) == 0 {
+		return nil
+	}
+
+	return values[0]
+}
+
+// GetString returns the first value matched by path formatted as a string.
+func GetString(doc interface{}, path string) string {
+	switch v := Get(doc, path).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (s step) apply(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if s.wildcard {
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			result := make([]interface{}, 0, len(keys))
+			for _, k := range keys {
+				result = append(result, v[k])
+			}
+			return result
+		}
+		if child, ok := v[s.key]; ok && !s.isIndex {
+			return []interface{}{child}
+		}
+	case []interface{}:
+		if s.wildcard {
+			return v
+		}
+		if s.filter != nil {
+			result := []interface{}{}
+			for _, item := range v {
+				if s.filter.matches(item) {
+					result = append(result, item)
+				}
+			}
+			return result
+		}
+		if s.isIndex {
+			i := s.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i >= 0 && i < len(v) {
+				return []interface{}{v[i]}
+			}
+		}
+	}
+
+	return nil
+}
+
+// descendants returns value and all values nested in it, depth first.
+func descendants(value interface{}) []interface{} {
+	result := []interface{}{value}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			result = append(result, descendants(v[k])...)
+		}
+	case []interface{}:
+		for _, child := range v {
+			result = append(result, descendants(child)...)
+		}
+	}
+
+	return result
+}
+
+func parse(path string) ([]step, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+
+	steps := []step{}
+
+	for len(path) > 0 {
+		recursive := false
+
+		switch {
+		case strings.HasPrefix(path, ".."):
+			recursive = true
+			path = path[2:]
+		case path[0] == '.':
+			path = path[1:]
+		}
+
+		if strings.HasPrefix(path, "[") {
+			end := closingBracket(path)
+			if end < 0 {
+				return nil, fmt.Errorf("jsonpath: unterminated bracket in %q", path)
+			}
+
+			s, err := parseBracket(path[1:end])
+			if err != nil {
+				return nil, err
+			}
+
+			s.recursive = recursive
+			steps = append(steps, s)
+			path = path[end+1:]
+
+			continue
+		}
+
+		end := strings.IndexAny(path, ".[")
+		if end < 0 {
+			end = len(path)
+		}
+
+		key := path[:end]
+		if key == "" {
+			return nil, fmt.Errorf("jsonpath: empty key in %q", path)
+		}
+
+		steps = append(steps, step{key: key, wildcard: key == "*", recursive: recursive})
+		path = path[end:]
+	}
+
+	return steps, nil
+}
+
+func parseBracket(s string) (step, error) {
+	s = strings.TrimSpace(s)
+
+	if s == "*" {
+		return step{wildcard: true}, nil
+	}
+
+	if strings.HasPrefix(s, "?(") && strings.HasSuffix(s, ")") {
+		return parseFilter(s[2 : len(s)-1])
+	}
+
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return step{key: s[1 : len(s)-1]}, nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return step{}, fmt.Errorf("jsonpath: invalid subscript [%s]", s)
+	}
+
+	return step{index: i, isIndex: true}, nil
+}
+
+// closingBracket returns the index of the "]" closing the bracket at the start
+// of path, skipping over quoted strings.
+func closingBracket(path string) int {
+	var quote byte
+
+	for i := 1; i < len(path); i++ {
+		switch c := path[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+
+	return -1
+}
+
+// parseFilter parses expressions of the form `@.path == 'value'` or `@.path != 'value'`.
+func parseFilter(expr string) (step, error) {
+	op := "=="
+	left, right, found := strings.Cut(expr, "==")
+	if !found {
+		op = "!="
+		left, right, found = strings.Cut(expr, "!=")
+	}
+
+	left = strings.TrimSpace(left)
+	right = strings.TrimSpace(right)
+
+	if !found || !strings.HasPrefix(left, "@") {
+		return step{}, fmt.Errorf("jsonpath: unsupported filter %q", expr)
+	}
+
+	if len(right) >= 2 && (right[0] == '\'' || right[0] == '"') && right[len(right)-1] == right[0] {
+		right = right[1 : len(right)-1]
+	}
+
+	return step{filter: &filter{path: "$" + left[1:], value: right, negate: op == "!="}}, nil
+}
@@ -0,0 +1,64 @@
+package jsonpath_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/permafrost-dev/zeget/lib/jsonpath"
+)
+
+var _ = Describe("JSONPath", func() {
+	var doc interface{}
+
+	BeforeEach(func() {
+		Expect(json.Unmarshal([]byte(`{
+			"name": "terraform",
+			"versions": {
+				"1.9.0": {"version": "1.9.0", "builds": [{"os": "linux", "url": "https://example.com/linux.zip"}, {"os": "darwin", "url": "https://example.com/darwin.zip"}]},
+				"1.8.5": {"version": "1.8.5", "builds": [{"os": "linux", "url": "https://example.com/old.zip"}]}
+			},
+			"files": ["a", "b", "c"],
+			"builds": [{"kind": "archive", "name": "a.tar.gz"}, {"kind": "installer", "name": "a.pkg"}, {"kind": "archive", "name": "a.zip"}],
+			"count": 3
+		}`), &doc)).To(Succeed())
+	})
+
+	DescribeTable("Query",
+		func(path string, count int) {
+			values, err := Query(doc, path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(values).To(HaveLen(count))
+		},
+		Entry("child", "$.name", 1),
+		Entry("quoted child", "$['name']", 1),
+		Entry("wildcards", "$.versions.*.builds[*]", 3),
+		Entry("keys with dots", "$.versions['1.9.0'].builds[*].url", 2),
+		Entry("recursive descent", "$..url", 3),
+		Entry("negative index", "$.files[-1]", 1),
+		Entry("index out of range", "$.files[5]", 0),
+		Entry("missing key", "$.missing.key", 0),
+		Entry("equality filter", "$.builds[?(@.kind == 'archive')]", 2),
+		Entry("inequality filter", "$.builds[?(@.kind != 'archive')].name", 1),
+		Entry("filter with a bracket in the value", "$.builds[?(@.name == 'a]')]", 0),
+	)
+
+	DescribeTable("invalid paths",
+		func(path string) {
+			_, err := Query(doc, path)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("unterminated bracket", "$.files["),
+		Entry("invalid subscript", "$.files[x]"),
+		Entry("empty key", "$.."),
+		Entry("unsupported filter", "$.builds[?(@.size > 1)]"),
+	)
+
+	It("should format values as strings", func() {
+		Expect(GetString(doc, "$.files[1]")).To(Equal("b"))
+		Expect(GetString(doc, "name")).To(Equal("terraform"))
+		Expect(GetString(doc, "$.count")).To(Equal("3"))
+		Expect(GetString(doc, "$.missing")).To(BeEmpty())
+	})
+})
@@ -72,7 +72,7 @@ func (m HTTPClient) GetClient() *http.Client {
 	return nil
 }
 
-func (m HTTPClient) GetJSON(url string) (*http.Response, error) {
+func (m HTTPClient) findResponse(url string) (*http.Response, bool, error) {
 	before, _, _ := utilities.Cut(url, "?")
 	url = before
 
@@ -84,20 +84,36 @@ func (m HTTPClient) GetJSON(url string) (*http.Response, error) {
 				err = errors.New("mock 500 error")
 			}
 
-			return NewMockResponse(v[0].Body, v[0].StatusCode), err
+			return NewMockResponse(v[0].Body, v[0].StatusCode), true, err
 		}
 	}
 
+	return nil, false, nil
+}
+
+func (m HTTPClient) GetJSON(url string) (*http.Response, error) {
+	if resp, found, err := m.findResponse(url); found {
+		return resp, err
+	}
+
 	js := `{"message":"Not Found","documentation_url":"https://developer.github.com/v3"}`
 
 	return NewMockResponse(js, http.StatusNotFound), nil
 }
 
-func (m HTTPClient) GetBinaryFile(_ string) (*http.Response, error) {
+func (m HTTPClient) GetBinaryFile(url string) (*http.Response, error) {
+	if resp, found, err := m.findResponse(url); found {
+		return resp, err
+	}
+
 	return NewMockResponse("mock body", http.StatusOK), nil
 }
 
-func (m HTTPClient) GetText(_ string) (*http.Response, error) {
+func (m HTTPClient) GetText(url string) (*http.Response, error) {
+	if resp, found, err := m.findResponse(url); found {
+		return resp, err
+	}
+
 	return NewMockResponse("mock body", http.StatusOK), nil
 }
 
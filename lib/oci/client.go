@@ -0,0 +1,188 @@
+package oci
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/permafrost-dev/zeget/lib/credentials"
+	"github.com/permafrost-dev/zeget/lib/utilities"
+)
+
+// A Doer sends HTTP requests, like *http.Client or the download client, which
+// applies the configured mirrors, headers and TLS settings.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// A Client reads manifests from an OCI distribution registry using the
+// registry v2 API. Bearer tokens are obtained on demand from the auth
+// endpoint announced in the WWW-Authenticate header, anonymously or with a
+// basic credential from the credential store.
+type Client struct {
+	HTTP        Doer
+	Credentials *credentials.Store
+	tokens      map[string]string
+}
+
+func NewClient(httpClient Doer, store *credentials.Store) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		HTTP:        httpClient,
+		Credentials: store,
+		tokens:      map[string]string{},
+	}
+}
+
+// Token returns the bearer token obtained for the registry, if any.
+func (c *Client) Token(registry string) string {
+	return c.tokens[registry]
+}
+
+// GetManifest fetches the manifest or index for the given tag or digest.
+func (c *Client) GetManifest(ref *Reference, reference string) (*Manifest, error) {
+	u := ref.ManifestURL(reference)
+
+	resp, err := c.get(ref, u, ManifestAccept)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get manifest %s: %s: %s", u, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("get manifest %s: %w", u, err)
+	}
+
+	if manifest.MediaType == "" {
+		manifest.MediaType = strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	}
+
+	return &manifest, nil
+}
+
+func (c *Client) get(ref *Reference, u string, accept string) (*http.Response, error) {
+	resp, err := c.do(ref, u, accept)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || c.tokens[ref.Registry] != "" {
+		return resp, err
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	if err := c.authenticate(ref, challenge); err != nil {
+		return nil, err
+	}
+
+	return c.do(ref, u, accept)
+}
+
+func (c *Client) do(ref *Reference, u string, accept string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", accept)
+
+	if token := c.tokens[ref.Registry]; token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if cred := c.Credentials.Lookup(ref.Registry); cred != nil {
+		cred.Apply(req)
+	}
+
+	return c.HTTP.Do(req)
+}
+
+// authenticate exchanges credentials for a pull token, see
+// https://distribution.github.io/distribution/spec/auth/token/
+func (c *Client) authenticate(ref *Reference, challenge string) error {
+	scheme, params := ParseChallenge(challenge)
+	if !strings.EqualFold(scheme, "bearer") || params["realm"] == "" {
+		return fmt.Errorf("registry %s: unsupported authentication challenge %q", ref.Registry, challenge)
+	}
+
+	query := url.Values{}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", utilities.SetIf(params["scope"] == "", params["scope"], fmt.Sprintf("repository:%s:pull", ref.Repository)))
+
+	req, err := http.NewRequest("GET", params["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	if cred := c.Credentials.Lookup(ref.Registry); cred != nil && cred.Kind == credentials.KindBasic {
+		req.SetBasicAuth(cred.Username, cred.Password)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry %s: authentication failed: %s", ref.Registry, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return fmt.Errorf("registry %s: invalid token response: %w", ref.Registry, err)
+	}
+
+	c.tokens[ref.Registry] = utilities.SetIf(token.Token == "", token.Token, token.AccessToken)
+	if c.tokens[ref.Registry] == "" {
+		return fmt.Errorf("registry %s: no token in authentication response", ref.Registry)
+	}
+
+	return nil
+}
+
+// ParseChallenge splits a WWW-Authenticate header like
+// `Bearer realm="https://ghcr.io/token",service="ghcr.io"` into its scheme and parameters.
+func ParseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := map[string]string{}
+
+	for rest != "" {
+		var key, value string
+
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+
+		if key = strings.TrimSpace(key); key != "" {
+			params[strings.ToLower(key)] = value
+		}
+	}
+
+	return scheme, params
+}
@@ -0,0 +1,91 @@
+package oci_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/permafrost-dev/zeget/lib/credentials"
+	. "github.com/permafrost-dev/zeget/lib/oci"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server        *httptest.Server
+		ref           *Reference
+		store         *credentials.Store
+		tokenRequests int
+	)
+
+	BeforeEach(func() {
+		tokenRequests = 0
+		store = credentials.NewStore()
+		store.Getenv = func(string) string { return "" }
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+			tokenRequests++
+			Expect(r.URL.Query().Get("scope")).To(Equal("repository:org/tool:pull"))
+
+			if user, pass, ok := r.BasicAuth(); ok && (user != "user" || pass != "pass") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			fmt.Fprint(w, `{"token": "pull-token"}`)
+		})
+		mux.HandleFunc("/v2/org/tool/manifests/v1", func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer pull-token" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:org/tool:pull"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			Expect(r.Header.Get("Accept")).To(ContainSubstring(MediaTypeImageIndex))
+			w.Header().Set("Content-Type", MediaTypeImageManifest)
+			fmt.Fprint(w, `{"schemaVersion": 2, "layers": [{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": "sha256:aaa", "size": 3}]}`)
+		})
+
+		server = httptest.NewServer(mux)
+		ref, _ = ParseReference("oci://" + strings.TrimPrefix(server.URL, "http://") + "/org/tool:v1")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should authenticate with the token endpoint and fetch the manifest", func() {
+		client := NewClient(server.Client(), store)
+
+		manifest, err := client.GetManifest(ref, ref.Ref())
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.IsIndex()).To(BeFalse())
+		Expect(manifest.MediaType).To(Equal(MediaTypeImageManifest))
+		Expect(manifest.Layers[0].Extension()).To(Equal(".tar.gz"))
+		Expect(client.Token(ref.Registry)).To(Equal("pull-token"))
+		Expect(tokenRequests).To(Equal(1))
+	})
+
+	It("should send basic credentials to the token endpoint", func() {
+		store.Add(credentials.NewBasicCredential(ref.Registry, "user", "wrong"))
+
+		_, err := NewClient(server.Client(), store).GetManifest(ref, ref.Ref())
+		Expect(err).To(MatchError(ContainSubstring("authentication failed")))
+	})
+
+	It("should parse authentication challenges", func() {
+		scheme, params := ParseChallenge(`Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:org/tool:pull"`)
+
+		Expect(scheme).To(Equal("Bearer"))
+		Expect(params).To(Equal(map[string]string{
+			"realm":   "https://ghcr.io/token",
+			"service": "ghcr.io",
+			"scope":   "repository:org/tool:pull",
+		}))
+	})
+})
@@ -0,0 +1,114 @@
+package oci
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const scheme = "oci://"
+
+// A Reference identifies an artifact in an OCI distribution registry, given as
+// "oci://registry/repository[:tag][@digest]".
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// IsReference returns true if s uses the oci:// target syntax.
+func IsReference(s string) bool {
+	return strings.HasPrefix(s, scheme)
+}
+
+func ParseReference(s string) (*Reference, error) {
+	if !IsReference(s) {
+		return nil, fmt.Errorf("invalid OCI reference: %s", s)
+	}
+
+	registry, repository, found := strings.Cut(strings.TrimPrefix(s, scheme), "/")
+	if !found || registry == "" || repository == "" {
+		return nil, fmt.Errorf("invalid OCI reference, expected oci://registry/repository[:tag]: %s", s)
+	}
+
+	result := &Reference{Registry: registry, Tag: "latest"}
+
+	if before, digest, found := strings.Cut(repository, "@"); found {
+		repository = before
+		result.Digest = digest
+	}
+
+	// a tag can only appear in the last path segment
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		result.Tag = repository[i+1:]
+		repository = repository[:i]
+	}
+
+	if result.Registry == "docker.io" {
+		result.Registry = "registry-1.docker.io"
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+
+	result.Repository = repository
+
+	return result, nil
+}
+
+// Name returns the last path segment of the repository, e.g. "tool" for "org/tool".
+func (r *Reference) Name() string {
+	return path.Base(r.Repository)
+}
+
+// Ref returns the digest if given, otherwise the tag.
+func (r *Reference) Ref() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+
+	return r.Tag
+}
+
+// BaseURL returns the registry API endpoint. Registries on localhost are
+// accessed over plain http, like docker does.
+func (r *Reference) BaseURL() string {
+	host := r.Registry
+	if h, _, found := strings.Cut(host, ":"); found {
+		host = h
+	}
+
+	if host == "localhost" || host == "127.0.0.1" {
+		return "http://" + r.Registry
+	}
+
+	return "https://" + r.Registry
+}
+
+func (r *Reference) ManifestURL(reference string) string {
+	return fmt.Sprintf("%s/v2/%s/manifests/%s", r.BaseURL(), r.Repository, reference)
+}
+
+func (r *Reference) BlobURL(digest string) string {
+	return fmt.Sprintf("%s/v2/%s/blobs/%s", r.BaseURL(), r.Repository, digest)
+}
+
+func (r *Reference) String() string {
+	result := scheme + r.Registry + "/" + r.Repository + ":" + r.Tag
+	if r.Digest != "" {
+		result += "@" + r.Digest
+	}
+
+	return result
+}
+
+// BlobDigest returns the sha256 hex digest of a blob URL, if it is one.
+func BlobDigest(u string) (string, bool) {
+	_, digest, found := strings.Cut(u, "/blobs/sha256:")
+	if !found || len(digest) != 64 {
+		return "", false
+	}
+
+	return digest, true
+}
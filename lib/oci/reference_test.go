@@ -0,0 +1,63 @@
+package oci_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/permafrost-dev/zeget/lib/oci"
+)
+
+var _ = Describe("Reference", func() {
+	It("should parse registry, repository and tag", func() {
+		ref, err := ParseReference("oci://ghcr.io/org/tool:v1.2.3")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ref.Registry).To(Equal("ghcr.io"))
+		Expect(ref.Repository).To(Equal("org/tool"))
+		Expect(ref.Tag).To(Equal("v1.2.3"))
+		Expect(ref.Name()).To(Equal("tool"))
+		Expect(ref.ManifestURL(ref.Ref())).To(Equal("https://ghcr.io/v2/org/tool/manifests/v1.2.3"))
+	})
+
+	It("should default to the latest tag and keep registry ports", func() {
+		ref, err := ParseReference("oci://localhost:5000/tool")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ref.Registry).To(Equal("localhost:5000"))
+		Expect(ref.Tag).To(Equal("latest"))
+		Expect(ref.BlobURL("sha256:abc")).To(Equal("http://localhost:5000/v2/tool/blobs/sha256:abc"))
+	})
+
+	It("should prefer digests over tags", func() {
+		ref, err := ParseReference("oci://ghcr.io/org/tool:v1@sha256:1234")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ref.Tag).To(Equal("v1"))
+		Expect(ref.Ref()).To(Equal("sha256:1234"))
+	})
+
+	It("should map docker hub images to the registry host", func() {
+		ref, err := ParseReference("oci://docker.io/alpine:3")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ref.Registry).To(Equal("registry-1.docker.io"))
+		Expect(ref.Repository).To(Equal("library/alpine"))
+	})
+
+	It("should reject references without a repository", func() {
+		_, err := ParseReference("oci://ghcr.io")
+		Expect(err).To(HaveOccurred())
+		Expect(IsReference("ghcr.io/org/tool")).To(BeFalse())
+	})
+
+	It("should extract digests from blob URLs", func() {
+		digest := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+		hex, ok := BlobDigest("https://ghcr.io/v2/org/tool/blobs/sha256:" + digest)
+		Expect(ok).To(BeTrue())
+		Expect(hex).To(Equal(digest))
+
+		_, ok = BlobDigest("https://example.com/tool.tar.gz")
+		Expect(ok).To(BeFalse())
+	})
+})
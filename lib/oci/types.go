@@ -0,0 +1,79 @@
+package oci
+
+import "strings"
+
+const (
+	MediaTypeImageIndex         = "application/vnd.oci.image.index.v1+json"
+	MediaTypeImageManifest      = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	AnnotationTitle = "org.opencontainers.image.title"
+)
+
+// ManifestAccept is sent when requesting manifests so that registries return
+// an index for multi-platform artifacts.
+var ManifestAccept = strings.Join([]string{
+	MediaTypeImageIndex,
+	MediaTypeDockerManifestList,
+	MediaTypeImageManifest,
+	MediaTypeDockerManifest,
+}, ", ")
+
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// Matches returns true if the platform is for the given Go OS/Arch pair. An
+// empty os or arch matches anything.
+func (p *Platform) Matches(os string, arch string) bool {
+	if p == nil {
+		return false
+	}
+
+	return (os == "" || p.OS == os) && (arch == "" || p.Architecture == arch)
+}
+
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *Platform         `json:"platform,omitempty"`
+}
+
+// Title returns the file name annotation that ORAS sets on artifact layers.
+func (d Descriptor) Title() string {
+	return d.Annotations[AnnotationTitle]
+}
+
+// Extension guesses a file extension from the layer media type.
+func (d Descriptor) Extension() string {
+	switch {
+	case strings.HasSuffix(d.MediaType, "tar+gzip") || strings.HasSuffix(d.MediaType, "tar.gzip"):
+		return ".tar.gz"
+	case strings.HasSuffix(d.MediaType, "tar+zstd"):
+		return ".tar.zst"
+	case strings.HasSuffix(d.MediaType, ".tar") || strings.HasSuffix(d.MediaType, "tar"):
+		return ".tar"
+	case strings.HasSuffix(d.MediaType, "zip"):
+		return ".zip"
+	}
+
+	return ""
+}
+
+// A Manifest is either an image manifest (with layers) or an index (with manifests).
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+	Manifests     []Descriptor `json:"manifests"`
+}
+
+func (m *Manifest) IsIndex() bool {
+	return m.MediaType == MediaTypeImageIndex || m.MediaType == MediaTypeDockerManifestList || len(m.Manifests) > 0
+}
@@ -0,0 +1,90 @@
+package picker
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// FuzzyScore matches query against s as a case-insensitive subsequence and
+// returns a score that is higher for consecutive matches and matches at the
+// start of words. Spaces in the query are ignored.
+func FuzzyScore(query string, s string) (int, bool) {
+	needle := []rune(strings.ToLower(strings.Join(strings.Fields(query), "")))
+	if len(needle) == 0 {
+		return 0, true
+	}
+
+	target := []rune(strings.ToLower(s))
+	best, found := 0, false
+
+	// greedily match from every occurrence of the first rune and keep the best
+	for start := range target {
+		if target[start] != needle[0] {
+			continue
+		}
+
+		if score, ok := scoreFrom(needle, target, start); ok && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+
+	return best, found
+}
+
+func scoreFrom(needle []rune, target []rune, start int) (int, bool) {
+	score := 0
+	last := start - 1
+
+	for _, q := range needle {
+		found := -1
+		for i := last + 1; i < len(target); i++ {
+			if target[i] == q {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			return 0, false
+		}
+
+		score++
+		if found == last+1 && found > start {
+			score += 5
+		}
+		if found == 0 || !unicode.IsLetter(target[found-1]) && !unicode.IsDigit(target[found-1]) {
+			score += 3
+		}
+
+		last = found
+	}
+
+	return score, true
+}
+
+// FuzzyFilter returns the indices of the items matching query, best matches
+// first; items with equal scores keep their order.
+func FuzzyFilter(query string, items []string) []int {
+	type match struct {
+		index int
+		score int
+	}
+
+	matches := []match{}
+	for i, item := range items {
+		if score, ok := FuzzyScore(query, item); ok {
+			matches = append(matches, match{i, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	result := make([]int, len(matches))
+	for i, m := range matches {
+		result[i] = m.index
+	}
+
+	return result
+}
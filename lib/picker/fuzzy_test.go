@@ -0,0 +1,41 @@
+package picker_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/picker"
+)
+
+var _ = Describe("Fuzzy matching", func() {
+	DescribeTable("FuzzyScore matching",
+		func(query string, s string, expected bool) {
+			_, ok := FuzzyScore(query, s)
+			Expect(ok).To(Equal(expected))
+		},
+		Entry("an empty query", "", "anything", true),
+		Entry("a subsequence", "lnx64", "tool_linux_amd64.tar.gz", true),
+		Entry("case insensitive", "LINUX", "tool_linux_amd64", true),
+		Entry("spaces are ignored", "linux arm", "tool_linux_arm64", true),
+		Entry("wrong order", "amdlinux", "tool_linux_amd64", false),
+		Entry("missing characters", "windows", "tool_linux_amd64", false),
+	)
+
+	It("should prefer consecutive matches and word starts", func() {
+		consecutive, _ := FuzzyScore("arm", "tool_linux_arm64")
+		scattered, _ := FuzzyScore("arm", "tool_darwin_amd64_rm")
+		Expect(consecutive).To(BeNumerically(">", scattered))
+	})
+
+	It("should order items by score and keep ties in order", func() {
+		items := []string{
+			"tool_darwin_amd64.tar.gz",
+			"tool_linux_arm64.tar.gz",
+			"tool_linux_amd64.tar.gz",
+			"tool_windows_amd64.zip",
+		}
+
+		Expect(FuzzyFilter("linux", items)).To(Equal([]int{1, 2}))
+		Expect(FuzzyFilter("amd64", items)).To(Equal([]int{0, 2, 3}))
+		Expect(FuzzyFilter("", items)).To(Equal([]int{0, 1, 2, 3}))
+	})
+})
@@ -0,0 +1,99 @@
+package picker
+
+import "unicode/utf8"
+
+type KeyType int
+
+const (
+	KeyRune KeyType = iota
+	KeyUp
+	KeyDown
+	KeyPageUp
+	KeyPageDown
+	KeyEnter
+	KeyTab
+	KeyBackspace
+	KeyClear
+	KeySelectAll
+	KeyCancel
+)
+
+// A Key is a single key press; Rune is only set for KeyRune.
+type Key struct {
+	Type KeyType
+	Rune rune
+}
+
+var escapeSequences = map[string]KeyType{
+	"[A":  KeyUp,
+	"[B":  KeyDown,
+	"OA":  KeyUp,
+	"OB":  KeyDown,
+	"[5~": KeyPageUp,
+	"[6~": KeyPageDown,
+}
+
+// ParseKeys decodes the bytes read from a terminal in raw mode. Unknown escape
+// sequences and control characters are ignored; a lone escape cancels.
+func ParseKeys(b []byte) []Key {
+	keys := []Key{}
+
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			n, key := parseEscape(b[1:])
+			if key != nil {
+				keys = append(keys, *key)
+			}
+			b = b[1+n:]
+			continue
+		case c == '\r' || c == '\n':
+			keys = append(keys, Key{Type: KeyEnter})
+		case c == '\t':
+			keys = append(keys, Key{Type: KeyTab})
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, Key{Type: KeyBackspace})
+		case c == 0x03 || c == 0x04:
+			keys = append(keys, Key{Type: KeyCancel})
+		case c == 0x10:
+			keys = append(keys, Key{Type: KeyUp})
+		case c == 0x0e:
+			keys = append(keys, Key{Type: KeyDown})
+		case c == 0x15:
+			keys = append(keys, Key{Type: KeyClear})
+		case c == 0x01:
+			keys = append(keys, Key{Type: KeySelectAll})
+		case c >= 0x20:
+			r, size := utf8.DecodeRune(b)
+			if r != utf8.RuneError {
+				keys = append(keys, Key{Type: KeyRune, Rune: r})
+			}
+			b = b[size:]
+			continue
+		}
+
+		b = b[1:]
+	}
+
+	return keys
+}
+
+// parseEscape parses the bytes following an escape character and returns the
+// number of bytes consumed.
+func parseEscape(b []byte) (int, *Key) {
+	if len(b) == 0 || (b[0] != '[' && b[0] != 'O') {
+		return 0, &Key{Type: KeyCancel}
+	}
+
+	// CSI sequences end with a byte in the range 0x40-0x7e
+	for i := 1; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			if t, found := escapeSequences[string(b[:i+1])]; found {
+				return i + 1, &Key{Type: t}
+			}
+			return i + 1, nil
+		}
+	}
+
+	return len(b), nil
+}
@@ -0,0 +1,22 @@
+package picker_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/picker"
+)
+
+var _ = Describe("ParseKeys", func() {
+	DescribeTable("decoding terminal input",
+		func(input string, expected []Key) {
+			Expect(ParseKeys([]byte(input))).To(Equal(expected))
+		},
+		Entry("text", "aé", []Key{{Type: KeyRune, Rune: 'a'}, {Type: KeyRune, Rune: 'é'}}),
+		Entry("arrow keys", "\x1b[A\x1b[B\x1bOA", []Key{{Type: KeyUp}, {Type: KeyDown}, {Type: KeyUp}}),
+		Entry("page keys", "\x1b[5~\x1b[6~", []Key{{Type: KeyPageUp}, {Type: KeyPageDown}}),
+		Entry("unknown escape sequences", "\x1b[1;5Cx", []Key{{Type: KeyRune, Rune: 'x'}}),
+		Entry("a lone escape", "\x1b", []Key{{Type: KeyCancel}}),
+		Entry("control keys", "\r\t\x7f\x15\x01\x03", []Key{{Type: KeyEnter}, {Type: KeyTab}, {Type: KeyBackspace}, {Type: KeyClear}, {Type: KeySelectAll}, {Type: KeyCancel}}),
+		Entry("emacs movement", "\x10\x0e", []Key{{Type: KeyUp}, {Type: KeyDown}}),
+	)
+})
@@ -0,0 +1,254 @@
+// Package picker implements an interactive terminal list with fuzzy
+// filtering and optional multi-select.
+package picker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var ErrCancelled = errors.New("selection cancelled")
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	promptStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#1bef52")).Bold(true)
+	cursorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#efe51b")).Bold(true)
+	selectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#1bef52"))
+	fadedStyle    = lipgloss.NewStyle().Faint(true)
+)
+
+// A Model holds the state of a picker; it is driven by Update and drawn by View.
+type Model struct {
+	Title  string
+	Items  []string
+	Multi  bool
+	Height int // maximum number of visible items
+	Width  int // maximum line width, 0 for no limit
+
+	query     []rune
+	matches   []int // indices of the items matching the query, best first
+	cursor    int   // position in matches
+	offset    int   // first visible position in matches
+	selected  map[int]bool
+	done      bool
+	cancelled bool
+}
+
+func NewModel(title string, items []string, multi bool) *Model {
+	m := &Model{
+		Title:    title,
+		Items:    items,
+		Multi:    multi,
+		Height:   10,
+		selected: map[int]bool{},
+	}
+	m.filter()
+
+	return m
+}
+
+func (m *Model) Query() string {
+	return string(m.query)
+}
+
+// Matches returns the indices of the items matching the query, best first.
+func (m *Model) Matches() []int {
+	return m.matches
+}
+
+// Current returns the index of the item under the cursor, or -1 if nothing matches.
+func (m *Model) Current() int {
+	if len(m.matches) == 0 {
+		return -1
+	}
+
+	return m.matches[m.cursor]
+}
+
+func (m *Model) Done() bool {
+	return m.done || m.cancelled
+}
+
+func (m *Model) Cancelled() bool {
+	return m.cancelled
+}
+
+// Selection returns the sorted indices of the chosen items. Without any
+// toggled items, the item under the cursor is chosen.
+func (m *Model) Selection() []int {
+	result := []int{}
+	for i, selected := range m.selected {
+		if selected {
+			result = append(result, i)
+		}
+	}
+	sort.Ints(result)
+
+	if len(result) == 0 && m.Current() >= 0 {
+		result = append(result, m.Current())
+	}
+
+	return result
+}
+
+func (m *Model) Update(key Key) {
+	switch key.Type {
+	case KeyRune:
+		m.query = append(m.query, key.Rune)
+		m.filter()
+	case KeyBackspace:
+		if len(m.query) > 0 {
+			m.query = m.query[:len(m.query)-1]
+			m.filter()
+		}
+	case KeyClear:
+		m.query = nil
+		m.filter()
+	case KeyUp:
+		m.move(-1)
+	case KeyDown:
+		m.move(1)
+	case KeyPageUp:
+		m.move(-m.Height)
+	case KeyPageDown:
+		m.move(m.Height)
+	case KeyTab:
+		if m.Multi && m.Current() >= 0 {
+			m.selected[m.Current()] = !m.selected[m.Current()]
+			m.move(1)
+		}
+	case KeySelectAll:
+		if m.Multi {
+			all := true
+			for _, i := range m.matches {
+				all = all && m.selected[i]
+			}
+			for _, i := range m.matches {
+				m.selected[i] = !all
+			}
+		}
+	case KeyEnter:
+		m.done = len(m.Selection()) > 0
+	case KeyCancel:
+		m.cancelled = true
+	}
+}
+
+func (m *Model) filter() {
+	m.matches = FuzzyFilter(string(m.query), m.Items)
+	m.cursor = 0
+	m.offset = 0
+}
+
+func (m *Model) move(delta int) {
+	if len(m.matches) == 0 {
+		return
+	}
+
+	m.cursor = max(0, min(len(m.matches)-1, m.cursor+delta))
+
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.Height {
+		m.offset = m.cursor - m.Height + 1
+	}
+}
+
+func (m *Model) truncate(s string, reserved int) string {
+	if m.Width <= 0 || len([]rune(s))+reserved <= m.Width {
+		return s
+	}
+
+	runes := []rune(s)
+	return string(runes[:max(0, m.Width-reserved-1)]) + "…"
+}
+
+// View renders the picker as lines of text.
+func (m *Model) View() []string {
+	lines := []string{}
+
+	if m.Title != "" {
+		lines = append(lines, titleStyle.Render(m.truncate(m.Title, 0)))
+	}
+
+	lines = append(lines, promptStyle.Render("> ")+string(m.query))
+
+	end := min(len(m.matches), m.offset+m.Height)
+	for pos := m.offset; pos < end; pos++ {
+		i := m.matches[pos]
+		marker := "  "
+		if m.Multi {
+			marker = "  [ ] "
+			if m.selected[i] {
+				marker = "  [x] "
+			}
+		}
+		label := m.truncate(m.Items[i], len([]rune(marker)))
+
+		switch {
+		case pos == m.cursor:
+			lines = append(lines, cursorStyle.Render("›"+marker[1:]+label))
+		case m.selected[i]:
+			lines = append(lines, selectedStyle.Render(marker+label))
+		default:
+			lines = append(lines, marker+label)
+		}
+	}
+
+	hint := "↑/↓ move • enter confirm • esc cancel"
+	if m.Multi {
+		hint = "↑/↓ move • tab toggle • ctrl+a toggle all • enter confirm • esc cancel"
+	}
+	lines = append(lines, fadedStyle.Render(m.truncate(fmt.Sprintf("%d/%d • %s", len(m.matches), len(m.Items), hint), 0)))
+
+	return lines
+}
+
+// Run draws the picker on out and processes the keys read from in until the
+// user confirms or cancels. in is expected to be a terminal in raw mode.
+func (m *Model) Run(in io.Reader, out io.Writer) ([]int, error) {
+	drawn := 0
+	clear := func() {
+		if drawn > 1 {
+			fmt.Fprintf(out, "\x1b[%dA", drawn-1)
+		}
+		fmt.Fprint(out, "\r\x1b[J")
+	}
+
+	fmt.Fprint(out, "\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h")
+
+	buf := make([]byte, 256)
+	for {
+		clear()
+		lines := m.View()
+		fmt.Fprint(out, strings.Join(lines, "\r\n"))
+		drawn = len(lines)
+
+		n, err := in.Read(buf)
+		for _, key := range ParseKeys(buf[:n]) {
+			if m.Update(key); m.Done() {
+				break
+			}
+		}
+
+		if m.Done() || err != nil {
+			clear()
+
+			if err != nil && !m.Done() {
+				return nil, fmt.Errorf("read selection: %w", err)
+			}
+			if m.Cancelled() {
+				return nil, ErrCancelled
+			}
+
+			return m.Selection(), nil
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package picker_test
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/permafrost-dev/zeget/lib/picker"
+)
+
+var items = []string{
+	"tool_darwin_amd64.tar.gz",
+	"tool_darwin_arm64.tar.gz",
+	"tool_linux_amd64.tar.gz",
+	"tool_linux_arm64.tar.gz",
+	"tool_windows_amd64.zip",
+}
+
+func typeKeys(m *Model, input string) {
+	for _, key := range ParseKeys([]byte(input)) {
+		m.Update(key)
+	}
+}
+
+var _ = Describe("Model", func() {
+	It("should filter the items while typing", func() {
+		m := NewModel("", items, false)
+
+		typeKeys(m, "linux")
+		Expect(m.Query()).To(Equal("linux"))
+		Expect(m.Matches()).To(Equal([]int{2, 3}))
+
+		typeKeys(m, "\x7f\x7f\x7f\x7f\x7fwindows")
+		Expect(m.Matches()).To(Equal([]int{4}))
+
+		typeKeys(m, "\x15")
+		Expect(m.Matches()).To(HaveLen(len(items)))
+	})
+
+	It("should move the cursor within the matches", func() {
+		m := NewModel("", items, false)
+
+		typeKeys(m, "\x1b[B\x1b[B")
+		Expect(m.Current()).To(Equal(2))
+
+		typeKeys(m, "\x1b[A\x1b[A\x1b[A")
+		Expect(m.Current()).To(Equal(0))
+
+		typeKeys(m, "\x1b[6~")
+		Expect(m.Current()).To(Equal(4))
+	})
+
+	It("should select the item under the cursor", func() {
+		m := NewModel("", items, false)
+
+		typeKeys(m, "linux arm\r")
+		Expect(m.Done()).To(BeTrue())
+		Expect(m.Selection()).To(Equal([]int{3}))
+	})
+
+	It("should toggle several items in multi-select mode", func() {
+		m := NewModel("", items, true)
+
+		typeKeys(m, "\t\x1b[B\t")
+		Expect(m.Selection()).To(Equal([]int{0, 2}))
+
+		typeKeys(m, "\x15linux\x01")
+		Expect(m.Selection()).To(Equal([]int{0, 2, 3}))
+
+		typeKeys(m, "\x01\r")
+		Expect(m.Selection()).To(Equal([]int{0}))
+		Expect(m.Done()).To(BeTrue())
+	})
+
+	It("should not confirm without matches", func() {
+		m := NewModel("", items, false)
+
+		typeKeys(m, "xyz\r")
+		Expect(m.Done()).To(BeFalse())
+		Expect(m.Selection()).To(BeEmpty())
+	})
+
+	It("should render the visible items", func() {
+		m := NewModel("Select an asset", items, true)
+		m.Height = 2
+
+		typeKeys(m, "\t\x1b[B\x1b[B")
+		view := m.View()
+
+		Expect(view).To(HaveLen(5))
+		Expect(view[0]).To(ContainSubstring("Select an asset"))
+		Expect(view[2]).To(ContainSubstring("[ ] tool_linux_amd64.tar.gz"))
+		Expect(view[3]).To(ContainSubstring("› [ ] tool_linux_arm64.tar.gz"))
+		Expect(view[4]).To(ContainSubstring("5/5"))
+	})
+
+	It("should truncate long lines", func() {
+		m := NewModel("", []string{strings.Repeat("x", 100)}, false)
+		m.Width = 20
+
+		Expect(m.View()[1]).To(ContainSubstring(strings.Repeat("x", 17) + "…"))
+	})
+})
+
+var _ = Describe("Run", func() {
+	It("should return the confirmed selection", func() {
+		var out bytes.Buffer
+
+		selection, err := NewModel("", items, false).Run(strings.NewReader("windows\r"), &out)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selection).To(Equal([]int{4}))
+		Expect(out.String()).To(ContainSubstring("tool_windows_amd64.zip"))
+	})
+
+	It("should return an error when cancelled", func() {
+		_, err := NewModel("", items, false).Run(strings.NewReader("lin\x1b"), &bytes.Buffer{})
+		Expect(err).To(MatchError(ErrCancelled))
+	})
+
+	It("should return an error when the input ends", func() {
+		_, err := NewModel("", items, false).Run(strings.NewReader("lin"), &bytes.Buffer{})
+		Expect(err).To(HaveOccurred())
+	})
+})
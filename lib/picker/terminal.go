@@ -0,0 +1,32 @@
+package picker
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// IsTerminal returns true if f is connected to a terminal.
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// Select lets the user pick from items with the keyboard, reading from stdin
+// and drawing on stderr, and returns the indices of the chosen items.
+func Select(title string, items []string, multi bool) ([]int, error) {
+	fd := int(os.Stdin.Fd())
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	defer term.Restore(fd, state)
+
+	m := NewModel(title, items, multi)
+	if width, height, err := term.GetSize(int(os.Stderr.Fd())); err == nil {
+		m.Width = width
+		m.Height = max(1, min(m.Height, height-3))
+	}
+
+	return m.Run(os.Stdin, os.Stderr)
+}
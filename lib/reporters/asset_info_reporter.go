@@ -0,0 +1,38 @@
+package reporters
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/permafrost-dev/zeget/lib/assets"
+)
+
+// AssetInfoReporter writes the known metadata of an asset, such as its size,
+// release and release notes URL.
+type AssetInfoReporter struct {
+	Asset  *assets.Asset
+	Output io.Writer
+}
+
+func (r *AssetInfoReporter) Report(input ...interface{}) error {
+	if summary := r.Asset.Summary(); summary != "" {
+		fmt.Fprintf(r.Output, "› %s: %s\n", r.Asset.Name, summary)
+	}
+
+	if r.Asset.ContentType != "" {
+		fmt.Fprintf(r.Output, "› content type: %s\n", r.Asset.ContentType)
+	}
+
+	if r.Asset.ReleaseNotesURL != "" {
+		fmt.Fprintf(r.Output, "› release notes: %s\n", r.Asset.ReleaseNotesURL)
+	}
+
+	return nil
+}
+
+func NewAssetInfoReporter(asset *assets.Asset, output io.Writer) *AssetInfoReporter {
+	return &AssetInfoReporter{
+		Asset:  asset,
+		Output: output,
+	}
+}
@@ -0,0 +1,39 @@
+package reporters_test
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/permafrost-dev/zeget/lib/assets"
+	"github.com/permafrost-dev/zeget/lib/reporters"
+)
+
+var _ = Describe("AssetInfoReporter", func() {
+	var buffer *bytes.Buffer
+
+	BeforeEach(func() {
+		buffer = new(bytes.Buffer)
+	})
+
+	It("writes the known metadata of the asset", func() {
+		asset := &assets.Asset{
+			Name:            "tool_linux_amd64.tar.gz",
+			Size:            5 * 1024 * 1024,
+			ContentType:     "application/gzip",
+			DownloadCount:   42,
+			ReleaseTag:      "v1.2.0",
+			ReleaseNotesURL: "https://github.com/owner/tool/releases/tag/v1.2.0",
+		}
+
+		Expect(reporters.NewAssetInfoReporter(asset, buffer).Report()).To(Succeed())
+		Expect(buffer.String()).To(Equal("› tool_linux_amd64.tar.gz: 5.0 MiB, v1.2.0, 42 downloads\n" +
+			"› content type: application/gzip\n" +
+			"› release notes: https://github.com/owner/tool/releases/tag/v1.2.0\n"))
+	})
+
+	It("writes nothing when no metadata is known", func() {
+		Expect(reporters.NewAssetInfoReporter(&assets.Asset{Name: "tool"}, buffer).Report()).To(Succeed())
+		Expect(buffer.String()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,178 @@
+// Package sources holds the built-in source definitions that let zeget
+// install popular tools which are not published as GitHub releases, such as
+// `zeget terraform`. Definitions can be overridden from the user config.
+package sources
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/permafrost-dev/zeget/lib/finders"
+)
+
+//go:embed sources.toml
+var builtinDefinitions []byte
+
+// A Definition describes where to find the assets of a tool; the fields match
+// the index settings of repository sections in the config file.
+type Definition struct {
+	Name               string `toml:"name"`
+	Description        string `toml:"description"`
+	IndexURL           string `toml:"index_url"`
+	DownloadURL        string `toml:"download_url"`
+	VersionURL         string `toml:"version_url"`
+	ChecksumURL        string `toml:"checksum_url"`
+	IndexRegex         string `toml:"index_regex"`
+	IndexJSONPath      string `toml:"index_json_path"`
+	IndexNameField     string `toml:"index_name_field"`
+	IndexURLField      string `toml:"index_url_field"`
+	IndexVersionField  string `toml:"index_version_field"`
+	IndexChecksumField string `toml:"index_checksum_field"`
+}
+
+// IsEmpty returns true if the definition does not point at any assets.
+func (d Definition) IsEmpty() bool {
+	return d.IndexURL == "" && d.DownloadURL == ""
+}
+
+// Merge returns d with every non-empty field of override applied on top.
+func (d Definition) Merge(override Definition) Definition {
+	set := func(target *string, value string) {
+		if value != "" {
+			*target = value
+		}
+	}
+
+	set(&d.Name, override.Name)
+	set(&d.Description, override.Description)
+	set(&d.IndexURL, override.IndexURL)
+	set(&d.DownloadURL, override.DownloadURL)
+	set(&d.VersionURL, override.VersionURL)
+	set(&d.ChecksumURL, override.ChecksumURL)
+	set(&d.IndexRegex, override.IndexRegex)
+	set(&d.IndexJSONPath, override.IndexJSONPath)
+	set(&d.IndexNameField, override.IndexNameField)
+	set(&d.IndexURLField, override.IndexURLField)
+	set(&d.IndexVersionField, override.IndexVersionField)
+	set(&d.IndexChecksumField, override.IndexChecksumField)
+
+	return d
+}
+
+// Finder builds the index finder for the definition.
+func (d Definition) Finder() (*finders.IndexFinder, error) {
+	source := d.IndexURL
+	if source == "" {
+		source = d.DownloadURL
+	}
+
+	if d.IsEmpty() {
+		return nil, fmt.Errorf("source %s: either index_url or download_url is required", d.Name)
+	}
+
+	if d.DownloadURL == "" && d.IndexRegex == "" && d.IndexJSONPath == "" {
+		return nil, fmt.Errorf("index %s: either index_regex or index_json_path is required", source)
+	}
+
+	result := &finders.IndexFinder{
+		URL:           d.IndexURL,
+		DownloadURL:   d.DownloadURL,
+		VersionURL:    d.VersionURL,
+		ChecksumURL:   d.ChecksumURL,
+		JSONPath:      d.IndexJSONPath,
+		NameField:     d.IndexNameField,
+		URLField:      d.IndexURLField,
+		VersionField:  d.IndexVersionField,
+		ChecksumField: d.IndexChecksumField,
+	}
+
+	if d.IndexRegex != "" {
+		re, err := regexp.Compile(d.IndexRegex)
+		if err != nil {
+			return nil, fmt.Errorf("index %s: invalid index_regex: %w", source, err)
+		}
+		result.Regex = re
+	}
+
+	return result, nil
+}
+
+type builtinFile struct {
+	Version     int
+	Definitions map[string]Definition
+}
+
+var (
+	loadOnce sync.Once
+	builtin  builtinFile
+	loadErr  error
+)
+
+func load() (builtinFile, error) {
+	loadOnce.Do(func() {
+		var raw map[string]toml.Primitive
+
+		meta, err := toml.Decode(string(builtinDefinitions), &raw)
+		if err != nil {
+			loadErr = err
+			return
+		}
+
+		builtin.Definitions = map[string]Definition{}
+
+		for name, value := range raw {
+			if name == "version" {
+				loadErr = meta.PrimitiveDecode(value, &builtin.Version)
+				continue
+			}
+
+			var def Definition
+			if err := meta.PrimitiveDecode(value, &def); err != nil {
+				loadErr = fmt.Errorf("source %s: %w", name, err)
+				return
+			}
+
+			if def.Name == "" {
+				def.Name = name
+			}
+
+			builtin.Definitions[name] = def
+		}
+	})
+
+	return builtin, loadErr
+}
+
+// Version returns the version of the built-in definitions.
+func Version() int {
+	file, _ := load()
+	return file.Version
+}
+
+// Lookup returns the built-in definition for the given short name.
+func Lookup(name string) (Definition, bool) {
+	file, err := load()
+	if err != nil {
+		return Definition{}, false
+	}
+
+	def, found := file.Definitions[name]
+	return def, found
+}
+
+// Names returns the names of all built-in definitions, sorted.
+func Names() []string {
+	file, _ := load()
+
+	result := make([]string, 0, len(file.Definitions))
+	for name := range file.Definitions {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result
+}
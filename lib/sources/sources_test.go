@@ -0,0 +1,138 @@
+package sources_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/permafrost-dev/zeget/lib/download"
+	. "github.com/permafrost-dev/zeget/lib/sources"
+)
+
+// upstream responses served by the stub server, keyed by "host/path?query"
+var upstream = map[string]string{
+	"releases.hashicorp.com/terraform/index.json": `{"name": "terraform", "versions": {
+		"1.8.5": {"version": "1.8.5", "builds": [
+			{"os": "linux", "arch": "amd64", "version": "1.8.5", "filename": "terraform_1.8.5_linux_amd64.zip", "url": "https://releases.hashicorp.com/terraform/1.8.5/terraform_1.8.5_linux_amd64.zip"}
+		]},
+		"1.9.0": {"version": "1.9.0", "builds": [
+			{"os": "linux", "arch": "amd64", "version": "1.9.0", "filename": "terraform_1.9.0_linux_amd64.zip", "url": "https://releases.hashicorp.com/terraform/1.9.0/terraform_1.9.0_linux_amd64.zip"},
+			{"os": "darwin", "arch": "arm64", "version": "1.9.0", "filename": "terraform_1.9.0_darwin_arm64.zip", "url": "https://releases.hashicorp.com/terraform/1.9.0/terraform_1.9.0_darwin_arm64.zip"}
+		]},
+		"1.10.0-alpha1": {"version": "1.10.0-alpha1", "builds": [
+			{"os": "linux", "arch": "amd64", "version": "1.10.0-alpha1", "filename": "terraform_1.10.0-alpha1_linux_amd64.zip", "url": "https://releases.hashicorp.com/terraform/1.10.0-alpha1/terraform_1.10.0-alpha1_linux_amd64.zip"}
+		]}
+	}}`,
+	"dl.k8s.io/release/stable.txt": "v1.31.1\n",
+	"go.dev/dl/?mode=json&include=all": `[
+		{"version": "go1.24rc1", "stable": false, "files": [{"filename": "go1.24rc1.linux-amd64.tar.gz", "os": "linux", "arch": "amd64", "version": "go1.24rc1", "sha256": "aaa", "kind": "archive"}]},
+		{"version": "go1.23.2", "stable": true, "files": [
+			{"filename": "go1.23.2.src.tar.gz", "os": "", "arch": "", "version": "go1.23.2", "sha256": "src", "kind": "source"},
+			{"filename": "go1.23.2.linux-amd64.tar.gz", "os": "linux", "arch": "amd64", "version": "go1.23.2", "sha256": "bbb", "kind": "archive"},
+			{"filename": "go1.23.2.darwin-arm64.pkg", "os": "darwin", "arch": "arm64", "version": "go1.23.2", "sha256": "ccc", "kind": "installer"}
+		]},
+		{"version": "go1.22.8", "stable": true, "files": [{"filename": "go1.22.8.linux-amd64.tar.gz", "os": "linux", "arch": "amd64", "version": "go1.22.8", "sha256": "ddd", "kind": "archive"}]}
+	]`,
+}
+
+// rebase points every URL of a definition at the stub server, keeping the
+// original host as the first path element
+func rebase(def Definition, server string) Definition {
+	replace := func(s string) string {
+		return strings.Replace(s, "https://", server+"/", 1)
+	}
+
+	def.IndexURL = replace(def.IndexURL)
+	def.DownloadURL = replace(def.DownloadURL)
+	def.VersionURL = replace(def.VersionURL)
+	def.ChecksumURL = replace(def.ChecksumURL)
+
+	return def
+}
+
+var _ = Describe("Built-in sources", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := strings.TrimPrefix(r.URL.RequestURI(), "/")
+			if body, found := upstream[key]; found {
+				_, _ = w.Write([]byte(body))
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should have a version and sorted names", func() {
+		Expect(Version()).To(BeNumerically(">=", 1))
+		Expect(Names()).To(Equal([]string{"go", "kubectl", "terraform"}))
+	})
+
+	DescribeTable("finding assets",
+		func(name string, version string, wantNames []string, wantURL string, wantChecksum string, wantChecksumURL string) {
+			def, found := Lookup(name)
+			Expect(found).To(BeTrue())
+
+			finder, err := rebase(def, server.URL).Finder()
+			Expect(err).NotTo(HaveOccurred())
+
+			finder.Version = version
+			finder.OS = "linux"
+			finder.Arch = "amd64"
+
+			result := finder.Find(download.NewClient(""))
+			Expect(result.Error).NotTo(HaveOccurred())
+
+			names := []string{}
+			for _, a := range result.Assets {
+				names = append(names, a.Name)
+			}
+			Expect(names).To(Equal(wantNames))
+			Expect(result.Assets[0].DownloadURL).To(HaveSuffix(wantURL))
+			Expect(result.Assets[0].Checksum).To(Equal(wantChecksum))
+			Expect(result.Assets[0].ChecksumURL).To(HaveSuffix(wantChecksumURL))
+		},
+		Entry("terraform latest", "terraform", "latest",
+			[]string{"terraform_1.9.0_linux_amd64.zip", "terraform_1.9.0_darwin_arm64.zip"},
+			"/terraform/1.9.0/terraform_1.9.0_linux_amd64.zip", "", "/terraform/1.9.0/terraform_1.9.0_SHA256SUMS"),
+		Entry("terraform pinned", "terraform", "v1.8.5",
+			[]string{"terraform_1.8.5_linux_amd64.zip"},
+			"/terraform/1.8.5/terraform_1.8.5_linux_amd64.zip", "", "/terraform/1.8.5/terraform_1.8.5_SHA256SUMS"),
+		Entry("kubectl latest", "kubectl", "latest",
+			[]string{"kubectl"},
+			"dl.k8s.io/release/v1.31.1/bin/linux/amd64/kubectl", "", "dl.k8s.io/release/v1.31.1/bin/linux/amd64/kubectl.sha256"),
+		Entry("kubectl pinned", "kubectl", "v1.30.0",
+			[]string{"kubectl"},
+			"dl.k8s.io/release/v1.30.0/bin/linux/amd64/kubectl", "", "dl.k8s.io/release/v1.30.0/bin/linux/amd64/kubectl.sha256"),
+		Entry("go latest", "go", "latest",
+			[]string{"go1.23.2.linux-amd64.tar.gz"},
+			"go.dev/dl/go1.23.2.linux-amd64.tar.gz", "bbb", ""),
+		Entry("go pinned", "go", "1.22.8",
+			[]string{"go1.22.8.linux-amd64.tar.gz"},
+			"go.dev/dl/go1.22.8.linux-amd64.tar.gz", "ddd", ""),
+	)
+
+	It("should let user settings override built-in definitions", func() {
+		def, _ := Lookup("kubectl")
+		merged := def.Merge(Definition{VersionURL: "https://mirror.corp/kubectl/stable.txt"})
+
+		Expect(merged.VersionURL).To(Equal("https://mirror.corp/kubectl/stable.txt"))
+		Expect(merged.DownloadURL).To(Equal(def.DownloadURL))
+	})
+
+	It("should reject definitions without a source", func() {
+		_, err := Definition{Name: "empty"}.Finder()
+		Expect(err).To(HaveOccurred())
+
+		_, err = Definition{IndexURL: "https://example.com/"}.Finder()
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -121,6 +121,37 @@ func (rr *RepositoryReference) String() string {
 	return fmt.Sprintf("%s/%s", rr.Owner, rr.Name)
 }
 
+// An ActionsReference points at the artifacts of a GitHub Actions workflow, given
+// as "owner/repo@actions:workflow.yml[:branch]".
+type ActionsReference struct {
+	Repository RepositoryReference
+	Workflow   string
+	Branch     string
+}
+
+// IsActionsReference returns true if s looks like a workflow artifact reference.
+func IsActionsReference(s string) bool {
+	_, rest, found := strings.Cut(s, "@")
+	return found && strings.HasPrefix(rest, "actions:")
+}
+
+func ParseActionsReference(s string) (*ActionsReference, error) {
+	repo, rest, _ := strings.Cut(s, "@")
+	rest = strings.TrimPrefix(rest, "actions:")
+
+	ref, err := ParseRepositoryReference(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	workflow, branch, _ := strings.Cut(rest, ":")
+	if workflow == "" {
+		return nil, fmt.Errorf("no workflow given in actions reference: %s", s)
+	}
+
+	return &ActionsReference{Repository: *ref, Workflow: workflow, Branch: branch}, nil
+}
+
 // IsLocalFile returns true if the file at 's' exists.
 func IsLocalFile(s string) bool {
 	if s == "" {
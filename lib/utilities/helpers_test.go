@@ -133,6 +133,30 @@ var _ = Describe("Helpers", func() {
 		})
 	})
 
+	Describe("ParseActionsReference", func() {
+		It("parses workflow and branch", func() {
+			Expect(IsActionsReference("user/repo@actions:build.yml:main")).To(BeTrue())
+			Expect(IsActionsReference("user/repo")).To(BeFalse())
+
+			ref, err := ParseActionsReference("user/repo@actions:build.yml:main")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ref.Repository.String()).To(Equal("user/repo"))
+			Expect(ref.Workflow).To(Equal("build.yml"))
+			Expect(ref.Branch).To(Equal("main"))
+		})
+
+		It("allows the branch to be omitted", func() {
+			ref, err := ParseActionsReference("user/repo@actions:build.yml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ref.Branch).To(BeEmpty())
+		})
+
+		It("returns an error without a workflow", func() {
+			_, err := ParseActionsReference("user/repo@actions:")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Describe("IsLocalFile", func() {
 		It("checks if a file exists locally", func() {
 			filePath := filepath.Join(tempDir, "existent")
@@ -5,6 +5,9 @@ import (
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"net/http"
+
+	"github.com/permafrost-dev/zeget/lib/download"
 )
 
 type HashAlgorithm string
@@ -30,3 +33,13 @@ func DetermineHashTypeByLength(hash string) HashAlgorithm {
 
 	return "unknown"
 }
+
+// fetchChecksumFile requests a checksum asset. Release asset API URLs, used for
+// private repositories, only return the file contents when asked for a binary.
+func fetchChecksumFile(client download.ClientContract, url string) (*http.Response, error) {
+	if download.IsGithubAssetAPIURL(url) {
+		return client.GetBinaryFile(url)
+	}
+
+	return client.GetJSON(url)
+}
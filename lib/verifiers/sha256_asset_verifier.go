@@ -28,7 +28,7 @@ func (s256 *Sha256AssetVerifier) WithClient(client download.ClientContract) Veri
 }
 
 func (s256 *Sha256AssetVerifier) Verify(b []byte) error {
-	resp, err := s256.client.GetJSON(s256.AssetURL)
+	resp, err := fetchChecksumFile(s256.client, s256.AssetURL)
 
 	if err != nil {
 		return err
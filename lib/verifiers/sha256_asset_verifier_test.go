@@ -59,6 +59,17 @@ var _ = Describe("Sha256AssetVerifier", func() {
 			Expect(err).Should(BeAssignableToTypeOf(&verifiers.Sha256Error{}))
 		})
 
+		It("should request release asset API URLs as binary files", func() {
+			data := []byte("test data")
+			sum := sha256.Sum256(data)
+			apiURL := "https://api.github.com/repos/owner/repo/releases/assets/42"
+
+			mockClient.AddJSONResponse(apiURL, hex.EncodeToString(sum[0:]), 200)
+			verifier.AssetURL = apiURL
+
+			Expect(verifier.Verify(data)).Should(Succeed())
+		})
+
 		It("should fail if asset URL is not reachable", func() {
 			// Simulate HTTP error
 			mockClient.AddJSONResponse(assetURL, ``, 500)
@@ -31,7 +31,7 @@ func (s256 *Sha256SumFileAssetVerifier) WithClient(client download.ClientContrac
 
 func (s256 *Sha256SumFileAssetVerifier) Verify(b []byte) error {
 	got := sha256.Sum256(b)
-	resp1, err := s256.Client.GetJSON(s256.Sha256SumAssetURL)
+	resp1, err := fetchChecksumFile(s256.Client, s256.Sha256SumAssetURL)
 	if err != nil {
 		return err
 	}
@@ -56,7 +56,8 @@ func (s256 *Sha256SumFileAssetVerifier) Verify(b []byte) error {
 
 	expectedFound := false
 	scanner := bufio.NewScanner(resp1.Body)
-	sha256sumLinePattern := regexp.MustCompile(fmt.Sprintf("(%x)\\s+([\\w_\\-\\.]+)", got)) //, s256.BinaryName
+	// a file may also hold just the hash of a single asset
+	sha256sumLinePattern := regexp.MustCompile(fmt.Sprintf("(%x)(\\s+[\\w_\\-\\.]+|\\s*$)", got)) //, s256.BinaryName
 	for scanner.Scan() {
 		line := scanner.Bytes()
 		matches := sha256sumLinePattern.FindStringSubmatch(string(line))
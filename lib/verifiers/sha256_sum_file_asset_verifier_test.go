@@ -43,6 +43,16 @@ var _ = Describe("Sha256SumFileAssetVerifier", func() {
 			})
 		})
 
+		Context("when the file holds just the asset's checksum", func() {
+			It("should successfully verify the asset", func() {
+				mockClient.AddJSONResponse("https://example.com/sha256sums.txt", "cac0164a3e553aafd2d84f4e83c1aa3e30289eeaa2e4627e66af9b2413fd4a06\n", 200)
+
+				data := []byte("fake data")
+				err := verifier.Verify(data)
+				Expect(err).ShouldNot(HaveOccurred())
+			})
+		})
+
 		Context("when the asset's checksum does not match", func() {
 			It("should return an error", func() {
 				mockClient.AddJSONResponse("https://example.com/sha256sums.txt", "wrongchecksumhere  test-asset", 200)